@@ -88,6 +88,94 @@ func (c *CardServiceClient) CreateCard(ctx context.Context, setID, front, back s
 	}, nil
 }
 
+type CardInput struct {
+	Front    string
+	Back     string
+	ImageURL *string
+	AudioURL *string
+}
+
+type BatchCreateCardsResult struct {
+	CardIDs []string
+	// Rejected lists the cards the card service refused, which were left out.
+	Rejected []RejectedCard
+}
+
+type RejectedCard struct {
+	Index  int
+	Reason string
+}
+
+// BatchCreateCards creates the cards in one transaction. Cards the card service
+// rejects are left out and reported, and the rest are sent again; it fails only
+// if no card is accepted.
+func (c *CardServiceClient) BatchCreateCards(ctx context.Context, setID, ownerID string, cards []CardInput) (*BatchCreateCardsResult, error) {
+	resp, err := c.batchCreateCards(ctx, setID, ownerID, cards)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Applied {
+		return batchCreated(resp, nil), nil
+	}
+
+	var rejected []RejectedCard
+	accepted := make([]CardInput, 0, len(cards))
+	for _, item := range resp.Results {
+		if item.Error != "" {
+			rejected = append(rejected, RejectedCard{Index: int(item.Index), Reason: item.Error})
+		} else if int(item.Index) < len(cards) {
+			accepted = append(accepted, cards[item.Index])
+		}
+	}
+	if len(rejected) == 0 {
+		return nil, fmt.Errorf("cards rejected")
+	}
+	if len(accepted) == 0 {
+		return nil, fmt.Errorf("all %d cards rejected, card %d: %s", len(cards), rejected[0].Index+1, rejected[0].Reason)
+	}
+
+	resp, err = c.batchCreateCards(ctx, setID, ownerID, accepted)
+	if err != nil {
+		return nil, err
+	}
+	if !resp.Applied {
+		return nil, fmt.Errorf("cards rejected")
+	}
+	return batchCreated(resp, rejected), nil
+}
+
+func (c *CardServiceClient) batchCreateCards(ctx context.Context, setID, ownerID string, cards []CardInput) (*pb.BatchCreateCardsResponse, error) {
+	req := &pb.BatchCreateCardsRequest{
+		SetId:   setID,
+		OwnerId: ownerID,
+		Cards:   make([]*pb.CardInput, 0, len(cards)),
+	}
+	for _, card := range cards {
+		input := &pb.CardInput{Front: card.Front, Back: card.Back}
+		if card.ImageURL != nil {
+			input.ImageUrl = *card.ImageURL
+		}
+		if card.AudioURL != nil {
+			input.AudioUrl = *card.AudioURL
+		}
+		req.Cards = append(req.Cards, input)
+	}
+
+	resp, err := c.client.BatchCreateCards(ctx, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cards: %w", err)
+	}
+	return resp, nil
+}
+
+func batchCreated(resp *pb.BatchCreateCardsResponse, rejected []RejectedCard) *BatchCreateCardsResult {
+	result := &BatchCreateCardsResult{CardIDs: make([]string, 0, len(resp.Results)), Rejected: rejected}
+	for _, item := range resp.Results {
+		result.CardIDs = append(result.CardIDs, item.CardId)
+	}
+	return result
+}
+
 type DeleteCardSetResult struct{}
 
 func (c *CardServiceClient) DeleteCardSet(ctx context.Context, setID, ownerID string) (*DeleteCardSetResult, error) {
@@ -3,6 +3,7 @@ package services
 import (
 	"context"
 	"fmt"
+	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -171,16 +172,14 @@ func (s *AIService) processGeneration(ctx context.Context, taskID string, req Ge
 		return
 	}
 
-	// Create individual cards with progress updates
-	for i, card := range cards {
-		if err := s.createCard(ctx, setID, card.Front, card.Back, nil, nil); err != nil {
-			_ = s.deleteCardSet(ctx, setID, userID)
-			_ = s.taskStorage.FailTask(ctx, taskID, fmt.Sprintf("Failed to create card %d: %v", i+1, err))
-			return
-		}
-		// Update progress after each card
-		_ = s.taskStorage.UpdateProgress(ctx, taskID, i+1, models.TaskStatusProcessing)
+	// Create all cards in one batch
+	cards, err = s.createCards(ctx, setID, userID, cards)
+	if err != nil {
+		_ = s.deleteCardSet(ctx, setID, userID)
+		_ = s.taskStorage.FailTask(ctx, taskID, fmt.Sprintf("Failed to create cards: %v", err))
+		return
 	}
+	_ = s.taskStorage.UpdateProgress(ctx, taskID, len(cards), models.TaskStatusProcessing)
 
 	// Mark task as completed
 	if err := s.taskStorage.CompleteTask(ctx, taskID, setID); err != nil {
@@ -227,11 +226,10 @@ func (s *AIService) GenerateCards(ctx context.Context, userID string, req Genera
 		return nil, fmt.Errorf("create card set: %w", err)
 	}
 
-	for _, card := range cards {
-		if err := s.createCard(ctx, setID, card.Front, card.Back, nil, nil); err != nil {
-			_ = s.deleteCardSet(ctx, setID, userID)
-			return nil, fmt.Errorf("create card: %w", err)
-		}
+	cards, err = s.createCards(ctx, setID, userID, cards)
+	if err != nil {
+		_ = s.deleteCardSet(ctx, setID, userID)
+		return nil, fmt.Errorf("create cards: %w", err)
 	}
 
 	return &GenerateCardsResponse{
@@ -316,11 +314,10 @@ func (s *AIService) GenerateCardsFromImage(ctx context.Context, userID string, r
 		return nil, fmt.Errorf("create card set: %w", err)
 	}
 
-	for _, card := range cards {
-		if err := s.createCard(ctx, setID, card.Front, card.Back, nil, nil); err != nil {
-			_ = s.deleteCardSet(ctx, setID, userID)
-			return nil, fmt.Errorf("create card: %w", err)
-		}
+	cards, err = s.createCards(ctx, setID, userID, cards)
+	if err != nil {
+		_ = s.deleteCardSet(ctx, setID, userID)
+		return nil, fmt.Errorf("create cards: %w", err)
 	}
 
 	return &GenerateCardsResponse{
@@ -338,9 +335,23 @@ func (s *AIService) createCardSet(ctx context.Context, userID, name string, desc
 	return result.SetID, nil
 }
 
-func (s *AIService) createCard(ctx context.Context, setID, front, back string, imageURL, audioURL *string) error {
-	_, err := s.cardClient.CreateCard(ctx, setID, front, back, imageURL, audioURL)
-	return err
+// createCards adds all the cards to the set at once, so a failure leaves no
+// partially filled set behind. Generated cards the card service rejects are
+// skipped; it returns the cards created.
+func (s *AIService) createCards(ctx context.Context, setID, userID string, cards []GeneratedCard) ([]GeneratedCard, error) {
+	if len(cards) == 0 {
+		return cards, nil
+	}
+
+	inputs := make([]clients.CardInput, 0, len(cards))
+	for _, card := range cards {
+		inputs = append(inputs, clients.CardInput{Front: card.Front, Back: card.Back})
+	}
+	result, err := s.cardClient.BatchCreateCards(ctx, setID, userID, inputs)
+	if err != nil {
+		return nil, err
+	}
+	return skipRejected(cards, result.Rejected), nil
 }
 
 func (s *AIService) deleteCardSet(ctx context.Context, setID, userID string) error {
@@ -348,6 +359,26 @@ func (s *AIService) deleteCardSet(ctx context.Context, setID, userID string) err
 	return err
 }
 
+// skipRejected returns the cards without those the card service rejected.
+func skipRejected(cards []GeneratedCard, rejected []clients.RejectedCard) []GeneratedCard {
+	if len(rejected) == 0 {
+		return cards
+	}
+
+	skip := make(map[int]bool, len(rejected))
+	for _, r := range rejected {
+		log.Printf("Skipping generated card %d: %s", r.Index+1, r.Reason)
+		skip[r.Index] = true
+	}
+	result := make([]GeneratedCard, 0, len(cards)-len(rejected))
+	for i, card := range cards {
+		if !skip[i] {
+			result = append(result, card)
+		}
+	}
+	return result
+}
+
 // DeduplicateCards removes duplicate cards based on front+back combination
 func DeduplicateCards(cards []GeneratedCard) []GeneratedCard {
 	seen := make(map[string]bool)
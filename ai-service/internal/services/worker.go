@@ -92,23 +92,22 @@ func (w *WorkerService) ProcessTask(ctx context.Context, task *kafka.GenerationT
 		return fmt.Errorf("create card set: %w", err)
 	}
 
-	// Create individual cards with progress updates
-	for i, card := range cards {
-		if err := w.createCard(ctx, setID, card.Front, card.Back, nil, nil); err != nil {
-			_ = w.deleteCardSet(ctx, setID, task.UserID)
-			_ = w.taskStorage.FailTask(ctx, task.TaskID, fmt.Sprintf("Failed to create card %d: %v", i+1, err))
-			_ = w.kafkaProducer.PublishEvent(ctx, &kafka.GenerationEventMessage{
-				TaskID:    task.TaskID,
-				UserID:    task.UserID,
-				EventType: "task.failed",
-				Status:    "failed",
-				Error:     fmt.Sprintf("Failed to create card %d: %v", i+1, err),
-				Timestamp: time.Now(),
-			})
-			return fmt.Errorf("create card %d: %w", i+1, err)
-		}
-		_ = w.taskStorage.UpdateProgress(ctx, task.TaskID, i+1, models.TaskStatusProcessing)
+	// Create all cards in one batch
+	cards, err = w.createCards(ctx, setID, task.UserID, cards)
+	if err != nil {
+		_ = w.deleteCardSet(ctx, setID, task.UserID)
+		_ = w.taskStorage.FailTask(ctx, task.TaskID, fmt.Sprintf("Failed to create cards: %v", err))
+		_ = w.kafkaProducer.PublishEvent(ctx, &kafka.GenerationEventMessage{
+			TaskID:    task.TaskID,
+			UserID:    task.UserID,
+			EventType: "task.failed",
+			Status:    "failed",
+			Error:     fmt.Sprintf("Failed to create cards: %v", err),
+			Timestamp: time.Now(),
+		})
+		return fmt.Errorf("create cards: %w", err)
 	}
+	_ = w.taskStorage.UpdateProgress(ctx, task.TaskID, len(cards), models.TaskStatusProcessing)
 
 	// Mark task as completed
 	if err := w.taskStorage.CompleteTask(ctx, task.TaskID, setID); err != nil {
@@ -145,9 +144,23 @@ func (w *WorkerService) createCardSet(ctx context.Context, userID, name string,
 	return result.SetID, nil
 }
 
-func (w *WorkerService) createCard(ctx context.Context, setID, front, back string, imageURL, audioURL *string) error {
-	_, err := w.cardClient.CreateCard(ctx, setID, front, back, imageURL, audioURL)
-	return err
+// createCards adds all the cards to the set at once, so a failure leaves no
+// partially filled set behind. Generated cards the card service rejects are
+// skipped; it returns the cards created.
+func (w *WorkerService) createCards(ctx context.Context, setID, userID string, cards []GeneratedCard) ([]GeneratedCard, error) {
+	if len(cards) == 0 {
+		return cards, nil
+	}
+
+	inputs := make([]clients.CardInput, 0, len(cards))
+	for _, card := range cards {
+		inputs = append(inputs, clients.CardInput{Front: card.Front, Back: card.Back})
+	}
+	result, err := w.cardClient.BatchCreateCards(ctx, setID, userID, inputs)
+	if err != nil {
+		return nil, err
+	}
+	return skipRejected(cards, result.Rejected), nil
 }
 
 func (w *WorkerService) deleteCardSet(ctx context.Context, setID, userID string) error {
@@ -11,9 +11,11 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 
 	"github.com/gin-gonic/gin"
 	"github.com/karto4ki/karto4ki-backend/card-service/internal/config"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/fileclient"
 	"github.com/karto4ki/karto4ki-backend/card-service/internal/grpc"
 	"github.com/karto4ki/karto4ki-backend/card-service/internal/handlers"
 	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
@@ -35,20 +37,57 @@ func main() {
 
 	cardSetStorage := storage.NewCardSetStorage(db)
 	cardStorage := storage.NewCardStorage(db)
+	progressStorage := storage.NewCardProgressStorage(db)
 	sessionStorage := storage.NewStudySessionStorage(db)
 	statsStorage := storage.NewStatisticsStorage(db)
+	settingsStorage := storage.NewUserSettingsStorage(db)
+	quizSessionStorage := storage.NewQuizSessionStorage(db)
+	setViewStorage := storage.NewSetViewStorage(db)
+	setShareStorage := storage.NewSetShareStorage(db)
+	folderStorage := storage.NewFolderStorage(db)
+	trashStorage := storage.NewTrashStorage(db)
+	revisionStorage := storage.NewRevisionStorage(db)
+	reviewStorage := storage.NewReviewStorage(db)
 
 	userClient := userclient.NewClient("http://user-service:8080")
 
-	cardSetService := services.NewCardSetService(cardSetStorage, cardStorage, statsStorage, userClient)
-	cardService := services.NewCardService(cardSetStorage, cardStorage)
-	learningService := services.NewLearningService(cardSetStorage, cardStorage, sessionStorage, statsStorage)
-	quizService := services.NewQuizService(cardStorage)
+	fileClient, err := fileclient.NewClient(cfg.FileStorage.GRPCAddr, cfg.FileStorage.MediaURLPrefix)
+	if err != nil {
+		log.Fatalf("Failed to create file storage client: %v", err)
+	}
+	defer fileClient.Close()
+
+	authz := services.NewAuthorizer(cardSetStorage, cardStorage, setShareStorage)
+
+	cardSetService := services.NewCardSetService(cardSetStorage, cardStorage, statsStorage, setViewStorage, authz, userClient, services.ViewOptions{
+		DedupWindow:      time.Duration(cfg.Views.DedupWindowHours) * time.Hour,
+		TrendingWindow:   time.Duration(cfg.Views.TrendingWindowDays) * 24 * time.Hour,
+		TrendingHalfLife: time.Duration(cfg.Views.TrendingHalfLifeHours) * time.Hour,
+	})
+	cardService := services.NewCardService(cardSetStorage, cardStorage, progressStorage, authz)
+	learningService := services.NewLearningService(cardSetStorage, cardStorage, progressStorage, sessionStorage, statsStorage, settingsStorage, folderStorage, authz)
+	importService := services.NewImportService(cardSetStorage, fileClient, cfg.Import.MaxCards)
+	exportService := services.NewExportService(cardSetStorage, cardStorage, progressStorage, authz, fileClient)
+	quizService := services.NewQuizService(cardSetStorage, cardStorage, quizSessionStorage, learningService, authz, time.Duration(cfg.Quiz.SessionTTLMinutes)*time.Minute)
+	settingsService := services.NewSettingsService(settingsStorage)
+	sharingService := services.NewSharingService(setShareStorage, authz, userClient)
+	folderService := services.NewFolderService(folderStorage, authz)
+	revisionService := services.NewRevisionService(cardStorage, revisionStorage, authz, userClient)
+	reviewService := services.NewReviewService(reviewStorage, authz)
+	trashService := services.NewTrashService(trashStorage, authz, time.Duration(cfg.Trash.RetentionDays)*24*time.Hour)
 
 	cardSetHandler := handlers.NewCardSetHandler(cardSetService)
 	cardHandler := handlers.NewCardHandler(cardService)
 	learningHandler := handlers.NewLearningHandler(learningService)
 	quizHandler := handlers.NewQuizHandler(quizService)
+	settingsHandler := handlers.NewSettingsHandler(settingsService)
+	importHandler := handlers.NewImportHandler(importService, int64(cfg.Import.MaxFileSizeMB)<<20)
+	exportHandler := handlers.NewExportHandler(exportService)
+	sharingHandler := handlers.NewSharingHandler(sharingService)
+	folderHandler := handlers.NewFolderHandler(folderService)
+	trashHandler := handlers.NewTrashHandler(trashService)
+	revisionHandler := handlers.NewRevisionHandler(revisionService)
+	reviewHandler := handlers.NewReviewHandler(reviewService)
 
 	jwtConf := loadJWTConfig(cfg.JWT)
 	authMiddleware := auth.NewJWT(&auth.JWTConfig{
@@ -63,17 +102,48 @@ func main() {
 	sets := r.Group("/v1.0/sets", authMiddleware)
 	{
 		sets.GET("", cardSetHandler.GetCardSets)
+		sets.POST("/import", importHandler.ImportSet)
 		sets.GET("/:setId", cardSetHandler.GetCardSet)
 		sets.PUT("/:setId", cardSetHandler.UpdateCardSet)
 		sets.DELETE("/:setId", cardSetHandler.DeleteCardSet)
+		sets.POST("/:setId/restore", trashHandler.RestoreSet)
+		sets.PUT("/:setId/folder", folderHandler.MoveSet)
+		sets.GET("/:setId/export", exportHandler.ExportSet)
+		sets.GET("/:setId/diff", cardSetHandler.GetCloneDiff)
+		sets.POST("/:setId/pull", cardSetHandler.PullFromOriginal)
+		sets.GET("/:setId/revisions", revisionHandler.GetSetRevisions)
+		sets.POST("/:setId/revert", revisionHandler.RevertSet)
+
+		sets.GET("/:setId/shares", sharingHandler.GetShares)
+		sets.POST("/:setId/shares", sharingHandler.ShareSet)
+		sets.PUT("/:setId/shares/:userId", sharingHandler.UpdateShare)
+		sets.DELETE("/:setId/shares/:userId", sharingHandler.RemoveShare)
 
 		sets.GET("/:setId/cards", cardHandler.GetCards)
 		sets.POST("/:setId/cards", cardHandler.CreateCard)
+		sets.PUT("/:setId/cards/order", cardHandler.ReorderCards)
+		sets.POST("/:setId/cards\\:batch", cardHandler.BatchCreateCards)
+		sets.POST("/:setId/cards\\:batchUpdate", cardHandler.BatchUpdateCards)
+		sets.POST("/:setId/cards\\:batchDelete", cardHandler.BatchDeleteCards)
+		sets.POST("/:setId/cards\\:batchMove", cardHandler.BatchMoveCards)
 
 		sets.POST("/:setId/study", learningHandler.StartStudySession)
 		sets.GET("/:setId/stats", learningHandler.GetSetStatistics)
+		sets.GET("/:setId/leeches", learningHandler.GetLeeches)
 
 		sets.POST("/:setId/quiz/start", quizHandler.StartQuizSession)
+		sets.GET("/:setId/quiz/history", quizHandler.GetQuizHistory)
+	}
+
+	folders := r.Group("/v1.0/folders", authMiddleware)
+	{
+		folders.GET("", folderHandler.GetFolderContents)
+		folders.POST("", folderHandler.CreateFolder)
+		folders.GET("/:folderId", folderHandler.GetFolderContents)
+		folders.PUT("/:folderId", folderHandler.RenameFolder)
+		folders.PUT("/:folderId/parent", folderHandler.MoveFolder)
+		folders.DELETE("/:folderId", folderHandler.DeleteFolder)
+		folders.POST("/:folderId/study", learningHandler.StartStudySessionFolder)
 	}
 
 	cards := r.Group("/v1.0/cards", authMiddleware)
@@ -81,15 +151,26 @@ func main() {
 		cards.GET("/:cardId", cardHandler.GetCard)
 		cards.PUT("/:cardId", cardHandler.UpdateCard)
 		cards.DELETE("/:cardId", cardHandler.DeleteCard)
+		cards.POST("/:cardId/restore", trashHandler.RestoreCard)
+		cards.GET("/:cardId/revisions", revisionHandler.GetCardRevisions)
+		cards.POST("/:cardId/revert", revisionHandler.RevertCard)
+		cards.GET("/:cardId/stats", reviewHandler.GetCardStats)
+		cards.GET("/:cardId/reviews", reviewHandler.GetCardReviews)
+		cards.POST("/:cardId/suspend", learningHandler.SuspendCard)
+		cards.POST("/:cardId/unsuspend", learningHandler.UnsuspendCard)
+		cards.POST("/:cardId/bury", learningHandler.BuryCard)
 	}
 
 	study := r.Group("/v1.0/study", authMiddleware)
 	{
+		study.GET("/:sessionId", learningHandler.GetStudySession)
 		study.POST("/:sessionId/answer", learningHandler.SubmitAnswer)
+		study.POST("/:sessionId/finish", learningHandler.FinishStudySession)
 	}
 
 	quiz := r.Group("/v1.0/quiz", authMiddleware)
 	{
+		quiz.GET("/:sessionId", quizHandler.GetQuizSession)
 		quiz.POST("/:sessionId/answer", quizHandler.SubmitAnswer)
 		quiz.POST("/:sessionId/finish", quizHandler.FinishQuiz)
 	}
@@ -97,13 +178,24 @@ func main() {
 	search := r.Group("/v1.0/search", authMiddleware)
 	{
 		search.GET("", cardSetHandler.SearchPublicSets)
+		search.GET("/tags", cardSetHandler.SuggestTags)
+		search.GET("/trending", cardSetHandler.GetTrendingSets)
 		search.POST("/:setId/clone", cardSetHandler.CloneSet)
 	}
 
 	me := r.Group("/v1.0/me", authMiddleware)
 	{
 		me.GET("/stats", learningHandler.GetUserStatistics)
+		me.GET("/reviews/export", reviewHandler.ExportReviews)
+		me.GET("/tags", cardSetHandler.GetUserTags)
+		me.GET("/shared", sharingHandler.GetSharedSets)
+		me.GET("/trash", trashHandler.GetTrash)
+		me.GET("/invitations", sharingHandler.GetInvitations)
+		me.POST("/invitations/:setId/accept", sharingHandler.AcceptInvitation)
+		me.POST("/invitations/:setId/decline", sharingHandler.DeclineInvitation)
 		me.POST("/study-all", learningHandler.StartStudySessionAll)
+		me.GET("/settings", settingsHandler.GetSettings)
+		me.PUT("/settings", settingsHandler.UpdateSettings)
 	}
 
 	httpAddr := fmt.Sprintf(":%d", cfg.HTTPPort)
@@ -124,6 +216,33 @@ func main() {
 		log.Fatalf("Failed to listen on gRPC port %d: %v", cfg.GRPCPort, err)
 	}
 
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+
+	go runPeriodically(jobsCtx, "quiz session cleanup", time.Duration(cfg.Quiz.CleanupIntervalMinutes)*time.Minute, func(ctx context.Context) error {
+		deleted, err := quizService.CleanupExpiredSessions(ctx)
+		if err == nil && deleted > 0 {
+			log.Printf("Removed %d expired quiz sessions", deleted)
+		}
+		return err
+	})
+
+	go runPeriodically(jobsCtx, "set views cleanup", time.Duration(cfg.Views.CleanupIntervalHours)*time.Hour, func(ctx context.Context) error {
+		updated, err := cardSetService.CleanupViews(ctx)
+		if err == nil && updated > 0 {
+			log.Printf("Recounted views of %d sets", updated)
+		}
+		return err
+	})
+
+	go runPeriodically(jobsCtx, "trash purge", time.Duration(cfg.Trash.PurgeIntervalHours)*time.Hour, func(ctx context.Context) error {
+		purged, err := trashService.PurgeExpired(ctx)
+		if err == nil && purged > 0 {
+			log.Printf("Purged %d sets and cards from the trash", purged)
+		}
+		return err
+	})
+
 	grpcServer := grpcLib.NewServer()
 	pb.RegisterCardServiceServer(grpcServer, grpc.NewCardGRPCService(cardSetService, cardService))
 
@@ -138,6 +257,7 @@ func main() {
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 	log.Println("Shutting down servers...")
+	stopJobs()
 
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -167,6 +287,28 @@ func loadConfig() config.Config {
 			Audience:      []string{"card_service"},
 			KeyFilePath:   "/app/keys/rsa.pub",
 		},
+		Quiz: config.QuizConfig{
+			SessionTTLMinutes:      120,
+			CleanupIntervalMinutes: 30,
+		},
+		FileStorage: config.FileStorageConfig{
+			GRPCAddr:       "filestorage-service:9090",
+			MediaURLPrefix: "http://localhost/api/storage/v1.0/files",
+		},
+		Import: config.ImportConfig{
+			MaxFileSizeMB: 50,
+			MaxCards:      5000,
+		},
+		Views: config.ViewsConfig{
+			DedupWindowHours:      24,
+			CleanupIntervalHours:  24,
+			TrendingWindowDays:    7,
+			TrendingHalfLifeHours: 48,
+		},
+		Trash: config.TrashConfig{
+			RetentionDays:      30,
+			PurgeIntervalHours: 6,
+		},
 	}
 
 	data, err := os.ReadFile("config.yml")
@@ -179,6 +321,23 @@ func loadConfig() config.Config {
 	return cfg
 }
 
+// runPeriodically runs job every interval until ctx is cancelled.
+func runPeriodically(ctx context.Context, name string, interval time.Duration, job func(ctx context.Context) error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := job(ctx); err != nil {
+				log.Printf("%s failed: %v", name, err)
+			}
+		}
+	}
+}
+
 func loadJWTConfig(cfg config.JWTConfig) *jwt.Config {
 	config := &jwt.Config{
 		SigningMethod: cfg.SigningMethod,
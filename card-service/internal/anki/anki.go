@@ -0,0 +1,116 @@
+// Package anki reads and writes Anki .apkg packages: a zip archive with the
+// collection as an SQLite database and media files stored under numeric names.
+package anki
+
+import (
+	"errors"
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrInvalidPackage means the file is not an .apkg archive Anki could have written.
+	ErrInvalidPackage = errors.New("invalid anki package")
+	// ErrUnsupportedVersion is returned for packages with only the newer compressed
+	// collection format, which have to be exported with "Support older Anki versions".
+	ErrUnsupportedVersion = errors.New("unsupported anki package version")
+	// ErrTooLarge is returned for archive entries that decompress to more than
+	// their size limit, as a zip bomb would.
+	ErrTooLarge = errors.New("anki package entry is too large")
+)
+
+// fieldSeparator separates note fields in the notes.flds column.
+const fieldSeparator = "\x1f"
+
+// ModelType tells basic note types from cloze ones.
+type ModelType int
+
+const (
+	ModelStandard ModelType = 0
+	ModelCloze    ModelType = 1
+)
+
+// Note is a single Anki note with its fields in the order of its note type.
+type Note struct {
+	ModelName  string
+	ModelType  ModelType
+	FieldNames []string
+	Fields     []string
+	Tags       []string
+}
+
+// Field returns the first field whose name matches one of names, ignoring case.
+func (n Note) Field(names ...string) (string, bool) {
+	for _, name := range names {
+		for i, fieldName := range n.FieldNames {
+			if strings.EqualFold(fieldName, name) && i < len(n.Fields) {
+				return n.Fields[i], true
+			}
+		}
+	}
+	return "", false
+}
+
+var (
+	imageRe     = regexp.MustCompile(`(?i)<img[^>]*\ssrc\s*=\s*["']?([^"'>\s]+)["']?[^>]*>`)
+	soundRe     = regexp.MustCompile(`\[sound:([^\]]+)\]`)
+	lineBreakRe = regexp.MustCompile(`(?i)<br\s*/?>|</div>|</p>|</li>`)
+	tagRe       = regexp.MustCompile(`<[^>]*>`)
+	clozeRe     = regexp.MustCompile(`\{\{c\d+::(.*?)(?:::(.*?))?\}\}`)
+)
+
+// FieldContent turns an HTML note field into plain text and collects the media
+// files it references: images from <img> tags and audio from [sound:] tags.
+func FieldContent(field string) (text string, images, sounds []string) {
+	for _, m := range imageRe.FindAllStringSubmatch(field, -1) {
+		images = append(images, html.UnescapeString(m[1]))
+	}
+	for _, m := range soundRe.FindAllStringSubmatch(field, -1) {
+		sounds = append(sounds, m[1])
+	}
+
+	text = soundRe.ReplaceAllString(field, "")
+	text = lineBreakRe.ReplaceAllString(text, "\n")
+	text = tagRe.ReplaceAllString(text, "")
+	text = html.UnescapeString(text)
+	text = strings.ReplaceAll(text, "\u00a0", " ")
+
+	lines := strings.Split(text, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n"), images, sounds
+}
+
+// RenderCloze renders the text of a cloze note with every deletion either hidden
+// as [...] (or [hint] when it has one) or revealed.
+func RenderCloze(text string, reveal bool) string {
+	return clozeRe.ReplaceAllStringFunc(text, func(m string) string {
+		parts := clozeRe.FindStringSubmatch(m)
+		if reveal {
+			return parts[1]
+		}
+		if parts[2] != "" {
+			return "[" + parts[2] + "]"
+		}
+		return "[...]"
+	})
+}
+
+// FieldHTML is the reverse of FieldContent: it turns plain text into a note
+// field and appends references to the given media files.
+func FieldHTML(text string, images, sounds []string) string {
+	var b strings.Builder
+	b.WriteString(strings.ReplaceAll(html.EscapeString(text), "\n", "<br>"))
+	for _, image := range images {
+		b.WriteString(`<br><img src="` + html.EscapeString(image) + `">`)
+	}
+	for _, sound := range sounds {
+		b.WriteString("[sound:" + sound + "]")
+	}
+	return b.String()
+}
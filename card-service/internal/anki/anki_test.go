@@ -0,0 +1,175 @@
+package anki_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/anki"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	_ "modernc.org/sqlite"
+)
+
+func TestFieldContent(t *testing.T) {
+	tests := []struct {
+		name           string
+		field          string
+		expectedText   string
+		expectedImages []string
+		expectedSounds []string
+	}{
+		{"Plain", "cat", "cat", nil, nil},
+		{"Entities and tags", "<b>Tom &amp; Jerry</b>&nbsp;show", "Tom & Jerry show", nil, nil},
+		{"Line breaks", "<div>one</div><div>two<br>three</div>", "one\ntwo\nthree", nil, nil},
+		{"Media", `кошка<img src="cat.jpg">[sound:cat.mp3]`, "кошка", []string{"cat.jpg"}, []string{"cat.mp3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, images, sounds := anki.FieldContent(tt.field)
+			assert.Equal(t, tt.expectedText, text)
+			assert.Equal(t, tt.expectedImages, images)
+			assert.Equal(t, tt.expectedSounds, sounds)
+		})
+	}
+}
+
+func TestRenderCloze(t *testing.T) {
+	text := "{{c1::Paris}} is the capital of {{c2::France::country}}"
+
+	assert.Equal(t, "[...] is the capital of [country]", anki.RenderCloze(text, false))
+	assert.Equal(t, "Paris is the capital of France", anki.RenderCloze(text, true))
+}
+
+// buildPackage writes a minimal legacy .apkg with the given notes and media.
+func buildPackage(t *testing.T, notes [][2]string, media map[string][]byte) []byte {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "collection.anki2")
+	db, err := sql.Open("sqlite", path)
+	require.NoError(t, err)
+
+	_, err = db.Exec(`CREATE TABLE col (models TEXT); CREATE TABLE notes (id INTEGER, mid INTEGER, tags TEXT, flds TEXT)`)
+	require.NoError(t, err)
+	_, err = db.Exec(`INSERT INTO col VALUES (?)`, `{"1": {"name": "Basic", "type": 0, "flds": [{"name": "Back", "ord": 1}, {"name": "Front", "ord": 0}]}}`)
+	require.NoError(t, err)
+	for i, note := range notes {
+		_, err = db.Exec(`INSERT INTO notes VALUES (?, 1, ' tag ', ?)`, i+1, note[0]+"\x1f"+note[1])
+		require.NoError(t, err)
+	}
+	require.NoError(t, db.Close())
+
+	collection, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.Create("collection.anki2")
+	require.NoError(t, err)
+	_, err = w.Write(collection)
+	require.NoError(t, err)
+
+	index := "{"
+	i := 0
+	for name, data := range media {
+		entry := string(rune('0' + i))
+		w, err := zw.Create(entry)
+		require.NoError(t, err)
+		_, err = w.Write(data)
+		require.NoError(t, err)
+		if i > 0 {
+			index += ","
+		}
+		index += `"` + entry + `": "` + name + `"`
+		i++
+	}
+	w, err = zw.Create("media")
+	require.NoError(t, err)
+	_, err = w.Write([]byte(index + "}"))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	return buf.Bytes()
+}
+
+func TestOpen(t *testing.T) {
+	data := buildPackage(t, [][2]string{{"cat", "кошка"}, {"dog<img src=\"dog.png\">", "собака"}}, map[string][]byte{"dog.png": []byte("png")})
+
+	pkg, err := anki.Open(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+	require.Len(t, pkg.Notes, 2)
+
+	note := pkg.Notes[0]
+	assert.Equal(t, "Basic", note.ModelName)
+	assert.Equal(t, []string{"Front", "Back"}, note.FieldNames)
+	assert.Equal(t, []string{"tag"}, note.Tags)
+	front, _ := note.Field("front")
+	assert.Equal(t, "cat", front)
+
+	media, found, err := pkg.Media("dog.png")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("png"), media)
+
+	_, found, err = pkg.Media("missing.png")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestOpen_TooLarge(t *testing.T) {
+	data := buildPackage(t, [][2]string{{"cat<img src=\"big.png\">", "кошка"}}, map[string][]byte{"big.png": make([]byte, anki.MaxMediaSize+1)})
+
+	pkg, err := anki.Open(bytes.NewReader(data), int64(len(data)))
+	require.NoError(t, err)
+
+	_, found, err := pkg.Media("big.png")
+	assert.True(t, found)
+	assert.ErrorIs(t, err, anki.ErrTooLarge)
+}
+
+func TestOpen_Invalid(t *testing.T) {
+	_, err := anki.Open(bytes.NewReader([]byte("not a zip")), 9)
+	assert.ErrorIs(t, err, anki.ErrInvalidPackage)
+}
+
+func TestWrite_RoundTrip(t *testing.T) {
+	deck := anki.Deck{
+		Name: "Animals",
+		Notes: []anki.ExportNote{
+			{GUID: "a", Front: anki.FieldHTML("cat & kitten", nil, nil), Back: anki.FieldHTML("кошка\nкотёнок", []string{"cat.jpg"}, []string{"cat.mp3"}), Tags: []string{"animals"}},
+			{GUID: "b", Front: "dog", Back: "собака", Schedule: &anki.Schedule{IntervalDays: 10, Due: time.Now().AddDate(0, 0, 3), Ease: 2.5, Reps: 4}},
+		},
+		Media: map[string][]byte{"cat.jpg": []byte("jpg"), "cat.mp3": []byte("mp3")},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, anki.Write(&buf, deck))
+
+	pkg, err := anki.Open(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	require.Len(t, pkg.Notes, 2)
+
+	note := pkg.Notes[0]
+	assert.Equal(t, []string{"Front", "Back"}, note.FieldNames)
+	assert.Equal(t, []string{"animals"}, note.Tags)
+
+	front, _ := note.Field("Front")
+	text, _, _ := anki.FieldContent(front)
+	assert.Equal(t, "cat & kitten", text)
+
+	back, _ := note.Field("Back")
+	text, images, sounds := anki.FieldContent(back)
+	assert.Equal(t, "кошка\nкотёнок", text)
+	assert.Equal(t, []string{"cat.jpg"}, images)
+	assert.Equal(t, []string{"cat.mp3"}, sounds)
+
+	media, found, err := pkg.Media("cat.mp3")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, []byte("mp3"), media)
+}
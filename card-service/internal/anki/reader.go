@@ -0,0 +1,216 @@
+package anki
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+// Size limits of archive entries once decompressed.
+const (
+	MaxCollectionSize = 256 << 20
+	MaxMediaSize      = 20 << 20
+	maxMediaIndexSize = 16 << 20
+)
+
+// Package is an opened .apkg file. Media is read lazily, so the archive has to
+// stay readable while the package is in use.
+type Package struct {
+	Notes []Note
+	// media maps file names used in note fields to their entries in the archive.
+	media map[string]*zip.File
+}
+
+// Open reads the notes of an .apkg archive.
+func Open(r io.ReaderAt, size int64) (*Package, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPackage, err)
+	}
+
+	entries := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		entries[f.Name] = f
+	}
+
+	collection := entries["collection.anki21"]
+	if collection == nil {
+		collection = entries["collection.anki2"]
+	}
+	if collection == nil {
+		if entries["collection.anki21b"] != nil {
+			return nil, ErrUnsupportedVersion
+		}
+		return nil, fmt.Errorf("%w: no collection in archive", ErrInvalidPackage)
+	}
+
+	notes, err := readCollection(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	media, err := readMediaIndex(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Package{Notes: notes, media: media}, nil
+}
+
+// Media returns the contents of a media file referenced by a note.
+func (p *Package) Media(name string) ([]byte, bool, error) {
+	f, ok := p.media[name]
+	if !ok {
+		return nil, false, nil
+	}
+
+	var data bytes.Buffer
+	if err := copyEntry(&data, f, MaxMediaSize); err != nil {
+		return nil, true, err
+	}
+	return data.Bytes(), true, nil
+}
+
+func readMediaIndex(entries map[string]*zip.File) (map[string]*zip.File, error) {
+	media := make(map[string]*zip.File)
+
+	index := entries["media"]
+	if index == nil {
+		return media, nil
+	}
+
+	var data bytes.Buffer
+	if err := copyEntry(&data, index, maxMediaIndexSize); err != nil {
+		return nil, err
+	}
+
+	// The media index maps archive entry names ("0", "1", ...) to original file names
+	var names map[string]string
+	if err := json.Unmarshal(data.Bytes(), &names); err != nil {
+		return nil, fmt.Errorf("%w: unreadable media index: %v", ErrUnsupportedVersion, err)
+	}
+
+	for entry, name := range names {
+		if f := entries[entry]; f != nil {
+			media[name] = f
+		}
+	}
+	return media, nil
+}
+
+type noteModel struct {
+	Name   string    `json:"name"`
+	Type   ModelType `json:"type"`
+	Fields []struct {
+		Name string `json:"name"`
+		Ord  int    `json:"ord"`
+	} `json:"flds"`
+}
+
+// readCollection copies the collection out of the archive, since SQLite needs
+// a real file, and reads its notes in creation order.
+func readCollection(collection *zip.File) ([]Note, error) {
+	dir, err := os.MkdirTemp("", "apkg-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "collection.sqlite")
+	if err := extract(collection, path); err != nil {
+		return nil, err
+	}
+
+	db, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var modelsJSON string
+	if err := db.QueryRow(`SELECT models FROM col`).Scan(&modelsJSON); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPackage, err)
+	}
+
+	var noteModels map[string]noteModel
+	if err := json.Unmarshal([]byte(modelsJSON), &noteModels); err != nil {
+		return nil, fmt.Errorf("%w: unreadable note types: %v", ErrInvalidPackage, err)
+	}
+
+	rows, err := db.Query(`SELECT mid, tags, flds FROM notes ORDER BY id`)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPackage, err)
+	}
+	defer rows.Close()
+
+	var notes []Note
+	for rows.Next() {
+		var modelID int64
+		var tags, fields string
+		if err := rows.Scan(&modelID, &tags, &fields); err != nil {
+			return nil, err
+		}
+
+		note := Note{
+			Fields: strings.Split(fields, fieldSeparator),
+			Tags:   strings.Fields(tags),
+		}
+		if model, ok := noteModels[strconv.FormatInt(modelID, 10)]; ok {
+			note.ModelName = model.Name
+			note.ModelType = model.Type
+			sort.Slice(model.Fields, func(i, j int) bool { return model.Fields[i].Ord < model.Fields[j].Ord })
+			for _, field := range model.Fields {
+				note.FieldNames = append(note.FieldNames, field.Name)
+			}
+		}
+		notes = append(notes, note)
+	}
+	return notes, rows.Err()
+}
+
+func extract(f *zip.File, path string) error {
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := copyEntry(out, f, MaxCollectionSize); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// copyEntry decompresses an archive entry into w. Entries that claim to be
+// larger than limit are refused up front, and reading stops once limit is
+// passed, whatever the entry claims.
+func copyEntry(w io.Writer, f *zip.File, limit int64) error {
+	if f.UncompressedSize64 > uint64(limit) {
+		return fmt.Errorf("%w: %s", ErrTooLarge, f.Name)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+
+	n, err := io.Copy(w, io.LimitReader(rc, limit+1))
+	if err != nil {
+		return err
+	}
+	if n > limit {
+		return fmt.Errorf("%w: %s", ErrTooLarge, f.Name)
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package anki
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Deck is what Write puts into a package: one deck of notes of a basic
+// Front/Back note type, each producing a single card.
+type Deck struct {
+	Name        string
+	Description string
+	Notes       []ExportNote
+	// Media maps file names referenced from note fields to their contents.
+	Media map[string][]byte
+}
+
+// ExportNote is a note to write. Front and Back are HTML.
+type ExportNote struct {
+	GUID  string
+	Front string
+	Back  string
+	Tags  []string
+	// Schedule is nil for cards that should be imported as new.
+	Schedule *Schedule
+}
+
+// Schedule is the review state of a card that has been studied.
+type Schedule struct {
+	IntervalDays int
+	Due          time.Time
+	// Ease is the SM-2 ease factor, e.g. 2.5.
+	Ease   float64
+	Reps   int
+	Lapses int
+}
+
+const collectionSchema = `
+CREATE TABLE col (id integer primary key, crt integer not null, mod integer not null, scm integer not null, ver integer not null,
+	dty integer not null, usn integer not null, ls integer not null, conf text not null, models text not null,
+	decks text not null, dconf text not null, tags text not null);
+CREATE TABLE notes (id integer primary key, guid text not null, mid integer not null, mod integer not null, usn integer not null,
+	tags text not null, flds text not null, sfld integer not null, csum integer not null, flags integer not null, data text not null);
+CREATE TABLE cards (id integer primary key, nid integer not null, did integer not null, ord integer not null, mod integer not null,
+	usn integer not null, type integer not null, queue integer not null, due integer not null, ivl integer not null,
+	factor integer not null, reps integer not null, lapses integer not null, left integer not null, odue integer not null,
+	odid integer not null, flags integer not null, data text not null);
+CREATE TABLE revlog (id integer primary key, cid integer not null, usn integer not null, ease integer not null, ivl integer not null,
+	lastIvl integer not null, factor integer not null, time integer not null, type integer not null);
+CREATE TABLE graves (usn integer not null, oid integer not null, type integer not null);
+CREATE INDEX ix_notes_usn on notes (usn);
+CREATE INDEX ix_cards_usn on cards (usn);
+CREATE INDEX ix_revlog_usn on revlog (usn);
+CREATE INDEX ix_cards_nid on cards (nid);
+CREATE INDEX ix_cards_sched on cards (did, queue, due);
+CREATE INDEX ix_revlog_cid on revlog (cid);
+CREATE INDEX ix_notes_csum on notes (csum);
+`
+
+const cardCSS = `.card {
+ font-family: arial;
+ font-size: 20px;
+ text-align: center;
+ color: black;
+ background-color: white;
+}`
+
+// Write writes the deck as a legacy (collection.anki2) .apkg, which every Anki
+// version can import.
+func Write(w io.Writer, deck Deck) error {
+	dir, err := os.MkdirTemp("", "apkg-*")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "collection.anki2")
+	if err := writeCollection(path, deck, time.Now()); err != nil {
+		return err
+	}
+
+	zw := zip.NewWriter(w)
+	if err := addFile(zw, "collection.anki2", path); err != nil {
+		return err
+	}
+
+	index := make(map[string]string, len(deck.Media))
+	i := 0
+	for name, data := range deck.Media {
+		entry := strconv.Itoa(i)
+		f, err := zw.Create(entry)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(data); err != nil {
+			return err
+		}
+		index[entry] = name
+		i++
+	}
+
+	f, err := zw.Create("media")
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(f).Encode(index); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func addFile(zw *zip.Writer, name, path string) error {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(dst, src)
+	return err
+}
+
+func writeCollection(path string, deck Deck, now time.Time) error {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(collectionSchema); err != nil {
+		return err
+	}
+
+	// Ids in Anki are creation times in milliseconds; consecutive values keep them unique
+	baseID := now.UnixMilli()
+	modelID, deckID := baseID, baseID+1
+	created := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	conf, models, decks, dconf, err := collectionJSON(deck, modelID, deckID, now)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`INSERT INTO col VALUES (1, ?, ?, ?, 11, 0, 0, 0, ?, ?, ?, ?, '{}')`,
+		created.Unix(), now.UnixMilli(), now.UnixMilli(), conf, models, decks, dconf)
+	if err != nil {
+		return err
+	}
+
+	for i, note := range deck.Notes {
+		noteID := baseID + 2 + int64(i)
+		front := note.Front
+		sortField := stripHTML(front)
+
+		tags := ""
+		if len(note.Tags) > 0 {
+			tags = " " + strings.Join(note.Tags, " ") + " "
+		}
+
+		_, err := tx.Exec(`INSERT INTO notes VALUES (?, ?, ?, ?, -1, ?, ?, ?, ?, 0, '')`,
+			noteID, note.GUID, modelID, now.Unix(), tags, front+fieldSeparator+note.Back, sortField, checksum(sortField))
+		if err != nil {
+			return err
+		}
+
+		// New cards: type and queue 0, due is the position. Review cards: type and
+		// queue 2, due is the day number counted from the collection's creation.
+		cardType, due, interval, factor, reps, lapses := 0, i+1, 0, 0, 0, 0
+		if s := note.Schedule; s != nil {
+			cardType = 2
+			due = int(s.Due.Sub(created).Hours() / 24)
+			interval = max(s.IntervalDays, 1)
+			factor = int(s.Ease * 1000)
+			reps, lapses = s.Reps, s.Lapses
+		}
+
+		_, err = tx.Exec(`INSERT INTO cards VALUES (?, ?, ?, 0, ?, -1, ?, ?, ?, ?, ?, ?, ?, 0, 0, 0, 0, '')`,
+			noteID, noteID, deckID, now.Unix(), cardType, cardType, due, interval, factor, reps, lapses)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func collectionJSON(deck Deck, modelID, deckID int64, now time.Time) (conf, models, decks, dconf string, err error) {
+	field := func(name string, ord int) map[string]any {
+		return map[string]any{"name": name, "ord": ord, "sticky": false, "rtl": false, "font": "Arial", "size": 20, "media": []any{}}
+	}
+	deckJSON := func(id int64, name, desc string) map[string]any {
+		return map[string]any{
+			"id": id, "name": name, "desc": desc, "mod": now.Unix(), "usn": -1, "collapsed": false, "dyn": 0, "conf": 1,
+			"extendNew": 10, "extendRev": 50,
+			"newToday": []int{0, 0}, "revToday": []int{0, 0}, "lrnToday": []int{0, 0}, "timeToday": []int{0, 0},
+		}
+	}
+
+	parts := []any{
+		map[string]any{
+			"nextPos": len(deck.Notes) + 1, "estTimes": true, "activeDecks": []int64{1}, "sortType": "noteFld", "timeLim": 0,
+			"sortBackwards": false, "addToCur": true, "curDeck": 1, "newBury": true, "newSpread": 0, "dueCounts": true,
+			"curModel": strconv.FormatInt(modelID, 10), "collapseTime": 1200,
+		},
+		map[string]any{
+			strconv.FormatInt(modelID, 10): map[string]any{
+				"id": modelID, "name": "Basic (karto4ki)", "type": 0, "mod": now.Unix(), "usn": -1, "sortf": 0, "did": deckID,
+				"tmpls": []any{map[string]any{
+					"name": "Card 1", "ord": 0, "qfmt": "{{Front}}", "afmt": "{{FrontSide}}\n\n<hr id=answer>\n\n{{Back}}",
+					"did": nil, "bqfmt": "", "bafmt": "",
+				}},
+				"flds":      []any{field("Front", 0), field("Back", 1)},
+				"css":       cardCSS,
+				"latexPre":  "\\documentclass[12pt]{article}\n\\special{papersize=3in,5in}\n\\usepackage[utf8]{inputenc}\n\\usepackage{amssymb,amsmath}\n\\pagestyle{empty}\n\\setlength{\\parindent}{0in}\n\\begin{document}\n",
+				"latexPost": "\\end{document}",
+				"tags":      []any{},
+				"vers":      []any{},
+				"req":       []any{[]any{0, "any", []int{0}}},
+			},
+		},
+		map[string]any{
+			"1":                           deckJSON(1, "Default", ""),
+			strconv.FormatInt(deckID, 10): deckJSON(deckID, deck.Name, deck.Description),
+		},
+		map[string]any{
+			"1": map[string]any{
+				"id": 1, "name": "Default", "mod": 0, "usn": 0, "maxTaken": 60, "autoplay": true, "timer": 0, "replayq": true, "dyn": false,
+				"new":   map[string]any{"delays": []int{1, 10}, "ints": []int{1, 4, 7}, "initialFactor": 2500, "order": 1, "perDay": 20, "bury": true, "separate": true},
+				"rev":   map[string]any{"perDay": 200, "ease4": 1.3, "fuzz": 0.05, "ivlFct": 1, "maxIvl": 36500, "bury": true, "minSpace": 1},
+				"lapse": map[string]any{"delays": []int{10}, "mult": 0, "minInt": 1, "leechFails": 8, "leechAction": 0},
+			},
+		},
+	}
+
+	encoded := make([]string, len(parts))
+	for i, part := range parts {
+		data, err := json.Marshal(part)
+		if err != nil {
+			return "", "", "", "", err
+		}
+		encoded[i] = string(data)
+	}
+	return encoded[0], encoded[1], encoded[2], encoded[3], nil
+}
+
+func stripHTML(s string) string {
+	text, _, _ := FieldContent(s)
+	return text
+}
+
+// checksum is Anki's duplicate check value: the first 8 hex digits of the SHA-1 of the sort field.
+func checksum(s string) int64 {
+	sum := sha1.Sum([]byte(s))
+	return int64(binary.BigEndian.Uint32(sum[:4]))
+}
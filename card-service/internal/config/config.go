@@ -1,10 +1,15 @@
 package config
 
 type Config struct {
-	HTTPPort int        `yaml:"http_port"`
-	GRPCPort int        `yaml:"grpc_port"`
-	DB       DBConfig   `yaml:"db"`
-	JWT      JWTConfig  `yaml:"jwt"`
+	HTTPPort    int               `yaml:"http_port"`
+	GRPCPort    int               `yaml:"grpc_port"`
+	DB          DBConfig          `yaml:"db"`
+	JWT         JWTConfig         `yaml:"jwt"`
+	Quiz        QuizConfig        `yaml:"quiz"`
+	FileStorage FileStorageConfig `yaml:"file_storage"`
+	Import      ImportConfig      `yaml:"import"`
+	Views       ViewsConfig       `yaml:"views"`
+	Trash       TrashConfig       `yaml:"trash"`
 }
 
 type DBConfig struct {
@@ -22,3 +27,37 @@ type JWTConfig struct {
 	Audience      []string `yaml:"audience"`
 	KeyFilePath   string   `yaml:"key_file_path"`
 }
+
+type QuizConfig struct {
+	// SessionTTLMinutes is how long a quiz session stays available without activity.
+	SessionTTLMinutes      int `yaml:"session_ttl_minutes"`
+	CleanupIntervalMinutes int `yaml:"cleanup_interval_minutes"`
+}
+
+type FileStorageConfig struct {
+	GRPCAddr string `yaml:"grpc_addr"`
+	// MediaURLPrefix is where filestorage-service serves files; exports only
+	// download media from under it.
+	MediaURLPrefix string `yaml:"media_url_prefix"`
+}
+
+type ImportConfig struct {
+	MaxFileSizeMB int   `yaml:"max_file_size_mb"`
+	MaxCards      int32 `yaml:"max_cards"`
+}
+
+type ViewsConfig struct {
+	// DedupWindowHours is how long repeated views of a set by the same user count once.
+	DedupWindowHours     int `yaml:"dedup_window_hours"`
+	CleanupIntervalHours int `yaml:"cleanup_interval_hours"`
+	// Trending sets are ranked by the views and clones of the last TrendingWindowDays, each
+	// counting half as much every TrendingHalfLifeHours.
+	TrendingWindowDays    int `yaml:"trending_window_days"`
+	TrendingHalfLifeHours int `yaml:"trending_half_life_hours"`
+}
+
+type TrashConfig struct {
+	// RetentionDays is how long deleted sets and cards can be restored before they are purged.
+	RetentionDays      int `yaml:"retention_days"`
+	PurgeIntervalHours int `yaml:"purge_interval_hours"`
+}
@@ -0,0 +1,150 @@
+package fileclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	pb "github.com/karto4ki/karto4ki-backend/shared/proto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// MaxDownloadSize caps media downloaded for exports.
+const MaxDownloadSize = 10 << 20
+
+var (
+	// ErrForeignURL is returned for media that filestorage-service does not serve.
+	ErrForeignURL = errors.New("media is not served by file storage")
+	// ErrBlockedAddress is returned when a media host resolves to an address
+	// that is not on the public internet.
+	ErrBlockedAddress = errors.New("media host is not a public address")
+)
+
+// Client uploads card media to filestorage-service and downloads it back by URL.
+type Client struct {
+	conn       *grpc.ClientConn
+	client     pb.FileStorageServiceClient
+	httpClient *http.Client
+	// mediaURL is the prefix of the URLs of the files filestorage-service serves.
+	mediaURL *url.URL
+}
+
+// NewClient connects to filestorage-service at addr. Only media under
+// mediaURLPrefix, where filestorage-service serves its files, is downloaded.
+func NewClient(addr, mediaURLPrefix string) (*Client, error) {
+	mediaURL, err := url.Parse(mediaURLPrefix)
+	if err != nil || mediaURL.Host == "" {
+		return nil, fmt.Errorf("invalid media URL prefix %q", mediaURLPrefix)
+	}
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to filestorage service: %w", err)
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: publicAddressOnly}
+	return &Client{
+		conn:   conn,
+		client: pb.NewFileStorageServiceClient(conn),
+		httpClient: &http.Client{
+			Timeout:   20 * time.Second,
+			Transport: &http.Transport{DialContext: dialer.DialContext},
+			// A redirect could lead anywhere; it is not followed
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+		mediaURL: mediaURL,
+	}, nil
+}
+
+// publicAddressOnly refuses connections to loopback, private and link-local
+// addresses. It checks the address being dialed, after the name is resolved.
+func publicAddressOnly(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast() {
+		return ErrBlockedAddress
+	}
+	return nil
+}
+
+// serves tells whether filestorage-service serves the file at u.
+func (c *Client) serves(u *url.URL) bool {
+	return u.Scheme == c.mediaURL.Scheme && strings.EqualFold(u.Host, c.mediaURL.Host) && u.User == nil &&
+		strings.HasPrefix(u.Path, strings.TrimSuffix(c.mediaURL.Path, "/")+"/")
+}
+
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
+
+// UploadFile stores a media file on behalf of ownerID and returns its file id and URL.
+func (c *Client) UploadFile(ctx context.Context, data []byte, fileName, mimeType, ownerID string) (string, string, error) {
+	fileType := pb.FileType_FILE_TYPE_OTHER
+	if strings.HasPrefix(mimeType, "image/") {
+		fileType = pb.FileType_FILE_TYPE_CARD_IMAGE
+	}
+
+	resp, err := c.client.UploadFile(ctx, &pb.UploadFileRequest{
+		Data:     data,
+		FileName: fileName,
+		MimeType: mimeType,
+		FileType: fileType,
+		OwnerId:  ownerID,
+	})
+	if err != nil {
+		return "", "", err
+	}
+	return resp.FileId, resp.FileUrl, nil
+}
+
+func (c *Client) DeleteFile(ctx context.Context, fileID string) error {
+	_, err := c.client.DeleteFile(ctx, &pb.DeleteFileRequest{FileId: fileID})
+	return err
+}
+
+// Download fetches a media file by its URL. Only files served by
+// filestorage-service are downloaded; anything else is ErrForeignURL.
+func (c *Client) Download(ctx context.Context, rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || !c.serves(u) {
+		return nil, ErrForeignURL
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, MaxDownloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxDownloadSize {
+		return nil, errors.New("file is too large")
+	}
+	return data, nil
+}
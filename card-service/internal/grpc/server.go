@@ -2,7 +2,9 @@ package grpc
 
 import (
 	"context"
+	"errors"
 
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
 	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
 	pb "github.com/karto4ki/karto4ki-backend/shared/proto/card"
 	"google.golang.org/grpc/codes"
@@ -29,7 +31,7 @@ func (s *CardGRPCService) CreateCardSet(ctx context.Context, req *pb.CreateCardS
 		description = &req.Description
 	}
 
-	set, err := s.cardSetService.CreateCardSet(ctx, req.OwnerId, req.Name, description, req.IsPublic)
+	set, err := s.cardSetService.CreateCardSet(ctx, req.OwnerId, req.Name, description, req.IsPublic, nil)
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to create card set: %v", err)
 	}
@@ -88,7 +90,7 @@ func (s *CardGRPCService) UpdateCardSet(ctx context.Context, req *pb.UpdateCardS
 		description = &req.Description
 	}
 
-	set, err := s.cardSetService.UpdateCardSet(ctx, req.SetId, req.OwnerId, req.Name, description, req.IsPublic)
+	set, err := s.cardSetService.UpdateCardSet(ctx, req.SetId, req.OwnerId, req.Name, description, req.IsPublic, nil)
 	if err != nil {
 		if err == services.ErrNotFound {
 			return nil, status.Errorf(codes.NotFound, "card set not found")
@@ -118,7 +120,7 @@ func (s *CardGRPCService) UpdateCardSet(ctx context.Context, req *pb.UpdateCardS
 }
 
 func (s *CardGRPCService) DeleteCardSet(ctx context.Context, req *pb.DeleteCardSetRequest) (*pb.DeleteCardSetResponse, error) {
-	err := s.cardSetService.DeleteCardSet(ctx, req.SetId, req.OwnerId)
+	err := s.cardSetService.PurgeCardSet(ctx, req.SetId, req.OwnerId)
 	if err != nil {
 		if err == services.ErrNotFound {
 			return nil, status.Errorf(codes.NotFound, "card set not found")
@@ -141,11 +143,14 @@ func (s *CardGRPCService) CreateCard(ctx context.Context, req *pb.CreateCardRequ
 		audioURL = &req.AudioUrl
 	}
 
-	card, err := s.cardService.CreateCard(ctx, req.SetId, req.Front, req.Back, imageURL, audioURL)
+	card, err := s.cardService.AddCard(ctx, req.SetId, models.CardInput{Front: req.Front, Back: req.Back, ImageURL: imageURL, AudioURL: audioURL})
 	if err != nil {
 		if err == services.ErrNotFound {
 			return nil, status.Errorf(codes.NotFound, "card set not found")
 		}
+		if errors.Is(err, services.ErrInvalidContent) {
+			return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
 		return nil, status.Errorf(codes.Internal, "failed to create card: %v", err)
 	}
 
@@ -211,7 +216,7 @@ func (s *CardGRPCService) UpdateCard(ctx context.Context, req *pb.UpdateCardRequ
 		audioURL = &req.AudioUrl
 	}
 
-	card, err := s.cardService.UpdateCard(ctx, req.CardId, "", req.Front, req.Back, imageURL, audioURL)
+	card, err := s.cardService.UpdateCard(ctx, req.CardId, "", models.CardInput{Front: req.Front, Back: req.Back, ImageURL: imageURL, AudioURL: audioURL})
 	if err != nil {
 		if err == services.ErrNotFound {
 			return nil, status.Errorf(codes.NotFound, "card not found")
@@ -219,6 +224,9 @@ func (s *CardGRPCService) UpdateCard(ctx context.Context, req *pb.UpdateCardRequ
 		if err == services.ErrForbidden {
 			return nil, status.Errorf(codes.PermissionDenied, "access denied")
 		}
+		if errors.Is(err, services.ErrInvalidContent) {
+			return nil, status.Errorf(codes.InvalidArgument, "%v", err)
+		}
 		return nil, status.Errorf(codes.Internal, "failed to update card: %v", err)
 	}
 
@@ -258,3 +266,88 @@ func (s *CardGRPCService) DeleteCard(ctx context.Context, req *pb.DeleteCardRequ
 
 	return &pb.DeleteCardResponse{}, nil
 }
+
+func cardInputs(cards []*pb.CardInput) []models.CardInput {
+	inputs := make([]models.CardInput, len(cards))
+	for i, card := range cards {
+		inputs[i] = models.CardInput{ID: card.CardId, Front: card.Front, Back: card.Back}
+		if card.ImageUrl != "" {
+			inputs[i].ImageURL = &card.ImageUrl
+		}
+		if card.AudioUrl != "" {
+			inputs[i].AudioURL = &card.AudioUrl
+		}
+	}
+	return inputs
+}
+
+func batchResults(result *models.BatchResult) []*pb.BatchItemResult {
+	items := make([]*pb.BatchItemResult, len(result.Results))
+	for i, r := range result.Results {
+		items[i] = &pb.BatchItemResult{Index: int32(r.Index), CardId: r.CardID, Error: r.Error}
+		if card := r.Card; card != nil {
+			items[i].Card = &pb.Card{
+				Id:        card.ID,
+				SetId:     card.SetID,
+				Front:     card.Front,
+				Back:      card.Back,
+				CreatedAt: timestamppb.New(card.CreatedAt),
+			}
+			if card.ImageURL != nil {
+				items[i].Card.ImageUrl = *card.ImageURL
+			}
+			if card.AudioURL != nil {
+				items[i].Card.AudioUrl = *card.AudioURL
+			}
+		}
+	}
+	return items
+}
+
+func batchError(op string, err error) error {
+	switch err {
+	case services.ErrNotFound:
+		return status.Errorf(codes.NotFound, "card set or card not found")
+	case services.ErrForbidden:
+		return status.Errorf(codes.PermissionDenied, "access denied")
+	case services.ErrInvalidParam:
+		return status.Errorf(codes.InvalidArgument, "a batch must have 1 to %d cards and move them to another set", services.MaxBatchSize)
+	}
+	return status.Errorf(codes.Internal, "failed to %s: %v", op, err)
+}
+
+func (s *CardGRPCService) BatchCreateCards(ctx context.Context, req *pb.BatchCreateCardsRequest) (*pb.BatchCreateCardsResponse, error) {
+	result, err := s.cardService.BatchCreateCards(ctx, req.SetId, req.OwnerId, cardInputs(req.Cards))
+	if err != nil {
+		return nil, batchError("create cards", err)
+	}
+
+	return &pb.BatchCreateCardsResponse{Applied: result.Applied, Results: batchResults(result)}, nil
+}
+
+func (s *CardGRPCService) BatchUpdateCards(ctx context.Context, req *pb.BatchUpdateCardsRequest) (*pb.BatchUpdateCardsResponse, error) {
+	result, err := s.cardService.BatchUpdateCards(ctx, req.SetId, req.OwnerId, cardInputs(req.Cards))
+	if err != nil {
+		return nil, batchError("update cards", err)
+	}
+
+	return &pb.BatchUpdateCardsResponse{Applied: result.Applied, Results: batchResults(result)}, nil
+}
+
+func (s *CardGRPCService) BatchDeleteCards(ctx context.Context, req *pb.BatchDeleteCardsRequest) (*pb.BatchDeleteCardsResponse, error) {
+	result, err := s.cardService.BatchDeleteCards(ctx, req.SetId, req.OwnerId, req.CardIds)
+	if err != nil {
+		return nil, batchError("delete cards", err)
+	}
+
+	return &pb.BatchDeleteCardsResponse{Applied: result.Applied, Results: batchResults(result)}, nil
+}
+
+func (s *CardGRPCService) BatchMoveCards(ctx context.Context, req *pb.BatchMoveCardsRequest) (*pb.BatchMoveCardsResponse, error) {
+	result, err := s.cardService.BatchMoveCards(ctx, req.SetId, req.OwnerId, req.CardIds, req.TargetSetId)
+	if err != nil {
+		return nil, batchError("move cards", err)
+	}
+
+	return &pb.BatchMoveCardsResponse{Applied: result.Applied, Results: batchResults(result)}, nil
+}
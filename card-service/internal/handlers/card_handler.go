@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
 	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
 )
 
@@ -16,14 +19,44 @@ func NewCardHandler(service *services.CardService) *CardHandler {
 	return &CardHandler{service: service}
 }
 
+// CreateCardRequest is a card in content version 1 or, with a format, a type
+// other than basic or any of the rich fields, in content version 2. Cloze
+// cards may leave the back empty.
 type CreateCardRequest struct {
-	Front    string  `json:"front" binding:"required"`
-	Back     string  `json:"back" binding:"required"`
-	ImageURL *string `json:"image_url"`
-	AudioURL *string `json:"audio_url"`
+	Type        models.CardType      `json:"type" binding:"omitempty,oneof=basic basic_reverse cloze"`
+	Front       string               `json:"front" binding:"required"`
+	Back        string               `json:"back" binding:"required_unless=Type cloze"`
+	ImageURL    *string              `json:"image_url"`
+	AudioURL    *string              `json:"audio_url"`
+	Format      models.ContentFormat `json:"format" binding:"omitempty,oneof=plain markdown"`
+	FrontImages []string             `json:"front_images"`
+	BackImages  []string             `json:"back_images"`
+	Notes       *string              `json:"notes"`
+	Hint        *string              `json:"hint"`
+	Example     *string              `json:"example"`
+	// Position is the zero-based place in the set to insert the card at; the
+	// card goes to the end without it.
+	Position *int32 `json:"position" binding:"omitempty,min=0"`
+}
+
+func (r CreateCardRequest) input() models.CardInput {
+	return models.CardInput{
+		Front:       r.Front,
+		Back:        r.Back,
+		ImageURL:    r.ImageURL,
+		AudioURL:    r.AudioURL,
+		Format:      r.Format,
+		FrontImages: r.FrontImages,
+		BackImages:  r.BackImages,
+		Notes:       r.Notes,
+		Hint:        r.Hint,
+		Example:     r.Example,
+		Type:        r.Type,
+	}
 }
 
 func (h *CardHandler) CreateCard(c *gin.Context) {
+	userID := c.GetString("user_id")
 	setID := c.Param("setId")
 	var req CreateCardRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -31,11 +64,19 @@ func (h *CardHandler) CreateCard(c *gin.Context) {
 		return
 	}
 
-	card, err := h.service.CreateCard(c.Request.Context(), setID, req.Front, req.Back, req.ImageURL, req.AudioURL)
+	card, err := h.service.CreateCard(c.Request.Context(), setID, userID, req.input(), req.Position)
+	if errors.Is(err, services.ErrInvalidContent) {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": err.Error()})
+		return
+	}
 	if err == services.ErrNotFound {
 		c.JSON(http.StatusNotFound, gin.H{"error_type": "not_found", "error_message": "Set not found"})
 		return
 	}
+	if err == services.ErrForbidden {
+		c.JSON(http.StatusForbidden, gin.H{"error_type": "forbidden", "error_message": "Access denied"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
 		return
@@ -103,7 +144,11 @@ func (h *CardHandler) UpdateCard(c *gin.Context) {
 		return
 	}
 
-	card, err := h.service.UpdateCard(c.Request.Context(), cardID, userID, req.Front, req.Back, req.ImageURL, req.AudioURL)
+	card, err := h.service.UpdateCard(c.Request.Context(), cardID, userID, req.input())
+	if errors.Is(err, services.ErrInvalidContent) {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": err.Error()})
+		return
+	}
 	if err == services.ErrNotFound {
 		c.JSON(http.StatusNotFound, gin.H{"error_type": "not_found", "error_message": "Card not found"})
 		return
@@ -140,3 +185,146 @@ func (h *CardHandler) DeleteCard(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"data": gin.H{}})
 }
+
+type BatchCardsRequest struct {
+	Cards []models.CardInput `json:"cards" binding:"required"`
+}
+
+type BatchCardIDsRequest struct {
+	CardIDs []string `json:"card_ids" binding:"required"`
+}
+
+type BatchMoveCardsRequest struct {
+	CardIDs     []string `json:"card_ids" binding:"required"`
+	TargetSetID string   `json:"target_set_id" binding:"required"`
+}
+
+// writeBatchResult answers a batch request. A batch rejected because of
+// invalid items is a 422 carrying the per-item results.
+func writeBatchResult(c *gin.Context, result *models.BatchResult, err error) {
+	if err == services.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error_type": "not_found", "error_message": "Set or card not found"})
+		return
+	}
+	if err == services.ErrForbidden {
+		c.JSON(http.StatusForbidden, gin.H{"error_type": "forbidden", "error_message": "Access denied"})
+		return
+	}
+	if err == services.ErrInvalidParam {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "invalid_param", "error_message": fmt.Sprintf("A batch must have 1 to %d cards and move them to another set", services.MaxBatchSize)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
+		return
+	}
+
+	if !result.Applied {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error_type":    "validation_failed",
+			"error_message": "Some cards are invalid, nothing was changed",
+			"data":          result,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+func (h *CardHandler) BatchCreateCards(c *gin.Context) {
+	userID := c.GetString("user_id")
+	setID := c.Param("setId")
+	var req BatchCardsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": err.Error()})
+		return
+	}
+
+	result, err := h.service.BatchCreateCards(c.Request.Context(), setID, userID, req.Cards)
+	writeBatchResult(c, result, err)
+}
+
+func (h *CardHandler) BatchUpdateCards(c *gin.Context) {
+	userID := c.GetString("user_id")
+	setID := c.Param("setId")
+	var req BatchCardsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": err.Error()})
+		return
+	}
+
+	result, err := h.service.BatchUpdateCards(c.Request.Context(), setID, userID, req.Cards)
+	writeBatchResult(c, result, err)
+}
+
+func (h *CardHandler) BatchDeleteCards(c *gin.Context) {
+	userID := c.GetString("user_id")
+	setID := c.Param("setId")
+	var req BatchCardIDsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": err.Error()})
+		return
+	}
+
+	result, err := h.service.BatchDeleteCards(c.Request.Context(), setID, userID, req.CardIDs)
+	writeBatchResult(c, result, err)
+}
+
+func (h *CardHandler) BatchMoveCards(c *gin.Context) {
+	userID := c.GetString("user_id")
+	setID := c.Param("setId")
+	var req BatchMoveCardsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": err.Error()})
+		return
+	}
+
+	result, err := h.service.BatchMoveCards(c.Request.Context(), setID, userID, req.CardIDs, req.TargetSetID)
+	writeBatchResult(c, result, err)
+}
+
+// ReorderCardsRequest either lists every card of the set in the new order, or
+// moves one card before or after a neighbour or to a zero-based position.
+type ReorderCardsRequest struct {
+	CardIDs  []string `json:"card_ids"`
+	CardID   string   `json:"card_id"`
+	BeforeID string   `json:"before_id"`
+	AfterID  string   `json:"after_id"`
+	Position *int32   `json:"position"`
+}
+
+func (h *CardHandler) ReorderCards(c *gin.Context) {
+	userID := c.GetString("user_id")
+	setID := c.Param("setId")
+	var req ReorderCardsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": err.Error()})
+		return
+	}
+
+	order, err := h.service.ReorderCards(c.Request.Context(), setID, userID, services.Reorder{
+		CardIDs:  req.CardIDs,
+		CardID:   req.CardID,
+		BeforeID: req.BeforeID,
+		AfterID:  req.AfterID,
+		Position: req.Position,
+	})
+	if err == services.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error_type": "not_found", "error_message": "Set not found"})
+		return
+	}
+	if err == services.ErrForbidden {
+		c.JSON(http.StatusForbidden, gin.H{"error_type": "forbidden", "error_message": "Access denied"})
+		return
+	}
+	if err == services.ErrInvalidParam {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "invalid_param", "error_message": "Give either every card of the set once, or one card of the set with one neighbour or position"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"card_ids": order}})
+}
@@ -1,10 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
 	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
 )
 
@@ -20,6 +23,35 @@ type CreateCardSetRequest struct {
 	Name        string  `json:"name" binding:"required"`
 	Description *string `json:"description"`
 	IsPublic    bool    `json:"is_public"`
+	// Tags are left unchanged on update when omitted.
+	Tags []string `json:"tags"`
+}
+
+// maxSearchLimit is the largest page of search results.
+const maxSearchLimit = 50
+
+// searchPage reads the offset and limit of a search page, bringing values out
+// of range back into it.
+func searchPage(c *gin.Context) (int32, int32) {
+	offset, _ := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 32)
+	limit, err := strconv.ParseInt(c.DefaultQuery("limit", "10"), 10, 32)
+	if err != nil || limit < 1 {
+		limit = 10
+	}
+	return int32(max(offset, 0)), int32(min(limit, maxSearchLimit))
+}
+
+// queryTags reads tags given comma-separated or as repeated parameters.
+func queryTags(c *gin.Context) []string {
+	var tags []string
+	for _, value := range c.QueryArray("tags") {
+		for _, tag := range strings.Split(value, ",") {
+			if tag = services.NormalizeTag(tag); tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
 }
 
 func (h *CardSetHandler) CreateCardSet(c *gin.Context) {
@@ -30,7 +62,11 @@ func (h *CardSetHandler) CreateCardSet(c *gin.Context) {
 		return
 	}
 
-	set, err := h.service.CreateCardSet(c.Request.Context(), userID, req.Name, req.Description, req.IsPublic)
+	set, err := h.service.CreateCardSet(c.Request.Context(), userID, req.Name, req.Description, req.IsPublic, req.Tags)
+	if errors.Is(err, services.ErrInvalidTags) {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
 		return
@@ -65,7 +101,7 @@ func (h *CardSetHandler) GetCardSets(c *gin.Context) {
 	offset, _ := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 32)
 	limit, _ := strconv.ParseInt(c.DefaultQuery("limit", "10"), 10, 32)
 
-	sets, err := h.service.GetCardSets(c.Request.Context(), userID, int32(offset), int32(limit))
+	sets, err := h.service.GetCardSets(c.Request.Context(), userID, queryTags(c), int32(offset), int32(limit))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
 		return
@@ -89,7 +125,11 @@ func (h *CardSetHandler) UpdateCardSet(c *gin.Context) {
 		return
 	}
 
-	set, err := h.service.UpdateCardSet(c.Request.Context(), setID, userID, req.Name, req.Description, req.IsPublic)
+	set, err := h.service.UpdateCardSet(c.Request.Context(), setID, userID, req.Name, req.Description, req.IsPublic, req.Tags)
+	if errors.Is(err, services.ErrInvalidTags) {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": err.Error()})
+		return
+	}
 	if err == services.ErrNotFound {
 		c.JSON(http.StatusNotFound, gin.H{"error_type": "not_found", "error_message": "Set not found"})
 		return
@@ -127,17 +167,62 @@ func (h *CardSetHandler) DeleteCardSet(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": gin.H{}})
 }
 
+// SearchPublicSets searches public sets by text and filters. Tags may be given
+// comma-separated or as repeated parameters; a set must have all of them.
 func (h *CardSetHandler) SearchPublicSets(c *gin.Context) {
-	query := c.Query("query")
-	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error_type": "invalid_param", "error_message": "query parameter is required"})
+	params := models.SearchParams{
+		Query:    strings.TrimSpace(c.Query("query")),
+		AuthorID: c.Query("author_id"),
+	}
+	author := strings.TrimPrefix(strings.TrimSpace(c.Query("author")), "@")
+	params.Tags = queryTags(c)
+
+	for name, target := range map[string]**int32{"min_cards": &params.MinCards, "max_cards": &params.MaxCards} {
+		value := c.Query(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error_type": "invalid_param", "error_message": name + " must be a non-negative number"})
+			return
+		}
+		count := int32(n)
+		*target = &count
+	}
+
+	if params.Query == "" && len(params.Tags) == 0 && params.AuthorID == "" && author == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "invalid_param", "error_message": "query, tags or author parameter is required"})
 		return
 	}
 
+	params.Offset, params.Limit = searchPage(c)
+
+	sets, err := h.service.SearchPublicSets(c.Request.Context(), params, author)
+	if err == services.ErrInvalidParam {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "invalid_param", "error_message": "Invalid author_id or card count range"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"sets":   sets,
+			"offset": params.Offset,
+			"count":  len(sets),
+		},
+	})
+}
+
+// GetTrendingSets lists the public sets getting the most attention recently.
+func (h *CardSetHandler) GetTrendingSets(c *gin.Context) {
 	offset, _ := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 32)
 	limit, _ := strconv.ParseInt(c.DefaultQuery("limit", "10"), 10, 32)
 
-	sets, err := h.service.SearchPublicSets(c.Request.Context(), query, int32(offset), int32(limit))
+	sets, err := h.service.GetTrendingSets(c.Request.Context(), int32(offset), int32(limit))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
 		return
@@ -152,6 +237,32 @@ func (h *CardSetHandler) SearchPublicSets(c *gin.Context) {
 	})
 }
 
+// GetUserTags lists the tags of the user's sets with their counts.
+func (h *CardSetHandler) GetUserTags(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	tags, err := h.service.GetUserTags(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"tags": tags}})
+}
+
+// SuggestTags autocompletes tags from those used on public sets.
+func (h *CardSetHandler) SuggestTags(c *gin.Context) {
+	limit, _ := strconv.ParseInt(c.DefaultQuery("limit", "10"), 10, 32)
+
+	tags, err := h.service.SuggestTags(c.Request.Context(), c.Query("prefix"), int32(limit))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"tags": tags}})
+}
+
 func (h *CardSetHandler) CloneSet(c *gin.Context) {
 	userID := c.GetString("user_id")
 	setID := c.Param("setId")
@@ -172,3 +283,60 @@ func (h *CardSetHandler) CloneSet(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"data": clonedSet})
 }
+
+func (h *CardSetHandler) GetCloneDiff(c *gin.Context) {
+	userID := c.GetString("user_id")
+	setID := c.Param("setId")
+
+	diff, err := h.service.GetCloneDiff(c.Request.Context(), setID, userID)
+	if err != nil {
+		h.writeCloneError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": diff})
+}
+
+type PullFromOriginalRequest struct {
+	CardIDs       []string `json:"card_ids"`
+	Overwrite     bool     `json:"overwrite"`
+	RemoveDeleted bool     `json:"remove_deleted"`
+}
+
+func (h *CardSetHandler) PullFromOriginal(c *gin.Context) {
+	userID := c.GetString("user_id")
+	setID := c.Param("setId")
+
+	var req PullFromOriginalRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": err.Error()})
+			return
+		}
+	}
+
+	result, err := h.service.PullFromOriginal(c.Request.Context(), setID, userID, services.PullOptions{
+		CardIDs:       req.CardIDs,
+		Overwrite:     req.Overwrite,
+		RemoveDeleted: req.RemoveDeleted,
+	})
+	if err != nil {
+		h.writeCloneError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
+
+func (h *CardSetHandler) writeCloneError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error_type": "not_found", "error_message": "Set not found"})
+	case services.ErrForbidden:
+		c.JSON(http.StatusForbidden, gin.H{"error_type": "forbidden", "error_message": "Access denied"})
+	case services.ErrNotClone:
+		c.JSON(http.StatusConflict, gin.H{"error_type": "not_clone", "error_message": "Set is not a clone or its original was deleted"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
+	}
+}
@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+)
+
+type ExportHandler struct {
+	service *services.ExportService
+}
+
+func NewExportHandler(service *services.ExportService) *ExportHandler {
+	return &ExportHandler{service: service}
+}
+
+type ExportSetRequest struct {
+	Format          models.ExportFormat `form:"format" binding:"omitempty,oneof=csv json apkg"`
+	IncludeProgress bool                `form:"include_progress"`
+}
+
+func (h *ExportHandler) ExportSet(c *gin.Context) {
+	userID := c.GetString("user_id")
+	setID := c.Param("setId")
+
+	var req ExportSetRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": err.Error()})
+		return
+	}
+	if req.Format == "" {
+		req.Format = models.ExportFormatCSV
+	}
+
+	export, err := h.service.ExportSet(c.Request.Context(), setID, userID, req.Format, req.IncludeProgress)
+	if err == services.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error_type": "not_found", "error_message": "Set not found"})
+		return
+	}
+	if err == services.ErrForbidden {
+		c.JSON(http.StatusForbidden, gin.H{"error_type": "forbidden", "error_message": "Access denied"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": export.FileName}))
+	c.Data(http.StatusOK, export.ContentType, export.Data)
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+)
+
+type FolderHandler struct {
+	service *services.FolderService
+}
+
+func NewFolderHandler(service *services.FolderService) *FolderHandler {
+	return &FolderHandler{service: service}
+}
+
+type CreateFolderRequest struct {
+	Name     string  `json:"name" binding:"required"`
+	ParentID *string `json:"parent_id"`
+}
+
+type RenameFolderRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+type MoveFolderRequest struct {
+	// ParentID is the folder to move into; null moves to the top level.
+	ParentID *string `json:"parent_id"`
+}
+
+type MoveSetRequest struct {
+	// FolderID is the folder to move the set into; null moves it to the top level.
+	FolderID *string `json:"folder_id"`
+}
+
+func writeFolderError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error_type": "not_found", "error_message": "Folder or set not found"})
+	case services.ErrForbidden:
+		c.JSON(http.StatusForbidden, gin.H{"error_type": "forbidden", "error_message": "Access denied"})
+	case services.ErrInvalidParam:
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "invalid_param", "error_message": "Invalid folder name or a folder can't be moved into itself"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
+	}
+}
+
+func (h *FolderHandler) CreateFolder(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req CreateFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": err.Error()})
+		return
+	}
+
+	folder, err := h.service.CreateFolder(c.Request.Context(), userID, req.Name, req.ParentID)
+	if err != nil {
+		writeFolderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": folder})
+}
+
+// GetFolderContents lists a folder, or the top level when there is no folderId.
+func (h *FolderHandler) GetFolderContents(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var folderID *string
+	if id := c.Param("folderId"); id != "" {
+		folderID = &id
+	}
+
+	contents, err := h.service.GetFolderContents(c.Request.Context(), folderID, userID)
+	if err != nil {
+		writeFolderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": contents})
+}
+
+func (h *FolderHandler) RenameFolder(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req RenameFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": err.Error()})
+		return
+	}
+
+	folder, err := h.service.RenameFolder(c.Request.Context(), c.Param("folderId"), userID, req.Name)
+	if err != nil {
+		writeFolderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": folder})
+}
+
+func (h *FolderHandler) MoveFolder(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req MoveFolderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": err.Error()})
+		return
+	}
+
+	folder, err := h.service.MoveFolder(c.Request.Context(), c.Param("folderId"), userID, req.ParentID)
+	if err != nil {
+		writeFolderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": folder})
+}
+
+func (h *FolderHandler) DeleteFolder(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	if err := h.service.DeleteFolder(c.Request.Context(), c.Param("folderId"), userID); err != nil {
+		writeFolderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{}})
+}
+
+func (h *FolderHandler) MoveSet(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req MoveSetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": err.Error()})
+		return
+	}
+
+	set, err := h.service.MoveSet(c.Request.Context(), c.Param("setId"), userID, req.FolderID)
+	if err != nil {
+		writeFolderError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": set})
+}
@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+)
+
+type ImportHandler struct {
+	service     *services.ImportService
+	maxFileSize int64
+}
+
+func NewImportHandler(service *services.ImportService, maxFileSize int64) *ImportHandler {
+	return &ImportHandler{service: service, maxFileSize: maxFileSize}
+}
+
+// ImportSetRequest is the multipart form of an import; the file itself is sent as "file".
+type ImportSetRequest struct {
+	Name        string  `form:"name" binding:"max=100"`
+	Description *string `form:"description"`
+	IsPublic    bool    `form:"is_public"`
+	// Format is detected from the file extension when empty.
+	Format      models.ImportFormat `form:"format" binding:"omitempty,oneof=csv tsv apkg"`
+	Delimiter   string              `form:"delimiter"`
+	HasHeader   *bool               `form:"has_header"`
+	FrontColumn string              `form:"front_column"`
+	BackColumn  string              `form:"back_column"`
+	ImageColumn string              `form:"image_column"`
+	AudioColumn string              `form:"audio_column"`
+}
+
+func (h *ImportHandler) ImportSet(c *gin.Context) {
+	userID := c.GetString("user_id")
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxFileSize)
+
+	var req ImportSetRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": err.Error()})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": "file is required"})
+		return
+	}
+
+	ext := strings.ToLower(filepath.Ext(fileHeader.Filename))
+	format := req.Format
+	if format == "" {
+		format = models.ImportFormat(strings.TrimPrefix(ext, "."))
+	}
+
+	info := services.ImportSetInfo{
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		IsPublic:    req.IsPublic,
+	}
+	if info.Name == "" {
+		info.Name = strings.TrimSuffix(filepath.Base(fileHeader.Filename), filepath.Ext(fileHeader.Filename))
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
+		return
+	}
+	defer file.Close()
+
+	var report *models.ImportReport
+	switch format {
+	case models.ImportFormatAnki:
+		report, err = h.service.ImportAnki(c.Request.Context(), userID, info, file, fileHeader.Size)
+	case models.ImportFormatCSV, models.ImportFormatTSV, "txt":
+		opts := services.CSVOptions{
+			HasHeader:   req.HasHeader,
+			FrontColumn: req.FrontColumn,
+			BackColumn:  req.BackColumn,
+			ImageColumn: req.ImageColumn,
+			AudioColumn: req.AudioColumn,
+		}
+		switch {
+		case req.Delimiter == `\t` || strings.EqualFold(req.Delimiter, "tab"):
+			opts.Delimiter = '\t'
+		case utf8.RuneCountInString(req.Delimiter) == 1:
+			opts.Delimiter, _ = utf8.DecodeRuneInString(req.Delimiter)
+		case req.Delimiter == "" && format == models.ImportFormatTSV:
+			opts.Delimiter = '\t'
+		case req.Delimiter != "":
+			c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": "delimiter must be a single character"})
+			return
+		}
+
+		var data []byte
+		data, err = io.ReadAll(file)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
+			return
+		}
+		report, err = h.service.ImportCSV(c.Request.Context(), userID, info, data, opts)
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": "Unsupported file format, use csv, tsv or apkg"})
+		return
+	}
+
+	if errors.Is(err, services.ErrInvalidImport) {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "invalid_file", "error_message": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
@@ -19,12 +19,28 @@ func NewLearningHandler(service *services.LearningService) *LearningHandler {
 type StartStudyRequest struct {
 	SessionType models.SessionType `json:"session_type"`
 	Limit       int32              `json:"limit"`
+	// InSetOrder makes a learn session follow the order of the cards in the
+	// set instead of putting the worst known cards first.
+	InSetOrder bool `json:"in_set_order"`
 }
 
 type SubmitAnswerRequest struct {
-	CardID      string             `json:"card_id" binding:"required"`
-	Rating      models.CardRating  `json:"rating" binding:"oneof=0 1"`
-	TimeSpentMs int64              `json:"time_spent_ms"`
+	CardID string `json:"card_id" binding:"required"`
+	// Item is the review item of the card answered. Older clients leave it out,
+	// and the card's next item in the session is answered then.
+	Item *int16 `json:"item" binding:"omitempty,min=0"`
+	// Grade is one of again, hard, good, easy.
+	Grade string `json:"grade" binding:"omitempty,oneof=again hard good easy"`
+	// Rating is the binary forgot (0) / remember (1) answer of older clients, used when Grade is empty.
+	Rating      int   `json:"rating" binding:"oneof=0 1"`
+	TimeSpentMs int64 `json:"time_spent_ms"`
+}
+
+func (r SubmitAnswerRequest) cardRating() models.CardRating {
+	if rating, ok := models.ParseRating(r.Grade); ok {
+		return rating
+	}
+	return models.RatingFromBinary(r.Rating == 1)
 }
 
 func (h *LearningHandler) StartStudySession(c *gin.Context) {
@@ -40,7 +56,7 @@ func (h *LearningHandler) StartStudySession(c *gin.Context) {
 		return
 	}
 
-	session, err := h.service.StartStudySession(c.Request.Context(), setID, userID, req.SessionType, req.Limit)
+	session, err := h.service.StartStudySession(c.Request.Context(), setID, userID, req.SessionType, req.InSetOrder, req.Limit)
 	if err == services.ErrNotFound {
 		c.JSON(http.StatusNotFound, gin.H{"error_type": "not_found", "error_message": "No cards available for study"})
 		return
@@ -78,6 +94,36 @@ func (h *LearningHandler) StartStudySessionAll(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": session})
 }
 
+func (h *LearningHandler) StartStudySessionFolder(c *gin.Context) {
+	userID := c.GetString("user_id")
+	folderID := c.Param("folderId")
+
+	var req StartStudyRequest
+	req.SessionType = models.SessionTypeReview
+	req.Limit = 20
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": err.Error()})
+		return
+	}
+
+	session, err := h.service.StartStudySessionFolder(c.Request.Context(), folderID, userID, req.SessionType, req.Limit)
+	if err == services.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error_type": "not_found", "error_message": "Folder not found or no cards available for study"})
+		return
+	}
+	if err == services.ErrForbidden {
+		c.JSON(http.StatusForbidden, gin.H{"error_type": "forbidden", "error_message": "Access denied"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": session})
+}
+
 func (h *LearningHandler) SubmitAnswer(c *gin.Context) {
 	sessionID := c.Param("sessionId")
 	userID := c.GetString("user_id")
@@ -87,11 +133,27 @@ func (h *LearningHandler) SubmitAnswer(c *gin.Context) {
 		return
 	}
 
-	result, err := h.service.SubmitAnswer(c.Request.Context(), sessionID, req.CardID, userID, req.Rating, req.TimeSpentMs)
+	result, err := h.service.SubmitAnswer(c.Request.Context(), sessionID, req.CardID, req.Item, userID, req.cardRating(), req.TimeSpentMs)
 	if err == services.ErrNotFound {
 		c.JSON(http.StatusNotFound, gin.H{"error_type": "not_found", "error_message": "Session or card not found"})
 		return
 	}
+	if err == services.ErrForbidden {
+		c.JSON(http.StatusForbidden, gin.H{"error_type": "forbidden", "error_message": "Access denied"})
+		return
+	}
+	if err == services.ErrInvalidParam {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "invalid_param", "error_message": "Invalid rating"})
+		return
+	}
+	if err == services.ErrCardNotInSession {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "invalid_param", "error_message": "Card is not in this session"})
+		return
+	}
+	if err == services.ErrSessionFinished {
+		c.JSON(http.StatusConflict, gin.H{"error_type": "session_finished", "error_message": "Session is already finished"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
 		return
@@ -100,14 +162,61 @@ func (h *LearningHandler) SubmitAnswer(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"data": result})
 }
 
+func (h *LearningHandler) GetStudySession(c *gin.Context) {
+	sessionID := c.Param("sessionId")
+	userID := c.GetString("user_id")
+
+	session, err := h.service.GetStudySession(c.Request.Context(), sessionID, userID)
+	if err == services.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error_type": "not_found", "error_message": "Session not found"})
+		return
+	}
+	if err == services.ErrForbidden {
+		c.JSON(http.StatusForbidden, gin.H{"error_type": "forbidden", "error_message": "Access denied"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": session})
+}
+
+func (h *LearningHandler) FinishStudySession(c *gin.Context) {
+	sessionID := c.Param("sessionId")
+	userID := c.GetString("user_id")
+
+	summary, err := h.service.FinishStudySession(c.Request.Context(), sessionID, userID)
+	if err == services.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error_type": "not_found", "error_message": "Session not found"})
+		return
+	}
+	if err == services.ErrForbidden {
+		c.JSON(http.StatusForbidden, gin.H{"error_type": "forbidden", "error_message": "Access denied"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": summary})
+}
+
 func (h *LearningHandler) GetSetStatistics(c *gin.Context) {
+	userID := c.GetString("user_id")
 	setID := c.Param("setId")
 
-	stats, err := h.service.GetSetStatistics(c.Request.Context(), setID)
+	stats, err := h.service.GetSetStatistics(c.Request.Context(), setID, userID)
 	if err == services.ErrNotFound {
 		c.JSON(http.StatusNotFound, gin.H{"error_type": "not_found", "error_message": "Set not found"})
 		return
 	}
+	if err == services.ErrForbidden {
+		c.JSON(http.StatusForbidden, gin.H{"error_type": "forbidden", "error_message": "Access denied"})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
 		return
@@ -127,3 +236,73 @@ func (h *LearningHandler) GetUserStatistics(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{"data": stats})
 }
+
+// writeCardAccessError answers the errors of acting on a card the user may not reach.
+func writeCardAccessError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error_type": "not_found", "error_message": "Card not found"})
+	case services.ErrForbidden:
+		c.JSON(http.StatusForbidden, gin.H{"error_type": "forbidden", "error_message": "Access denied"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
+	}
+}
+
+func (h *LearningHandler) SuspendCard(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cardID := c.Param("cardId")
+
+	if err := h.service.SuspendCard(c.Request.Context(), cardID, userID); err != nil {
+		writeCardAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"card_id": cardID, "suspended": true}})
+}
+
+func (h *LearningHandler) UnsuspendCard(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cardID := c.Param("cardId")
+
+	if err := h.service.UnsuspendCard(c.Request.Context(), cardID, userID); err != nil {
+		writeCardAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"card_id": cardID, "suspended": false}})
+}
+
+func (h *LearningHandler) BuryCard(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cardID := c.Param("cardId")
+
+	until, err := h.service.BuryCard(c.Request.Context(), cardID, userID)
+	if err != nil {
+		writeCardAccessError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"card_id": cardID, "buried_until": until}})
+}
+
+func (h *LearningHandler) GetLeeches(c *gin.Context) {
+	userID := c.GetString("user_id")
+	setID := c.Param("setId")
+
+	cards, err := h.service.GetLeeches(c.Request.Context(), setID, userID)
+	if err == services.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"error_type": "not_found", "error_message": "Set not found"})
+		return
+	}
+	if err == services.ErrForbidden {
+		c.JSON(http.StatusForbidden, gin.H{"error_type": "forbidden", "error_message": "Access denied"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"cards": cards, "count": len(cards)}})
+}
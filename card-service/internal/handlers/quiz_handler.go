@@ -2,7 +2,7 @@ package handlers
 
 import (
 	"net/http"
-	"sync"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
@@ -11,7 +11,6 @@ import (
 
 type QuizHandler struct {
 	service *services.QuizService
-	sessions sync.Map
 }
 
 func NewQuizHandler(service *services.QuizService) *QuizHandler {
@@ -20,12 +19,16 @@ func NewQuizHandler(service *services.QuizService) *QuizHandler {
 
 type StartQuizRequest struct {
 	QuestionCount int `json:"question_count" binding:"required,min=4,max=50"`
+	// QuestionTypes is the mix of question types, multiple choice only by default.
+	QuestionTypes []models.QuizQuestionType `json:"question_types" binding:"omitempty,dive,oneof=multiple_choice reverse typed true_false"`
 }
 
 type SubmitQuizAnswerRequest struct {
-	QuestionIndex int  `json:"question_index" binding:"required,min=0"`
-	SelectedIndex int  `json:"selected_index" binding:"required,min=0,max=3"`
-	TimeSpentMs   int64 `json:"time_spent_ms"`
+	QuestionIndex int    `json:"question_index" binding:"min=0"`
+	SelectedIndex int    `json:"selected_index" binding:"min=0,max=3"`
+	TextAnswer    string `json:"text_answer" binding:"max=1000"`
+	BoolAnswer    *bool  `json:"bool_answer"`
+	TimeSpentMs   int64  `json:"time_spent_ms" binding:"min=0"`
 }
 
 func (h *QuizHandler) StartQuizSession(c *gin.Context) {
@@ -41,7 +44,7 @@ func (h *QuizHandler) StartQuizSession(c *gin.Context) {
 		return
 	}
 
-	session, err := h.service.StartQuizSession(c.Request.Context(), setID, userID, req.QuestionCount)
+	session, err := h.service.StartQuizSession(c.Request.Context(), setID, userID, req.QuestionCount, req.QuestionTypes)
 	if err == services.ErrNotFound {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error_type":    "not_found",
@@ -49,6 +52,20 @@ func (h *QuizHandler) StartQuizSession(c *gin.Context) {
 		})
 		return
 	}
+	if err == services.ErrForbidden {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error_type":    "forbidden",
+			"error_message": "Access denied",
+		})
+		return
+	}
+	if err == services.ErrInvalidParam {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error_type":    "invalid_param",
+			"error_message": "Unknown question type",
+		})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error_type":    "internal",
@@ -57,45 +74,29 @@ func (h *QuizHandler) StartQuizSession(c *gin.Context) {
 		return
 	}
 
-	h.sessions.Store(session.ID, &QuizSessionState{
-		Session:  session,
-		Answers:  make([]models.QuizAnswerResult, 0),
-		Started:  true,
-	})
-
 	c.JSON(http.StatusOK, gin.H{
-		"data": session,
+		"data": session.View(),
 	})
 }
 
-type QuizSessionState struct {
-	Session  *models.QuizSession
-	Answers  []models.QuizAnswerResult
-	Started  bool
-	Finished bool
-}
-
-func (h *QuizHandler) SubmitAnswer(c *gin.Context) {
+func (h *QuizHandler) GetQuizSession(c *gin.Context) {
 	userID := c.GetString("user_id")
 	sessionID := c.Param("sessionId")
 
-	val, ok := h.sessions.Load(sessionID)
-	if !ok {
-		c.JSON(http.StatusNotFound, gin.H{
-			"error_type":    "not_found",
-			"error_message": "Quiz session not found",
-		})
+	session, err := h.service.GetSession(c.Request.Context(), sessionID, userID)
+	if err != nil {
+		h.writeSessionError(c, err)
 		return
 	}
 
-	state := val.(*QuizSessionState)
-	if state.Session.UserID != userID {
-		c.JSON(http.StatusForbidden, gin.H{
-			"error_type":    "forbidden",
-			"error_message": "Access denied",
-		})
-		return
-	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": session.View(),
+	})
+}
+
+func (h *QuizHandler) SubmitAnswer(c *gin.Context) {
+	userID := c.GetString("user_id")
+	sessionID := c.Param("sessionId")
 
 	var req SubmitQuizAnswerRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -106,18 +107,18 @@ func (h *QuizHandler) SubmitAnswer(c *gin.Context) {
 		return
 	}
 
-	result, err := h.service.SubmitAnswer(c.Request.Context(), state.Session, req.QuestionIndex, req.SelectedIndex)
+	result, err := h.service.SubmitAnswer(c.Request.Context(), sessionID, userID, models.QuizAnswerRequest{
+		QuestionIndex: req.QuestionIndex,
+		SelectedIndex: req.SelectedIndex,
+		TextAnswer:    req.TextAnswer,
+		BoolAnswer:    req.BoolAnswer,
+		TimeSpentMs:   req.TimeSpentMs,
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error_type":    "internal",
-			"error_message": err.Error(),
-		})
+		h.writeSessionError(c, err)
 		return
 	}
 
-	state.Answers = append(state.Answers, *result)
-	h.sessions.Store(sessionID, state)
-
 	c.JSON(http.StatusOK, gin.H{
 		"data": result,
 	})
@@ -127,29 +128,86 @@ func (h *QuizHandler) FinishQuiz(c *gin.Context) {
 	userID := c.GetString("user_id")
 	sessionID := c.Param("sessionId")
 
-	val, ok := h.sessions.Load(sessionID)
-	if !ok {
+	quizResult, err := h.service.FinishQuiz(c.Request.Context(), sessionID, userID)
+	if err != nil {
+		h.writeSessionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": quizResult,
+	})
+}
+
+func (h *QuizHandler) GetQuizHistory(c *gin.Context) {
+	userID := c.GetString("user_id")
+	setID := c.Param("setId")
+	offset, _ := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 32)
+	limit, _ := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 32)
+
+	results, err := h.service.GetQuizHistory(c.Request.Context(), setID, userID, int32(offset), int32(limit))
+	if err == services.ErrNotFound {
 		c.JSON(http.StatusNotFound, gin.H{
 			"error_type":    "not_found",
-			"error_message": "Quiz session not found",
+			"error_message": "Set not found",
 		})
 		return
 	}
-
-	state := val.(*QuizSessionState)
-	if state.Session.UserID != userID {
+	if err == services.ErrForbidden {
 		c.JSON(http.StatusForbidden, gin.H{
 			"error_type":    "forbidden",
 			"error_message": "Access denied",
 		})
 		return
 	}
-
-	quizResult := h.service.CalculateQuizResult(state.Session, state.Answers, 0)
-
-	h.sessions.Delete(sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error_type":    "internal",
+			"error_message": err.Error(),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"data": quizResult,
+		"data": gin.H{
+			"results": results,
+			"offset":  offset,
+			"count":   len(results),
+		},
 	})
 }
+
+func (h *QuizHandler) writeSessionError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error_type":    "not_found",
+			"error_message": "Quiz session not found",
+		})
+	case services.ErrForbidden:
+		c.JSON(http.StatusForbidden, gin.H{
+			"error_type":    "forbidden",
+			"error_message": "Access denied",
+		})
+	case services.ErrInvalidParam:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error_type":    "invalid_param",
+			"error_message": "Question index out of range or answer does not match the question type",
+		})
+	case services.ErrAlreadyAnswered:
+		c.JSON(http.StatusConflict, gin.H{
+			"error_type":    "already_answered",
+			"error_message": "Question has already been answered",
+		})
+	case services.ErrSessionFinished:
+		c.JSON(http.StatusConflict, gin.H{
+			"error_type":    "session_finished",
+			"error_message": "Quiz session is already finished",
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error_type":    "internal",
+			"error_message": err.Error(),
+		})
+	}
+}
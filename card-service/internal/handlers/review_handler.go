@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+)
+
+type ReviewHandler struct {
+	service *services.ReviewService
+}
+
+func NewReviewHandler(service *services.ReviewService) *ReviewHandler {
+	return &ReviewHandler{service: service}
+}
+
+type ExportReviewsRequest struct {
+	Format models.ExportFormat `form:"format" binding:"omitempty,oneof=csv json"`
+}
+
+func writeReviewError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error_type": "not_found", "error_message": "Card not found"})
+	case services.ErrForbidden:
+		c.JSON(http.StatusForbidden, gin.H{"error_type": "forbidden", "error_message": "Access denied"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
+	}
+}
+
+func (h *ReviewHandler) GetCardStats(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cardID := c.Param("cardId")
+
+	stats, err := h.service.GetCardStats(c.Request.Context(), cardID, userID)
+	if err != nil {
+		writeReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": stats})
+}
+
+func (h *ReviewHandler) GetCardReviews(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cardID := c.Param("cardId")
+	offset, _ := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 32)
+	limit, _ := strconv.ParseInt(c.DefaultQuery("limit", "50"), 10, 32)
+
+	reviews, err := h.service.GetCardReviews(c.Request.Context(), cardID, userID, int32(offset), int32(limit))
+	if err != nil {
+		writeReviewError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"reviews": reviews,
+			"offset":  offset,
+			"count":   len(reviews),
+		},
+	})
+}
+
+func (h *ReviewHandler) ExportReviews(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req ExportReviewsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": err.Error()})
+		return
+	}
+	if req.Format == "" {
+		req.Format = models.ExportFormatCSV
+	}
+
+	export, err := h.service.ExportReviews(c.Request.Context(), userID, req.Format)
+	if err != nil {
+		writeReviewError(c, err)
+		return
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": export.FileName}))
+	c.Header("Content-Type", export.ContentType)
+	c.Status(http.StatusOK)
+	// The response has started, a failure now can only cut the file short
+	if err := export.Write(c.Writer); err != nil {
+		_ = c.Error(err)
+	}
+}
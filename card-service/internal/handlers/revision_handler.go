@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+)
+
+type RevisionHandler struct {
+	service *services.RevisionService
+}
+
+func NewRevisionHandler(service *services.RevisionService) *RevisionHandler {
+	return &RevisionHandler{service: service}
+}
+
+type RevertCardRequest struct {
+	RevisionID int64 `json:"revision_id" binding:"required"`
+}
+
+type RevertSetRequest struct {
+	// At is the point in time to bring the cards back to.
+	At time.Time `json:"at" binding:"required"`
+}
+
+func writeRevisionError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error_type": "not_found", "error_message": "Set, card or revision not found"})
+	case services.ErrForbidden:
+		c.JSON(http.StatusForbidden, gin.H{"error_type": "forbidden", "error_message": "Access denied"})
+	case services.ErrInvalidParam:
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "invalid_param", "error_message": "A set can only be reverted to a point in the past"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
+	}
+}
+
+func (h *RevisionHandler) GetCardRevisions(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cardID := c.Param("cardId")
+	offset, _ := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 32)
+	limit, _ := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 32)
+
+	revisions, err := h.service.GetCardRevisions(c.Request.Context(), cardID, userID, int32(offset), int32(limit))
+	if err != nil {
+		writeRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"revisions": revisions,
+			"offset":    offset,
+			"count":     len(revisions),
+		},
+	})
+}
+
+func (h *RevisionHandler) GetSetRevisions(c *gin.Context) {
+	userID := c.GetString("user_id")
+	setID := c.Param("setId")
+	offset, _ := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 32)
+	limit, _ := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 32)
+
+	revisions, err := h.service.GetSetRevisions(c.Request.Context(), setID, userID, int32(offset), int32(limit))
+	if err != nil {
+		writeRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"revisions": revisions,
+			"offset":    offset,
+			"count":     len(revisions),
+		},
+	})
+}
+
+func (h *RevisionHandler) RevertCard(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cardID := c.Param("cardId")
+	var req RevertCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": err.Error()})
+		return
+	}
+
+	card, err := h.service.RevertCard(c.Request.Context(), cardID, req.RevisionID, userID)
+	if err != nil {
+		writeRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": card})
+}
+
+func (h *RevisionHandler) RevertSet(c *gin.Context) {
+	userID := c.GetString("user_id")
+	setID := c.Param("setId")
+	var req RevertSetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": err.Error()})
+		return
+	}
+
+	result, err := h.service.RevertSet(c.Request.Context(), setID, userID, req.At)
+	if err != nil {
+		writeRevisionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": result})
+}
@@ -0,0 +1,68 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+)
+
+type SettingsHandler struct {
+	service *services.SettingsService
+}
+
+func NewSettingsHandler(service *services.SettingsService) *SettingsHandler {
+	return &SettingsHandler{service: service}
+}
+
+type UpdateSettingsRequest struct {
+	Scheduler            *models.SchedulerType `json:"scheduler" binding:"omitempty,oneof=sm2 fsrs"`
+	Timezone             *string               `json:"timezone"`
+	DailyGoalType        *models.DailyGoalType `json:"daily_goal_type" binding:"omitempty,oneof=cards minutes"`
+	DailyGoal            *int32                `json:"daily_goal" binding:"omitempty,min=1,max=1000"`
+	StreakFreezesPerWeek *int32                `json:"streak_freezes_per_week" binding:"omitempty,min=0,max=6"`
+	LeechThreshold       *int32                `json:"leech_threshold" binding:"omitempty,min=0,max=100"`
+	SuspendLeeches       *bool                 `json:"suspend_leeches"`
+}
+
+func (h *SettingsHandler) GetSettings(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	settings, err := h.service.GetSettings(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": settings})
+}
+
+func (h *SettingsHandler) UpdateSettings(c *gin.Context) {
+	userID := c.GetString("user_id")
+	var req UpdateSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": err.Error()})
+		return
+	}
+
+	settings, err := h.service.UpdateSettings(c.Request.Context(), userID, services.SettingsUpdate{
+		Scheduler:            req.Scheduler,
+		Timezone:             req.Timezone,
+		DailyGoalType:        req.DailyGoalType,
+		DailyGoal:            req.DailyGoal,
+		StreakFreezesPerWeek: req.StreakFreezesPerWeek,
+		LeechThreshold:       req.LeechThreshold,
+		SuspendLeeches:       req.SuspendLeeches,
+	})
+	if err == services.ErrInvalidParam {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "invalid_param", "error_message": "Invalid settings"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": settings})
+}
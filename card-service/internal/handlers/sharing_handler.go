@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+)
+
+type SharingHandler struct {
+	service *services.SharingService
+}
+
+func NewSharingHandler(service *services.SharingService) *SharingHandler {
+	return &SharingHandler{service: service}
+}
+
+type ShareSetRequest struct {
+	Username string         `json:"username" binding:"required"`
+	Role     models.SetRole `json:"role" binding:"required,oneof=viewer editor"`
+}
+
+type UpdateShareRequest struct {
+	Role models.SetRole `json:"role" binding:"required,oneof=viewer editor"`
+}
+
+func writeSharingError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error_type": "not_found", "error_message": "Set or share not found"})
+	case services.ErrForbidden:
+		c.JSON(http.StatusForbidden, gin.H{"error_type": "forbidden", "error_message": "Access denied"})
+	case services.ErrUserNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error_type": "user_not_found", "error_message": "User not found"})
+	case services.ErrInvalidParam:
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "invalid_param", "error_message": "Invalid role or the set can't be shared with its owner"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
+	}
+}
+
+func (h *SharingHandler) ShareSet(c *gin.Context) {
+	userID := c.GetString("user_id")
+	setID := c.Param("setId")
+	var req ShareSetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": err.Error()})
+		return
+	}
+
+	username := strings.TrimPrefix(strings.TrimSpace(req.Username), "@")
+	share, err := h.service.ShareSet(c.Request.Context(), setID, userID, username, req.Role)
+	if err != nil {
+		writeSharingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": share})
+}
+
+func (h *SharingHandler) GetShares(c *gin.Context) {
+	userID := c.GetString("user_id")
+	setID := c.Param("setId")
+
+	shares, err := h.service.GetShares(c.Request.Context(), setID, userID)
+	if err != nil {
+		writeSharingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"shares": shares}})
+}
+
+func (h *SharingHandler) UpdateShare(c *gin.Context) {
+	userID := c.GetString("user_id")
+	setID := c.Param("setId")
+	var req UpdateShareRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error_type": "validation_failed", "error_message": err.Error()})
+		return
+	}
+
+	if err := h.service.UpdateShareRole(c.Request.Context(), setID, userID, c.Param("userId"), req.Role); err != nil {
+		writeSharingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{}})
+}
+
+func (h *SharingHandler) RemoveShare(c *gin.Context) {
+	userID := c.GetString("user_id")
+	setID := c.Param("setId")
+
+	if err := h.service.RemoveShare(c.Request.Context(), setID, userID, c.Param("userId")); err != nil {
+		writeSharingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{}})
+}
+
+func (h *SharingHandler) GetInvitations(c *gin.Context) {
+	userID := c.GetString("user_id")
+	offset, _ := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 32)
+	limit, _ := strconv.ParseInt(c.DefaultQuery("limit", "10"), 10, 32)
+
+	invitations, err := h.service.GetInvitations(c.Request.Context(), userID, int32(offset), int32(limit))
+	if err != nil {
+		writeSharingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"invitations": invitations,
+			"offset":      offset,
+			"count":       len(invitations),
+		},
+	})
+}
+
+func (h *SharingHandler) AcceptInvitation(c *gin.Context) {
+	h.respond(c, true)
+}
+
+func (h *SharingHandler) DeclineInvitation(c *gin.Context) {
+	h.respond(c, false)
+}
+
+func (h *SharingHandler) respond(c *gin.Context, accept bool) {
+	userID := c.GetString("user_id")
+	setID := c.Param("setId")
+
+	if err := h.service.RespondToInvitation(c.Request.Context(), setID, userID, accept); err != nil {
+		writeSharingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{}})
+}
+
+func (h *SharingHandler) GetSharedSets(c *gin.Context) {
+	userID := c.GetString("user_id")
+	offset, _ := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 32)
+	limit, _ := strconv.ParseInt(c.DefaultQuery("limit", "10"), 10, 32)
+
+	sets, err := h.service.GetSharedSets(c.Request.Context(), userID, int32(offset), int32(limit))
+	if err != nil {
+		writeSharingError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"sets":   sets,
+			"offset": offset,
+			"count":  len(sets),
+		},
+	})
+}
@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+)
+
+type TrashHandler struct {
+	service *services.TrashService
+}
+
+func NewTrashHandler(service *services.TrashService) *TrashHandler {
+	return &TrashHandler{service: service}
+}
+
+func writeTrashError(c *gin.Context, err error) {
+	switch err {
+	case services.ErrNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error_type": "not_found", "error_message": "Not found in the trash"})
+	case services.ErrForbidden:
+		c.JSON(http.StatusForbidden, gin.H{"error_type": "forbidden", "error_message": "Access denied"})
+	case services.ErrSetDeleted:
+		c.JSON(http.StatusConflict, gin.H{"error_type": "set_deleted", "error_message": "The card's set is in the trash, restore it first"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error_type": "internal", "error_message": err.Error()})
+	}
+}
+
+func (h *TrashHandler) GetTrash(c *gin.Context) {
+	userID := c.GetString("user_id")
+	offset, _ := strconv.ParseInt(c.DefaultQuery("offset", "0"), 10, 32)
+	limit, _ := strconv.ParseInt(c.DefaultQuery("limit", "20"), 10, 32)
+
+	items, err := h.service.GetTrash(c.Request.Context(), userID, int32(offset), int32(limit))
+	if err != nil {
+		writeTrashError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{
+			"items":  items,
+			"offset": offset,
+			"count":  len(items),
+		},
+	})
+}
+
+func (h *TrashHandler) RestoreSet(c *gin.Context) {
+	userID := c.GetString("user_id")
+	setID := c.Param("setId")
+
+	set, err := h.service.RestoreSet(c.Request.Context(), setID, userID)
+	if err != nil {
+		writeTrashError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": set})
+}
+
+func (h *TrashHandler) RestoreCard(c *gin.Context) {
+	userID := c.GetString("user_id")
+	cardID := c.Param("cardId")
+
+	card, err := h.service.RestoreCard(c.Request.Context(), cardID, userID)
+	if err != nil {
+		writeTrashError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": card})
+}
@@ -14,8 +14,48 @@ const (
 type CardRating int
 
 const (
-	RatingForgot CardRating = 0
-	RatingRemember CardRating = 1
+	RatingNone  CardRating = 0
+	RatingAgain CardRating = 1
+	RatingHard  CardRating = 2
+	RatingGood  CardRating = 3
+	RatingEasy  CardRating = 4
+)
+
+// ParseRating maps a grade name ("again", "hard", "good", "easy") to a rating.
+func ParseRating(grade string) (CardRating, bool) {
+	switch grade {
+	case "again":
+		return RatingAgain, true
+	case "hard":
+		return RatingHard, true
+	case "good":
+		return RatingGood, true
+	case "easy":
+		return RatingEasy, true
+	}
+	return RatingNone, false
+}
+
+// RatingFromBinary converts the forgot/remember answer of older clients.
+func RatingFromBinary(remembered bool) CardRating {
+	if remembered {
+		return RatingGood
+	}
+	return RatingAgain
+}
+
+type SchedulerType string
+
+const (
+	SchedulerSM2  SchedulerType = "sm2"
+	SchedulerFSRS SchedulerType = "fsrs"
+)
+
+type DailyGoalType string
+
+const (
+	DailyGoalCards   DailyGoalType = "cards"
+	DailyGoalMinutes DailyGoalType = "minutes"
 )
 
 type SessionType string
@@ -35,43 +75,148 @@ type QuizOption struct {
 	IsCorrect  bool   `json:"is_correct"`
 }
 
+type QuizQuestionType string
+
+const (
+	// QuestionMultipleChoice shows the front and asks to pick the back.
+	QuestionMultipleChoice QuizQuestionType = "multiple_choice"
+	// QuestionReverse shows the back and asks to pick the front.
+	QuestionReverse QuizQuestionType = "reverse"
+	// QuestionTyped shows the front and asks to type the back.
+	QuestionTyped QuizQuestionType = "typed"
+	// QuestionTrueFalse shows the front with a back that is either its own or another card's.
+	QuestionTrueFalse QuizQuestionType = "true_false"
+)
+
+// QuizQuestion is a question together with its answer, as the server keeps it.
+// Clients get its QuizQuestionView.
 type QuizQuestion struct {
-	CardID       string       `json:"card_id"`
-	Front        string       `json:"front"`
-	Back         string       `json:"back"`
-	Options      []QuizOption `json:"options"`
-	CorrectIndex int          `json:"correct_index"`
+	CardID string           `json:"card_id"`
+	// Item is the review item of the card asked about; Front and Back are
+	// what it asks and answers.
+	Item   int16            `json:"item,omitempty"`
+	Type   QuizQuestionType `json:"type"`
+	// Prompt is the side shown to the user.
+	Prompt  string       `json:"prompt"`
+	Front   string       `json:"front"`
+	Back    string       `json:"back"`
+	Options []QuizOption `json:"options,omitempty"`
+	// CorrectIndex is -1 for questions without options.
+	CorrectIndex int `json:"correct_index"`
+	// Statement is the answer proposed in a true/false question.
+	Statement       string `json:"statement,omitempty"`
+	StatementIsTrue bool   `json:"statement_is_true,omitempty"`
+}
+
+// Answer is the text a correct answer to the question matches.
+func (q QuizQuestion) Answer() string {
+	if q.Type == QuestionReverse {
+		return q.Front
+	}
+	return q.Back
+}
+
+// QuizOptionView is an option of a question without telling whether it is correct.
+type QuizOptionView struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+// QuizQuestionView is what the client is shown of a question: what it asks,
+// the options to pick from and the statement to judge, but not the answer.
+type QuizQuestionView struct {
+	CardID    string           `json:"card_id"`
+	Item      int16            `json:"item,omitempty"`
+	Type      QuizQuestionType `json:"type"`
+	Prompt    string           `json:"prompt"`
+	Options   []QuizOptionView `json:"options,omitempty"`
+	Statement string           `json:"statement,omitempty"`
+}
+
+// View returns what the client is shown of the question.
+func (q QuizQuestion) View() QuizQuestionView {
+	view := QuizQuestionView{CardID: q.CardID, Item: q.Item, Type: q.Type, Prompt: q.Prompt, Statement: q.Statement}
+	for _, option := range q.Options {
+		view.Options = append(view.Options, QuizOptionView{ID: option.ID, Text: option.Text})
+	}
+	return view
 }
 
 type QuizSession struct {
-	ID          string         `json:"id"`
-	SetID       string         `json:"set_id"`
-	UserID      string         `json:"user_id"`
-	QuestionCount int          `json:"question_count"`
-	Questions   []QuizQuestion `json:"questions"`
-	CreatedAt   time.Time      `json:"created_at"`
+	ID            string             `json:"id"`
+	SetID         string             `json:"set_id"`
+	UserID        string             `json:"user_id"`
+	QuestionCount int                `json:"question_count"`
+	Questions     []QuizQuestion     `json:"questions"`
+	Answers       []QuizAnswerResult `json:"answers"`
+	CreatedAt     time.Time          `json:"created_at"`
+	ExpiresAt     time.Time          `json:"expires_at"`
+	FinishedAt    *time.Time         `json:"finished_at,omitempty"`
+}
+
+// QuizSessionView is a quiz session as the client is shown it: the answers to
+// questions not answered yet stay on the server.
+type QuizSessionView struct {
+	ID            string             `json:"id"`
+	SetID         string             `json:"set_id"`
+	QuestionCount int                `json:"question_count"`
+	Questions     []QuizQuestionView `json:"questions"`
+	Answers       []QuizAnswerResult `json:"answers"`
+	CreatedAt     time.Time          `json:"created_at"`
+	ExpiresAt     time.Time          `json:"expires_at"`
+	FinishedAt    *time.Time         `json:"finished_at,omitempty"`
+}
+
+// View returns what the client is shown of the session.
+func (s *QuizSession) View() *QuizSessionView {
+	view := &QuizSessionView{
+		ID:            s.ID,
+		SetID:         s.SetID,
+		QuestionCount: s.QuestionCount,
+		Questions:     make([]QuizQuestionView, 0, len(s.Questions)),
+		Answers:       s.Answers,
+		CreatedAt:     s.CreatedAt,
+		ExpiresAt:     s.ExpiresAt,
+		FinishedAt:    s.FinishedAt,
+	}
+	for _, question := range s.Questions {
+		view.Questions = append(view.Questions, question.View())
+	}
+	return view
 }
 
+// QuizAnswerRequest carries the answer in the field matching the question type:
+// SelectedIndex for multiple choice and reverse, TextAnswer for typed and
+// BoolAnswer for true/false questions.
 type QuizAnswerRequest struct {
-	QuestionIndex int  `json:"question_index"`
-	SelectedIndex int  `json:"selected_index"`
-	TimeSpentMs   int64 `json:"time_spent_ms"`
+	QuestionIndex int    `json:"question_index"`
+	SelectedIndex int    `json:"selected_index"`
+	TextAnswer    string `json:"text_answer,omitempty"`
+	BoolAnswer    *bool  `json:"bool_answer,omitempty"`
+	TimeSpentMs   int64  `json:"time_spent_ms"`
 }
 
 type QuizAnswerResult struct {
 	QuestionIndex int    `json:"question_index"`
+	SelectedIndex int    `json:"selected_index"`
+	TextAnswer    string `json:"text_answer,omitempty"`
+	BoolAnswer    *bool  `json:"bool_answer,omitempty"`
 	IsCorrect     bool   `json:"is_correct"`
 	CorrectIndex  int    `json:"correct_index"`
+	CorrectAnswer string `json:"correct_answer,omitempty"`
 	Explanation   string `json:"explanation,omitempty"`
+	TimeSpentMs   int64  `json:"time_spent_ms"`
 }
 
 type QuizResult struct {
 	SessionID       string `json:"session_id"`
+	SetID           string `json:"set_id"`
 	TotalQuestions  int    `json:"total_questions"`
 	CorrectAnswers  int    `json:"correct_answers"`
 	IncorrectAnswers int   `json:"incorrect_answers"`
 	ScorePercentage float32 `json:"score_percentage"`
 	TimeSpentMs     int64  `json:"time_spent_ms"`
+	FinishedAt      time.Time `json:"finished_at"`
 }
 
 type CardSet struct {
@@ -85,9 +230,72 @@ type CardSet struct {
 	IsPublic          bool        `json:"is_public"`
 	ViewsCount        int64       `json:"views_count"`
 	ClonesCount       int64       `json:"clones_count,omitempty"`
-	Tags              []string    `json:"tags,omitempty"`
+	Tags              []string    `json:"tags"`
 	CreatedAt         time.Time   `json:"created_at"`
 	Author            *AuthorInfo `json:"author,omitempty"`
+	// ClonedFromSetID is the set this one was copied from, if it still exists.
+	ClonedFromSetID *string `json:"cloned_from_set_id,omitempty"`
+	// SyncedAt is when changes were last pulled from the original.
+	SyncedAt *time.Time `json:"synced_at,omitempty"`
+	// Role is what the requesting user is to the set; empty for public sets
+	// they have no role on.
+	Role SetRole `json:"role,omitempty"`
+	// FolderID is the owner's folder the set is in, nil at the top level.
+	FolderID *string `json:"folder_id,omitempty"`
+}
+
+// Folder groups a user's sets. Counts cover the sets in the folder and in all
+// of its subfolders.
+type Folder struct {
+	ID                string    `json:"id"`
+	OwnerID           string    `json:"-"`
+	ParentID          *string   `json:"parent_id,omitempty"`
+	Name              string    `json:"name"`
+	CreatedAt         time.Time `json:"created_at"`
+	SetCount          int32     `json:"set_count"`
+	CardCount         int32     `json:"card_count"`
+	LearnedCount      int32     `json:"learned_count"`
+	MasteryPercentage float32   `json:"mastery_percentage"`
+}
+
+// FolderContents is what a folder holds directly. Folder is nil and Path empty
+// at the top level.
+type FolderContents struct {
+	Folder *Folder `json:"folder,omitempty"`
+	// Path lists the folders above this one, starting from the top.
+	Path    []Folder  `json:"path"`
+	Folders []Folder  `json:"folders"`
+	Sets    []CardSet `json:"sets"`
+}
+
+type SetRole string
+
+const (
+	SetRoleOwner  SetRole = "owner"
+	SetRoleEditor SetRole = "editor"
+	SetRoleViewer SetRole = "viewer"
+)
+
+type ShareStatus string
+
+const (
+	ShareStatusPending  ShareStatus = "pending"
+	ShareStatusAccepted ShareStatus = "accepted"
+	ShareStatusDeclined ShareStatus = "declined"
+)
+
+// SetShare gives a user a role on someone else's set once they accept it.
+type SetShare struct {
+	SetID       string      `json:"set_id"`
+	UserID      string      `json:"user_id"`
+	User        *AuthorInfo `json:"user,omitempty"`
+	Role        SetRole     `json:"role"`
+	Status      ShareStatus `json:"status"`
+	InvitedBy   string      `json:"invited_by"`
+	CreatedAt   time.Time   `json:"created_at"`
+	RespondedAt *time.Time  `json:"responded_at,omitempty"`
+	// Set is filled in when listing the shares of a user.
+	Set *CardSet `json:"set,omitempty"`
 }
 
 type CardSetDetail struct {
@@ -102,13 +310,287 @@ type Card struct {
 	Back       string     `json:"back"`
 	ImageURL   *string    `json:"image_url,omitempty"`
 	AudioURL   *string    `json:"audio_url,omitempty"`
+	// Rich is the full content of a card with rich content; Front and Back
+	// then hold its plain-text rendering.
+	Rich       *RichContent `json:"rich,omitempty"`
+	// Item is the review item of the card Status and the rest are the progress
+	// on: its first item in card lists. A study queue has an entry for every
+	// item, with Prompt and Answer, what it asks and answers in the card's format.
+	Item       int16      `json:"item,omitempty"`
+	Prompt     string     `json:"prompt,omitempty"`
+	Answer     string     `json:"answer,omitempty"`
 	Status     CardStatus `json:"status"`
 	ErrorCount int32      `json:"error_count"`
 	LastRating CardRating `json:"last_rating"`
 	NextReview *time.Time `json:"next_review,omitempty"`
+	Lapses     int32      `json:"lapses,omitempty"`
+	Leech      bool       `json:"leech,omitempty"`
+	// Suspended and BuriedUntil tell why a card is left out of study and quizzes.
+	Suspended   bool       `json:"suspended,omitempty"`
+	BuriedUntil *time.Time `json:"buried_until,omitempty"`
 	CreatedAt  time.Time  `json:"created_at"`
 }
 
+// CardInput is a card to create or, with ID, to update in a batch. A card
+// gets rich content if it has a format or any of the fields after AudioURL.
+type CardInput struct {
+	ID          string        `json:"id,omitempty"`
+	Front       string        `json:"front"`
+	Back        string        `json:"back"`
+	ImageURL    *string       `json:"image_url,omitempty"`
+	AudioURL    *string       `json:"audio_url,omitempty"`
+	Format      ContentFormat `json:"format,omitempty"`
+	FrontImages []string      `json:"front_images,omitempty"`
+	BackImages  []string      `json:"back_images,omitempty"`
+	Notes       *string       `json:"notes,omitempty"`
+	Hint        *string       `json:"hint,omitempty"`
+	Example     *string       `json:"example,omitempty"`
+	// Type is basic if empty; other types make the card rich.
+	Type CardType `json:"type,omitempty"`
+}
+
+// BatchItemResult reports on one item of a batch, in request order.
+type BatchItemResult struct {
+	Index  int    `json:"index"`
+	CardID string `json:"card_id,omitempty"`
+	// Error is empty if the item is valid.
+	Error string `json:"error,omitempty"`
+	Card  *Card  `json:"card,omitempty"`
+}
+
+// BatchResult is the outcome of a batch: either every item is applied or, if
+// any of them is invalid, none is.
+type BatchResult struct {
+	Applied bool              `json:"applied"`
+	Results []BatchItemResult `json:"results"`
+}
+
+// CardProgress is the learning state of a single review item of a card for a single user.
+type CardProgress struct {
+	UserID     string     `json:"-"`
+	CardID     string     `json:"card_id"`
+	// Item is the review item of the card the progress is on.
+	Item       int16      `json:"item,omitempty"`
+	Status     CardStatus `json:"status"`
+	ErrorCount int32      `json:"error_count"`
+	LastRating CardRating `json:"last_rating"`
+	NextReview *time.Time `json:"next_review,omitempty"`
+	Streak     int32      `json:"streak"`
+	// Reps is the number of successful reviews in a row.
+	Reps           int32      `json:"reps"`
+	IntervalDays   int32      `json:"interval_days"`
+	Ease           float64    `json:"ease"`
+	Stability      float64    `json:"stability"`
+	Difficulty     float64    `json:"difficulty"`
+	LastReviewedAt *time.Time `json:"last_reviewed_at,omitempty"`
+	// Lapses counts the times the item was forgotten after it had been learned.
+	Lapses int32 `json:"lapses"`
+	// Leech marks an item with LeechThreshold lapses or more.
+	Leech       bool       `json:"leech"`
+	SuspendedAt *time.Time `json:"suspended_at,omitempty"`
+	// BuriedUntil is when a buried item comes back to study.
+	BuriedUntil *time.Time `json:"buried_until,omitempty"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type UserSettings struct {
+	Scheduler SchedulerType `json:"scheduler"`
+	// Timezone is an IANA name; study days and streaks are counted in it.
+	Timezone      string        `json:"timezone"`
+	DailyGoalType DailyGoalType `json:"daily_goal_type"`
+	// DailyGoal is the number of cards or minutes a day needs to count towards the streak.
+	DailyGoal int32 `json:"daily_goal"`
+	// StreakFreezesPerWeek is how many missed days a week the streak survives.
+	StreakFreezesPerWeek int32 `json:"streak_freezes_per_week"`
+	// LeechThreshold is the number of lapses that makes an item a leech, 0 for never.
+	LeechThreshold int32 `json:"leech_threshold"`
+	// SuspendLeeches suspends items when they become leeches.
+	SuspendLeeches bool `json:"suspend_leeches"`
+}
+
+// DefaultLeechThreshold is the number of lapses that makes an item a leech
+// unless the user sets another.
+const DefaultLeechThreshold = 8
+
+// DefaultUserSettings are used until the user changes anything.
+func DefaultUserSettings() *UserSettings {
+	return &UserSettings{
+		Scheduler:      SchedulerSM2,
+		Timezone:       "UTC",
+		DailyGoalType:  DailyGoalCards,
+		DailyGoal:      1,
+		LeechThreshold: DefaultLeechThreshold,
+	}
+}
+
+// CardContent is what a card shows, without any learning state.
+type CardContent struct {
+	Front    string       `json:"front"`
+	Back     string       `json:"back"`
+	ImageURL *string      `json:"image_url,omitempty"`
+	AudioURL *string      `json:"audio_url,omitempty"`
+	Rich     *RichContent `json:"rich,omitempty"`
+}
+
+type ContentFormat string
+
+const (
+	// FormatPlain is text shown as it is.
+	FormatPlain ContentFormat = "plain"
+	// FormatMarkdown is Markdown with LaTeX formulas between $...$, $$...$$,
+	// \(...\) or \[...\].
+	FormatMarkdown ContentFormat = "markdown"
+)
+
+// Content versions tell clients what a card carries. Version 1 is plain text
+// front and back with at most one image; version 2 adds RichContent.
+const (
+	ContentVersionPlain int32 = 1
+	ContentVersionRich  int32 = 2
+)
+
+type CardType string
+
+const (
+	// CardBasic asks for the back of the card given the front.
+	CardBasic CardType = "basic"
+	// CardBasicReverse also asks for the front given the back; the two
+	// directions are scheduled independently.
+	CardBasicReverse CardType = "basic_reverse"
+	// CardCloze is text on the front with deletions marked as {{c1::answer}} or
+	// {{c1::answer::hint}}; each deletion number is asked separately, and the
+	// back holds extra information shown with the answer.
+	CardCloze CardType = "cloze"
+)
+
+// Review items of basic and reversible cards; the items of a cloze card are its
+// deletion numbers.
+const (
+	ItemForward int16 = 0
+	ItemReverse int16 = 1
+)
+
+// RichContent is the content of a card for clients that understand content
+// version 2. Text fields are in Format, sanitized when it is Markdown.
+type RichContent struct {
+	Version     int32         `json:"version"`
+	// Type is basic if empty.
+	Type        CardType      `json:"type,omitempty"`
+	// Items are the review items of a card that is not basic, in order.
+	Items       []int16       `json:"items,omitempty"`
+	Format      ContentFormat `json:"format"`
+	Front       string        `json:"front"`
+	Back        string        `json:"back"`
+	FrontImages []string      `json:"front_images,omitempty"`
+	BackImages  []string      `json:"back_images,omitempty"`
+	Notes       *string       `json:"notes,omitempty"`
+	Hint        *string       `json:"hint,omitempty"`
+	Example     *string       `json:"example,omitempty"`
+}
+
+// ClonedCardState pairs a card of an original set with its copy in a clone.
+// Either side is nil when the card has no counterpart. The Changed flags tell
+// whether a side changed since the card was copied or last pulled.
+type ClonedCardState struct {
+	OriginalID      string
+	Original        *CardContent
+	OriginalChanged bool
+	CopyID          string
+	Copy            *CardContent
+	CopyChanged     bool
+}
+
+type CardChange string
+
+const (
+	// CardAdded is a card of the original the clone doesn't have.
+	CardAdded CardChange = "added"
+	// CardUpdated is a card changed in the original and untouched in the clone.
+	CardUpdated CardChange = "updated"
+	// CardConflict is a card changed both in the original and in the clone.
+	CardConflict CardChange = "conflict"
+	// CardRemoved is a card deleted from the original but still in the clone.
+	CardRemoved CardChange = "removed"
+)
+
+// CardDiff is one difference between a clone and its original.
+type CardDiff struct {
+	Change         CardChange   `json:"change"`
+	OriginalCardID string       `json:"original_card_id,omitempty"`
+	CardID         string       `json:"card_id,omitempty"`
+	Original       *CardContent `json:"original,omitempty"`
+	Copy           *CardContent `json:"copy,omitempty"`
+}
+
+type SetDiff struct {
+	SetID         string     `json:"set_id"`
+	OriginalSetID string     `json:"original_set_id"`
+	SyncedAt      *time.Time `json:"synced_at,omitempty"`
+	Changes       []CardDiff `json:"changes"`
+}
+
+type PullResult struct {
+	Added   int32 `json:"added"`
+	Updated int32 `json:"updated"`
+	Removed int32 `json:"removed"`
+}
+
+type RevisionAction string
+
+const (
+	RevisionCreated  RevisionAction = "created"
+	RevisionUpdated  RevisionAction = "updated"
+	RevisionDeleted  RevisionAction = "deleted"
+	RevisionRestored RevisionAction = "restored"
+	RevisionMoved    RevisionAction = "moved"
+)
+
+// CardRevision is a card as it was right after one change to it. Previous is
+// the card before the change, nil for the revision that created it.
+type CardRevision struct {
+	ID       int64          `json:"id"`
+	CardID   string         `json:"card_id"`
+	SetID    string         `json:"set_id"`
+	Action   RevisionAction `json:"action"`
+	EditorID *string        `json:"-"`
+	Editor   *AuthorInfo    `json:"editor,omitempty"`
+	CardContent
+	Previous *CardContent `json:"previous,omitempty"`
+	// Changed names the content fields that differ from Previous.
+	Changed   []string  `json:"changed,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// SetRevertResult counts the cards a set revert brought back to their earlier
+// content or out of the trash, and the cards it deleted because they didn't
+// exist yet.
+type SetRevertResult struct {
+	Reverted int32 `json:"reverted"`
+	Deleted  int32 `json:"deleted"`
+}
+
+type TrashItemType string
+
+const (
+	TrashItemSet  TrashItemType = "set"
+	TrashItemCard TrashItemType = "card"
+)
+
+// TrashItem is a deleted set, or a deleted card of a set that is still in
+// place. It can be restored until PurgeAt, when it is removed for good.
+type TrashItem struct {
+	Type  TrashItemType `json:"type"`
+	ID    string        `json:"id"`
+	SetID string        `json:"set_id"`
+	// Title is the name of a set or the front of a card.
+	Title   string `json:"title"`
+	SetName string `json:"set_name"`
+	// CardCount is the number of cards a deleted set takes with it.
+	CardCount int32     `json:"card_count,omitempty"`
+	DeletedAt time.Time `json:"deleted_at"`
+	PurgeAt   time.Time `json:"purge_at"`
+}
+
 type CardPreview struct {
 	ID     string     `json:"id"`
 	Front  string     `json:"front"`
@@ -122,22 +604,75 @@ type AuthorInfo struct {
 }
 
 type StudySession struct {
-	ID            string      `json:"id"`
-	SetID         *string     `json:"set_id,omitempty"`
-	UserID        string      `json:"user_id"`
-	SessionType   SessionType `json:"session_type"`
-	Cards         []Card      `json:"cards"`
-	CurrentCardID string      `json:"current_card_id,omitempty"`
-	CreatedAt     time.Time   `json:"created_at"`
+	ID              string      `json:"id"`
+	SetID           *string     `json:"set_id,omitempty"`
+	// FolderID is set for sessions over the sets of a folder.
+	FolderID        *string     `json:"folder_id,omitempty"`
+	UserID          string      `json:"user_id"`
+	SessionType     SessionType `json:"session_type"`
+	Cards           []Card      `json:"cards"`
+	// AnsweredCardIDs has a card once for every answered item of it.
+	AnsweredCardIDs []string    `json:"answered_card_ids"`
+	AnsweredItems   []CardItem  `json:"answered_items"`
+	// CurrentCardID and CurrentItem are the first entry in the queue that has
+	// not been answered yet.
+	CurrentCardID string     `json:"current_card_id,omitempty"`
+	CurrentItem   int16      `json:"current_item,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	FinishedAt    *time.Time `json:"finished_at,omitempty"`
+	// ElapsedSeconds is the wall-clock time from start to finish, stored when
+	// the session is finished.
+	ElapsedSeconds *int64 `json:"elapsed_seconds,omitempty"`
+}
+
+// CardItem names one review item of a card.
+type CardItem struct {
+	CardID string `json:"card_id"`
+	Item   int16  `json:"item"`
+}
+
+// StudySessionCard is a review item in a session's queue together with how it was answered.
+type StudySessionCard struct {
+	CardID          string
+	Item            int16
+	Position        int32
+	StatusBefore    *CardStatus
+	StatusAfter     *CardStatus
+	LastRating      CardRating
+	Attempts        int32
+	CorrectAttempts int32
+	TimeSpentMs     int64
+	AnsweredAt      *time.Time
+}
+
+type StatusTransition struct {
+	From  CardStatus `json:"from"`
+	To    CardStatus `json:"to"`
+	Count int32      `json:"count"`
+}
+
+type StudySessionSummary struct {
+	SessionID      string             `json:"session_id"`
+	TotalCards     int32              `json:"total_cards"`
+	CardsReviewed  int32              `json:"cards_reviewed"`
+	Answers        int32              `json:"answers"`
+	CorrectAnswers int32              `json:"correct_answers"`
+	Accuracy       float32            `json:"accuracy"`
+	ElapsedSeconds int64              `json:"elapsed_seconds"`
+	TimeSpentMs    int64              `json:"time_spent_ms"`
+	Transitions    []StatusTransition `json:"transitions"`
+	StartedAt      time.Time          `json:"started_at"`
+	FinishedAt     time.Time          `json:"finished_at"`
 }
 
 type AnswerResult struct {
-	CardID     string     `json:"card_id"`
-	NewStatus  CardStatus `json:"new_status"`
-	NextReview time.Time  `json:"next_review"`
-	Streak     int32      `json:"streak"`
-	ErrorCount int32      `json:"error_count"`
-	LastRating CardRating `json:"last_rating"`
+	CardID       string     `json:"card_id"`
+	NewStatus    CardStatus `json:"new_status"`
+	NextReview   time.Time  `json:"next_review"`
+	IntervalDays int32      `json:"interval_days"`
+	Streak       int32      `json:"streak"`
+	ErrorCount   int32      `json:"error_count"`
+	LastRating   CardRating `json:"last_rating"`
 }
 
 type SetStatistics struct {
@@ -147,13 +682,16 @@ type SetStatistics struct {
 	LearningCards    int32       `json:"learning_cards"`
 	NewCards         int32       `json:"new_cards"`
 	MasteryPercentage float32     `json:"mastery_percentage"`
+	TotalStudyTimeSeconds int64 `json:"total_study_time_seconds"`
 	StudyHistory     []StudyDay  `json:"study_history"`
 }
 
 type StudyDay struct {
-	Date            string `json:"date"`
-	CardsStudied    int32  `json:"cards_studied"`
-	TimeSpentMinutes int32 `json:"time_spent_minutes"`
+	Date             string `json:"date"`
+	CardsStudied     int32  `json:"cards_studied"`
+	TimeSpentSeconds int64  `json:"time_spent_seconds"`
+	// Deprecated: use TimeSpentSeconds.
+	TimeSpentMinutes int32  `json:"time_spent_minutes"`
 }
 
 type UserStatistics struct {
@@ -163,12 +701,174 @@ type UserStatistics struct {
 	CurrentStreak       int32      `json:"current_streak"`
 	LongestStreak       int32      `json:"longest_streak"`
 	LastStudyDate       *time.Time `json:"last_study_date,omitempty"`
+	GoalMetToday        bool       `json:"goal_met_today"`
+	DailyGoalType       DailyGoalType `json:"daily_goal_type"`
+	DailyGoal           int32      `json:"daily_goal"`
+	// FrozenDays are the missed days within the current streak covered by freezes.
+	FrozenDays          []string   `json:"frozen_days"`
+	FreezesLeftThisWeek int32      `json:"freezes_left_this_week"`
+	TotalStudyTimeSeconds int64    `json:"total_study_time_seconds"`
+	// Deprecated: use TotalStudyTimeSeconds.
 	TotalStudyTimeMinutes int32    `json:"total_study_time_minutes"`
 	StudyHistory        []StudyDay `json:"study_history"`
 }
 
+type ImportFormat string
+
+const (
+	ImportFormatCSV  ImportFormat = "csv"
+	ImportFormatTSV  ImportFormat = "tsv"
+	ImportFormatAnki ImportFormat = "apkg"
+)
+
+type ExportFormat string
+
+const (
+	ExportFormatCSV  ExportFormat = "csv"
+	ExportFormatJSON ExportFormat = "json"
+	ExportFormatAnki ExportFormat = "apkg"
+)
+
+// ImportReport describes the outcome of importing a file into a new set.
+type ImportReport struct {
+	Set       *CardSet         `json:"set"`
+	TotalRows int32            `json:"total_rows"`
+	Imported  int32            `json:"imported"`
+	Skipped   int32            `json:"skipped"`
+	Errors    []ImportRowError `json:"errors"`
+}
+
+// ImportRowError is a problem with one row (or Anki note) of an import file. The
+// row is either skipped or imported without the part that failed, e.g. its media.
+type ImportRowError struct {
+	Row     int32  `json:"row"`
+	Message string `json:"message"`
+	Skipped bool   `json:"skipped"`
+}
+
 type SearchResultSet struct {
 	Sets   []CardSet `json:"sets"`
 	Offset int32     `json:"offset"`
 	Count  int32     `json:"count"`
 }
+
+// TrendingSet is a public set ranked by the attention it got recently.
+type TrendingSet struct {
+	CardSet
+	RecentViews int32   `json:"recent_views"`
+	Score       float64 `json:"score"`
+}
+
+// TagCount is a tag with the number of sets carrying it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int32  `json:"count"`
+}
+
+// SearchParams is a full-text search over public sets. An empty query matches
+// every set that passes the filters, ranked by popularity alone.
+type SearchParams struct {
+	Query    string
+	Tags     []string
+	MinCards *int32
+	MaxCards *int32
+	AuthorID string
+	Offset   int32
+	Limit    int32
+}
+
+type SearchResult struct {
+	CardSet
+	Rank       float64          `json:"rank"`
+	Highlights SearchHighlights `json:"highlights"`
+}
+
+// SearchHighlights are HTML snippets with the matched words wrapped in <mark>.
+type SearchHighlights struct {
+	Name        string         `json:"name,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Card        *CardHighlight `json:"card,omitempty"`
+}
+
+// CardHighlight is the card of the set that matches the query best.
+type CardHighlight struct {
+	CardID string `json:"card_id"`
+	Front  string `json:"front"`
+	Back   string `json:"back"`
+}
+
+type ReviewSource string
+
+const (
+	ReviewFromStudy ReviewSource = "study"
+	ReviewFromQuiz  ReviewSource = "quiz"
+)
+
+// Review is an entry of the review log, one answer to a review item of a card.
+// The log is only ever appended to.
+type Review struct {
+	ID     int64  `json:"id"`
+	UserID string `json:"-"`
+	CardID string `json:"card_id"`
+	Item   int16  `json:"item"`
+	SetID  string `json:"set_id"`
+	// SessionID is the study session or quiz the answer was given in, after Source.
+	Source       ReviewSource `json:"source"`
+	SessionID    *string      `json:"session_id,omitempty"`
+	Rating       CardRating   `json:"rating"`
+	StatusBefore CardStatus   `json:"status_before"`
+	StatusAfter  CardStatus   `json:"status_after"`
+	// PreviousIntervalDays and IntervalDays are the item's interval before and
+	// after the answer, as set by Scheduler.
+	PreviousIntervalDays int32         `json:"previous_interval_days"`
+	IntervalDays         int32         `json:"interval_days"`
+	Scheduler            SchedulerType `json:"scheduler"`
+	// ResponseTimeMs is the time the client reports the answer took, 0 if unknown.
+	ResponseTimeMs int64     `json:"response_time_ms"`
+	ReviewedAt     time.Time `json:"reviewed_at"`
+}
+
+// ReviewCursor marks the end of a page of the review log; the zero cursor is
+// its start.
+type ReviewCursor struct {
+	ReviewedAt time.Time
+	ID         int64
+}
+
+// ReviewTotals sums up part of the review log of a user.
+type ReviewTotals struct {
+	Reviews int32
+	// Correct reviews are the ones not rated again.
+	Correct int32
+	// Lapses are reviews rated again of an item that had been learned.
+	Lapses int32
+	// Timed reviews are the ones with a response time, summing up to ResponseTimeMs.
+	Timed           int32
+	ResponseTimeMs  int64
+	FirstReviewedAt *time.Time
+	LastReviewedAt  *time.Time
+}
+
+// ReviewStats describes how a user did answering a card or one of its items.
+type ReviewStats struct {
+	Reviews               int32      `json:"reviews"`
+	CorrectReviews        int32      `json:"correct_reviews"`
+	Accuracy              float32    `json:"accuracy"`
+	AverageResponseTimeMs int64      `json:"average_response_time_ms"`
+	Lapses                int32      `json:"lapses"`
+	FirstReviewedAt       *time.Time `json:"first_reviewed_at,omitempty"`
+	LastReviewedAt        *time.Time `json:"last_reviewed_at,omitempty"`
+}
+
+// CardReviewStats are the statistics of a card over all its review items,
+// with a breakdown by item.
+type CardReviewStats struct {
+	CardID string `json:"card_id"`
+	ReviewStats
+	Items []ItemReviewStats `json:"items"`
+}
+
+type ItemReviewStats struct {
+	Item int16 `json:"item"`
+	ReviewStats
+}
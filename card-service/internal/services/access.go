@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/storage"
+)
+
+// Action is something a user does with a set.
+type Action int
+
+const (
+	// ActionView covers reading the set and its cards, studying, quizzes and export.
+	ActionView Action = iota
+	// ActionEditCards covers creating, updating and deleting cards.
+	ActionEditCards
+	// ActionManage covers changing or deleting the set itself and sharing it.
+	ActionManage
+)
+
+// Allowed tells whether a role permits an action. Anyone may view a public set.
+func Allowed(role models.SetRole, isPublic bool, action Action) bool {
+	switch action {
+	case ActionView:
+		return isPublic || role != ""
+	case ActionEditCards:
+		return role == models.SetRoleOwner || role == models.SetRoleEditor
+	case ActionManage:
+		return role == models.SetRoleOwner
+	}
+	return false
+}
+
+// Authorizer decides what users may do with sets: owners may do anything,
+// users the set is shared with act by their role, and everyone may view
+// public sets.
+type Authorizer struct {
+	setStorage   storage.CardSetStorage
+	cardStorage  storage.CardStorage
+	shareStorage storage.SetShareStorage
+}
+
+func NewAuthorizer(setStorage storage.CardSetStorage, cardStorage storage.CardStorage, shareStorage storage.SetShareStorage) *Authorizer {
+	return &Authorizer{setStorage: setStorage, cardStorage: cardStorage, shareStorage: shareStorage}
+}
+
+// Role returns the user's role on the set, empty if they have none. Shares
+// count only once accepted.
+func (a *Authorizer) Role(ctx context.Context, set *models.CardSet, userID string) (models.SetRole, error) {
+	if set.OwnerID == userID {
+		return models.SetRoleOwner, nil
+	}
+	if userID == "" {
+		return "", nil
+	}
+
+	share, err := a.shareStorage.Get(ctx, set.ID, userID)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if share.Status != models.ShareStatusAccepted {
+		return "", nil
+	}
+	return share.Role, nil
+}
+
+// Check returns ErrForbidden unless the user may perform the action on the
+// set. It fills in the user's role on the set and hides the owner's folder
+// from anyone else.
+func (a *Authorizer) Check(ctx context.Context, set *models.CardSet, userID string, action Action) error {
+	role, err := a.Role(ctx, set, userID)
+	if err != nil {
+		return err
+	}
+	set.Role = role
+	if role != models.SetRoleOwner {
+		set.FolderID = nil
+	}
+
+	if !Allowed(role, set.IsPublic, action) {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// Authorize loads a set the user may perform the action on.
+func (a *Authorizer) Authorize(ctx context.Context, setID, userID string, action Action) (*models.CardSet, error) {
+	set, err := a.setStorage.GetByID(ctx, setID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if err := a.Check(ctx, set, userID, action); err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// AuthorizeCard loads a card, without progress, whose set the user may perform the action on.
+func (a *Authorizer) AuthorizeCard(ctx context.Context, cardID, userID string, action Action) (*models.Card, *models.CardSet, error) {
+	card, err := a.cardStorage.GetByID(ctx, cardID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil, ErrNotFound
+		}
+		return nil, nil, err
+	}
+
+	set, err := a.Authorize(ctx, card.SetID, userID, action)
+	if err != nil {
+		return nil, nil, err
+	}
+	return card, set, nil
+}
@@ -0,0 +1,35 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAllowed(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     models.SetRole
+		isPublic bool
+		action   services.Action
+		expected bool
+	}{
+		{"stranger views private set", "", false, services.ActionView, false},
+		{"stranger views public set", "", true, services.ActionView, true},
+		{"stranger edits public set", "", true, services.ActionEditCards, false},
+		{"viewer views", models.SetRoleViewer, false, services.ActionView, true},
+		{"viewer edits", models.SetRoleViewer, false, services.ActionEditCards, false},
+		{"editor edits", models.SetRoleEditor, false, services.ActionEditCards, true},
+		{"editor manages", models.SetRoleEditor, false, services.ActionManage, false},
+		{"owner edits", models.SetRoleOwner, false, services.ActionEditCards, true},
+		{"owner manages", models.SetRoleOwner, false, services.ActionManage, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, services.Allowed(tt.role, tt.isPublic, tt.action))
+		})
+	}
+}
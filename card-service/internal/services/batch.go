@@ -0,0 +1,266 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+)
+
+// MaxBatchSize is the most cards a single batch operation may touch.
+const MaxBatchSize = 500
+
+// ValidateCardInputs checks the cards of a batch and reports on each of them.
+// Cards to update must name an existing card, each at most once. It returns
+// whether all of them are valid.
+func ValidateCardInputs(inputs []models.CardInput, update bool) ([]models.BatchItemResult, bool) {
+	results := make([]models.BatchItemResult, len(inputs))
+	seen := make(map[string]bool, len(inputs))
+	valid := true
+	for i, in := range inputs {
+		results[i] = models.BatchItemResult{Index: i, CardID: in.ID}
+		if update {
+			results[i].Error = checkCardID(in.ID, seen)
+		}
+		if results[i].Error == "" {
+			results[i].Error = checkCardContent(in)
+		}
+		if results[i].Error != "" {
+			valid = false
+		}
+	}
+	return results, valid
+}
+
+// ValidateCardIDs checks the card ids of a batch: each must be a card id and
+// appear once. It returns whether all of them are valid.
+func ValidateCardIDs(ids []string) ([]models.BatchItemResult, bool) {
+	results := make([]models.BatchItemResult, len(ids))
+	seen := make(map[string]bool, len(ids))
+	valid := true
+	for i, id := range ids {
+		results[i] = models.BatchItemResult{Index: i, CardID: id, Error: checkCardID(id, seen)}
+		if results[i].Error != "" {
+			valid = false
+		}
+	}
+	return results, valid
+}
+
+func checkCardID(id string, seen map[string]bool) string {
+	if _, err := uuid.Parse(id); err != nil {
+		return "invalid card id"
+	}
+	if seen[id] {
+		return "card is given more than once"
+	}
+	seen[id] = true
+	return ""
+}
+
+func checkCardContent(in models.CardInput) string {
+	_, reason := cardContent(in)
+	return reason
+}
+
+func checkBatchSize(n int) error {
+	if n == 0 || n > MaxBatchSize {
+		return ErrInvalidParam
+	}
+	return nil
+}
+
+// markMissing reports cards that are not in the set. It returns whether all of them are.
+func (s *CardService) markMissing(ctx context.Context, setID string, results []models.BatchItemResult) (bool, error) {
+	ids := make([]string, len(results))
+	for i, r := range results {
+		ids[i] = r.CardID
+	}
+
+	existing, err := s.cardStorage.GetExistingIDs(ctx, setID, ids)
+	if err != nil {
+		return false, err
+	}
+	found := make(map[string]bool, len(existing))
+	for _, id := range existing {
+		found[id] = true
+	}
+
+	valid := true
+	for i := range results {
+		if !found[results[i].CardID] {
+			results[i].Error = "card not found in the set"
+			valid = false
+		}
+	}
+	return valid, nil
+}
+
+// batchFailed maps the storage failing on a card that is no longer in the set.
+func batchFailed(err error) error {
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	return err
+}
+
+// BatchCreateCards adds cards to a set in one transaction. If any card is
+// invalid, none is created and the result tells which ones failed.
+func (s *CardService) BatchCreateCards(ctx context.Context, setID, userID string, inputs []models.CardInput) (*models.BatchResult, error) {
+	if err := checkBatchSize(len(inputs)); err != nil {
+		return nil, err
+	}
+	if _, err := s.authz.Authorize(ctx, setID, userID, ActionEditCards); err != nil {
+		return nil, err
+	}
+
+	results, valid := ValidateCardInputs(inputs, false)
+	if !valid {
+		return &models.BatchResult{Results: results}, nil
+	}
+
+	now := time.Now()
+	cards := make([]models.Card, len(inputs))
+	for i, in := range inputs {
+		content, err := BuildCardContent(in)
+		if err != nil {
+			return nil, err
+		}
+		cards[i] = *newCard(setID, content)
+		cards[i].CreatedAt = now
+	}
+
+	if err := s.cardStorage.CreateBatch(ctx, cards, userID); err != nil {
+		return nil, err
+	}
+
+	for i := range results {
+		results[i].CardID, results[i].Card = cards[i].ID, &cards[i]
+	}
+	return &models.BatchResult{Applied: true, Results: results}, nil
+}
+
+// BatchUpdateCards changes cards of a set in one transaction. If any card is
+// invalid or not in the set, none is changed.
+func (s *CardService) BatchUpdateCards(ctx context.Context, setID, userID string, inputs []models.CardInput) (*models.BatchResult, error) {
+	if err := checkBatchSize(len(inputs)); err != nil {
+		return nil, err
+	}
+	if _, err := s.authz.Authorize(ctx, setID, userID, ActionEditCards); err != nil {
+		return nil, err
+	}
+
+	results, valid := ValidateCardInputs(inputs, true)
+	if !valid {
+		return &models.BatchResult{Results: results}, nil
+	}
+	valid, err := s.markMissing(ctx, setID, results)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return &models.BatchResult{Results: results}, nil
+	}
+
+	ids := make([]string, len(inputs))
+	for i, in := range inputs {
+		ids[i] = in.ID
+	}
+	current, err := s.cardStorage.GetByIDs(ctx, ids, userID)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.Card, len(current))
+	for i := range current {
+		byID[current[i].ID] = &current[i]
+	}
+
+	cards := make([]models.Card, len(inputs))
+	for i, in := range inputs {
+		if card, ok := byID[in.ID]; ok {
+			in = KeepRichContent(in, card)
+		}
+		content, err := BuildCardContent(in)
+		if err != nil {
+			return nil, err
+		}
+		cards[i] = models.Card{ID: in.ID, Status: models.StatusNew}
+		setContent(&cards[i], content)
+	}
+
+	if err := s.cardStorage.UpdateBatch(ctx, setID, cards, userID); err != nil {
+		return nil, batchFailed(err)
+	}
+
+	for i := range results {
+		results[i].Card = &cards[i]
+	}
+	return &models.BatchResult{Applied: true, Results: results}, nil
+}
+
+// BatchDeleteCards deletes cards of a set in one transaction. If any card is
+// not in the set, none is deleted.
+func (s *CardService) BatchDeleteCards(ctx context.Context, setID, userID string, cardIDs []string) (*models.BatchResult, error) {
+	if err := checkBatchSize(len(cardIDs)); err != nil {
+		return nil, err
+	}
+	if _, err := s.authz.Authorize(ctx, setID, userID, ActionEditCards); err != nil {
+		return nil, err
+	}
+
+	results, valid := ValidateCardIDs(cardIDs)
+	if !valid {
+		return &models.BatchResult{Results: results}, nil
+	}
+	valid, err := s.markMissing(ctx, setID, results)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return &models.BatchResult{Results: results}, nil
+	}
+
+	if err := s.cardStorage.DeleteBatch(ctx, setID, cardIDs, userID); err != nil {
+		return nil, batchFailed(err)
+	}
+
+	return &models.BatchResult{Applied: true, Results: results}, nil
+}
+
+// BatchMoveCards moves cards of a set to another set the user may edit, in one
+// transaction. Progress on the cards moves with them. If any card is not in
+// the set, none is moved.
+func (s *CardService) BatchMoveCards(ctx context.Context, setID, userID string, cardIDs []string, targetSetID string) (*models.BatchResult, error) {
+	if err := checkBatchSize(len(cardIDs)); err != nil {
+		return nil, err
+	}
+	if _, err := uuid.Parse(targetSetID); err != nil || targetSetID == setID {
+		return nil, ErrInvalidParam
+	}
+	if _, err := s.authz.Authorize(ctx, setID, userID, ActionEditCards); err != nil {
+		return nil, err
+	}
+	if _, err := s.authz.Authorize(ctx, targetSetID, userID, ActionEditCards); err != nil {
+		return nil, err
+	}
+
+	results, valid := ValidateCardIDs(cardIDs)
+	if !valid {
+		return &models.BatchResult{Results: results}, nil
+	}
+	valid, err := s.markMissing(ctx, setID, results)
+	if err != nil {
+		return nil, err
+	}
+	if !valid {
+		return &models.BatchResult{Results: results}, nil
+	}
+
+	if err := s.cardStorage.MoveBatch(ctx, setID, targetSetID, cardIDs, userID); err != nil {
+		return nil, batchFailed(err)
+	}
+
+	return &models.BatchResult{Applied: true, Results: results}, nil
+}
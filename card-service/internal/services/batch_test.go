@@ -0,0 +1,79 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateCardInputs(t *testing.T) {
+	ftp := "ftp://example.com/cat.png"
+	id := uuid.New().String()
+
+	tests := []struct {
+		name   string
+		inputs []models.CardInput
+		update bool
+		valid  bool
+		errors []bool
+	}{
+		{
+			name:   "valid cards",
+			inputs: []models.CardInput{{Front: "cat", Back: "кошка"}, {Front: "dog", Back: "собака"}},
+			valid:  true,
+			errors: []bool{false, false},
+		},
+		{
+			name:   "empty side",
+			inputs: []models.CardInput{{Front: "cat", Back: "кошка"}, {Front: "  ", Back: "собака"}},
+			errors: []bool{false, true},
+		},
+		{
+			name:   "bad image url",
+			inputs: []models.CardInput{{Front: "cat", Back: "кошка", ImageURL: &ftp}},
+			errors: []bool{true},
+		},
+		{
+			name:   "update needs an id",
+			inputs: []models.CardInput{{Front: "cat", Back: "кошка"}},
+			update: true,
+			errors: []bool{true},
+		},
+		{
+			name:   "update of the same card twice",
+			inputs: []models.CardInput{{ID: id, Front: "cat", Back: "кошка"}, {ID: id, Front: "cat", Back: "кот"}},
+			update: true,
+			errors: []bool{false, true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results, valid := services.ValidateCardInputs(tt.inputs, tt.update)
+			assert.Equal(t, tt.valid, valid)
+			require.Len(t, results, len(tt.errors))
+			for i, hasError := range tt.errors {
+				assert.Equal(t, i, results[i].Index)
+				assert.Equal(t, hasError, results[i].Error != "", "item %d: %q", i, results[i].Error)
+			}
+		})
+	}
+}
+
+func TestValidateCardIDs(t *testing.T) {
+	id := uuid.New().String()
+
+	results, valid := services.ValidateCardIDs([]string{id, "not-a-uuid", id})
+	assert.False(t, valid)
+	require.Len(t, results, 3)
+	assert.Empty(t, results[0].Error)
+	assert.Equal(t, "invalid card id", results[1].Error)
+	assert.Equal(t, "card is given more than once", results[2].Error)
+
+	_, valid = services.ValidateCardIDs([]string{id, uuid.New().String()})
+	assert.True(t, valid)
+}
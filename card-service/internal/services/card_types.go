@@ -0,0 +1,124 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+)
+
+// MaxClozeNumber is the highest deletion number a cloze card may use.
+const MaxClozeNumber = 100
+
+// clozeRe matches a cloze deletion, {{c1::answer}} or {{c1::answer::hint}}.
+var clozeRe = regexp.MustCompile(`(?s)\{\{c(\d+)::(.*?)(?:::(.*?))?\}\}`)
+
+// clozeHidden replaces a deletion in the prompt when it has no hint.
+const clozeHidden = "[...]"
+
+type clozeDeletion struct {
+	number int
+	answer string
+	hint   string
+}
+
+// replaceCloze replaces every deletion in text with what fn returns for it.
+func replaceCloze(text string, fn func(d clozeDeletion) string) string {
+	return clozeRe.ReplaceAllStringFunc(text, func(match string) string {
+		sub := clozeRe.FindStringSubmatch(match)
+		number, _ := strconv.Atoi(sub[1])
+		return fn(clozeDeletion{number: number, answer: sub[2], hint: sub[3]})
+	})
+}
+
+// clozeItems returns the deletion numbers used in text, in order, or the
+// reason the text isn't a valid cloze.
+func clozeItems(text string) ([]int16, string) {
+	var items []int16
+	for _, sub := range clozeRe.FindAllStringSubmatch(text, -1) {
+		number, err := strconv.Atoi(sub[1])
+		if err != nil || number < 1 || number > MaxClozeNumber {
+			return nil, fmt.Sprintf("cloze numbers must be from 1 to %d", MaxClozeNumber)
+		}
+		if strings.TrimSpace(sub[2]) == "" {
+			return nil, fmt.Sprintf("cloze c%d has no answer", number)
+		}
+		if item := int16(number); !slices.Contains(items, item) {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		return nil, "cloze text needs at least one deletion such as {{c1::answer}}"
+	}
+	slices.Sort(items)
+	return items, ""
+}
+
+// CardItems lists the review items of a card: ItemForward for a basic card,
+// both directions for a reversible one and the deletion numbers of a cloze.
+func CardItems(card *models.Card) []int16 {
+	if card.Rich == nil || len(card.Rich.Items) == 0 {
+		return []int16{models.ItemForward}
+	}
+	return card.Rich.Items
+}
+
+// RenderItem returns what a review item of the card asks and answers, in the
+// card's format. The prompt of a cloze item hides the deletions with its
+// number, showing their hint if they have one; the answer reveals them. ok is
+// false if the card has no such item.
+func RenderItem(card *models.Card, item int16) (prompt, answer string, ok bool) {
+	if !slices.Contains(CardItems(card), item) {
+		return "", "", false
+	}
+	front, back := card.Front, card.Back
+	if card.Rich != nil {
+		front, back = card.Rich.Front, card.Rich.Back
+	}
+	prompt, answer = itemSides(card, front, back, item)
+	if cardType(card) == models.CardCloze {
+		answer = replaceCloze(front, func(d clozeDeletion) string { return d.answer })
+	}
+	return prompt, answer, true
+}
+
+// QuizItem returns the plain-text sides of a review item the way a quiz asks
+// it: the answer to a cloze item is only what its deletions hide.
+func QuizItem(card *models.Card, item int16) (front, back string) {
+	return itemSides(card, card.Front, card.Back, item)
+}
+
+func itemSides(card *models.Card, front, back string, item int16) (string, string) {
+	switch cardType(card) {
+	case models.CardBasicReverse:
+		if item == models.ItemReverse {
+			return back, front
+		}
+	case models.CardCloze:
+		var answers []string
+		prompt := replaceCloze(front, func(d clozeDeletion) string {
+			switch {
+			case d.number != int(item):
+				return d.answer
+			case d.hint != "":
+				answers = append(answers, d.answer)
+				return "[" + d.hint + "]"
+			default:
+				answers = append(answers, d.answer)
+				return clozeHidden
+			}
+		})
+		return prompt, strings.Join(answers, ", ")
+	}
+	return front, back
+}
+
+func cardType(card *models.Card) models.CardType {
+	if card.Rich == nil || card.Rich.Type == "" {
+		return models.CardBasic
+	}
+	return card.Rich.Type
+}
@@ -0,0 +1,99 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCardContentTypes(t *testing.T) {
+	reverse, err := services.BuildCardContent(models.CardInput{Type: models.CardBasicReverse, Front: "cat", Back: "кошка"})
+	require.NoError(t, err)
+	require.NotNil(t, reverse.Rich)
+	assert.Equal(t, models.CardBasicReverse, reverse.Rich.Type)
+	assert.Equal(t, []int16{models.ItemForward, models.ItemReverse}, reverse.Rich.Items)
+
+	cloze, err := services.BuildCardContent(models.CardInput{
+		Type:   models.CardCloze,
+		Front:  "{{c2::Paris}} is the capital of {{c1::France::country}}, {{c2::Paris}} again",
+		Format: models.FormatMarkdown,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []int16{1, 2}, cloze.Rich.Items)
+	assert.Equal(t, "", cloze.Back, "the back of a cloze is optional")
+
+	basic, err := services.BuildCardContent(models.CardInput{Type: models.CardBasic, Front: "cat", Back: "кошка"})
+	require.NoError(t, err)
+	assert.Nil(t, basic.Rich, "basic cards stay plain")
+}
+
+func TestBuildCardContentInvalidTypes(t *testing.T) {
+	tests := []struct {
+		name  string
+		input models.CardInput
+	}{
+		{"unknown type", models.CardInput{Type: "double", Front: "cat", Back: "кошка"}},
+		{"empty back", models.CardInput{Type: models.CardBasicReverse, Front: "cat"}},
+		{"no deletions", models.CardInput{Type: models.CardCloze, Front: "Paris is the capital of France"}},
+		{"empty deletion", models.CardInput{Type: models.CardCloze, Front: "{{c1:: }} is the capital of France"}},
+		{"deletion zero", models.CardInput{Type: models.CardCloze, Front: "{{c0::Paris}} is the capital of France"}},
+		{"deletion too high", models.CardInput{Type: models.CardCloze, Front: "{{c101::Paris}} is the capital of France"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := services.BuildCardContent(tt.input)
+			assert.ErrorIs(t, err, services.ErrInvalidContent)
+		})
+	}
+}
+
+func TestRenderItem(t *testing.T) {
+	content, err := services.BuildCardContent(models.CardInput{
+		Type:   models.CardCloze,
+		Front:  "**{{c1::Paris}}** is the capital of {{c2::France::country}}",
+		Back:   "Since 508",
+		Format: models.FormatMarkdown,
+	})
+	require.NoError(t, err)
+	cloze := &models.Card{Front: content.Front, Back: content.Back, Rich: content.Rich}
+	reverse := &models.Card{Front: "cat", Back: "кошка", Rich: &models.RichContent{
+		Type: models.CardBasicReverse, Items: []int16{models.ItemForward, models.ItemReverse}, Format: models.FormatPlain, Front: "cat", Back: "кошка",
+	}}
+	basic := &models.Card{Front: "dog", Back: "собака"}
+
+	tests := []struct {
+		name       string
+		card       *models.Card
+		item       int16
+		prompt     string
+		answer     string
+		quizFront  string
+		quizAnswer string
+	}{
+		{"basic", basic, models.ItemForward, "dog", "собака", "dog", "собака"},
+		{"forward", reverse, models.ItemForward, "cat", "кошка", "cat", "кошка"},
+		{"reverse", reverse, models.ItemReverse, "кошка", "cat", "кошка", "cat"},
+		{"cloze", cloze, 1, "**[...]** is the capital of France", "**Paris** is the capital of France", "[...] is the capital of France", "Paris"},
+		{"cloze with hint", cloze, 2, "**Paris** is the capital of [country]", "**Paris** is the capital of France", "Paris is the capital of [country]", "France"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prompt, answer, ok := services.RenderItem(tt.card, tt.item)
+			require.True(t, ok)
+			assert.Equal(t, tt.prompt, prompt)
+			assert.Equal(t, tt.answer, answer)
+
+			front, back := services.QuizItem(tt.card, tt.item)
+			assert.Equal(t, tt.quizFront, front)
+			assert.Equal(t, tt.quizAnswer, back)
+		})
+	}
+
+	_, _, ok := services.RenderItem(cloze, 3)
+	assert.False(t, ok)
+	_, _, ok = services.RenderItem(basic, models.ItemReverse)
+	assert.False(t, ok)
+}
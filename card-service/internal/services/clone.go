@@ -0,0 +1,142 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+)
+
+// ErrNotClone is returned for lineage operations on a set that wasn't cloned
+// or whose original has been deleted.
+var ErrNotClone = errors.New("set is not a clone")
+
+// PullOptions choose what to take over from the original.
+type PullOptions struct {
+	// CardIDs limits the pull to these cards, given by the original card id or,
+	// for removed cards, by the id of the copy. Empty means every change.
+	CardIDs []string
+	// Overwrite takes the original's version of cards changed on both sides.
+	Overwrite bool
+	// RemoveDeleted deletes copies of cards deleted from the original.
+	RemoveDeleted bool
+}
+
+// DiffClone lists how a clone differs from its original. Cards changed on both
+// sides to the same content are not a difference.
+func DiffClone(states []models.ClonedCardState) []models.CardDiff {
+	diffs := []models.CardDiff{}
+	for _, state := range states {
+		diff := models.CardDiff{
+			OriginalCardID: state.OriginalID,
+			CardID:         state.CopyID,
+			Original:       state.Original,
+			Copy:           state.Copy,
+		}
+
+		switch {
+		case state.Copy == nil:
+			diff.Change = models.CardAdded
+		case state.Original == nil:
+			diff.Change = models.CardRemoved
+		case !state.OriginalChanged || sameContent(state.Original, state.Copy):
+			continue
+		case state.CopyChanged:
+			diff.Change = models.CardConflict
+		default:
+			diff.Change = models.CardUpdated
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
+
+func sameContent(a, b *models.CardContent) bool {
+	return len(ChangedFields(a, b)) == 0
+}
+
+// PlanPull picks the changes a pull applies: original cards to add, original
+// cards whose copies to overwrite, and copies to remove.
+func PlanPull(diffs []models.CardDiff, opts PullOptions) (add, update, remove []string) {
+	for _, diff := range diffs {
+		if len(opts.CardIDs) > 0 && !slices.Contains(opts.CardIDs, diff.OriginalCardID) && !slices.Contains(opts.CardIDs, diff.CardID) {
+			continue
+		}
+
+		switch diff.Change {
+		case models.CardAdded:
+			add = append(add, diff.OriginalCardID)
+		case models.CardUpdated:
+			update = append(update, diff.OriginalCardID)
+		case models.CardConflict:
+			if opts.Overwrite {
+				update = append(update, diff.OriginalCardID)
+			}
+		case models.CardRemoved:
+			if opts.RemoveDeleted {
+				remove = append(remove, diff.CardID)
+			}
+		}
+	}
+	return add, update, remove
+}
+
+// getOriginal returns a clone managed by the user and the set it was cloned
+// from, which the user must still be allowed to see.
+func (s *CardSetService) getOriginal(ctx context.Context, setID, userID string) (*models.CardSet, *models.CardSet, error) {
+	set, err := s.authz.Authorize(ctx, setID, userID, ActionManage)
+	if err != nil {
+		return nil, nil, err
+	}
+	if set.ClonedFromSetID == nil {
+		return nil, nil, ErrNotClone
+	}
+
+	original, err := s.authz.Authorize(ctx, *set.ClonedFromSetID, userID, ActionView)
+	if err == ErrNotFound {
+		return nil, nil, ErrNotClone
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return set, original, nil
+}
+
+// GetCloneDiff shows what changed in the original since the set was cloned or last pulled.
+func (s *CardSetService) GetCloneDiff(ctx context.Context, setID, userID string) (*models.SetDiff, error) {
+	set, original, err := s.getOriginal(ctx, setID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	states, err := s.setStorage.GetCloneState(ctx, set.ID, original.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SetDiff{
+		SetID:         set.ID,
+		OriginalSetID: original.ID,
+		SyncedAt:      set.SyncedAt,
+		Changes:       DiffClone(states),
+	}, nil
+}
+
+// PullFromOriginal applies changes of the original to the clone. Changed cards
+// are updated in place, so the user's progress on them is kept.
+func (s *CardSetService) PullFromOriginal(ctx context.Context, setID, userID string, opts PullOptions) (*models.PullResult, error) {
+	set, original, err := s.getOriginal(ctx, setID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	states, err := s.setStorage.GetCloneState(ctx, set.ID, original.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	add, update, remove := PlanPull(DiffClone(states), opts)
+	return s.setStorage.PullFromOriginal(ctx, set.ID, original.ID, add, update, remove)
+}
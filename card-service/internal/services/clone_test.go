@@ -0,0 +1,67 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func cardContent(front, back string) *models.CardContent {
+	return &models.CardContent{Front: front, Back: back}
+}
+
+func TestDiffClone(t *testing.T) {
+	states := []models.ClonedCardState{
+		{OriginalID: "o1", Original: cardContent("cat", "кот"), CopyID: "c1", Copy: cardContent("cat", "кот")},
+		{OriginalID: "o2", Original: cardContent("dog", "собака")},
+		{CopyID: "c3", Copy: cardContent("cow", "корова")},
+		{OriginalID: "o4", Original: cardContent("house", "дом"), OriginalChanged: true, CopyID: "c4", Copy: cardContent("home", "дом")},
+		{OriginalID: "o5", Original: cardContent("tree", "дерево"), OriginalChanged: true, CopyID: "c5", Copy: cardContent("tree", "древо"), CopyChanged: true},
+		{OriginalID: "o6", Original: cardContent("sun", "солнце"), OriginalChanged: true, CopyID: "c6", Copy: cardContent("sun", "солнце"), CopyChanged: true},
+		{OriginalID: "o7", Original: cardContent("moon", "луна"), CopyID: "c7", Copy: cardContent("moon!", "луна"), CopyChanged: true},
+	}
+
+	diffs := services.DiffClone(states)
+
+	changes := make(map[string]models.CardChange)
+	for _, diff := range diffs {
+		changes[diff.OriginalCardID+"/"+diff.CardID] = diff.Change
+	}
+	assert.Equal(t, map[string]models.CardChange{
+		"o2/":   models.CardAdded,
+		"/c3":   models.CardRemoved,
+		"o4/c4": models.CardUpdated,
+		"o5/c5": models.CardConflict,
+	}, changes)
+}
+
+func TestPlanPull(t *testing.T) {
+	diffs := []models.CardDiff{
+		{Change: models.CardAdded, OriginalCardID: "o1"},
+		{Change: models.CardUpdated, OriginalCardID: "o2", CardID: "c2"},
+		{Change: models.CardConflict, OriginalCardID: "o3", CardID: "c3"},
+		{Change: models.CardRemoved, CardID: "c4"},
+	}
+
+	tests := []struct {
+		name          string
+		opts          services.PullOptions
+		add, upd, rem []string
+	}{
+		{"Defaults keep local changes and deleted cards", services.PullOptions{}, []string{"o1"}, []string{"o2"}, nil},
+		{"Everything", services.PullOptions{Overwrite: true, RemoveDeleted: true}, []string{"o1"}, []string{"o2", "o3"}, []string{"c4"}},
+		{"Selected cards", services.PullOptions{CardIDs: []string{"o3", "c4"}, Overwrite: true, RemoveDeleted: true}, nil, []string{"o3"}, []string{"c4"}},
+		{"Conflict not overwritten even if selected", services.PullOptions{CardIDs: []string{"o3"}}, nil, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			add, update, remove := services.PlanPull(diffs, tt.opts)
+			assert.Equal(t, tt.add, add)
+			assert.Equal(t, tt.upd, update)
+			assert.Equal(t, tt.rem, remove)
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+)
+
+const (
+	MaxCardSideLength  = 10000
+	MaxCardExtraLength = 2000
+	MaxImagesPerSide   = 10
+)
+
+// ErrInvalidContent is returned, wrapped with the reason, when card content can't be accepted.
+var ErrInvalidContent = errors.New("invalid card content")
+
+// BuildCardContent validates a card and brings its content to the form it is
+// stored in. Input with neither a format nor rich fields is a plain card, as
+// clients before content version 2 send it. Otherwise the card gets rich
+// content, and its front and back become the plain-text rendering of it. A
+// cloze card may leave the back empty; its front must have deletions.
+func BuildCardContent(in models.CardInput) (*models.CardContent, error) {
+	content, reason := cardContent(in)
+	if reason != "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidContent, reason)
+	}
+	return content, nil
+}
+
+func hasRichFields(in models.CardInput) bool {
+	return (in.Type != "" && in.Type != models.CardBasic) ||
+		in.Format != "" || len(in.FrontImages) > 0 || len(in.BackImages) > 0 || in.Notes != nil || in.Hint != nil || in.Example != nil
+}
+
+// cardContent is BuildCardContent reporting why the content is invalid.
+func cardContent(in models.CardInput) (*models.CardContent, string) {
+	if strings.TrimSpace(in.Front) == "" {
+		return nil, "front must not be empty"
+	}
+	if strings.TrimSpace(in.Back) == "" && in.Type != models.CardCloze {
+		return nil, "back must not be empty"
+	}
+
+	content := &models.CardContent{Front: in.Front, Back: in.Back}
+	var err error
+	if in.ImageURL != nil {
+		if content.ImageURL, err = mediaURL(*in.ImageURL); err != nil {
+			return nil, fmt.Sprintf("image: %v", err)
+		}
+	}
+	if in.AudioURL != nil {
+		if content.AudioURL, err = mediaURL(*in.AudioURL); err != nil {
+			return nil, fmt.Sprintf("audio: %v", err)
+		}
+	}
+	if !hasRichFields(in) {
+		return content, ""
+	}
+
+	rich := &models.RichContent{Version: models.ContentVersionRich, Format: in.Format}
+	switch rich.Format {
+	case "":
+		rich.Format = models.FormatPlain
+	case models.FormatPlain, models.FormatMarkdown:
+	default:
+		return nil, fmt.Sprintf("unknown format %q", in.Format)
+	}
+
+	var reason string
+	if rich.Front, reason = richText(rich.Format, "front", in.Front, MaxCardSideLength); reason != "" {
+		return nil, reason
+	}
+	switch in.Type {
+	case "", models.CardBasic:
+	case models.CardBasicReverse:
+		rich.Type, rich.Items = in.Type, []int16{models.ItemForward, models.ItemReverse}
+	case models.CardCloze:
+		rich.Type = in.Type
+		if rich.Items, reason = clozeItems(rich.Front); reason != "" {
+			return nil, reason
+		}
+	default:
+		return nil, fmt.Sprintf("unknown card type %q", in.Type)
+	}
+	if rich.Back, reason = richText(rich.Format, "back", in.Back, MaxCardSideLength); reason != "" {
+		return nil, reason
+	}
+	if rich.FrontImages, reason = images("front", in.FrontImages); reason != "" {
+		return nil, reason
+	}
+	if rich.BackImages, reason = images("back", in.BackImages); reason != "" {
+		return nil, reason
+	}
+	extras := []struct {
+		name string
+		in   *string
+		out  **string
+	}{
+		{"notes", in.Notes, &rich.Notes},
+		{"hint", in.Hint, &rich.Hint},
+		{"example", in.Example, &rich.Example},
+	}
+	for _, extra := range extras {
+		if extra.in == nil || strings.TrimSpace(*extra.in) == "" {
+			continue
+		}
+		text, reason := richText(rich.Format, extra.name, *extra.in, MaxCardExtraLength)
+		if reason != "" {
+			return nil, reason
+		}
+		*extra.out = &text
+	}
+
+	content.Front = plainOr(rich.Format, rich.Front)
+	content.Back = plainOr(rich.Format, rich.Back)
+	if content.ImageURL == nil && len(rich.FrontImages) > 0 {
+		image := rich.FrontImages[0]
+		content.ImageURL = &image
+	}
+	content.Rich = rich
+	return content, ""
+}
+
+// richText checks the length of a text field and sanitizes it if it is Markdown.
+func richText(format models.ContentFormat, field, text string, maxLength int) (string, string) {
+	if utf8.RuneCountInString(text) > maxLength {
+		return "", fmt.Sprintf("%s is longer than %d characters", field, maxLength)
+	}
+	if format != models.FormatMarkdown {
+		return text, ""
+	}
+	sanitized, err := SanitizeMarkdown(text)
+	if err != nil {
+		return "", fmt.Sprintf("%s: %v", field, err)
+	}
+	return sanitized, ""
+}
+
+func images(side string, urls []string) ([]string, string) {
+	if len(urls) > MaxImagesPerSide {
+		return nil, fmt.Sprintf("%s: at most %d images are allowed", side, MaxImagesPerSide)
+	}
+	var valid []string
+	for _, u := range urls {
+		image, err := mediaURL(strings.TrimSpace(u))
+		if err != nil {
+			return nil, fmt.Sprintf("%s image: %v", side, err)
+		}
+		if image != nil {
+			valid = append(valid, *image)
+		}
+	}
+	return valid, ""
+}
+
+func sameText(x, y *string) bool {
+	return (x == nil && y == nil) || (x != nil && y != nil && *x == *y)
+}
+
+// plainOr renders text as plain text, keeping it as it is if nothing would be left.
+func plainOr(format models.ContentFormat, text string) string {
+	if plain := PlainText(format, text); strings.TrimSpace(plain) != "" {
+		return plain
+	}
+	return text
+}
+
+// KeepRichContent fills in what a client before content version 2 leaves out
+// when it edits a card with rich content, so that the edit doesn't drop it.
+// A side sent back as the plain text it was given keeps its rich text.
+func KeepRichContent(in models.CardInput, current *models.Card) models.CardInput {
+	if hasRichFields(in) || current.Rich == nil {
+		return in
+	}
+
+	rich := current.Rich
+	if in.Front == current.Front {
+		in.Front = rich.Front
+	}
+	if in.Back == current.Back {
+		in.Back = rich.Back
+	}
+	in.Type, in.Format = rich.Type, rich.Format
+	in.FrontImages, in.BackImages = rich.FrontImages, rich.BackImages
+	in.Notes, in.Hint, in.Example = rich.Notes, rich.Hint, rich.Example
+	return in
+}
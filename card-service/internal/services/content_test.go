@@ -0,0 +1,108 @@
+package services_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCardContentPlain(t *testing.T) {
+	image := "https://cdn.example.com/cat.png"
+	empty := ""
+
+	content, err := services.BuildCardContent(models.CardInput{Front: "**cat**", Back: "кошка", ImageURL: &image, AudioURL: &empty})
+	require.NoError(t, err)
+	assert.Equal(t, "**cat**", content.Front, "cards without rich fields are kept as they are")
+	assert.Equal(t, &image, content.ImageURL)
+	assert.Nil(t, content.AudioURL)
+	assert.Nil(t, content.Rich)
+}
+
+func TestBuildCardContentRich(t *testing.T) {
+	hint := "starts with *E*"
+	blank := "  "
+
+	content, err := services.BuildCardContent(models.CardInput{
+		Front:       "Mass–energy <b>equivalence</b>",
+		Back:        "$E = mc^2$",
+		Format:      models.FormatMarkdown,
+		FrontImages: []string{"https://cdn.example.com/einstein.png", ""},
+		BackImages:  []string{"https://cdn.example.com/formula.png"},
+		Hint:        &hint,
+		Notes:       &blank,
+	})
+	require.NoError(t, err)
+
+	require.NotNil(t, content.Rich)
+	assert.Equal(t, models.ContentVersionRich, content.Rich.Version)
+	assert.Equal(t, models.FormatMarkdown, content.Rich.Format)
+	assert.Equal(t, "Mass–energy \\<b>equivalence\\</b>", content.Rich.Front)
+	assert.Equal(t, "$E = mc^2$", content.Rich.Back)
+	assert.Equal(t, []string{"https://cdn.example.com/einstein.png"}, content.Rich.FrontImages)
+	assert.Equal(t, &hint, content.Rich.Hint)
+	assert.Nil(t, content.Rich.Notes, "blank fields are dropped")
+
+	assert.Equal(t, "Mass–energy <b>equivalence</b>", content.Front, "old clients get plain text")
+	assert.Equal(t, "E = mc^2", content.Back)
+	require.NotNil(t, content.ImageURL)
+	assert.Equal(t, "https://cdn.example.com/einstein.png", *content.ImageURL, "old clients get the first image")
+}
+
+func TestBuildCardContentInvalid(t *testing.T) {
+	ftp := "ftp://example.com/cat.png"
+	tooMany := make([]string, services.MaxImagesPerSide+1)
+	for i := range tooMany {
+		tooMany[i] = "https://cdn.example.com/cat.png"
+	}
+	long := strings.Repeat("a", services.MaxCardExtraLength+1)
+
+	tests := []struct {
+		name  string
+		input models.CardInput
+	}{
+		{"empty side", models.CardInput{Front: "cat", Back: " "}},
+		{"bad image url", models.CardInput{Front: "cat", Back: "кошка", ImageURL: &ftp}},
+		{"unknown format", models.CardInput{Front: "cat", Back: "кошка", Format: "html"}},
+		{"bad side image", models.CardInput{Front: "cat", Back: "кошка", BackImages: []string{ftp}}},
+		{"too many images", models.CardInput{Front: "cat", Back: "кошка", FrontImages: tooMany}},
+		{"broken formula", models.CardInput{Front: "$$x", Back: "x", Format: models.FormatMarkdown}},
+		{"long notes", models.CardInput{Front: "cat", Back: "кошка", Notes: &long}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := services.BuildCardContent(tt.input)
+			assert.ErrorIs(t, err, services.ErrInvalidContent)
+		})
+	}
+}
+
+func TestKeepRichContent(t *testing.T) {
+	notes := "from the 1905 paper"
+	card := &models.Card{
+		Front: "Energy",
+		Back:  "E = mc^2",
+		Rich: &models.RichContent{
+			Version: models.ContentVersionRich,
+			Format:  models.FormatMarkdown,
+			Front:   "**Energy**",
+			Back:    "$E = mc^2$",
+			Notes:   &notes,
+		},
+	}
+
+	in := services.KeepRichContent(models.CardInput{Front: "Rest energy", Back: "E = mc^2"}, card)
+	assert.Equal(t, "Rest energy", in.Front, "an edited side takes the new text")
+	assert.Equal(t, "$E = mc^2$", in.Back, "an untouched side keeps its rich text")
+	assert.Equal(t, models.FormatMarkdown, in.Format)
+	assert.Equal(t, &notes, in.Notes)
+
+	rich := models.CardInput{Front: "Energy", Back: "E", Format: models.FormatPlain}
+	assert.Equal(t, rich, services.KeepRichContent(rich, card), "clients sending rich content replace it")
+
+	plain := models.CardInput{Front: "cat", Back: "кошка"}
+	assert.Equal(t, plain, services.KeepRichContent(plain, &models.Card{Front: "dog", Back: "собака"}))
+}
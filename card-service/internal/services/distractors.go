@@ -0,0 +1,141 @@
+package services
+
+import (
+	"math"
+	"math/rand"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type answerType int
+
+const (
+	answerText answerType = iota
+	answerNumber
+	answerDate
+	answerName
+)
+
+var (
+	dateRe = regexp.MustCompile(`^(\d{1,2}[./-]\d{1,2}[./-]\d{2,4}|\d{4}-\d{1,2}-\d{1,2}|[12]\d{3})(\s*(г\.?|год|year))?$`)
+	// numberRe allows a unit or percent after the number, e.g. "9.8 м/с²" or "42%"
+	numberRe = regexp.MustCompile(`^[-+−]?\d[\d\s]*([.,]\d+)?\s*\S{0,5}$`)
+)
+
+// classifyAnswer guesses what kind of value an answer is so distractors can be
+// of the same kind: a year is offered alongside years, a name alongside names.
+func classifyAnswer(answer string) answerType {
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return answerText
+	}
+	if dateRe.MatchString(strings.ToLower(answer)) {
+		return answerDate
+	}
+	if numberRe.MatchString(answer) {
+		return answerNumber
+	}
+	if _, err := strconv.ParseFloat(answer, 64); err == nil {
+		return answerNumber
+	}
+
+	words := strings.Fields(answer)
+	if len(words) <= 4 {
+		capitalized := 0
+		for _, word := range words {
+			r, _ := utf8.DecodeRuneInString(word)
+			if unicode.IsUpper(r) {
+				capitalized++
+			}
+		}
+		// Every word capitalized, "Lev Tolstoy" or "Санкт-Петербург", but not a single lowercase word
+		if capitalized == len(words) && (len(words) > 1 || utf8.RuneCountInString(answer) > 1) {
+			return answerName
+		}
+	}
+	return answerText
+}
+
+func answerTokens(answer string) map[string]struct{} {
+	tokens := make(map[string]struct{})
+	for _, token := range strings.FieldsFunc(strings.ToLower(answer), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		tokens[token] = struct{}{}
+	}
+	return tokens
+}
+
+// answerSimilarity scores how plausible candidate is as a wrong option for correct.
+// The same answer type weighs most, then shared words, then similar length.
+func answerSimilarity(correct, candidate string) float64 {
+	score := 0.0
+	if classifyAnswer(correct) == classifyAnswer(candidate) {
+		score += 3
+	}
+
+	a, b := answerTokens(correct), answerTokens(candidate)
+	if len(a) > 0 && len(b) > 0 {
+		shared := 0
+		for token := range a {
+			if _, ok := b[token]; ok {
+				shared++
+			}
+		}
+		score += 2 * float64(shared) / float64(len(a)+len(b)-shared)
+	}
+
+	la, lb := float64(utf8.RuneCountInString(correct)), float64(utf8.RuneCountInString(candidate))
+	if longest := math.Max(la, lb); longest > 0 {
+		score += 1 - math.Abs(la-lb)/longest
+	}
+	return score
+}
+
+func normalizeOption(option string) string {
+	return strings.Join(strings.Fields(strings.ToLower(option)), " ")
+}
+
+// SelectDistractors picks up to count wrong options for correct out of candidates.
+// Candidates most similar to the correct answer are preferred, with some random
+// jitter so the same question does not always get the same options. Blank
+// candidates and ones equal to the correct answer or to each other are skipped.
+func SelectDistractors(correct string, candidates []string, count int, rng *rand.Rand) []string {
+	type scored struct {
+		text  string
+		score float64
+	}
+
+	seen := map[string]struct{}{normalizeOption(correct): {}}
+	pool := make([]scored, 0, len(candidates))
+	for _, candidate := range candidates {
+		candidate = strings.TrimSpace(candidate)
+		key := normalizeOption(candidate)
+		if key == "" {
+			continue
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		pool = append(pool, scored{
+			text:  candidate,
+			score: answerSimilarity(correct, candidate) + rng.Float64(),
+		})
+	}
+
+	sort.Slice(pool, func(i, j int) bool { return pool[i].score > pool[j].score })
+
+	if len(pool) > count {
+		pool = pool[:count]
+	}
+	distractors := make([]string, 0, len(pool))
+	for _, p := range pool {
+		distractors = append(distractors, p.text)
+	}
+	return distractors
+}
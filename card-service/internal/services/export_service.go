@@ -0,0 +1,302 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/anki"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/fileclient"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/storage"
+)
+
+// SetExport is an exported set ready to be sent as a file.
+type SetExport struct {
+	FileName    string
+	ContentType string
+	Data        []byte
+}
+
+type ExportService struct {
+	setStorage      storage.CardSetStorage
+	cardStorage     storage.CardStorage
+	progressStorage storage.CardProgressStorage
+	authz           *Authorizer
+	files           *fileclient.Client
+}
+
+func NewExportService(setStorage storage.CardSetStorage, cardStorage storage.CardStorage, progressStorage storage.CardProgressStorage, authz *Authorizer, files *fileclient.Client) *ExportService {
+	return &ExportService{setStorage: setStorage, cardStorage: cardStorage, progressStorage: progressStorage, authz: authz, files: files}
+}
+
+// ExportSet exports the cards of a set the user may view, optionally with the
+// user's own progress on them.
+func (s *ExportService) ExportSet(ctx context.Context, setID, userID string, format models.ExportFormat, includeProgress bool) (*SetExport, error) {
+	set, err := s.authz.Authorize(ctx, setID, userID, ActionView)
+	if err != nil {
+		return nil, err
+	}
+
+	cards, err := s.cardStorage.GetAllBySetID(ctx, setID)
+	if err != nil {
+		return nil, err
+	}
+
+	var progress map[string]models.CardProgress
+	if includeProgress {
+		list, err := s.progressStorage.GetBySet(ctx, userID, setID)
+		if err != nil {
+			return nil, err
+		}
+		progress = make(map[string]models.CardProgress, len(list))
+		for _, p := range list {
+			progress[p.CardID] = p
+		}
+	}
+
+	export := &SetExport{FileName: exportFileName(set.Name) + "." + string(format)}
+	switch format {
+	case models.ExportFormatCSV:
+		export.ContentType = "text/csv; charset=utf-8"
+		export.Data, err = ExportCSV(set, cards, progress)
+	case models.ExportFormatJSON:
+		export.ContentType = "application/json"
+		export.Data, err = ExportJSON(set, cards, progress, time.Now())
+	case models.ExportFormatAnki:
+		export.ContentType = "application/octet-stream"
+		export.Data, err = s.exportAnki(ctx, set, cards, progress)
+	default:
+		return nil, ErrInvalidParam
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+var unsafeFileNameRe = regexp.MustCompile(`[^\p{L}\p{N}._ -]+`)
+
+func exportFileName(name string) string {
+	name = strings.TrimSpace(unsafeFileNameRe.ReplaceAllString(name, ""))
+	if name == "" {
+		return "cards"
+	}
+	return name
+}
+
+func optional(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// ExportCSV writes one row per card. Progress columns are added only when
+// progress is given; cards never studied leave them empty apart from the status.
+func ExportCSV(set *models.CardSet, cards []models.Card, progress map[string]models.CardProgress) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+
+	header := []string{"front", "back", "image_url", "audio_url", "tags"}
+	if progress != nil {
+		header = append(header, "status", "next_review", "interval_days", "ease", "reps", "last_reviewed_at")
+	}
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+
+	tags := strings.Join(set.Tags, " ")
+	for _, card := range cards {
+		row := []string{card.Front, card.Back, optional(card.ImageURL), optional(card.AudioURL), tags}
+		if progress != nil {
+			p, ok := progress[card.ID]
+			if !ok {
+				row = append(row, string(models.StatusNew), "", "", "", "", "")
+			} else {
+				row = append(row, string(p.Status), formatTime(p.NextReview), strconv.Itoa(int(p.IntervalDays)),
+					strconv.FormatFloat(p.Ease, 'f', 2, 64), strconv.Itoa(int(p.Reps)), formatTime(p.LastReviewedAt))
+			}
+		}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+type exportedSet struct {
+	Name        string         `json:"name"`
+	Description *string        `json:"description,omitempty"`
+	Tags        []string       `json:"tags"`
+	ExportedAt  time.Time      `json:"exported_at"`
+	Cards       []exportedCard `json:"cards"`
+}
+
+type exportedCard struct {
+	Front    string              `json:"front"`
+	Back     string              `json:"back"`
+	ImageURL *string             `json:"image_url,omitempty"`
+	AudioURL *string             `json:"audio_url,omitempty"`
+	Rich     *models.RichContent `json:"rich,omitempty"`
+	Progress *exportedProgress   `json:"progress,omitempty"`
+}
+
+type exportedProgress struct {
+	Status         models.CardStatus `json:"status"`
+	NextReview     *time.Time        `json:"next_review,omitempty"`
+	IntervalDays   int32             `json:"interval_days"`
+	Ease           float64           `json:"ease"`
+	Stability      float64           `json:"stability"`
+	Difficulty     float64           `json:"difficulty"`
+	Reps           int32             `json:"reps"`
+	ErrorCount     int32             `json:"error_count"`
+	LastReviewedAt *time.Time        `json:"last_reviewed_at,omitempty"`
+}
+
+// ExportJSON writes the set and its cards; cards carry progress only if the user studied them.
+func ExportJSON(set *models.CardSet, cards []models.Card, progress map[string]models.CardProgress, now time.Time) ([]byte, error) {
+	out := exportedSet{
+		Name:        set.Name,
+		Description: set.Description,
+		Tags:        set.Tags,
+		ExportedAt:  now.UTC(),
+		Cards:       make([]exportedCard, 0, len(cards)),
+	}
+	if out.Tags == nil {
+		out.Tags = []string{}
+	}
+
+	for _, card := range cards {
+		item := exportedCard{Front: card.Front, Back: card.Back, ImageURL: card.ImageURL, AudioURL: card.AudioURL, Rich: card.Rich}
+		if p, ok := progress[card.ID]; ok {
+			item.Progress = &exportedProgress{
+				Status:         p.Status,
+				NextReview:     p.NextReview,
+				IntervalDays:   p.IntervalDays,
+				Ease:           p.Ease,
+				Stability:      p.Stability,
+				Difficulty:     p.Difficulty,
+				Reps:           p.Reps,
+				ErrorCount:     p.ErrorCount,
+				LastReviewedAt: p.LastReviewedAt,
+			}
+		}
+		out.Cards = append(out.Cards, item)
+	}
+
+	return json.MarshalIndent(out, "", "  ")
+}
+
+// exportAnki bundles card media into the package. Media that can't be
+// downloaded is left out: images are then linked by URL, audio is dropped.
+func (s *ExportService) exportAnki(ctx context.Context, set *models.CardSet, cards []models.Card, progress map[string]models.CardProgress) ([]byte, error) {
+	deck := anki.Deck{
+		Name:        set.Name,
+		Description: optional(set.Description),
+		Notes:       make([]anki.ExportNote, 0, len(cards)),
+		Media:       make(map[string][]byte),
+	}
+
+	names := make(map[string]string)
+	bundle := func(url string) (string, bool) {
+		if name, ok := names[url]; ok {
+			return name, name != ""
+		}
+		names[url] = ""
+		if s.files == nil {
+			return "", false
+		}
+
+		data, err := s.files.Download(ctx, url)
+		if err == fileclient.ErrForeignURL {
+			return "", false
+		}
+		if err != nil {
+			log.Printf("Failed to download %s for export: %v", url, err)
+			return "", false
+		}
+
+		name := mediaFileName(url, data, len(deck.Media))
+		deck.Media[name] = data
+		names[url] = name
+		return name, true
+	}
+
+	for _, card := range cards {
+		var images, sounds []string
+		if card.ImageURL != nil {
+			if name, ok := bundle(*card.ImageURL); ok {
+				images = append(images, name)
+			} else {
+				images = append(images, *card.ImageURL)
+			}
+		}
+		if card.AudioURL != nil {
+			if name, ok := bundle(*card.AudioURL); ok {
+				sounds = append(sounds, name)
+			}
+		}
+
+		note := anki.ExportNote{
+			GUID:  card.ID,
+			Front: anki.FieldHTML(card.Front, nil, nil),
+			Back:  anki.FieldHTML(card.Back, images, sounds),
+			Tags:  set.Tags,
+		}
+		if p, ok := progress[card.ID]; ok && p.Reps > 0 && p.NextReview != nil {
+			note.Schedule = &anki.Schedule{
+				IntervalDays: int(p.IntervalDays),
+				Due:          *p.NextReview,
+				Ease:         p.Ease,
+				Reps:         int(p.Reps),
+				Lapses:       int(p.ErrorCount),
+			}
+			if note.Schedule.Ease == 0 {
+				note.Schedule.Ease = sm2DefaultEase
+			}
+		}
+		deck.Notes = append(deck.Notes, note)
+	}
+
+	var buf bytes.Buffer
+	if err := anki.Write(&buf, deck); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// mediaFileName names a bundled file after its URL, numbered so names never
+// collide, with an extension guessed from the contents if the URL has none.
+func mediaFileName(url string, data []byte, n int) string {
+	base := path.Base(strings.SplitN(url, "?", 2)[0])
+	ext := path.Ext(base)
+	if ext == "" {
+		if exts, err := mime.ExtensionsByType(http.DetectContentType(data)); err == nil && len(exts) > 0 {
+			ext = exts[0]
+		}
+	}
+	base = strings.TrimSuffix(base, path.Ext(base))
+	base = exportFileName(base)
+	return fmt.Sprintf("%d-%s%s", n+1, base, ext)
+}
@@ -0,0 +1,90 @@
+package services_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func exportFixture() (*models.CardSet, []models.Card) {
+	image := "https://example.com/cat.jpg"
+	set := &models.CardSet{ID: "set", Name: "Animals", Tags: []string{"animals", "en"}}
+	cards := []models.Card{
+		{ID: "c1", Front: "cat", Back: "кошка, \"кот\"", ImageURL: &image},
+		{ID: "c2", Front: "dog", Back: "собака"},
+	}
+	return set, cards
+}
+
+func TestExportCSV(t *testing.T) {
+	set, cards := exportFixture()
+
+	data, err := services.ExportCSV(set, cards, nil)
+	require.NoError(t, err)
+
+	expected := "front,back,image_url,audio_url,tags\n" +
+		"cat,\"кошка, \"\"кот\"\"\",https://example.com/cat.jpg,,animals en\n" +
+		"dog,собака,,,animals en\n"
+	assert.Equal(t, expected, string(data))
+
+	// Exported files can be imported back
+	imported, rowErrors, err := services.ParseCSV(data, services.CSVOptions{})
+	require.NoError(t, err)
+	assert.Empty(t, rowErrors)
+	require.Len(t, imported, 2)
+	assert.Equal(t, cards[0].Back, imported[0].Back)
+	assert.Equal(t, cards[0].ImageURL, imported[0].ImageURL)
+}
+
+func TestExportCSV_WithProgress(t *testing.T) {
+	set, cards := exportFixture()
+	next := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	progress := map[string]models.CardProgress{
+		"c1": {CardID: "c1", Status: models.StatusReviewing, NextReview: &next, IntervalDays: 6, Ease: 2.5, Reps: 2},
+	}
+
+	data, err := services.ExportCSV(set, cards, progress)
+	require.NoError(t, err)
+
+	expected := "front,back,image_url,audio_url,tags,status,next_review,interval_days,ease,reps,last_reviewed_at\n" +
+		"cat,\"кошка, \"\"кот\"\"\",https://example.com/cat.jpg,,animals en,reviewing,2024-06-01T12:00:00Z,6,2.50,2,\n" +
+		"dog,собака,,,animals en,new,,,,,\n"
+	assert.Equal(t, expected, string(data))
+}
+
+func TestExportJSON(t *testing.T) {
+	set, cards := exportFixture()
+	progress := map[string]models.CardProgress{
+		"c2": {CardID: "c2", Status: models.StatusLearning, IntervalDays: 1, Reps: 1},
+	}
+
+	data, err := services.ExportJSON(set, cards, progress, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+
+	var out struct {
+		Name  string   `json:"name"`
+		Tags  []string `json:"tags"`
+		Cards []struct {
+			Front    string  `json:"front"`
+			ImageURL *string `json:"image_url"`
+			Progress *struct {
+				Status string `json:"status"`
+			} `json:"progress"`
+		} `json:"cards"`
+	}
+	require.NoError(t, json.Unmarshal(data, &out))
+
+	assert.Equal(t, "Animals", out.Name)
+	assert.Equal(t, []string{"animals", "en"}, out.Tags)
+	require.Len(t, out.Cards, 2)
+	assert.Equal(t, "cat", out.Cards[0].Front)
+	assert.NotNil(t, out.Cards[0].ImageURL)
+	assert.Nil(t, out.Cards[0].Progress)
+	require.NotNil(t, out.Cards[1].Progress)
+	assert.Equal(t, "learning", out.Cards[1].Progress.Status)
+}
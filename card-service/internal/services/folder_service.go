@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/storage"
+)
+
+const MaxFolderNameLength = 100
+
+// MaxFolderDepth is how many levels deep folders may be nested.
+const MaxFolderDepth = 100
+
+type FolderService struct {
+	folderStorage storage.FolderStorage
+	authz         *Authorizer
+}
+
+func NewFolderService(folderStorage storage.FolderStorage, authz *Authorizer) *FolderService {
+	return &FolderService{folderStorage: folderStorage, authz: authz}
+}
+
+// NormalizeFolderName trims the name and checks it fits.
+func NormalizeFolderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > MaxFolderNameLength {
+		return "", ErrInvalidParam
+	}
+	return name, nil
+}
+
+// FolderDepthAllowed tells whether a folder spanning levels levels of folders,
+// counting itself, may be put into a folder parentLevels deep.
+func FolderDepthAllowed(parentLevels, levels int) bool {
+	return levels > 0 && parentLevels+levels <= MaxFolderDepth
+}
+
+func masteryPercentage(learned, total int32) float32 {
+	if total == 0 {
+		return 0
+	}
+	return float32(learned) / float32(total) * 100
+}
+
+// getOwnFolder returns a folder of the user.
+func getOwnFolder(ctx context.Context, folderStorage storage.FolderStorage, id, userID string) (*models.Folder, error) {
+	folder, err := folderStorage.GetByID(ctx, id)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if folder.OwnerID != userID {
+		return nil, ErrForbidden
+	}
+
+	return folder, nil
+}
+
+// CreateFolder creates a folder at the top level or, with parentID, inside another folder of the user.
+func (s *FolderService) CreateFolder(ctx context.Context, userID, name string, parentID *string) (*models.Folder, error) {
+	name, err := NormalizeFolderName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID != nil {
+		if _, err := getOwnFolder(ctx, s.folderStorage, *parentID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	folder := &models.Folder{
+		ID:        uuid.New().String(),
+		OwnerID:   userID,
+		ParentID:  parentID,
+		Name:      name,
+		CreatedAt: time.Now(),
+	}
+
+	created, err := s.folderStorage.Create(ctx, folder, FolderDepthAllowed)
+	if err != nil {
+		return nil, err
+	}
+	if !created {
+		return nil, ErrInvalidParam
+	}
+
+	return folder, nil
+}
+
+// GetFolderContents lists the subfolders and sets of a folder, or of the top
+// level if folderID is nil.
+func (s *FolderService) GetFolderContents(ctx context.Context, folderID *string, userID string) (*models.FolderContents, error) {
+	contents := &models.FolderContents{Path: []models.Folder{}}
+
+	if folderID != nil {
+		folder, err := getOwnFolder(ctx, s.folderStorage, *folderID, userID)
+		if err != nil {
+			return nil, err
+		}
+		contents.Folder = folder
+
+		path, err := s.folderStorage.GetPath(ctx, *folderID)
+		if err != nil {
+			return nil, err
+		}
+		contents.Path = path[:len(path)-1]
+	}
+
+	folders, err := s.folderStorage.GetChildren(ctx, userID, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	sets, err := s.folderStorage.GetSets(ctx, userID, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	var total models.Folder
+	for i := range folders {
+		folders[i].MasteryPercentage = masteryPercentage(folders[i].LearnedCount, folders[i].CardCount)
+		total.SetCount += folders[i].SetCount
+		total.CardCount += folders[i].CardCount
+		total.LearnedCount += folders[i].LearnedCount
+	}
+	for i := range sets {
+		sets[i].Role = models.SetRoleOwner
+		sets[i].MasteryPercentage = masteryPercentage(sets[i].LearnedCount, sets[i].CardCount)
+		total.SetCount++
+		total.CardCount += sets[i].CardCount
+		total.LearnedCount += sets[i].LearnedCount
+	}
+	contents.Folders, contents.Sets = folders, sets
+
+	if folder := contents.Folder; folder != nil {
+		folder.SetCount, folder.CardCount, folder.LearnedCount = total.SetCount, total.CardCount, total.LearnedCount
+		folder.MasteryPercentage = masteryPercentage(total.LearnedCount, total.CardCount)
+	}
+
+	return contents, nil
+}
+
+func (s *FolderService) RenameFolder(ctx context.Context, id, userID, name string) (*models.Folder, error) {
+	name, err := NormalizeFolderName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	folder, err := getOwnFolder(ctx, s.folderStorage, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.folderStorage.Rename(ctx, id, name); err != nil {
+		return nil, err
+	}
+
+	folder.Name = name
+	return folder, nil
+}
+
+// MoveFolder puts the folder inside another one of the user's folders, or at
+// the top level if parentID is nil. A folder can't be moved into itself or
+// into a folder below it, nor nested deeper than the folder tree allows.
+func (s *FolderService) MoveFolder(ctx context.Context, id, userID string, parentID *string) (*models.Folder, error) {
+	folder, err := getOwnFolder(ctx, s.folderStorage, id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if parentID != nil {
+		if _, err := getOwnFolder(ctx, s.folderStorage, *parentID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	moved, err := s.folderStorage.Move(ctx, id, parentID, FolderDepthAllowed)
+	if err != nil {
+		return nil, err
+	}
+	if !moved {
+		return nil, ErrInvalidParam
+	}
+
+	folder.ParentID = parentID
+	return folder, nil
+}
+
+// DeleteFolder deletes the folder and its subfolders. Their sets are kept and
+// moved to the folder's parent.
+func (s *FolderService) DeleteFolder(ctx context.Context, id, userID string) error {
+	if _, err := getOwnFolder(ctx, s.folderStorage, id, userID); err != nil {
+		return err
+	}
+
+	return s.folderStorage.Delete(ctx, id)
+}
+
+// MoveSet puts one of the user's sets into a folder, or at the top level if folderID is nil.
+func (s *FolderService) MoveSet(ctx context.Context, setID, userID string, folderID *string) (*models.CardSet, error) {
+	set, err := s.authz.Authorize(ctx, setID, userID, ActionManage)
+	if err != nil {
+		return nil, err
+	}
+
+	if folderID != nil {
+		if _, err := getOwnFolder(ctx, s.folderStorage, *folderID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.folderStorage.MoveSet(ctx, setID, folderID); err != nil {
+		return nil, err
+	}
+
+	set.FolderID = folderID
+	return set, nil
+}
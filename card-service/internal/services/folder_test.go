@@ -0,0 +1,45 @@
+package services_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeFolderName(t *testing.T) {
+	name, err := services.NormalizeFolderName("  Languages ")
+	require.NoError(t, err)
+	assert.Equal(t, "Languages", name)
+
+	_, err = services.NormalizeFolderName("   ")
+	assert.Equal(t, services.ErrInvalidParam, err)
+
+	_, err = services.NormalizeFolderName(strings.Repeat("я", services.MaxFolderNameLength+1))
+	assert.Equal(t, services.ErrInvalidParam, err)
+}
+
+func TestFolderDepthAllowed(t *testing.T) {
+	tests := []struct {
+		name         string
+		parentLevels int
+		levels       int
+		want         bool
+	}{
+		{"new folder at the top", 0, 1, true},
+		{"new folder in the deepest allowed place", services.MaxFolderDepth - 1, 1, true},
+		{"new folder below the limit", services.MaxFolderDepth, 1, false},
+		{"subtree that just fits", 40, services.MaxFolderDepth - 40, true},
+		{"subtree one level too tall", 40, services.MaxFolderDepth - 39, false},
+		{"whole-depth subtree to the top", 0, services.MaxFolderDepth, true},
+		{"whole-depth subtree into a folder", 1, services.MaxFolderDepth, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, services.FolderDepthAllowed(tt.parentLevels, tt.levels))
+		})
+	}
+}
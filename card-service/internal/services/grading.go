@@ -0,0 +1,77 @@
+package services
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeAnswer brings a typed answer to a form in which insignificant
+// differences disappear: case, diacritics (ё → е, é → e), punctuation and
+// repeated whitespace.
+func NormalizeAnswer(answer string) string {
+	stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), answer)
+	if err != nil {
+		stripped = answer
+	}
+
+	var b strings.Builder
+	for _, r := range strings.ToLower(stripped) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// typoTolerance is the number of edits accepted for an answer of the given length.
+func typoTolerance(length int) int {
+	switch {
+	case length <= 3:
+		return 0
+	case length <= 7:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// GradeTypedAnswer reports whether a typed answer matches the expected one,
+// forgiving case, whitespace, diacritics and small typos.
+func GradeTypedAnswer(expected, given string) bool {
+	e, g := NormalizeAnswer(expected), NormalizeAnswer(given)
+	if g == "" {
+		return false
+	}
+	if e == g {
+		return true
+	}
+	return levenshtein([]rune(e), []rune(g)) <= typoTolerance(len([]rune(e)))
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
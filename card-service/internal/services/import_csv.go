@@ -0,0 +1,229 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+)
+
+// CSVOptions describe how to read a CSV/TSV file. Zero values mean "detect".
+type CSVOptions struct {
+	Delimiter rune
+	HasHeader *bool
+	// Columns are given by header name or by 1-based number.
+	FrontColumn string
+	BackColumn  string
+	ImageColumn string
+	AudioColumn string
+}
+
+// ImportedCard is a card read from an import file, before it is stored.
+type ImportedCard struct {
+	Row      int32
+	Front    string
+	Back     string
+	ImageURL *string
+	AudioURL *string
+	// ImageFile and AudioFile name media inside an Anki package that still has to be uploaded.
+	ImageFile string
+	AudioFile string
+}
+
+var delimiterCandidates = []rune{'\t', ';', ',', '|'}
+
+var (
+	frontHeaders = []string{"front", "question", "term", "word", "вопрос", "термин", "слово", "лицевая сторона"}
+	backHeaders  = []string{"back", "answer", "definition", "translation", "ответ", "определение", "перевод", "обратная сторона"}
+	imageHeaders = []string{"image", "image_url", "picture", "картинка", "изображение"}
+	audioHeaders = []string{"audio", "audio_url", "sound", "аудио", "звук"}
+)
+
+// DetectDelimiter guesses the delimiter of a CSV/TSV file from its first lines:
+// the candidate found on every line the most times wins, tabs first on a tie.
+func DetectDelimiter(data []byte) rune {
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	sample := make([]string, 0, 5)
+	for _, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			sample = append(sample, line)
+		}
+		if len(sample) == cap(sample) {
+			break
+		}
+	}
+
+	best, bestScore := ',', 0
+	for _, candidate := range delimiterCandidates {
+		score := -1
+		for _, line := range sample {
+			n := strings.Count(line, string(candidate))
+			if score == -1 || n < score {
+				score = n
+			}
+		}
+		if score > bestScore {
+			best, bestScore = candidate, score
+		}
+	}
+	return best
+}
+
+func isKnownHeader(cell string, headers []string) bool {
+	cell = strings.ToLower(strings.TrimSpace(cell))
+	for _, h := range headers {
+		if cell == h {
+			return true
+		}
+	}
+	return false
+}
+
+func looksLikeHeader(record []string) bool {
+	for _, cell := range record {
+		for _, headers := range [][]string{frontHeaders, backHeaders, imageHeaders, audioHeaders} {
+			if isKnownHeader(cell, headers) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// resolveColumn returns the 0-based index of a column, -1 if it is optional and absent.
+func resolveColumn(spec string, header []string, known []string, fallback int) (int, error) {
+	spec = strings.TrimSpace(spec)
+	if spec != "" {
+		if n, err := strconv.Atoi(spec); err == nil {
+			if n < 1 {
+				return 0, fmt.Errorf("%w: column numbers start at 1", ErrInvalidImport)
+			}
+			return n - 1, nil
+		}
+		for i, cell := range header {
+			if strings.EqualFold(strings.TrimSpace(cell), spec) {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("%w: column %q not found", ErrInvalidImport, spec)
+	}
+
+	for i, cell := range header {
+		if isKnownHeader(cell, known) {
+			return i, nil
+		}
+	}
+	return fallback, nil
+}
+
+func cell(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+func mediaURL(value string) (*string, error) {
+	if value == "" {
+		return nil, nil
+	}
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%q is not an http(s) URL", value)
+	}
+	return &value, nil
+}
+
+// ParseCSV reads cards from a CSV/TSV file. Problems with single rows are
+// reported as row errors; an error is returned only if the file can't be read at all.
+func ParseCSV(data []byte, opts CSVOptions) ([]ImportedCard, []models.ImportRowError, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	delimiter := opts.Delimiter
+	if delimiter == 0 {
+		delimiter = DetectDelimiter(data)
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	var records [][]string
+	var lines []int32
+	var rowErrors []models.ImportRowError
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rowErrors = append(rowErrors, models.ImportRowError{Row: int32(parseErr.StartLine), Message: parseErr.Err.Error(), Skipped: true})
+				continue
+			}
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+		}
+		line, _ := reader.FieldPos(0)
+		records = append(records, record)
+		lines = append(lines, int32(line))
+	}
+
+	if len(records) == 0 {
+		return nil, nil, fmt.Errorf("%w: file is empty", ErrInvalidImport)
+	}
+
+	hasHeader := looksLikeHeader(records[0])
+	if opts.HasHeader != nil {
+		hasHeader = *opts.HasHeader
+	}
+
+	var header []string
+	if hasHeader {
+		header, records, lines = records[0], records[1:], lines[1:]
+	}
+
+	front, err := resolveColumn(opts.FrontColumn, header, frontHeaders, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	back, err := resolveColumn(opts.BackColumn, header, backHeaders, 1)
+	if err != nil {
+		return nil, nil, err
+	}
+	image, err := resolveColumn(opts.ImageColumn, header, imageHeaders, -1)
+	if err != nil {
+		return nil, nil, err
+	}
+	audio, err := resolveColumn(opts.AudioColumn, header, audioHeaders, -1)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cards := make([]ImportedCard, 0, len(records))
+	for i, record := range records {
+		row := lines[i]
+		card := ImportedCard{Row: row, Front: cell(record, front), Back: cell(record, back)}
+		if card.Front == "" || card.Back == "" {
+			rowErrors = append(rowErrors, models.ImportRowError{Row: row, Message: "front and back must not be empty", Skipped: true})
+			continue
+		}
+
+		if card.ImageURL, err = mediaURL(cell(record, image)); err != nil {
+			rowErrors = append(rowErrors, models.ImportRowError{Row: row, Message: "image skipped: " + err.Error()})
+		}
+		if card.AudioURL, err = mediaURL(cell(record, audio)); err != nil {
+			rowErrors = append(rowErrors, models.ImportRowError{Row: row, Message: "audio skipped: " + err.Error()})
+		}
+		cards = append(cards, card)
+	}
+
+	return cards, rowErrors, nil
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/anki"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/fileclient"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/storage"
+)
+
+// ErrInvalidImport is returned, wrapped with the reason, when an import file can't be used at all.
+var ErrInvalidImport = errors.New("invalid import file")
+
+// MaxSetNameLength is the longest set name that can be stored; longer names
+// of imported sets are cut.
+const MaxSetNameLength = 100
+
+// ImportSetInfo describes the set an import creates.
+type ImportSetInfo struct {
+	Name        string
+	Description *string
+	IsPublic    bool
+	Tags        []string
+}
+
+type ImportService struct {
+	setStorage storage.CardSetStorage
+	files      *fileclient.Client
+	maxCards   int32
+}
+
+func NewImportService(setStorage storage.CardSetStorage, files *fileclient.Client, maxCards int32) *ImportService {
+	return &ImportService{setStorage: setStorage, files: files, maxCards: maxCards}
+}
+
+// ImportCSV creates a set from a CSV/TSV file.
+func (s *ImportService) ImportCSV(ctx context.Context, ownerID string, info ImportSetInfo, data []byte, opts CSVOptions) (*models.ImportReport, error) {
+	cards, rowErrors, err := ParseCSV(data, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.createSet(ctx, ownerID, info, cards, rowErrors, nil)
+}
+
+// ImportAnki creates a set from the notes of an Anki package, uploading the
+// media they use to file storage.
+func (s *ImportService) ImportAnki(ctx context.Context, ownerID string, info ImportSetInfo, r io.ReaderAt, size int64) (*models.ImportReport, error) {
+	pkg, err := anki.Open(r, size)
+	if errors.Is(err, anki.ErrUnsupportedVersion) {
+		return nil, fmt.Errorf("%w: re-export the deck with \"Support older Anki versions\" enabled", ErrInvalidImport)
+	}
+	if errors.Is(err, anki.ErrInvalidPackage) || errors.Is(err, anki.ErrTooLarge) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImport, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cards, rowErrors := AnkiCards(pkg.Notes)
+	info.Tags = AnkiTags(pkg.Notes)
+	return s.createSet(ctx, ownerID, info, cards, rowErrors, pkg)
+}
+
+// AnkiTags picks the tags of the set out of the tags of the notes, the most
+// used first. Tags that can't be set tags, such as hierarchical ones, and any
+// beyond MaxTagsPerSet are left out.
+func AnkiTags(notes []anki.Note) []string {
+	counts := make(map[string]int)
+	tags := []string{}
+	for _, note := range notes {
+		for _, tag := range note.Tags {
+			normalized, err := NormalizeTags([]string{tag})
+			if err != nil || len(normalized) == 0 {
+				continue
+			}
+			if counts[normalized[0]] == 0 {
+				tags = append(tags, normalized[0])
+			}
+			counts[normalized[0]]++
+		}
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool { return counts[tags[i]] > counts[tags[j]] })
+	return tags[:min(len(tags), MaxTagsPerSet)]
+}
+
+// AnkiCards turns Anki notes into cards. Standard notes use their Front/Back
+// fields (or the first two), cloze notes show the text with deletions hidden on
+// the front and revealed on the back. HTML is reduced to plain text.
+func AnkiCards(notes []anki.Note) ([]ImportedCard, []models.ImportRowError) {
+	var cards []ImportedCard
+	var rowErrors []models.ImportRowError
+
+	for i, note := range notes {
+		row := int32(i + 1)
+
+		var frontHTML, backHTML string
+		if note.ModelType == anki.ModelCloze {
+			text, ok := note.Field("Text")
+			if !ok && len(note.Fields) > 0 {
+				text = note.Fields[0]
+			}
+			frontHTML = anki.RenderCloze(text, false)
+			backHTML = anki.RenderCloze(text, true)
+			if extra, ok := note.Field("Back Extra", "Extra"); ok && extra != "" {
+				backHTML += "<br>" + extra
+			}
+		} else {
+			var okFront, okBack bool
+			frontHTML, okFront = note.Field("Front", "Question", "Вопрос")
+			backHTML, okBack = note.Field("Back", "Answer", "Ответ")
+			if !okFront && len(note.Fields) > 0 {
+				frontHTML = note.Fields[0]
+			}
+			if !okBack && len(note.Fields) > 1 {
+				backHTML = note.Fields[1]
+			}
+		}
+
+		front, frontImages, frontSounds := anki.FieldContent(frontHTML)
+		back, backImages, backSounds := anki.FieldContent(backHTML)
+		images := append(frontImages, backImages...)
+		sounds := append(frontSounds, backSounds...)
+
+		// A side may consist of media alone, e.g. a picture to name
+		if front == "" && len(frontImages) > 0 {
+			front = "[image]"
+		}
+		if front == "" || back == "" {
+			rowErrors = append(rowErrors, models.ImportRowError{Row: row, Message: "front and back must not be empty", Skipped: true})
+			continue
+		}
+
+		card := ImportedCard{Row: row, Front: front, Back: back}
+		if len(images) > 0 {
+			card.ImageFile = images[0]
+		}
+		if len(sounds) > 0 {
+			card.AudioFile = sounds[0]
+		}
+		if len(images) > 1 || len(sounds) > 1 {
+			rowErrors = append(rowErrors, models.ImportRowError{Row: row, Message: "only the first image and audio are kept"})
+		}
+		cards = append(cards, card)
+	}
+
+	return cards, rowErrors
+}
+
+func (s *ImportService) createSet(ctx context.Context, ownerID string, info ImportSetInfo, imported []ImportedCard, rowErrors []models.ImportRowError, pkg *anki.Package) (*models.ImportReport, error) {
+	if len(imported) == 0 {
+		return nil, fmt.Errorf("%w: no cards to import", ErrInvalidImport)
+	}
+	if s.maxCards > 0 && int32(len(imported)) > s.maxCards {
+		return nil, fmt.Errorf("%w: at most %d cards can be imported at once", ErrInvalidImport, s.maxCards)
+	}
+
+	name := strings.TrimSpace(info.Name)
+	if runes := []rune(name); len(runes) > MaxSetNameLength {
+		name = strings.TrimSpace(string(runes[:MaxSetNameLength]))
+	}
+	tags := info.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+
+	set := &models.CardSet{
+		ID:          uuid.New().String(),
+		OwnerID:     ownerID,
+		Name:        name,
+		Description: info.Description,
+		IsPublic:    info.IsPublic,
+		Tags:        tags,
+		CreatedAt:   time.Now(),
+	}
+
+	uploader := &mediaUploader{files: s.files, pkg: pkg, ownerID: ownerID, urls: make(map[string]string)}
+
+	cards := make([]models.Card, 0, len(imported))
+	for _, item := range imported {
+		card := models.Card{
+			ID:        uuid.New().String(),
+			SetID:     set.ID,
+			Front:     item.Front,
+			Back:      item.Back,
+			ImageURL:  item.ImageURL,
+			AudioURL:  item.AudioURL,
+			Status:    models.StatusNew,
+			CreatedAt: time.Now(),
+		}
+
+		var err error
+		if item.ImageFile != "" {
+			if card.ImageURL, err = uploader.upload(ctx, item.ImageFile); err != nil {
+				rowErrors = append(rowErrors, models.ImportRowError{Row: item.Row, Message: fmt.Sprintf("image %q skipped: %v", item.ImageFile, err)})
+			}
+		}
+		if item.AudioFile != "" {
+			if card.AudioURL, err = uploader.upload(ctx, item.AudioFile); err != nil {
+				rowErrors = append(rowErrors, models.ImportRowError{Row: item.Row, Message: fmt.Sprintf("audio %q skipped: %v", item.AudioFile, err)})
+			}
+		}
+		cards = append(cards, card)
+	}
+
+	if err := s.setStorage.CreateWithCards(ctx, set, cards); err != nil {
+		uploader.cleanup()
+		return nil, err
+	}
+	set.CardCount = int32(len(cards))
+
+	sort.SliceStable(rowErrors, func(i, j int) bool { return rowErrors[i].Row < rowErrors[j].Row })
+
+	report := &models.ImportReport{
+		Set:      set,
+		Imported: int32(len(cards)),
+		Errors:   rowErrors,
+	}
+	if report.Errors == nil {
+		report.Errors = []models.ImportRowError{}
+	}
+	for _, rowErr := range rowErrors {
+		if rowErr.Skipped {
+			report.Skipped++
+		}
+	}
+	report.TotalRows = report.Imported + report.Skipped
+
+	return report, nil
+}
+
+// mediaUploader uploads Anki media to file storage, each file once.
+type mediaUploader struct {
+	files   *fileclient.Client
+	pkg     *anki.Package
+	ownerID string
+	urls    map[string]string
+	fileIDs []string
+}
+
+func (u *mediaUploader) upload(ctx context.Context, name string) (*string, error) {
+	if url, ok := u.urls[name]; ok {
+		return &url, nil
+	}
+	if u.pkg == nil || u.files == nil {
+		return nil, errors.New("media storage is not available")
+	}
+
+	data, found, err := u.pkg.Media(name)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		return nil, errors.New("file is missing from the package")
+	}
+
+	mimeType := mime.TypeByExtension(filepath.Ext(name))
+	if mimeType == "" {
+		mimeType = http.DetectContentType(data)
+	}
+
+	fileID, url, err := u.files.UploadFile(ctx, data, name, mimeType, u.ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	u.urls[name] = url
+	u.fileIDs = append(u.fileIDs, fileID)
+	return &url, nil
+}
+
+// cleanup removes uploaded files when the import did not go through.
+func (u *mediaUploader) cleanup() {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	for _, fileID := range u.fileIDs {
+		if err := u.files.DeleteFile(ctx, fileID); err != nil {
+			log.Printf("Failed to delete imported file %s: %v", fileID, err)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package services_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/anki"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectDelimiter(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     string
+		expected rune
+	}{
+		{"Comma", "cat,кошка\ndog,собака\n", ','},
+		{"Semicolon with commas in text", "cat;кошка, кот\ndog;собака\n", ';'},
+		{"Tab", "cat\tкошка\ndog\tсобака, пёс\n", '\t'},
+		{"Pipe", "cat|кошка\ndog|собака\n", '|'},
+		{"Single column falls back to comma", "cat\ndog\n", ','},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, services.DetectDelimiter([]byte(tt.data)))
+		})
+	}
+}
+
+func TestParseCSV(t *testing.T) {
+	data := "\xef\xbb\xbfTerm;Definition;Image\n" +
+		"cat;кошка;https://example.com/cat.jpg\n" +
+		"dog;;\n" +
+		"\"bird; small\";птица;not-a-url\n"
+
+	cards, rowErrors, err := services.ParseCSV([]byte(data), services.CSVOptions{})
+	require.NoError(t, err)
+
+	require.Len(t, cards, 2)
+	assert.Equal(t, int32(2), cards[0].Row)
+	assert.Equal(t, "cat", cards[0].Front)
+	assert.Equal(t, "кошка", cards[0].Back)
+	require.NotNil(t, cards[0].ImageURL)
+	assert.Equal(t, "https://example.com/cat.jpg", *cards[0].ImageURL)
+	assert.Equal(t, "bird; small", cards[1].Front)
+	assert.Nil(t, cards[1].ImageURL)
+
+	assert.Equal(t, []models.ImportRowError{
+		{Row: 3, Message: "front and back must not be empty", Skipped: true},
+		{Row: 4, Message: `image skipped: "not-a-url" is not an http(s) URL`},
+	}, rowErrors)
+}
+
+func TestParseCSV_ColumnMapping(t *testing.T) {
+	noHeader := false
+	data := "1\tкошка\tcat\n2\tсобака\tdog\n"
+
+	cards, rowErrors, err := services.ParseCSV([]byte(data), services.CSVOptions{HasHeader: &noHeader, FrontColumn: "3", BackColumn: "2"})
+	require.NoError(t, err)
+	assert.Empty(t, rowErrors)
+	require.Len(t, cards, 2)
+	assert.Equal(t, "cat", cards[0].Front)
+	assert.Equal(t, "кошка", cards[0].Back)
+
+	_, _, err = services.ParseCSV([]byte("front,back\na,b\n"), services.CSVOptions{FrontColumn: "question"})
+	assert.ErrorIs(t, err, services.ErrInvalidImport)
+
+	_, _, err = services.ParseCSV([]byte(""), services.CSVOptions{})
+	assert.ErrorIs(t, err, services.ErrInvalidImport)
+}
+
+func TestAnkiCards(t *testing.T) {
+	notes := []anki.Note{
+		{ModelType: anki.ModelStandard, FieldNames: []string{"Front", "Back"}, Fields: []string{"<b>cat</b>", `кошка<img src="cat.jpg">[sound:cat.mp3]`}},
+		{ModelType: anki.ModelCloze, FieldNames: []string{"Text", "Back Extra"}, Fields: []string{"{{c1::Paris}} is in France", "capital"}},
+		{ModelType: anki.ModelStandard, FieldNames: []string{"Word", "Meaning"}, Fields: []string{"dog", "собака"}},
+		{ModelType: anki.ModelStandard, FieldNames: []string{"Front", "Back"}, Fields: []string{"empty", ""}},
+	}
+
+	cards, rowErrors := services.AnkiCards(notes)
+
+	require.Len(t, cards, 3)
+	assert.Equal(t, services.ImportedCard{Row: 1, Front: "cat", Back: "кошка", ImageFile: "cat.jpg", AudioFile: "cat.mp3"}, cards[0])
+	assert.Equal(t, "[...] is in France", cards[1].Front)
+	assert.Equal(t, "Paris is in France\ncapital", cards[1].Back)
+	assert.Equal(t, "dog", cards[2].Front)
+	assert.Equal(t, "собака", cards[2].Back)
+	assert.Equal(t, []models.ImportRowError{{Row: 4, Message: "front and back must not be empty", Skipped: true}}, rowErrors)
+}
+
+func TestAnkiTags(t *testing.T) {
+	notes := []anki.Note{
+		{Tags: []string{"Verbs", "lang::english"}},
+		{Tags: []string{"animals", "verbs"}},
+		{Tags: []string{"animals", "#Verbs"}},
+		{},
+	}
+	assert.Equal(t, []string{"verbs", "animals"}, services.AnkiTags(notes))
+
+	var many []string
+	for i := range services.MaxTagsPerSet + 5 {
+		many = append(many, fmt.Sprintf("tag%d", i))
+	}
+	assert.Len(t, services.AnkiTags([]anki.Note{{Tags: many}}), services.MaxTagsPerSet)
+	assert.Equal(t, []string{}, services.AnkiTags(nil))
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"time"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+)
+
+// MaxLeechThreshold bounds the number of lapses that makes an item a leech.
+const MaxLeechThreshold = 100
+
+// ApplyLapse counts a lapse on the scheduled progress of an item when it is
+// rated again after it had been learned, and marks the item a leech once its
+// lapses reach the user's threshold, suspending it if the user asked for that.
+// It reports whether the item has just become a leech.
+func ApplyLapse(previous models.CardStatus, next *models.CardProgress, rating models.CardRating, settings models.UserSettings, now time.Time) bool {
+	if rating != models.RatingAgain || (previous != models.StatusReviewing && previous != models.StatusMastered) {
+		return false
+	}
+
+	next.Lapses++
+	if next.Leech || settings.LeechThreshold <= 0 || next.Lapses < settings.LeechThreshold {
+		return false
+	}
+
+	next.Leech = true
+	if settings.SuspendLeeches && next.SuspendedAt == nil {
+		next.SuspendedAt = &now
+	}
+	return true
+}
+
+// NextDayStart returns when the day after the one it is at now begins in the
+// given timezone; buried items come back to study then.
+func NextDayStart(now time.Time, timezone string) time.Time {
+	local := now.In(userLocation(timezone))
+	return time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, local.Location())
+}
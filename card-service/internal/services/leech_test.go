@@ -0,0 +1,69 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplyLapse(t *testing.T) {
+	now := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	settings := *models.DefaultUserSettings()
+	settings.LeechThreshold = 3
+	suspending := settings
+	suspending.SuspendLeeches = true
+	never := settings
+	never.LeechThreshold = 0
+
+	tests := []struct {
+		name           string
+		previous       models.CardStatus
+		progress       models.CardProgress
+		rating         models.CardRating
+		settings       models.UserSettings
+		expectedLapses int32
+		expectedLeech  bool
+		becameLeech    bool
+		suspended      bool
+	}{
+		{"Good answer is no lapse", models.StatusReviewing, models.CardProgress{Lapses: 2}, models.RatingGood, settings, 2, false, false, false},
+		{"Forgetting while learning is no lapse", models.StatusLearning, models.CardProgress{Lapses: 2}, models.RatingAgain, settings, 2, false, false, false},
+		{"Lapse below the threshold", models.StatusReviewing, models.CardProgress{Lapses: 1}, models.RatingAgain, settings, 2, false, false, false},
+		{"Lapse reaching the threshold", models.StatusMastered, models.CardProgress{Lapses: 2}, models.RatingAgain, settings, 3, true, true, false},
+		{"Leech stays a leech", models.StatusReviewing, models.CardProgress{Lapses: 3, Leech: true}, models.RatingAgain, settings, 4, true, false, false},
+		{"Leech suspended", models.StatusReviewing, models.CardProgress{Lapses: 2}, models.RatingAgain, suspending, 3, true, true, true},
+		{"Threshold 0 never marks leeches", models.StatusReviewing, models.CardProgress{Lapses: 20}, models.RatingAgain, never, 21, false, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := tt.progress
+			became := services.ApplyLapse(tt.previous, &next, tt.rating, tt.settings, now)
+			assert.Equal(t, tt.becameLeech, became)
+			assert.Equal(t, tt.expectedLapses, next.Lapses)
+			assert.Equal(t, tt.expectedLeech, next.Leech)
+			assert.Equal(t, tt.suspended, next.SuspendedAt != nil)
+		})
+	}
+}
+
+func TestNextDayStart(t *testing.T) {
+	tests := []struct {
+		name     string
+		now      time.Time
+		timezone string
+		expected time.Time
+	}{
+		{"UTC", time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC), "UTC", time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},
+		{"Already the next day locally", time.Date(2026, 3, 1, 22, 0, 0, 0, time.UTC), "Europe/Moscow", time.Date(2026, 3, 2, 21, 0, 0, 0, time.UTC)},
+		{"End of the month", time.Date(2026, 2, 28, 12, 0, 0, 0, time.UTC), "UTC", time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"Unknown timezone is UTC", time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC), "Nowhere/City", time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.True(t, tt.expected.Equal(services.NextDayStart(tt.now, tt.timezone)))
+		})
+	}
+}
@@ -0,0 +1,492 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	gmtext "github.com/yuin/goldmark/text"
+)
+
+// span is a piece of Markdown that is not text: code, or a LaTeX formula.
+type span struct {
+	start, end int
+	// inner is the code or formula without its delimiters.
+	inner   string
+	formula bool
+}
+
+// splitMarkdown finds the code and formulas in Markdown. Code is fenced blocks
+// and `inline` spans, formulas are $inline$, $$display$$, \(inline\) and
+// \[display\]. A single $ that can't open or close a formula, as in "$5", is
+// plain text; display and bracketed formulas must be closed.
+func splitMarkdown(text string) ([]span, error) {
+	var spans []span
+	for i := 0; i < len(text); {
+		switch c := text[i]; {
+		case opensFence(text, i):
+			end, inner := fencedBlock(text, i)
+			spans = append(spans, span{start: i, end: end, inner: inner})
+			i = end
+		case c == '`':
+			n := runLength(text, i, '`')
+			closing := closingBackticks(text, i+n, n)
+			if closing < 0 {
+				i += n
+				continue
+			}
+			spans = append(spans, span{start: i, end: closing + n, inner: text[i+n : closing]})
+			i = closing + n
+		case c == '\\' && i+1 < len(text) && (text[i+1] == '(' || text[i+1] == '['):
+			delim := `\)`
+			if text[i+1] == '[' {
+				delim = `\]`
+			}
+			closing := strings.Index(text[i+2:], delim)
+			if closing < 0 {
+				return nil, fmt.Errorf("formula opened with %s is not closed", text[i:i+2])
+			}
+			end := i + 2 + closing + 2
+			spans = append(spans, span{start: i, end: end, inner: text[i+2 : end-2], formula: true})
+			i = end
+		case c == '\\':
+			i += 2
+		case strings.HasPrefix(text[i:], "$$"):
+			closing := strings.Index(text[i+2:], "$$")
+			if closing < 0 {
+				return nil, fmt.Errorf("formula opened with $$ is not closed")
+			}
+			end := i + 2 + closing + 2
+			spans = append(spans, span{start: i, end: end, inner: text[i+2 : end-2], formula: true})
+			i = end
+		case c == '$':
+			closing := inlineFormulaEnd(text, i)
+			if closing < 0 {
+				i++
+				continue
+			}
+			spans = append(spans, span{start: i, end: closing + 1, inner: text[i+1 : closing], formula: true})
+			i = closing + 1
+		default:
+			i++
+		}
+	}
+	return spans, nil
+}
+
+func runLength(text string, i int, c byte) int {
+	n := 0
+	for i+n < len(text) && text[i+n] == c {
+		n++
+	}
+	return n
+}
+
+// opensFence tells whether a code block fence, ``` or ~~~ indented by at
+// most three spaces, starts at i.
+func opensFence(text string, i int) bool {
+	c := text[i]
+	if c != '`' && c != '~' {
+		return false
+	}
+	lineStart := strings.LastIndexByte(text[:i], '\n') + 1
+	if i-lineStart > 3 || strings.Trim(text[lineStart:i], " ") != "" {
+		return false
+	}
+	n := runLength(text, i, c)
+	if n < 3 {
+		return false
+	}
+	info, _, _ := strings.Cut(text[i+n:], "\n")
+	return c == '~' || !strings.ContainsRune(info, '`')
+}
+
+// fencedBlock reads the code block whose fence starts at i. A block that is
+// never closed runs to the end of the text.
+func fencedBlock(text string, i int) (end int, inner string) {
+	c := text[i]
+	n := runLength(text, i, c)
+	lineEnd := strings.IndexByte(text[i:], '\n')
+	if lineEnd < 0 {
+		return len(text), ""
+	}
+
+	bodyStart := i + lineEnd + 1
+	for pos := bodyStart; pos < len(text); {
+		next := strings.IndexByte(text[pos:], '\n')
+		line := text[pos:]
+		if next >= 0 {
+			line = text[pos : pos+next]
+		}
+		trimmed := strings.TrimLeft(line, " ")
+		if len(line)-len(trimmed) <= 3 && runLength(trimmed, 0, c) >= n && strings.Trim(trimmed, string(c)+" ") == "" {
+			if next < 0 {
+				return len(text), text[bodyStart:pos]
+			}
+			return pos + next + 1, text[bodyStart:pos]
+		}
+		if next < 0 {
+			break
+		}
+		pos += next + 1
+	}
+	return len(text), text[bodyStart:]
+}
+
+// closingBackticks finds a run of exactly n backticks from position i on.
+func closingBackticks(text string, i, n int) int {
+	for i < len(text) {
+		j := strings.IndexByte(text[i:], '`')
+		if j < 0 {
+			return -1
+		}
+		i += j
+		run := runLength(text, i, '`')
+		if run == n {
+			return i
+		}
+		i += run
+	}
+	return -1
+}
+
+// inlineFormulaEnd finds the $ closing a formula opened at i. Following
+// Pandoc, the opening $ must be followed and the closing $ preceded by a
+// non-space, and the closing $ must not be followed by a digit.
+func inlineFormulaEnd(text string, i int) int {
+	if i+1 >= len(text) || isSpace(text[i+1]) {
+		return -1
+	}
+	for j := i + 1; j < len(text); j++ {
+		switch text[j] {
+		case '\\':
+			j++
+		case '\n':
+			if j+1 < len(text) && text[j+1] == '\n' {
+				return -1
+			}
+		case '$':
+			if !isSpace(text[j-1]) && (j+1 == len(text) || text[j+1] < '0' || text[j+1] > '9') {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// blockedCommands are LaTeX commands a formula must not use: they link out,
+// load files, inject HTML or define macros.
+var blockedCommands = map[string]bool{
+	"href": true, "url": true, "includegraphics": true, "input": true, "include": true,
+	"write": true, "immediate": true, "openout": true, "catcode": true,
+	"def": true, "gdef": true, "edef": true, "xdef": true, "let": true,
+	"newcommand": true, "renewcommand": true, "providecommand": true,
+}
+
+var commandPattern = regexp.MustCompile(`\\([A-Za-z]+)`)
+
+// checkFormula validates the LaTeX source of a formula.
+func checkFormula(formula string) error {
+	depth, left := 0, 0
+	for i := 0; i < len(formula); i++ {
+		switch formula[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth < 0 {
+				return fmt.Errorf("formula %q has an unmatched }", formula)
+			}
+		}
+	}
+	if depth != 0 {
+		return fmt.Errorf("formula %q has an unclosed {", formula)
+	}
+
+	for _, m := range commandPattern.FindAllStringSubmatch(formula, -1) {
+		command := m[1]
+		if blockedCommands[command] || strings.HasPrefix(command, "html") {
+			return fmt.Errorf("formula uses \\%s, which is not allowed", command)
+		}
+		switch command {
+		case "left":
+			left++
+		case "right":
+			left--
+		}
+	}
+	if left != 0 {
+		return fmt.Errorf("formula %q has unmatched \\left and \\right", formula)
+	}
+	return nil
+}
+
+// edit replaces text[start:end].
+type edit struct {
+	start, end int
+	text       string
+}
+
+// applyEdits makes the edits to text. An edit overlapping one that starts
+// before it is dropped.
+func applyEdits(text string, edits []edit) string {
+	slices.SortStableFunc(edits, func(a, b edit) int {
+		if a.start != b.start {
+			return a.start - b.start
+		}
+		return b.end - a.end
+	})
+
+	var b strings.Builder
+	pos := 0
+	for _, e := range edits {
+		if e.start < pos {
+			continue
+		}
+		b.WriteString(text[pos:e.start])
+		b.WriteString(e.text)
+		pos = e.end
+	}
+	b.WriteString(text[pos:])
+	return b.String()
+}
+
+// maskSpans blanks out code and formulas so that patterns looking for
+// Markdown syntax only match in text.
+func maskSpans(text string, spans []span) string {
+	masked := []byte(text)
+	for _, s := range spans {
+		for i := s.start; i < s.end; i++ {
+			masked[i] = 'x'
+		}
+	}
+	return string(masked)
+}
+
+var (
+	// linkPattern matches inline links and images: [text](url "title"). It
+	// finds the links worth showing as plain text, not every link a renderer sees.
+	linkPattern = regexp.MustCompile(`(!?)\[([^\[\]]*)\]\(\s*(<[^<>\n]*>|[^\s()]*(?:\([^\s()]*\)[^\s()]*)*)(?:\s+(?:"[^"\n]*"|'[^'\n]*'))?\s*\)`)
+	// linkDefinitionPattern matches reference link definitions: [id]: url.
+	linkDefinitionPattern = regexp.MustCompile(`(?m)^ {0,3}\[[^\]\n]+\]:[ \t]*(<[^<>\n]*>|\S+).*$`)
+	autolinkPattern       = regexp.MustCompile(`^<((?:https?://|mailto:)[^\s<>]*)>`)
+	escapedPunctuation    = regexp.MustCompile("\\\\([!-/:-@\\[-`{-~])")
+)
+
+// safeURL tells whether a link may point to the URL: http(s), mailto and
+// relative links are fine, images must be http(s). The URL is checked the way
+// a renderer would read it, after entities and escapes.
+func safeURL(raw string, image bool) bool {
+	raw = escapedPunctuation.ReplaceAllString(html.UnescapeString(strings.Trim(raw, "<>")), "$1")
+	raw = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, raw)
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		return u.Host != ""
+	case "mailto", "":
+		return !image
+	}
+	return false
+}
+
+// SanitizeMarkdown makes Markdown safe to render: raw HTML is escaped so it
+// shows as text, and links and images pointing anywhere but the web are shown
+// as text too. Formulas are validated; the error tells what is wrong.
+func SanitizeMarkdown(text string) (string, error) {
+	text = strings.ToValidUTF8(strings.ReplaceAll(text, "\x00", ""), "")
+
+	spans, err := splitMarkdown(text)
+	if err != nil {
+		return "", err
+	}
+	masked := maskSpans(text, spans)
+	var edits []edit
+	for i := 0; i < len(masked); i++ {
+		if masked[i] != '<' || i+1 == len(masked) || (!isASCIILetter(masked[i+1]) && !strings.ContainsRune("/!?", rune(masked[i+1]))) {
+			continue
+		}
+		if escaped(masked, i) || autolinkPattern.MatchString(masked[i:]) {
+			continue
+		}
+		edits = append(edits, edit{start: i, end: i, text: `\`})
+	}
+	if text, err = defuseLinks(applyEdits(text, edits)); err != nil {
+		return "", err
+	}
+
+	if spans, err = splitMarkdown(text); err != nil {
+		return "", err
+	}
+	for _, s := range spans {
+		if s.formula {
+			if err := checkFormula(s.inner); err != nil {
+				return "", err
+			}
+		}
+	}
+	return text, nil
+}
+
+// markdownParser reads Markdown the way a CommonMark renderer does.
+var markdownParser = goldmark.DefaultParser()
+
+// defuseLinks turns the links, images and autolinks with an unsafe target into
+// text by replacing their opening bracket with an entity, which never starts
+// a link. Reading the text as a renderer does finds links with nested brackets
+// and parentheses and links to reference definitions on several lines alike.
+// The text is read again until no unsafe link is left, as a link shown as text
+// can make room for another one, like an image it held.
+func defuseLinks(text string) (string, error) {
+	for {
+		source := []byte(text)
+		var edits []edit
+		var walkErr error
+		ast.Walk(markdownParser.Parse(gmtext.NewReader(source)), func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+			if !entering {
+				return ast.WalkContinue, nil
+			}
+			var target []byte
+			image := false
+			switch n := n.(type) {
+			case *ast.Link:
+				target = n.Destination
+			case *ast.Image:
+				target, image = n.Destination, true
+			case *ast.AutoLink:
+				target = n.URL(source)
+				if n.AutoLinkType == ast.AutoLinkEmail {
+					target = append([]byte("mailto:"), target...)
+				}
+			default:
+				return ast.WalkContinue, nil
+			}
+			if safeURL(string(target), image) {
+				return ast.WalkContinue, nil
+			}
+
+			pos := n.Pos()
+			if image {
+				pos++
+			}
+			if pos < 0 || pos >= len(source) || (source[pos] != '[' && source[pos] != '<') {
+				walkErr = fmt.Errorf("link to %q can't be shown as text", target)
+				return ast.WalkStop, nil
+			}
+			entity := "&#91;"
+			if source[pos] == '<' {
+				entity = "&lt;"
+			}
+			edits = append(edits, edit{start: pos, end: pos + 1, text: entity})
+			return ast.WalkContinue, nil
+		})
+		if walkErr != nil {
+			return "", walkErr
+		}
+		if len(edits) == 0 {
+			return text, nil
+		}
+		text = applyEdits(text, edits)
+	}
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// escaped tells whether the character at i is preceded by an odd number of backslashes.
+func escaped(text string, i int) bool {
+	n := 0
+	for i-n-1 >= 0 && text[i-n-1] == '\\' {
+		n++
+	}
+	return n%2 == 1
+}
+
+var (
+	headingPattern    = regexp.MustCompile(`(?m)^ {0,3}#{1,6}[ \t]+`)
+	quotePattern      = regexp.MustCompile(`(?m)^ {0,3}(?:>[ \t]?)+`)
+	bulletPattern     = regexp.MustCompile(`(?m)^[ \t]*([*+])[ \t]+`)
+	rulePattern       = regexp.MustCompile(`(?m)^ {0,3}[-*_](?:[ \t]*[-*_]){2,}[ \t]*$`)
+	strongPattern     = regexp.MustCompile(`\*\*|__|~~`)
+	blankLinesPattern = regexp.MustCompile(`\n{3,}`)
+)
+
+// PlainText renders card text for clients that only show plain text. Markdown
+// loses its syntax, links keep their text and images their description;
+// formulas and code are left as their source.
+func PlainText(format models.ContentFormat, text string) string {
+	if format != models.FormatMarkdown {
+		return text
+	}
+	spans, err := splitMarkdown(text)
+	if err != nil {
+		return text
+	}
+	masked := maskSpans(text, spans)
+
+	var edits []edit
+	for _, m := range linkPattern.FindAllStringSubmatchIndex(masked, -1) {
+		edits = append(edits, edit{start: m[0], end: m[1], text: PlainText(format, text[m[4]:m[5]])})
+	}
+	for _, m := range linkDefinitionPattern.FindAllStringIndex(masked, -1) {
+		edits = append(edits, edit{start: m[0], end: m[1]})
+	}
+	for _, s := range spans {
+		edits = append(edits, edit{start: s.start, end: s.end, text: s.inner})
+	}
+	for _, pattern := range []*regexp.Regexp{headingPattern, quotePattern, rulePattern, strongPattern} {
+		for _, m := range pattern.FindAllStringIndex(masked, -1) {
+			edits = append(edits, edit{start: m[0], end: m[1]})
+		}
+	}
+	for _, m := range bulletPattern.FindAllStringSubmatchIndex(masked, -1) {
+		edits = append(edits, edit{start: m[2], end: m[3], text: "-"})
+	}
+	for _, m := range escapedPunctuation.FindAllStringSubmatchIndex(masked, -1) {
+		edits = append(edits, edit{start: m[0], end: m[1], text: text[m[2]:m[3]]})
+	}
+	for i := 0; i < len(masked); i++ {
+		switch {
+		case masked[i] == '<':
+			if m := autolinkPattern.FindStringSubmatchIndex(masked[i:]); m != nil {
+				edits = append(edits, edit{start: i, end: i + m[1], text: masked[i+m[2] : i+m[3]]})
+			}
+		case masked[i] == '*' || (masked[i] == '_' && (!wordByte(masked, i-1) || !wordByte(masked, i+1))):
+			edits = append(edits, edit{start: i, end: i + 1})
+		}
+	}
+
+	plain := applyEdits(text, edits)
+	return strings.TrimSpace(blankLinesPattern.ReplaceAllString(plain, "\n\n"))
+}
+
+// wordByte tells whether text[i] belongs to a word; bytes of non-ASCII
+// characters count as letters.
+func wordByte(text string, i int) bool {
+	if i < 0 || i >= len(text) {
+		return false
+	}
+	c := text[i]
+	return c >= 0x80 || isASCIILetter(c) || (c >= '0' && c <= '9')
+}
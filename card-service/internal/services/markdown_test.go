@@ -0,0 +1,97 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizeMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"plain markdown", "**bold** and [docs](https://example.com)", "**bold** and [docs](https://example.com)"},
+		{"formulas", "$E = mc^2$ and $$\\frac{a}{b}$$", "$E = mc^2$ and $$\\frac{a}{b}$$"},
+		{"html is escaped", "<script>alert(1)</script>", "\\<script>alert(1)\\</script>"},
+		{"comparison in a formula", "$a<b$ but <b>c</b>", "$a<b$ but \\<b>c\\</b>"},
+		{"html in code", "`<b>` and\n```\n<i>\n```", "`<b>` and\n```\n<i>\n```"},
+		{"escaped html", "\\<b>", "\\<b>"},
+		{"autolink", "<https://example.com>", "<https://example.com>"},
+		{"script link", "[click](javascript:alert(1))", "&#91;click](javascript:alert(1))"},
+		{"obfuscated script link", "[click](JaVa&#115;cript&#58;alert(1))", "&#91;click](JaVa&#115;cript&#58;alert(1))"},
+		{"escaped script link", "[click](javascript\\:alert)", "&#91;click](javascript\\:alert)"},
+		{"data image", "![cat](data:image/png;base64,AAAA)", "!&#91;cat](data:image/png;base64,AAAA)"},
+		{"relative image", "![cat](/cat.png)", "!&#91;cat](/cat.png)"},
+		{"relative link", "[up](../index.html)", "[up](../index.html)"},
+		{"script definition", "[x]\n\n[x]: javascript:alert(1)", "&#91;x]\n\n[x]: javascript:alert(1)"},
+		{"definition on two lines", "[x]:\n javascript:alert(1)\n\n[x]", "[x]:\n javascript:alert(1)\n\n&#91;x]"},
+		{"image in a script link", "[![i](http://a/b.png)](javascript:alert(1))", "&#91;![i](http://a/b.png)](javascript:alert(1))"},
+		{"image in a web link", "[![i](http://a/b.png)](https://example.com)", "[![i](http://a/b.png)](https://example.com)"},
+		{"nested brackets", "[a [b] c](javascript:alert(1))", "&#91;a [b] c](javascript:alert(1))"},
+		{"nested parentheses", "[x](javascript:alert((1)))", "&#91;x](javascript:alert((1)))"},
+		{"script autolink", "<javascript:alert(1)>", "\\<javascript:alert(1)>"},
+		{"script link in brackets", "[a](<javascript:x>)", "&#91;a](\\<javascript:x>)"},
+		{"dollars", "costs $5 or $10", "costs $5 or $10"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := services.SanitizeMarkdown(tt.in)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+
+			again, err := services.SanitizeMarkdown(got)
+			require.NoError(t, err)
+			assert.Equal(t, got, again, "sanitizing twice changes nothing")
+		})
+	}
+}
+
+func TestSanitizeMarkdownInvalidFormulas(t *testing.T) {
+	tests := []string{
+		"$$x^2",
+		"\\(x",
+		"$\\frac{a}{b$",
+		"$a}$",
+		"$\\left( x$",
+		"$\\href{javascript:alert(1)}{x}$",
+		"$$\\htmlClass{x}{y}$$",
+		"\\[\\def\\x{1}\\]",
+	}
+	for _, in := range tests {
+		t.Run(in, func(t *testing.T) {
+			_, err := services.SanitizeMarkdown(in)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{"emphasis", "**bold**, *italic*, _also_ and ~~gone~~", "bold, italic, also and gone"},
+		{"words with underscores", "snake_case_name", "snake_case_name"},
+		{"heading and quote", "# Title\n> quoted", "Title\nquoted"},
+		{"bullets", "* one\n+ two\n- three", "- one\n- two\n- three"},
+		{"links and images", "see [the docs](https://example.com) ![a cat](https://example.com/cat.png)", "see the docs a cat"},
+		{"formulas", "Energy: $E = mc^2$, $$\\sum x$$", "Energy: E = mc^2, \\sum x"},
+		{"code", "run `go test` now", "run go test now"},
+		{"escapes", "\\*not emphasis\\* and \\<b>", "*not emphasis* and <b>"},
+		{"stars in a formula", "$a*b*c$", "a*b*c"},
+		{"formula in a link", "[$x^2$](https://example.com)", "x^2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, services.PlainText(models.FormatMarkdown, tt.in))
+		})
+	}
+
+	assert.Equal(t, "**as is**", services.PlainText(models.FormatPlain, "**as is**"))
+}
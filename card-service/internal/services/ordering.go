@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+)
+
+// Reorder changes the order of cards in a set. It either gives the full new
+// order in CardIDs or moves the single card CardID right before BeforeID,
+// right after AfterID, or to the zero-based Position.
+type Reorder struct {
+	CardIDs  []string
+	CardID   string
+	BeforeID string
+	AfterID  string
+	Position *int32
+}
+
+// PlanReorder applies the reorder to the current order of the set and returns
+// the new one. It fails with ErrInvalidParam if the reorder is ambiguous, a
+// full order doesn't list every card exactly once, or the cards it refers to
+// are not in the set. A position past the end moves the card to the end.
+func PlanReorder(order []string, r Reorder) ([]string, error) {
+	if len(r.CardIDs) > 0 {
+		if r.CardID != "" || !sameCards(order, r.CardIDs) {
+			return nil, ErrInvalidParam
+		}
+		return r.CardIDs, nil
+	}
+
+	targets := 0
+	for _, given := range []bool{r.BeforeID != "", r.AfterID != "", r.Position != nil} {
+		if given {
+			targets++
+		}
+	}
+	if r.CardID == "" || targets != 1 || !slices.Contains(order, r.CardID) {
+		return nil, ErrInvalidParam
+	}
+
+	rest := slices.DeleteFunc(slices.Clone(order), func(id string) bool { return id == r.CardID })
+	var index int
+	switch {
+	case r.Position != nil:
+		if *r.Position < 0 {
+			return nil, ErrInvalidParam
+		}
+		index = min(int(*r.Position), len(rest))
+	case r.BeforeID != "":
+		index = slices.Index(rest, r.BeforeID)
+	default:
+		index = slices.Index(rest, r.AfterID)
+		if index >= 0 {
+			index++
+		}
+	}
+	if index < 0 {
+		return nil, ErrInvalidParam
+	}
+
+	return slices.Insert(rest, index, r.CardID), nil
+}
+
+// sameCards tells whether ids lists exactly the cards in order, each once.
+func sameCards(order, ids []string) bool {
+	if len(order) != len(ids) {
+		return false
+	}
+	sorted := slices.Clone(ids)
+	slices.Sort(sorted)
+	if len(slices.Compact(sorted)) != len(ids) {
+		return false
+	}
+	for _, id := range order {
+		if _, found := slices.BinarySearch(sorted, id); !found {
+			return false
+		}
+	}
+	return true
+}
+
+// ReorderCards rearranges the cards of a set the user may edit and returns
+// the ids of its cards in the new order.
+func (s *CardService) ReorderCards(ctx context.Context, setID, userID string, r Reorder) ([]string, error) {
+	if _, err := s.authz.Authorize(ctx, setID, userID, ActionEditCards); err != nil {
+		return nil, err
+	}
+
+	order, err := s.cardStorage.GetOrder(ctx, setID)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err = PlanReorder(order, r)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.cardStorage.SetOrder(ctx, setID, order); err != nil {
+		if err == sql.ErrNoRows {
+			// Cards were added or deleted in the meantime
+			return nil, ErrInvalidParam
+		}
+		return nil, err
+	}
+
+	return order, nil
+}
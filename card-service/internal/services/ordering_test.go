@@ -0,0 +1,61 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanReorder(t *testing.T) {
+	order := []string{"a", "b", "c", "d"}
+	position := func(p int32) *int32 { return &p }
+
+	tests := []struct {
+		name    string
+		reorder services.Reorder
+		want    []string
+	}{
+		{"before a neighbour", services.Reorder{CardID: "d", BeforeID: "b"}, []string{"a", "d", "b", "c"}},
+		{"after a neighbour", services.Reorder{CardID: "a", AfterID: "c"}, []string{"b", "c", "a", "d"}},
+		{"after the last card", services.Reorder{CardID: "b", AfterID: "d"}, []string{"a", "c", "d", "b"}},
+		{"to the front", services.Reorder{CardID: "c", Position: position(0)}, []string{"c", "a", "b", "d"}},
+		{"past the end", services.Reorder{CardID: "a", Position: position(10)}, []string{"b", "c", "d", "a"}},
+		{"full order", services.Reorder{CardIDs: []string{"d", "c", "b", "a"}}, []string{"d", "c", "b", "a"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := services.PlanReorder(order, tt.reorder)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+	assert.Equal(t, []string{"a", "b", "c", "d"}, order, "the current order is left as it is")
+}
+
+func TestPlanReorderInvalid(t *testing.T) {
+	order := []string{"a", "b", "c"}
+	position := int32(1)
+
+	tests := []struct {
+		name    string
+		reorder services.Reorder
+	}{
+		{"nothing to do", services.Reorder{}},
+		{"no target", services.Reorder{CardID: "a"}},
+		{"two targets", services.Reorder{CardID: "a", BeforeID: "c", Position: &position}},
+		{"card not in the set", services.Reorder{CardID: "x", BeforeID: "a"}},
+		{"neighbour not in the set", services.Reorder{CardID: "a", AfterID: "x"}},
+		{"next to itself", services.Reorder{CardID: "a", BeforeID: "a"}},
+		{"card missing from full order", services.Reorder{CardIDs: []string{"a", "b"}}},
+		{"card repeated in full order", services.Reorder{CardIDs: []string{"a", "b", "b"}}},
+		{"unknown card in full order", services.Reorder{CardIDs: []string{"a", "b", "x"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := services.PlanReorder(order, tt.reorder)
+			assert.Equal(t, services.ErrInvalidParam, err)
+		})
+	}
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"context"
+	"database/sql"
 	"math/rand"
 	"time"
 
@@ -11,15 +12,41 @@ import (
 )
 
 type QuizService struct {
-	cardStorage storage.CardStorage
+	setStorage     storage.CardSetStorage
+	cardStorage    storage.CardStorage
+	sessionStorage storage.QuizSessionStorage
+	learning       *LearningService
+	authz          *Authorizer
+	sessionTTL     time.Duration
 }
 
-func NewQuizService(cardStorage storage.CardStorage) *QuizService {
-	return &QuizService{cardStorage: cardStorage}
+// NewQuizService creates the service; sessionTTL is how long a quiz stays
+// available after the last activity. Answers are graded through learning so
+// they update card progress like study sessions do.
+func NewQuizService(setStorage storage.CardSetStorage, cardStorage storage.CardStorage, sessionStorage storage.QuizSessionStorage, learning *LearningService, authz *Authorizer, sessionTTL time.Duration) *QuizService {
+	return &QuizService{setStorage: setStorage, cardStorage: cardStorage, sessionStorage: sessionStorage, learning: learning, authz: authz, sessionTTL: sessionTTL}
 }
 
-func (s *QuizService) StartQuizSession(ctx context.Context, setID, userID string, questionCount int) (*models.QuizSession, error) {
-	cards, err := s.cardStorage.GetCardsForQuiz(ctx, setID, int32(questionCount))
+// StartQuizSession builds a quiz over random cards of the set. Question types are
+// mixed from questionTypes; an empty list means multiple choice only.
+func (s *QuizService) StartQuizSession(ctx context.Context, setID, userID string, questionCount int, questionTypes []models.QuizQuestionType) (*models.QuizSession, error) {
+	if len(questionTypes) == 0 {
+		questionTypes = []models.QuizQuestionType{models.QuestionMultipleChoice}
+	}
+	for _, questionType := range questionTypes {
+		switch questionType {
+		case models.QuestionMultipleChoice, models.QuestionReverse, models.QuestionTyped, models.QuestionTrueFalse:
+		default:
+			return nil, ErrInvalidParam
+		}
+	}
+
+	if _, err := s.authz.Authorize(ctx, setID, userID, ActionView); err != nil {
+		return nil, err
+	}
+
+	// Each review item of a card is a question of its own
+	cards, err := s.cardStorage.GetCardsForQuiz(ctx, setID, userID, int32(questionCount))
 	if err != nil {
 		return nil, err
 	}
@@ -28,96 +55,283 @@ func (s *QuizService) StartQuizSession(ctx context.Context, setID, userID string
 		return nil, ErrNotFound
 	}
 
+	// Wrong options come from the whole set, not only from the cards picked for this quiz
+	setCards, err := s.cardStorage.GetAllBySetID(ctx, setID)
+	if err != nil {
+		return nil, err
+	}
+	pool := answerPool{}
+	for _, item := range quizItems(setCards) {
+		pool.backs = append(pool.backs, item.Back)
+		pool.fronts = append(pool.fronts, item.Front)
+	}
+
+	for i := range cards {
+		cards[i].Front, cards[i].Back = QuizItem(&cards[i], cards[i].Item)
+	}
+
+	rng := rand.New(rand.NewSource(time.Now().UnixNano()))
+	// Every requested type gets an equal share, in random order
+	types := make([]models.QuizQuestionType, len(cards))
+	for i := range types {
+		types[i] = questionTypes[i%len(questionTypes)]
+	}
+	rng.Shuffle(len(types), func(i, j int) { types[i], types[j] = types[j], types[i] })
+
 	questions := make([]models.QuizQuestion, 0, len(cards))
-	for _, card := range cards {
-		options, correctIndex := s.generateOptions(card, cards)
-		questions = append(questions, models.QuizQuestion{
-			CardID:       card.ID,
-			Front:        card.Front,
-			Back:         card.Back,
-			Options:      options,
-			CorrectIndex: correctIndex,
-		})
+	for i, card := range cards {
+		questions = append(questions, s.buildQuestion(card, types[i], pool, rng))
 	}
 
+	now := time.Now()
 	session := &models.QuizSession{
 		ID:            uuid.New().String(),
 		SetID:         setID,
 		UserID:        userID,
 		QuestionCount: len(questions),
 		Questions:     questions,
-		CreatedAt:     time.Now(),
+		Answers:       []models.QuizAnswerResult{},
+		CreatedAt:     now,
+		ExpiresAt:     now.Add(s.sessionTTL),
+	}
+
+	if err := s.sessionStorage.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// GetSession returns an active or finished quiz with the answers given so far, so it can be resumed.
+func (s *QuizService) GetSession(ctx context.Context, sessionID, userID string) (*models.QuizSession, error) {
+	session, err := s.sessionStorage.GetByID(ctx, sessionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if session.UserID != userID {
+		return nil, ErrForbidden
 	}
 
 	return session, nil
 }
 
-func (s *QuizService) generateOptions(correctCard models.Card, allCards []models.Card) ([]models.QuizOption, int) {
-	options := make([]models.QuizOption, 0, 4)
-	correctIndex := rand.Intn(4)
-
-	wrongAnswers := s.getWrongAnswers(correctCard, allCards, 3)
-
-	for i := 0; i < 4; i++ {
-		if i == correctIndex {
-			options = append(options, models.QuizOption{
-				ID:        uuid.New().String(),
-				Text:      correctCard.Back,
-				IsCorrect: true,
-			})
-		} else {
-			if len(wrongAnswers) > 0 {
-				wrong := wrongAnswers[0]
-				wrongAnswers = wrongAnswers[1:]
-				options = append(options, models.QuizOption{
-					ID:        uuid.New().String(),
-					Text:      wrong.Back,
-					IsCorrect: false,
-				})
-			} else {
-				options = append(options, models.QuizOption{
-					ID:        uuid.New().String(),
-					Text:      "Неверный вариант",
-					IsCorrect: false,
-				})
+// quizItems turns cards into one entry per review item, with the item's
+// sides as front and back.
+func quizItems(cards []models.Card) []models.Card {
+	items := make([]models.Card, 0, len(cards))
+	for _, card := range cards {
+		for _, item := range CardItems(&card) {
+			entry := card
+			entry.Item = item
+			entry.Front, entry.Back = QuizItem(&card, item)
+			items = append(items, entry)
+		}
+	}
+	return items
+}
+
+// answerPool holds both sides of every card in the set, the source of wrong options.
+type answerPool struct {
+	backs  []string
+	fronts []string
+}
+
+func (s *QuizService) buildQuestion(card models.Card, questionType models.QuizQuestionType, pool answerPool, rng *rand.Rand) models.QuizQuestion {
+	// Picking the cloze text back for an answer asks nothing useful
+	if questionType == models.QuestionReverse && cardType(&card) == models.CardCloze {
+		questionType = models.QuestionMultipleChoice
+	}
+
+	question := models.QuizQuestion{
+		CardID:       card.ID,
+		Item:         card.Item,
+		Type:         questionType,
+		Prompt:       card.Front,
+		Front:        card.Front,
+		Back:         card.Back,
+		CorrectIndex: -1,
+	}
+
+	switch questionType {
+	case models.QuestionReverse:
+		question.Prompt = card.Back
+		question.Options, question.CorrectIndex = s.generateOptions(card.Front, pool.fronts, rng)
+	case models.QuestionTyped:
+	case models.QuestionTrueFalse:
+		question.Statement = card.Back
+		question.StatementIsTrue = true
+		if rng.Intn(2) == 0 {
+			if swapped := SelectDistractors(card.Back, pool.backs, 1, rng); len(swapped) > 0 {
+				question.Statement = swapped[0]
+				question.StatementIsTrue = false
 			}
 		}
+	default:
+		question.Options, question.CorrectIndex = s.generateOptions(card.Back, pool.backs, rng)
+	}
+
+	return question
+}
+
+// quizOptionCount is the number of options of a multiple-choice question, including the correct one.
+const quizOptionCount = 4
+
+func (s *QuizService) generateOptions(correct string, candidates []string, rng *rand.Rand) ([]models.QuizOption, int) {
+	wrongAnswers := SelectDistractors(correct, candidates, quizOptionCount-1, rng)
+
+	options := make([]models.QuizOption, 0, len(wrongAnswers)+1)
+	for _, wrong := range wrongAnswers {
+		options = append(options, models.QuizOption{
+			ID:        uuid.New().String(),
+			Text:      wrong,
+			IsCorrect: false,
+		})
+	}
+
+	correctIndex := rng.Intn(len(options) + 1)
+	options = append(options, models.QuizOption{})
+	copy(options[correctIndex+1:], options[correctIndex:])
+	options[correctIndex] = models.QuizOption{
+		ID:        uuid.New().String(),
+		Text:      correct,
+		IsCorrect: true,
 	}
 
 	return options, correctIndex
 }
 
-func (s *QuizService) getWrongAnswers(correctCard models.Card, allCards []models.Card, count int) []models.Card {
-	wrong := make([]models.Card, 0, count)
-	for _, card := range allCards {
-		if card.ID != correctCard.ID && len(wrong) < count {
-			wrong = append(wrong, card)
+func (s *QuizService) SubmitAnswer(ctx context.Context, sessionID, userID string, req models.QuizAnswerRequest) (*models.QuizAnswerResult, error) {
+	session, err := s.GetSession(ctx, sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.FinishedAt != nil {
+		return nil, ErrSessionFinished
+	}
+
+	if req.QuestionIndex < 0 || req.QuestionIndex >= len(session.Questions) {
+		return nil, ErrInvalidParam
+	}
+
+	question := session.Questions[req.QuestionIndex]
+	result := answerTo(session, req.QuestionIndex)
+	if result == nil {
+		result, err = gradeAnswer(question, req)
+		if err != nil {
+			return nil, err
+		}
+
+		inserted, err := s.sessionStorage.AddAnswer(ctx, sessionID, result, time.Now().Add(s.sessionTTL))
+		if err != nil {
+			return nil, err
+		}
+		if !inserted {
+			return nil, ErrAlreadyAnswered
 		}
 	}
-	return wrong
-}
 
-func (s *QuizService) SubmitAnswer(ctx context.Context, session *models.QuizSession, questionIndex, selectedIndex int) (*models.QuizAnswerResult, error) {
-	if questionIndex < 0 || questionIndex >= len(session.Questions) {
-		return nil, ErrNotFound
+	// An answer stored before its review failed is reviewed on retry; one
+	// reviewed already comes back as ErrAlreadyAnswered. The card may have been
+	// deleted since the quiz started, the answer still counts for the quiz
+	_, err = s.learning.ReviewCard(ctx, userID, models.ReviewFromQuiz, sessionID, question.CardID, question.Item, models.RatingFromBinary(result.IsCorrect), result.TimeSpentMs)
+	if err != nil && err != ErrNotFound {
+		return nil, err
 	}
 
-	question := session.Questions[questionIndex]
-	isCorrect := selectedIndex == question.CorrectIndex
+	return result, nil
+}
+
+// answerTo returns the stored answer to the question, nil if it has none yet.
+func answerTo(session *models.QuizSession, questionIndex int) *models.QuizAnswerResult {
+	for i := range session.Answers {
+		if session.Answers[i].QuestionIndex == questionIndex {
+			answer := session.Answers[i]
+			if !answer.IsCorrect {
+				answer.Explanation = session.Questions[questionIndex].Answer()
+			}
+			return &answer
+		}
+	}
+	return nil
+}
 
+func gradeAnswer(question models.QuizQuestion, req models.QuizAnswerRequest) (*models.QuizAnswerResult, error) {
 	result := &models.QuizAnswerResult{
-		QuestionIndex: questionIndex,
-		IsCorrect:     isCorrect,
+		QuestionIndex: req.QuestionIndex,
 		CorrectIndex:  question.CorrectIndex,
+		TimeSpentMs:   req.TimeSpentMs,
+	}
+
+	switch question.Type {
+	case models.QuestionTyped:
+		if req.TextAnswer == "" {
+			return nil, ErrInvalidParam
+		}
+		result.TextAnswer = req.TextAnswer
+		result.IsCorrect = GradeTypedAnswer(question.Back, req.TextAnswer)
+		result.CorrectAnswer = question.Back
+	case models.QuestionTrueFalse:
+		if req.BoolAnswer == nil {
+			return nil, ErrInvalidParam
+		}
+		result.BoolAnswer = req.BoolAnswer
+		result.IsCorrect = *req.BoolAnswer == question.StatementIsTrue
+	default:
+		if req.SelectedIndex < 0 || req.SelectedIndex >= len(question.Options) {
+			return nil, ErrInvalidParam
+		}
+		result.SelectedIndex = req.SelectedIndex
+		result.IsCorrect = req.SelectedIndex == question.CorrectIndex
 	}
 
-	if !isCorrect {
-		result.Explanation = question.Back
+	if !result.IsCorrect {
+		result.Explanation = question.Answer()
+	}
+	return result, nil
+}
+
+func (s *QuizService) FinishQuiz(ctx context.Context, sessionID, userID string) (*models.QuizResult, error) {
+	session, err := s.GetSession(ctx, sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var timeSpentMs int64
+	for _, answer := range session.Answers {
+		timeSpentMs += answer.TimeSpentMs
+	}
+
+	if err := s.sessionStorage.Finish(ctx, sessionID); err != nil {
+		return nil, err
+	}
+
+	result := s.CalculateQuizResult(session, session.Answers, timeSpentMs)
+	if err := s.sessionStorage.SaveResult(ctx, userID, result); err != nil {
+		return nil, err
 	}
 
 	return result, nil
 }
 
+// GetQuizHistory lists the user's finished quizzes on a set, newest first.
+func (s *QuizService) GetQuizHistory(ctx context.Context, setID, userID string, offset, limit int32) ([]models.QuizResult, error) {
+	if _, err := s.authz.Authorize(ctx, setID, userID, ActionView); err != nil {
+		return nil, err
+	}
+
+	return s.sessionStorage.GetResults(ctx, setID, userID, offset, limit)
+}
+
+// CleanupExpiredSessions removes quizzes that have been idle longer than the session TTL.
+func (s *QuizService) CleanupExpiredSessions(ctx context.Context) (int64, error) {
+	return s.sessionStorage.DeleteExpired(ctx)
+}
+
 func (s *QuizService) CalculateQuizResult(session *models.QuizSession, answers []models.QuizAnswerResult, timeSpentMs int64) *models.QuizResult {
 	correct := 0
 	for _, answer := range answers {
@@ -132,12 +346,19 @@ func (s *QuizService) CalculateQuizResult(session *models.QuizSession, answers [
 		score = float32(correct) / float32(total) * 100
 	}
 
+	finishedAt := time.Now()
+	if session.FinishedAt != nil {
+		finishedAt = *session.FinishedAt
+	}
+
 	return &models.QuizResult{
 		SessionID:        session.ID,
+		SetID:            session.SetID,
 		TotalQuestions:   total,
 		CorrectAnswers:   correct,
 		IncorrectAnswers: total - correct,
 		ScorePercentage:  score,
 		TimeSpentMs:      timeSpentMs,
+		FinishedAt:       finishedAt,
 	}
 }
@@ -1,7 +1,7 @@
 package services_test
 
 import (
-	"context"
+	"math/rand"
 	"testing"
 
 	"github.com/google/uuid"
@@ -29,3 +29,60 @@ func TestQuizService_GenerateOptions_Count(t *testing.T) {
 	assert.GreaterOrEqual(t, len(wrongCards), 3, "Нужно минимум 3 карточки для неправильных ответов")
 	assert.Equal(t, "Programming language", correctCard.Back)
 }
+
+func TestSelectDistractors_PrefersSameAnswerType(t *testing.T) {
+	pool := []string{"1812", "Наполеон", "Кутузов", "1941", "Бородино", "1799", "река Нева", "Пётр Первый"}
+
+	for seed := int64(0); seed < 20; seed++ {
+		distractors := services.SelectDistractors("1945", pool, 3, rand.New(rand.NewSource(seed)))
+
+		assert.ElementsMatch(t, []string{"1812", "1941", "1799"}, distractors)
+	}
+}
+
+func TestSelectDistractors_NoDuplicatesOrCorrectAnswer(t *testing.T) {
+	pool := []string{"Database", "database ", "Programming language", "", "  ", "Framework", "DATABASE"}
+
+	distractors := services.SelectDistractors("Programming language", pool, 3, rand.New(rand.NewSource(1)))
+
+	assert.ElementsMatch(t, []string{"Database", "Framework"}, distractors)
+}
+
+func TestSelectDistractors_Randomized(t *testing.T) {
+	pool := []string{"Database", "Framework", "Library", "Tool", "Compiler", "Debugger", "Linker", "Profiler"}
+
+	seen := map[string]struct{}{}
+	for seed := int64(0); seed < 50; seed++ {
+		for _, d := range services.SelectDistractors("Language", pool, 3, rand.New(rand.NewSource(seed))) {
+			seen[d] = struct{}{}
+		}
+	}
+
+	assert.Greater(t, len(seen), 3)
+}
+
+func TestGradeTypedAnswer(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected string
+		given    string
+		correct  bool
+	}{
+		{"Exact", "Programming language", "Programming language", true},
+		{"Case and whitespace", "Programming language", "  programming   LANGUAGE ", true},
+		{"Diacritics", "Café", "cafe", true},
+		{"Russian ё", "Ёлка", "елка", true},
+		{"Punctuation", "Hello, world!", "hello world", true},
+		{"One typo in short word", "house", "hause", true},
+		{"Two typos in long answer", "Programming language", "Programing langage", true},
+		{"Short answer must be exact", "cat", "car", false},
+		{"Too many typos", "house", "mouse pad", false},
+		{"Empty answer", "house", "   ", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.correct, services.GradeTypedAnswer(tt.expected, tt.given))
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/storage"
+)
+
+// ReviewService reports on the review log: how a user does on a card, and the
+// user's whole log for export.
+type ReviewService struct {
+	reviewStorage storage.ReviewStorage
+	authz         *Authorizer
+}
+
+func NewReviewService(reviewStorage storage.ReviewStorage, authz *Authorizer) *ReviewService {
+	return &ReviewService{reviewStorage: reviewStorage, authz: authz}
+}
+
+// GetCardStats sums up the user's reviews of a card the user may view.
+func (s *ReviewService) GetCardStats(ctx context.Context, cardID, userID string) (*models.CardReviewStats, error) {
+	card, _, err := s.authz.AuthorizeCard(ctx, cardID, userID, ActionView)
+	if err != nil {
+		return nil, err
+	}
+
+	totals, err := s.reviewStorage.GetTotalsByItem(ctx, userID, card.ID)
+	if err != nil {
+		return nil, err
+	}
+	return SummarizeReviews(card.ID, CardItems(card), totals), nil
+}
+
+// GetCardReviews lists the user's reviews of a card the user may view, newest first.
+func (s *ReviewService) GetCardReviews(ctx context.Context, cardID, userID string, offset, limit int32) ([]models.Review, error) {
+	if _, _, err := s.authz.AuthorizeCard(ctx, cardID, userID, ActionView); err != nil {
+		return nil, err
+	}
+
+	return s.reviewStorage.GetByCard(ctx, userID, cardID, offset, limit)
+}
+
+// reviewExportPageSize is how many reviews an export reads from the log at a time.
+const reviewExportPageSize = 1000
+
+// ReviewExport is the user's review log ready to be sent as a file. It is read
+// from the log a page at a time while being written, so the log is never held
+// in memory whole.
+type ReviewExport struct {
+	FileName    string
+	ContentType string
+	newWriter   func(w io.Writer) reviewsWriter
+	next        func(after models.ReviewCursor) ([]models.Review, error)
+	page        []models.Review
+}
+
+// reviewsWriter writes the reviews of an export in its format.
+type reviewsWriter interface {
+	Begin() error
+	Write(reviews []models.Review) error
+	End() error
+}
+
+// ExportReviews exports the user's whole review log as CSV or JSON. The first
+// page is read right away, so that the log failing shows before anything is sent.
+func (s *ReviewService) ExportReviews(ctx context.Context, userID string, format models.ExportFormat) (*ReviewExport, error) {
+	export := &ReviewExport{FileName: "reviews." + string(format)}
+	switch format {
+	case models.ExportFormatCSV:
+		export.ContentType = "text/csv; charset=utf-8"
+		export.newWriter = func(w io.Writer) reviewsWriter { return &csvReviewsWriter{w: csv.NewWriter(w)} }
+	case models.ExportFormatJSON:
+		export.ContentType = "application/json"
+		export.newWriter = func(w io.Writer) reviewsWriter { return &jsonReviewsWriter{w: w} }
+	default:
+		return nil, ErrInvalidParam
+	}
+
+	export.next = func(after models.ReviewCursor) ([]models.Review, error) {
+		return s.reviewStorage.GetByUser(ctx, userID, after, reviewExportPageSize)
+	}
+	var err error
+	export.page, err = export.next(models.ReviewCursor{})
+	if err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// Write writes the file out, reading the rest of the log as it goes.
+func (e *ReviewExport) Write(w io.Writer) error {
+	out := e.newWriter(w)
+	if err := out.Begin(); err != nil {
+		return err
+	}
+
+	page := e.page
+	for len(page) > 0 {
+		if err := out.Write(page); err != nil {
+			return err
+		}
+		if len(page) < reviewExportPageSize {
+			break
+		}
+
+		last := page[len(page)-1]
+		var err error
+		page, err = e.next(models.ReviewCursor{ReviewedAt: last.ReviewedAt, ID: last.ID})
+		if err != nil {
+			return err
+		}
+	}
+	return out.End()
+}
+
+// csvReviewsWriter writes one row per review.
+type csvReviewsWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvReviewsWriter) Begin() error {
+	header := []string{"reviewed_at", "card_id", "item", "set_id", "source", "session_id", "rating", "status_before", "status_after",
+		"previous_interval_days", "interval_days", "scheduler", "response_time_ms"}
+	return c.w.Write(header)
+}
+
+func (c *csvReviewsWriter) Write(reviews []models.Review) error {
+	for _, r := range reviews {
+		row := []string{formatTime(&r.ReviewedAt), r.CardID, strconv.Itoa(int(r.Item)), r.SetID, string(r.Source), optional(r.SessionID),
+			strconv.Itoa(int(r.Rating)), string(r.StatusBefore), string(r.StatusAfter), strconv.Itoa(int(r.PreviousIntervalDays)),
+			strconv.Itoa(int(r.IntervalDays)), string(r.Scheduler), strconv.FormatInt(r.ResponseTimeMs, 10)}
+		if err := c.w.Write(row); err != nil {
+			return err
+		}
+	}
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvReviewsWriter) End() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// jsonReviewsWriter writes an object with the export time and the reviews,
+// indented as json.MarshalIndent would.
+type jsonReviewsWriter struct {
+	w       io.Writer
+	written int
+}
+
+func (j *jsonReviewsWriter) Begin() error {
+	exportedAt, err := json.Marshal(time.Now().UTC())
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(j.w, "{\n  \"exported_at\": %s,\n  \"reviews\": [", exportedAt)
+	return err
+}
+
+func (j *jsonReviewsWriter) Write(reviews []models.Review) error {
+	var buf bytes.Buffer
+	for _, r := range reviews {
+		if j.written > 0 {
+			buf.WriteByte(',')
+		}
+		data, err := json.MarshalIndent(r, "    ", "  ")
+		if err != nil {
+			return err
+		}
+		buf.WriteString("\n    ")
+		buf.Write(data)
+		j.written++
+	}
+	_, err := j.w.Write(buf.Bytes())
+	return err
+}
+
+func (j *jsonReviewsWriter) End() error {
+	end := "\n  ]\n}"
+	if j.written == 0 {
+		end = "]\n}"
+	}
+	_, err := io.WriteString(j.w, end)
+	return err
+}
+
+// SummarizeReviews builds the statistics of a card out of the review totals of
+// its items. The breakdown has the card's items and any other item reviewed
+// before the card was edited, in order.
+func SummarizeReviews(cardID string, items []int16, totals map[int16]models.ReviewTotals) *models.CardReviewStats {
+	items = slices.Clone(items)
+	for item := range totals {
+		if !slices.Contains(items, item) {
+			items = append(items, item)
+		}
+	}
+	slices.Sort(items)
+
+	var all models.ReviewTotals
+	stats := &models.CardReviewStats{CardID: cardID, Items: make([]models.ItemReviewStats, 0, len(items))}
+	for _, item := range items {
+		t := totals[item]
+		stats.Items = append(stats.Items, models.ItemReviewStats{Item: item, ReviewStats: reviewStats(t)})
+
+		all.Reviews += t.Reviews
+		all.Correct += t.Correct
+		all.Lapses += t.Lapses
+		all.Timed += t.Timed
+		all.ResponseTimeMs += t.ResponseTimeMs
+		if t.FirstReviewedAt != nil && (all.FirstReviewedAt == nil || t.FirstReviewedAt.Before(*all.FirstReviewedAt)) {
+			all.FirstReviewedAt = t.FirstReviewedAt
+		}
+		if t.LastReviewedAt != nil && (all.LastReviewedAt == nil || t.LastReviewedAt.After(*all.LastReviewedAt)) {
+			all.LastReviewedAt = t.LastReviewedAt
+		}
+	}
+	stats.ReviewStats = reviewStats(all)
+	return stats
+}
+
+// reviewStats turns review totals into statistics; accuracy is a percentage
+// and the average response time leaves out reviews without one.
+func reviewStats(t models.ReviewTotals) models.ReviewStats {
+	stats := models.ReviewStats{
+		Reviews:         t.Reviews,
+		CorrectReviews:  t.Correct,
+		Lapses:          t.Lapses,
+		FirstReviewedAt: t.FirstReviewedAt,
+		LastReviewedAt:  t.LastReviewedAt,
+	}
+	if t.Reviews > 0 {
+		stats.Accuracy = float32(t.Correct) / float32(t.Reviews) * 100
+	}
+	if t.Timed > 0 {
+		stats.AverageResponseTimeMs = t.ResponseTimeMs / int64(t.Timed)
+	}
+	return stats
+}
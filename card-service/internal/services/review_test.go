@@ -0,0 +1,122 @@
+package services_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummarizeReviews(t *testing.T) {
+	day1 := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	day3 := day1.AddDate(0, 0, 2)
+
+	stats := services.SummarizeReviews("card", []int16{1, 2}, map[int16]models.ReviewTotals{
+		1: {Reviews: 3, Correct: 2, Lapses: 1, Timed: 2, ResponseTimeMs: 3000, FirstReviewedAt: &day2, LastReviewedAt: &day3},
+		3: {Reviews: 1, Correct: 1, Timed: 1, ResponseTimeMs: 1500, FirstReviewedAt: &day1, LastReviewedAt: &day1},
+	})
+
+	assert.Equal(t, "card", stats.CardID)
+	assert.Equal(t, int32(4), stats.Reviews)
+	assert.Equal(t, int32(3), stats.CorrectReviews)
+	assert.InDelta(t, 75, stats.Accuracy, 0.01)
+	assert.Equal(t, int64(1500), stats.AverageResponseTimeMs, "reviews without a response time are left out")
+	assert.Equal(t, int32(1), stats.Lapses)
+	assert.Equal(t, &day1, stats.FirstReviewedAt)
+	assert.Equal(t, &day3, stats.LastReviewedAt)
+
+	require.Len(t, stats.Items, 3, "items of the card and items reviewed before an edit")
+	assert.Equal(t, int16(1), stats.Items[0].Item)
+	assert.InDelta(t, 66.67, stats.Items[0].Accuracy, 0.01)
+	assert.Equal(t, int16(2), stats.Items[1].Item)
+	assert.Equal(t, int32(0), stats.Items[1].Reviews)
+	assert.Zero(t, stats.Items[1].Accuracy)
+	assert.Equal(t, int16(3), stats.Items[2].Item)
+}
+
+// reviewLog serves a review log from memory, a page at a time.
+type reviewLog struct {
+	reviews []models.Review
+	pages   int
+}
+
+func (l *reviewLog) GetByCard(context.Context, string, string, int32, int32) ([]models.Review, error) {
+	return nil, nil
+}
+
+func (l *reviewLog) GetTotalsByItem(context.Context, string, string) (map[int16]models.ReviewTotals, error) {
+	return nil, nil
+}
+
+func (l *reviewLog) GetByUser(_ context.Context, _ string, after models.ReviewCursor, limit int32) ([]models.Review, error) {
+	l.pages++
+	page := []models.Review{}
+	for _, r := range l.reviews {
+		if r.ID > after.ID && len(page) < int(limit) {
+			page = append(page, r)
+		}
+	}
+	return page, nil
+}
+
+func TestExportReviews_Pages(t *testing.T) {
+	start := time.Date(2026, 3, 1, 10, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+	session := "3f0a7a4e-2b8e-4d6c-9a57-0c1b2e3d4f50"
+	log := &reviewLog{reviews: []models.Review{{
+		ID:                   1,
+		CardID:               "card",
+		Item:                 1,
+		SetID:                "set",
+		Source:               models.ReviewFromStudy,
+		SessionID:            &session,
+		Rating:               models.RatingGood,
+		StatusBefore:         models.StatusLearning,
+		StatusAfter:          models.StatusReviewing,
+		PreviousIntervalDays: 1,
+		IntervalDays:         3,
+		Scheduler:            models.SchedulerSM2,
+		ResponseTimeMs:       2100,
+		ReviewedAt:           start,
+	}}}
+	for i := 1; i < 2500; i++ {
+		log.reviews = append(log.reviews, models.Review{ID: int64(i + 1), CardID: "card", Rating: models.RatingGood, ReviewedAt: start.Add(time.Duration(i) * time.Minute)})
+	}
+	service := services.NewReviewService(log, nil)
+
+	export, err := service.ExportReviews(context.Background(), "user", models.ExportFormatCSV)
+	require.NoError(t, err)
+	var csv bytes.Buffer
+	require.NoError(t, export.Write(&csv))
+	lines := strings.Split(strings.TrimSpace(csv.String()), "\n")
+	assert.Len(t, lines, 2501)
+	assert.Equal(t, 3, log.pages)
+	assert.Equal(t, "reviewed_at,card_id,item,set_id,source,session_id,rating,status_before,status_after,previous_interval_days,interval_days,scheduler,response_time_ms", lines[0])
+	assert.Equal(t, "2026-03-01T07:00:00Z,card,1,set,study,"+session+",3,learning,reviewing,1,3,sm2,2100", lines[1])
+
+	export, err = service.ExportReviews(context.Background(), "user", models.ExportFormatJSON)
+	require.NoError(t, err)
+	var data bytes.Buffer
+	require.NoError(t, export.Write(&data))
+	var parsed struct {
+		Reviews []models.Review `json:"reviews"`
+	}
+	require.NoError(t, json.Unmarshal(data.Bytes(), &parsed))
+	require.Len(t, parsed.Reviews, 2500)
+	assert.Equal(t, int64(2500), parsed.Reviews[2499].ID)
+
+	log.reviews = nil
+	export, err = service.ExportReviews(context.Background(), "user", models.ExportFormatJSON)
+	require.NoError(t, err)
+	data.Reset()
+	require.NoError(t, export.Write(&data))
+	require.NoError(t, json.Unmarshal(data.Bytes(), &parsed))
+	assert.Empty(t, parsed.Reviews)
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"slices"
+	"time"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/storage"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/userclient"
+)
+
+// RevisionService shows the edit history of cards and reverts cards and sets
+// to earlier versions. Reverts are changes too and show up in the history.
+type RevisionService struct {
+	cardStorage     storage.CardStorage
+	revisionStorage storage.RevisionStorage
+	authz           *Authorizer
+	userClient      *userclient.Client
+}
+
+func NewRevisionService(cardStorage storage.CardStorage, revisionStorage storage.RevisionStorage, authz *Authorizer, userClient *userclient.Client) *RevisionService {
+	return &RevisionService{cardStorage: cardStorage, revisionStorage: revisionStorage, authz: authz, userClient: userClient}
+}
+
+// ChangedFields names the content fields that differ between two versions of
+// a card. There are none when the card has no previous version. A side whose
+// plain text stayed the same counts as changed if its rich text did not.
+func ChangedFields(previous, current *models.CardContent) []string {
+	if previous == nil {
+		return nil
+	}
+
+	before, after := richOrPlain(previous), richOrPlain(current)
+	fields := []struct {
+		name    string
+		changed bool
+	}{
+		{"front", previous.Front != current.Front || before.Front != after.Front},
+		{"back", previous.Back != current.Back || before.Back != after.Back},
+		{"image_url", !sameText(previous.ImageURL, current.ImageURL)},
+		{"audio_url", !sameText(previous.AudioURL, current.AudioURL)},
+		{"type", before.Type != after.Type},
+		{"format", before.Format != after.Format},
+		{"front_images", !slices.Equal(before.FrontImages, after.FrontImages)},
+		{"back_images", !slices.Equal(before.BackImages, after.BackImages)},
+		{"notes", !sameText(before.Notes, after.Notes)},
+		{"hint", !sameText(before.Hint, after.Hint)},
+		{"example", !sameText(before.Example, after.Example)},
+	}
+	var changed []string
+	for _, field := range fields {
+		if field.changed {
+			changed = append(changed, field.name)
+		}
+	}
+	return changed
+}
+
+// richOrPlain is the rich content of a card, or its plain text as such.
+func richOrPlain(content *models.CardContent) models.RichContent {
+	if content.Rich != nil {
+		return *content.Rich
+	}
+	return models.RichContent{Front: content.Front, Back: content.Back}
+}
+
+func (s *RevisionService) describe(ctx context.Context, revisions []models.CardRevision) []models.CardRevision {
+	users := newAuthorCache(s.userClient)
+	for i := range revisions {
+		rev := &revisions[i]
+		rev.Changed = ChangedFields(rev.Previous, &rev.CardContent)
+		if rev.EditorID != nil {
+			rev.Editor = users.get(ctx, *rev.EditorID)
+		}
+	}
+	return revisions
+}
+
+// GetCardRevisions lists the history of a card the user may view, newest first.
+func (s *RevisionService) GetCardRevisions(ctx context.Context, cardID, userID string, offset, limit int32) ([]models.CardRevision, error) {
+	if _, _, err := s.authz.AuthorizeCard(ctx, cardID, userID, ActionView); err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.revisionStorage.GetByCard(ctx, cardID, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.describe(ctx, revisions), nil
+}
+
+// GetSetRevisions lists the changes made to the cards of a set the user may view, newest first.
+func (s *RevisionService) GetSetRevisions(ctx context.Context, setID, userID string, offset, limit int32) ([]models.CardRevision, error) {
+	if _, err := s.authz.Authorize(ctx, setID, userID, ActionView); err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.revisionStorage.GetBySet(ctx, setID, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.describe(ctx, revisions), nil
+}
+
+// RevertCard gives a card the content it had in one of its revisions.
+func (s *RevisionService) RevertCard(ctx context.Context, cardID string, revisionID int64, userID string) (*models.Card, error) {
+	card, _, err := s.authz.AuthorizeCard(ctx, cardID, userID, ActionEditCards)
+	if err != nil {
+		return nil, err
+	}
+
+	rev, err := s.revisionStorage.GetByID(ctx, revisionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if rev.CardID != card.ID {
+		return nil, ErrNotFound
+	}
+
+	setContent(card, &rev.CardContent)
+	if err := s.cardStorage.Update(ctx, card, userID); err != nil {
+		return nil, err
+	}
+
+	return card, nil
+}
+
+// RevertSet brings the cards of a set the user may edit back to how they were
+// at the given time in the past.
+func (s *RevisionService) RevertSet(ctx context.Context, setID, userID string, at time.Time) (*models.SetRevertResult, error) {
+	if at.After(time.Now()) {
+		return nil, ErrInvalidParam
+	}
+
+	if _, err := s.authz.Authorize(ctx, setID, userID, ActionEditCards); err != nil {
+		return nil, err
+	}
+
+	return s.revisionStorage.RevertSet(ctx, setID, at, userID)
+}
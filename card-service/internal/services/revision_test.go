@@ -0,0 +1,35 @@
+package services_test
+
+import (
+	"testing"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChangedFields(t *testing.T) {
+	image := "https://cdn.example.com/cat.png"
+	otherImage := "https://cdn.example.com/dog.png"
+	card := models.CardContent{Front: "cat", Back: "кошка", ImageURL: &image}
+
+	tests := []struct {
+		name     string
+		previous *models.CardContent
+		want     []string
+	}{
+		{"created", nil, nil},
+		{"unchanged", &models.CardContent{Front: "cat", Back: "кошка", ImageURL: &image}, nil},
+		{"text", &models.CardContent{Front: "kat", Back: "кот", ImageURL: &image}, []string{"front", "back"}},
+		{"image added", &models.CardContent{Front: "cat", Back: "кошка"}, []string{"image_url"}},
+		{"image replaced", &models.CardContent{Front: "cat", Back: "кошка", ImageURL: &otherImage}, []string{"image_url"}},
+		{"audio removed", &models.CardContent{Front: "cat", Back: "кошка", ImageURL: &image, AudioURL: &image}, []string{"audio_url"}},
+		{"made rich", &models.CardContent{Front: "cat", Back: "кошка", ImageURL: &image, Rich: &models.RichContent{Format: models.FormatMarkdown, Front: "**cat**", Back: "кошка", FrontImages: []string{image}}}, []string{"front", "format", "front_images"}},
+		{"made reversible", &models.CardContent{Front: "cat", Back: "кошка", ImageURL: &image, Rich: &models.RichContent{Type: models.CardBasicReverse, Items: []int16{0, 1}, Front: "cat", Back: "кошка"}}, []string{"type"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, services.ChangedFields(tt.previous, &card))
+		})
+	}
+}
@@ -4,6 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"html"
+	"log"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,35 +17,59 @@ import (
 )
 
 var (
-	ErrNotFound     = errors.New("not found")
-	ErrForbidden    = errors.New("forbidden")
-	ErrInvalidParam = errors.New("invalid parameter")
+	ErrNotFound         = errors.New("not found")
+	ErrForbidden        = errors.New("forbidden")
+	ErrInvalidParam     = errors.New("invalid parameter")
+	ErrAlreadyAnswered  = errors.New("already answered")
+	ErrSessionFinished  = errors.New("session finished")
+	ErrCardNotInSession = errors.New("card is not in the session")
 )
 
+// ViewOptions control how views of public sets are counted and ranked.
+type ViewOptions struct {
+	// DedupWindow is how long repeated views by the same user count once.
+	DedupWindow      time.Duration
+	TrendingWindow   time.Duration
+	TrendingHalfLife time.Duration
+}
+
 type CardSetService struct {
 	setStorage   storage.CardSetStorage
 	cardStorage  storage.CardStorage
 	statsStorage storage.StatisticsStorage
+	viewStorage  storage.SetViewStorage
+	authz        *Authorizer
 	userClient   *userclient.Client
+	views        ViewOptions
 }
 
-func NewCardSetService(setStorage storage.CardSetStorage, cardStorage storage.CardStorage, statsStorage storage.StatisticsStorage, userClient *userclient.Client) *CardSetService {
+func NewCardSetService(setStorage storage.CardSetStorage, cardStorage storage.CardStorage, statsStorage storage.StatisticsStorage, viewStorage storage.SetViewStorage, authz *Authorizer, userClient *userclient.Client, views ViewOptions) *CardSetService {
 	return &CardSetService{
 		setStorage:   setStorage,
 		cardStorage:  cardStorage,
 		statsStorage: statsStorage,
+		viewStorage:  viewStorage,
+		authz:        authz,
 		userClient:   userClient,
+		views:        views,
 	}
 }
 
-func (s *CardSetService) CreateCardSet(ctx context.Context, ownerID, name string, description *string, isPublic bool) (*models.CardSet, error) {
+func (s *CardSetService) CreateCardSet(ctx context.Context, ownerID, name string, description *string, isPublic bool, tags []string) (*models.CardSet, error) {
+	tags, err := NormalizeTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
 	set := &models.CardSet{
 		ID:          uuid.New().String(),
 		OwnerID:     ownerID,
 		Name:        name,
 		Description: description,
 		IsPublic:    isPublic,
+		Tags:        tags,
 		CreatedAt:   time.Now(),
+		Role:        models.SetRoleOwner,
 	}
 
 	if err := s.setStorage.Create(ctx, set); err != nil {
@@ -51,20 +79,34 @@ func (s *CardSetService) CreateCardSet(ctx context.Context, ownerID, name string
 	return set, nil
 }
 
+// GetCardSet returns a set the user may view. Opening someone else's public set
+// counts as a view of it.
 func (s *CardSetService) GetCardSet(ctx context.Context, id, userID string) (*models.CardSet, error) {
-	set, err := s.setStorage.GetByID(ctx, id)
+	set, err := s.getCardSet(ctx, id, userID)
 	if err != nil {
-		return nil, ErrNotFound
+		return nil, err
 	}
 
-	if set.OwnerID != userID && !set.IsPublic {
-		return nil, ErrForbidden
+	if set.IsPublic && set.Role != models.SetRoleOwner {
+		if _, err := s.viewStorage.RecordView(ctx, set.ID, userID, s.views.DedupWindow); err != nil {
+			log.Printf("Failed to record view of set %s: %v", set.ID, err)
+		}
+	}
+
+	return set, nil
+}
+
+func (s *CardSetService) getCardSet(ctx context.Context, id, userID string) (*models.CardSet, error) {
+	set, err := s.authz.Authorize(ctx, id, userID, ActionView)
+	if err != nil {
+		return nil, err
 	}
 
 	count, _ := s.cardStorage.GetCountBySet(ctx, id)
 	set.CardCount = count
 
-	stats, err := s.statsStorage.GetSetStatistics(ctx, id)
+	// Only the counts are used here, so the server's date does for the history.
+	stats, err := s.statsStorage.GetSetStatistics(ctx, id, userID, time.Now().Format(dateLayout))
 	if err == nil {
 		set.LearnedCount = stats.LearnedCards
 		if stats.TotalCards > 0 {
@@ -83,8 +125,9 @@ func (s *CardSetService) GetCardSet(ctx context.Context, id, userID string) (*mo
 	return set, nil
 }
 
-func (s *CardSetService) GetCardSets(ctx context.Context, ownerID string, offset, limit int32) ([]models.CardSet, error) {
-	sets, err := s.setStorage.GetByOwner(ctx, ownerID, offset, limit)
+// GetCardSets lists the user's sets, only those having all the given tags if any.
+func (s *CardSetService) GetCardSets(ctx context.Context, ownerID string, tags []string, offset, limit int32) ([]models.CardSet, error) {
+	sets, err := s.setStorage.GetByOwner(ctx, ownerID, tags, offset, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -92,6 +135,7 @@ func (s *CardSetService) GetCardSets(ctx context.Context, ownerID string, offset
 	for i := range sets {
 		count, _ := s.cardStorage.GetCountBySet(ctx, sets[i].ID)
 		sets[i].CardCount = count
+		sets[i].Role = models.SetRoleOwner
 
 		if ownerInfo, err := s.userClient.GetPublicProfile(ctx, sets[i].OwnerID); err == nil && ownerInfo != nil {
 			sets[i].Author = &models.AuthorInfo{
@@ -105,16 +149,22 @@ func (s *CardSetService) GetCardSets(ctx context.Context, ownerID string, offset
 	return sets, nil
 }
 
-func (s *CardSetService) UpdateCardSet(ctx context.Context, id, userID, name string, description *string, isPublic bool) (*models.CardSet, error) {
-	set, err := s.GetCardSet(ctx, id, userID)
+// UpdateCardSet replaces the set's details. Tags are kept as they are when nil.
+func (s *CardSetService) UpdateCardSet(ctx context.Context, id, userID, name string, description *string, isPublic bool, tags []string) (*models.CardSet, error) {
+	set, err := s.getCardSet(ctx, id, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	if set.OwnerID != userID {
+	if !Allowed(set.Role, set.IsPublic, ActionManage) {
 		return nil, ErrForbidden
 	}
 
+	if tags != nil {
+		if set.Tags, err = NormalizeTags(tags); err != nil {
+			return nil, err
+		}
+	}
 	set.Name = name
 	set.Description = description
 	set.IsPublic = isPublic
@@ -127,70 +177,163 @@ func (s *CardSetService) UpdateCardSet(ctx context.Context, id, userID, name str
 }
 
 func (s *CardSetService) DeleteCardSet(ctx context.Context, id, userID string) error {
-	set, err := s.GetCardSet(ctx, id, userID)
+	set, err := s.authz.Authorize(ctx, id, userID, ActionManage)
 	if err != nil {
 		return err
 	}
 
-	if set.OwnerID != userID {
-		return ErrForbidden
+	return s.setStorage.Delete(ctx, set.ID)
+}
+
+// PurgeCardSet removes a set for good instead of moving it to the trash. It is
+// meant for sets the user never saw, such as those left by a failed generation.
+func (s *CardSetService) PurgeCardSet(ctx context.Context, id, userID string) error {
+	set, err := s.authz.Authorize(ctx, id, userID, ActionManage)
+	if err != nil {
+		return err
 	}
 
-	return s.setStorage.Delete(ctx, id)
+	return s.setStorage.Purge(ctx, set.ID)
 }
 
-func (s *CardSetService) SearchPublicSets(ctx context.Context, query string, offset, limit int32) ([]models.CardSet, error) {
-	return s.setStorage.GetPublic(ctx, query, offset, limit)
+// GetUserTags returns the tags of the user's sets with how many sets carry each.
+func (s *CardSetService) GetUserTags(ctx context.Context, userID string) ([]models.TagCount, error) {
+	return s.setStorage.GetOwnerTags(ctx, userID)
 }
 
-func (s *CardSetService) CloneSet(ctx context.Context, setID, userID string) (*models.CardSet, error) {
-	originalSet, err := s.setStorage.GetByID(ctx, setID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, ErrNotFound
-		}
-		return nil, err
+// SuggestTags completes a tag from those used on public sets.
+func (s *CardSetService) SuggestTags(ctx context.Context, prefix string, limit int32) ([]models.TagCount, error) {
+	prefix = NormalizeTag(prefix)
+	if prefix == "" {
+		return []models.TagCount{}, nil
 	}
+	return s.setStorage.SuggestPublicTags(ctx, prefix, limit)
+}
 
-	if !originalSet.IsPublic {
-		return nil, ErrForbidden
+// SearchPublicSets runs a full-text search over public sets. The author may be
+// given by username, in which case it is resolved to an id first; an unknown
+// username finds nothing.
+func (s *CardSetService) SearchPublicSets(ctx context.Context, params models.SearchParams, authorUsername string) ([]models.SearchResult, error) {
+	if params.AuthorID != "" {
+		if _, err := uuid.Parse(params.AuthorID); err != nil {
+			return nil, ErrInvalidParam
+		}
+	}
+	if params.MinCards != nil && params.MaxCards != nil && *params.MinCards > *params.MaxCards {
+		return nil, ErrInvalidParam
 	}
 
-	clonedSet := &models.CardSet{
-		ID:          uuid.New().String(),
-		OwnerID:     userID,
-		Name:        originalSet.Name + " (copy)",
-		Description: originalSet.Description,
-		IsPublic:    false,
-		CreatedAt:   time.Now(),
+	if authorUsername != "" {
+		profile, err := s.userClient.GetPublicProfileByUsername(ctx, authorUsername)
+		if err != nil {
+			return nil, err
+		}
+		if profile == nil || (params.AuthorID != "" && params.AuthorID != profile.ID) {
+			return []models.SearchResult{}, nil
+		}
+		params.AuthorID = profile.ID
 	}
 
-	if err := s.setStorage.Create(ctx, clonedSet); err != nil {
+	results, err := s.setStorage.Search(ctx, params)
+	if err != nil {
 		return nil, err
 	}
 
-	cards, err := s.cardStorage.GetBySetID(ctx, setID, 0, 1000)
+	authors := newAuthorCache(s.userClient)
+	for i := range results {
+		r := &results[i]
+		r.Highlights.Name = HighlightHTML(r.Highlights.Name)
+		r.Highlights.Description = HighlightHTML(r.Highlights.Description)
+		if card := r.Highlights.Card; card != nil {
+			card.Front = HighlightHTML(card.Front)
+			card.Back = HighlightHTML(card.Back)
+		}
+		r.Author = authors.get(ctx, r.OwnerID)
+	}
+
+	return results, nil
+}
+
+// GetTrendingSets returns the public sets most viewed and cloned recently.
+func (s *CardSetService) GetTrendingSets(ctx context.Context, offset, limit int32) ([]models.TrendingSet, error) {
+	sets, err := s.viewStorage.GetTrending(ctx, s.views.TrendingWindow, s.views.TrendingHalfLife, offset, limit)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, card := range cards {
-		clonedCard := &models.Card{
-			ID:        uuid.New().String(),
-			SetID:     clonedSet.ID,
-			Front:     card.Front,
-			Back:      card.Back,
-			ImageURL:  card.ImageURL,
-			AudioURL:  card.AudioURL,
-			Status:    models.StatusNew,
-			CreatedAt: time.Now(),
-		}
-		if err := s.cardStorage.Create(ctx, clonedCard); err != nil {
-			return nil, err
+	authors := newAuthorCache(s.userClient)
+	for i := range sets {
+		sets[i].Author = authors.get(ctx, sets[i].OwnerID)
+	}
+
+	return sets, nil
+}
+
+// CleanupViews drops views too old to matter and returns how many sets had
+// their view count corrected.
+func (s *CardSetService) CleanupViews(ctx context.Context) (int64, error) {
+	return s.viewStorage.Cleanup(ctx)
+}
+
+// authorCache looks up the authors of a list of sets, each of them once.
+type authorCache struct {
+	userClient *userclient.Client
+	authors    map[string]*models.AuthorInfo
+}
+
+func newAuthorCache(userClient *userclient.Client) *authorCache {
+	return &authorCache{userClient: userClient, authors: make(map[string]*models.AuthorInfo)}
+}
+
+func (c *authorCache) get(ctx context.Context, ownerID string) *models.AuthorInfo {
+	if author, ok := c.authors[ownerID]; ok {
+		return author
+	}
+
+	var author *models.AuthorInfo
+	if ownerInfo, err := c.userClient.GetPublicProfile(ctx, ownerID); err == nil && ownerInfo != nil {
+		author = &models.AuthorInfo{
+			ID:    ownerInfo.ID,
+			Name:  ownerInfo.Name,
+			Photo: ownerInfo.PhotoURL,
 		}
 	}
+	c.authors[ownerID] = author
+	return author
+}
 
-	clonedSet.CardCount = int32(len(cards))
+// HighlightHTML turns a search snippet into HTML: the text is escaped and the
+// matched words are wrapped in <mark>.
+func HighlightHTML(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	snippet = strings.ReplaceAll(snippet, storage.HighlightStart, "<mark>")
+	return strings.ReplaceAll(snippet, storage.HighlightStop, "</mark>")
+}
+
+// CloneSet copies a set the user may view with all its cards to the user. The
+// copy remembers where it came from, so later changes can be pulled from the original.
+func (s *CardSetService) CloneSet(ctx context.Context, setID, userID string) (*models.CardSet, error) {
+	originalSet, err := s.authz.Authorize(ctx, setID, userID, ActionView)
+	if err != nil {
+		return nil, err
+	}
+
+	clonedSet := &models.CardSet{
+		ID:              uuid.New().String(),
+		OwnerID:         userID,
+		Name:            originalSet.Name + " (copy)",
+		Description:     originalSet.Description,
+		IsPublic:        false,
+		Tags:            originalSet.Tags,
+		CreatedAt:       time.Now(),
+		ClonedFromSetID: &originalSet.ID,
+		Role:            models.SetRoleOwner,
+	}
+	clonedSet.SyncedAt = &clonedSet.CreatedAt
+
+	if clonedSet.CardCount, err = s.setStorage.Clone(ctx, setID, clonedSet); err != nil {
+		return nil, err
+	}
 
 	if ownerInfo, err := s.userClient.GetPublicProfile(ctx, userID); err == nil && ownerInfo != nil {
 		clonedSet.Author = &models.AuthorInfo{
@@ -204,48 +347,68 @@ func (s *CardSetService) CloneSet(ctx context.Context, setID, userID string) (*m
 }
 
 type CardService struct {
-	setStorage  storage.CardSetStorage
-	cardStorage storage.CardStorage
+	setStorage      storage.CardSetStorage
+	cardStorage     storage.CardStorage
+	progressStorage storage.CardProgressStorage
+	authz           *Authorizer
 }
 
-func NewCardService(setStorage storage.CardSetStorage, cardStorage storage.CardStorage) *CardService {
-	return &CardService{setStorage: setStorage, cardStorage: cardStorage}
+func NewCardService(setStorage storage.CardSetStorage, cardStorage storage.CardStorage, progressStorage storage.CardProgressStorage, authz *Authorizer) *CardService {
+	return &CardService{setStorage: setStorage, cardStorage: cardStorage, progressStorage: progressStorage, authz: authz}
 }
 
-func (s *CardService) CreateCard(ctx context.Context, setID, front, back string, imageURL, audioURL *string) (*models.Card, error) {
-	_, err := s.setStorage.GetByID(ctx, setID)
+// CreateCard adds a card to the end of the set or, with position given, at
+// that zero-based place in it.
+func (s *CardService) CreateCard(ctx context.Context, setID, userID string, in models.CardInput, position *int32) (*models.Card, error) {
+	if _, err := s.authz.Authorize(ctx, setID, userID, ActionEditCards); err != nil {
+		return nil, err
+	}
+
+	if position == nil {
+		return s.AddCard(ctx, setID, in)
+	}
+	if *position < 0 {
+		return nil, ErrInvalidParam
+	}
+
+	content, err := BuildCardContent(in)
 	if err != nil {
-		return nil, ErrNotFound
+		return nil, err
+	}
+	card := newCard(setID, content)
+	if err := s.cardStorage.CreateAt(ctx, card, *position, userID); err != nil {
+		return nil, err
 	}
 
+	return card, nil
+}
+
+func newCard(setID string, content *models.CardContent) *models.Card {
 	card := &models.Card{
 		ID:        uuid.New().String(),
 		SetID:     setID,
-		Front:     front,
-		Back:      back,
-		ImageURL:  imageURL,
-		AudioURL:  audioURL,
 		Status:    models.StatusNew,
 		CreatedAt: time.Now(),
 	}
+	setContent(card, content)
+	return card
+}
 
-	if err := s.cardStorage.Create(ctx, card); err != nil {
-		return nil, err
-	}
-
-	return card, nil
+func setContent(card *models.Card, content *models.CardContent) {
+	card.Front, card.Back = content.Front, content.Back
+	card.ImageURL, card.AudioURL = content.ImageURL, content.AudioURL
+	card.Rich = content.Rich
 }
 
-func (s *CardService) GetCard(ctx context.Context, id, userID string) (*models.Card, error) {
-	card, err := s.cardStorage.GetByID(ctx, id)
+// AddCard creates a card without checking who asks for it. It is meant for
+// trusted internal callers acting on behalf of the set owner, like the gRPC API.
+func (s *CardService) AddCard(ctx context.Context, setID string, in models.CardInput) (*models.Card, error) {
+	content, err := BuildCardContent(in)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, ErrNotFound
-		}
 		return nil, err
 	}
 
-	set, err := s.setStorage.GetByID(ctx, card.SetID)
+	set, err := s.setStorage.GetByID(ctx, setID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
@@ -253,56 +416,55 @@ func (s *CardService) GetCard(ctx context.Context, id, userID string) (*models.C
 		return nil, err
 	}
 
-	if set.OwnerID != userID && !set.IsPublic {
-		return nil, ErrForbidden
+	card := newCard(setID, content)
+	if err := s.cardStorage.Create(ctx, card, set.OwnerID); err != nil {
+		return nil, err
 	}
 
 	return card, nil
 }
 
-func (s *CardService) GetCards(ctx context.Context, setID, userID string, offset, limit int32) ([]models.Card, error) {
-	set, err := s.setStorage.GetByID(ctx, setID)
+func (s *CardService) GetCard(ctx context.Context, id, userID string) (*models.Card, error) {
+	card, _, err := s.authz.AuthorizeCard(ctx, id, userID, ActionView)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, ErrNotFound
-		}
 		return nil, err
 	}
 
-	if set.OwnerID != userID && !set.IsPublic {
-		return nil, ErrForbidden
+	if userID != "" {
+		// A card shows the progress on its first item, as in the set's card list
+		progress, err := s.progressStorage.Get(ctx, userID, card.ID, CardItems(card)[0])
+		if err != nil {
+			return nil, err
+		}
+		applyProgress(card, progress)
 	}
 
-	return s.cardStorage.GetBySetID(ctx, setID, offset, limit)
+	return card, nil
 }
 
-func (s *CardService) UpdateCard(ctx context.Context, id, userID, front, back string, imageURL, audioURL *string) (*models.Card, error) {
-	card, err := s.cardStorage.GetByID(ctx, id)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, ErrNotFound
-		}
+func (s *CardService) GetCards(ctx context.Context, setID, userID string, offset, limit int32) ([]models.Card, error) {
+	if _, err := s.authz.Authorize(ctx, setID, userID, ActionView); err != nil {
 		return nil, err
 	}
 
-	set, err := s.setStorage.GetByID(ctx, card.SetID)
+	return s.cardStorage.GetBySetID(ctx, setID, userID, offset, limit)
+}
+
+// UpdateCard replaces the content of a card. Rich content is kept when a
+// client that doesn't know it edits the card.
+func (s *CardService) UpdateCard(ctx context.Context, id, userID string, in models.CardInput) (*models.Card, error) {
+	card, _, err := s.authz.AuthorizeCard(ctx, id, userID, ActionEditCards)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, ErrNotFound
-		}
 		return nil, err
 	}
 
-	if set.OwnerID != userID {
-		return nil, ErrForbidden
+	content, err := BuildCardContent(KeepRichContent(in, card))
+	if err != nil {
+		return nil, err
 	}
+	setContent(card, content)
 
-	card.Front = front
-	card.Back = back
-	card.ImageURL = imageURL
-	card.AudioURL = audioURL
-
-	if err := s.cardStorage.Update(ctx, card); err != nil {
+	if err := s.cardStorage.Update(ctx, card, userID); err != nil {
 		return nil, err
 	}
 
@@ -310,54 +472,37 @@ func (s *CardService) UpdateCard(ctx context.Context, id, userID, front, back st
 }
 
 func (s *CardService) DeleteCard(ctx context.Context, id, userID string) error {
-	card, err := s.cardStorage.GetByID(ctx, id)
+	card, _, err := s.authz.AuthorizeCard(ctx, id, userID, ActionEditCards)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return ErrNotFound
-		}
 		return err
 	}
 
-	set, err := s.setStorage.GetByID(ctx, card.SetID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return ErrNotFound
-		}
-		return err
-	}
-
-	if set.OwnerID != userID {
-		return ErrForbidden
-	}
-
-	return s.cardStorage.Delete(ctx, id)
+	return s.cardStorage.Delete(ctx, card.ID, userID)
 }
 
 type LearningService struct {
-	setStorage     storage.CardSetStorage
-	cardStorage    storage.CardStorage
-	sessionStorage storage.StudySessionStorage
-	statsStorage   storage.StatisticsStorage
+	setStorage      storage.CardSetStorage
+	cardStorage     storage.CardStorage
+	progressStorage storage.CardProgressStorage
+	sessionStorage  storage.StudySessionStorage
+	statsStorage    storage.StatisticsStorage
+	settingsStorage storage.UserSettingsStorage
+	folderStorage   storage.FolderStorage
+	authz           *Authorizer
 }
 
-func NewLearningService(setStorage storage.CardSetStorage, cardStorage storage.CardStorage, sessionStorage storage.StudySessionStorage, statsStorage storage.StatisticsStorage) *LearningService {
-	return &LearningService{setStorage: setStorage, cardStorage: cardStorage, sessionStorage: sessionStorage, statsStorage: statsStorage}
+func NewLearningService(setStorage storage.CardSetStorage, cardStorage storage.CardStorage, progressStorage storage.CardProgressStorage, sessionStorage storage.StudySessionStorage, statsStorage storage.StatisticsStorage, settingsStorage storage.UserSettingsStorage, folderStorage storage.FolderStorage, authz *Authorizer) *LearningService {
+	return &LearningService{setStorage: setStorage, cardStorage: cardStorage, progressStorage: progressStorage, sessionStorage: sessionStorage, statsStorage: statsStorage, settingsStorage: settingsStorage, folderStorage: folderStorage, authz: authz}
 }
 
-func (s *LearningService) StartStudySession(ctx context.Context, setID, userID string, sessionType models.SessionType, limit int32) (*models.StudySession, error) {
-	set, err := s.setStorage.GetByID(ctx, setID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, ErrNotFound
-		}
+// StartStudySession starts a study session over one set. With inSetOrder, a
+// learn session goes through the cards in the order they are arranged in the set.
+func (s *LearningService) StartStudySession(ctx context.Context, setID, userID string, sessionType models.SessionType, inSetOrder bool, limit int32) (*models.StudySession, error) {
+	if _, err := s.authz.Authorize(ctx, setID, userID, ActionView); err != nil {
 		return nil, err
 	}
 
-	if set.OwnerID != userID && !set.IsPublic {
-		return nil, ErrForbidden
-	}
-
-	cards, err := s.cardStorage.GetCardsForStudy(ctx, setID, sessionType, limit)
+	cards, err := s.cardStorage.GetCardsForStudy(ctx, setID, userID, sessionType, inSetOrder, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -374,6 +519,7 @@ func (s *LearningService) StartStudySession(ctx context.Context, setID, userID s
 		Cards:       cards,
 		CreatedAt:   time.Now(),
 	}
+	startQueue(session)
 
 	if err := s.sessionStorage.Create(ctx, session); err != nil {
 		return nil, err
@@ -401,6 +547,7 @@ func (s *LearningService) StartStudySessionAll(ctx context.Context, userID strin
 		Cards:       cards,
 		CreatedAt:   time.Now(),
 	}
+	startQueue(session)
 
 	if err := s.sessionStorage.Create(ctx, session); err != nil {
 		return nil, err
@@ -409,105 +556,401 @@ func (s *LearningService) StartStudySessionAll(ctx context.Context, userID strin
 	return session, nil
 }
 
-func (s *LearningService) SubmitAnswer(ctx context.Context, sessionID, cardID, userID string, rating models.CardRating, timeSpentMs int64) (*AnswerResult, error) {
-	session, err := s.sessionStorage.GetByID(ctx, sessionID)
+// StartStudySessionFolder starts a study session over the sets in one of the
+// user's folders and in all folders below it.
+func (s *LearningService) StartStudySessionFolder(ctx context.Context, folderID, userID string, sessionType models.SessionType, limit int32) (*models.StudySession, error) {
+	if _, err := getOwnFolder(ctx, s.folderStorage, folderID, userID); err != nil {
+		return nil, err
+	}
+
+	cards, err := s.cardStorage.GetCardsForStudyFolder(ctx, folderID, userID, sessionType, limit)
 	if err != nil {
+		return nil, err
+	}
+
+	if len(cards) == 0 {
 		return nil, ErrNotFound
 	}
 
+	session := &models.StudySession{
+		ID:          uuid.New().String(),
+		FolderID:    &folderID,
+		UserID:      userID,
+		SessionType: sessionType,
+		Cards:       cards,
+		CreatedAt:   time.Now(),
+	}
+	startQueue(session)
+
+	if err := s.sessionStorage.Create(ctx, session); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// SubmitAnswer grades an answer to a review item in the session's queue. If
+// item is nil, the card's first item in the queue not answered yet is graded.
+func (s *LearningService) SubmitAnswer(ctx context.Context, sessionID, cardID string, item *int16, userID string, rating models.CardRating, timeSpentMs int64) (*AnswerResult, error) {
+	session, err := s.getOwnSession(ctx, sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if session.FinishedAt != nil {
+		return nil, ErrSessionFinished
+	}
+
+	if item == nil {
+		next, err := s.sessionStorage.NextItem(ctx, sessionID, cardID)
+		if err == sql.ErrNoRows {
+			return nil, ErrCardNotInSession
+		}
+		if err != nil {
+			return nil, err
+		}
+		item = &next
+	} else {
+		inSession, err := s.sessionStorage.HasCard(ctx, sessionID, cardID, *item)
+		if err != nil {
+			return nil, err
+		}
+		if !inSession {
+			return nil, ErrCardNotInSession
+		}
+	}
+
+	// The answer is stored in the session along with the review
+	return s.ReviewCard(ctx, userID, models.ReviewFromStudy, sessionID, cardID, *item, rating, timeSpentMs)
+}
+
+// GetStudySession returns a session with its cards in queue order so it can be
+// resumed, e.g. on another device.
+func (s *LearningService) GetStudySession(ctx context.Context, sessionID, userID string) (*models.StudySession, error) {
+	session, err := s.getOwnSession(ctx, sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	queue, err := s.sessionStorage.GetCards(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(queue))
+	for _, entry := range queue {
+		ids = append(ids, entry.CardID)
+	}
+
+	items, err := s.cardStorage.GetItems(ctx, ids, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	byItem := make(map[models.CardItem]models.Card, len(items))
+	for _, item := range items {
+		byItem[models.CardItem{CardID: item.ID, Item: item.Item}] = item
+	}
+
+	session.Cards = make([]models.Card, 0, len(queue))
+	session.AnsweredCardIDs, session.AnsweredItems = []string{}, []models.CardItem{}
+	for _, entry := range queue {
+		key := models.CardItem{CardID: entry.CardID, Item: entry.Item}
+		card, ok := byItem[key]
+		if !ok {
+			// Deleted cards, and items edited out of a card, drop out of the queue
+			continue
+		}
+		session.Cards = append(session.Cards, card)
+		if entry.Attempts > 0 {
+			session.AnsweredCardIDs = append(session.AnsweredCardIDs, entry.CardID)
+			session.AnsweredItems = append(session.AnsweredItems, key)
+		} else if session.CurrentCardID == "" {
+			session.CurrentCardID, session.CurrentItem = entry.CardID, entry.Item
+		}
+	}
+	renderQueue(session.Cards)
+
+	return session, nil
+}
+
+// FinishStudySession ends a session and returns its summary. Finishing an
+// already finished session returns the same summary again.
+func (s *LearningService) FinishStudySession(ctx context.Context, sessionID, userID string) (*models.StudySessionSummary, error) {
+	session, err := s.getOwnSession(ctx, sessionID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	finishedAt, elapsedSeconds, err := s.sessionStorage.Finish(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	queue, err := s.sessionStorage.GetCards(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	return SummarizeStudySession(session.ID, session.CreatedAt, finishedAt, elapsedSeconds, queue), nil
+}
+
+func (s *LearningService) getOwnSession(ctx context.Context, sessionID, userID string) (*models.StudySession, error) {
+	session, err := s.sessionStorage.GetByID(ctx, sessionID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
 	if session.UserID != userID {
 		return nil, ErrForbidden
 	}
 
+	return session, nil
+}
+
+// ReviewCard grades a review item of a card for the user: it moves the item's
+// progress through the user's scheduler, logs the review and records the study
+// time. Study sessions and quizzes, named by source and sessionID, both go through here.
+func (s *LearningService) ReviewCard(ctx context.Context, userID string, source models.ReviewSource, sessionID, cardID string, item int16, rating models.CardRating, timeSpentMs int64) (*AnswerResult, error) {
+	if !validRating(rating) {
+		return nil, ErrInvalidParam
+	}
+
 	card, err := s.cardStorage.GetByID(ctx, cardID)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	if !slices.Contains(CardItems(card), item) {
+		// The card was edited since the item was handed out
 		return nil, ErrNotFound
 	}
 
-	newStatus, nextReview, streak, errorCount := CalculateSpacedRepetition(card.Status, card.ErrorCount, rating)
-
-	if err := s.cardStorage.UpdateCardStatus(ctx, cardID, newStatus, errorCount, rating, nextReview, streak); err != nil {
+	progress, err := s.progressStorage.Get(ctx, userID, card.ID, item)
+	if err != nil {
 		return nil, err
 	}
 
-	timeSpentMinutes := int32(timeSpentMs / 60000)
-	if timeSpentMinutes < 1 {
-		timeSpentMinutes = 1
+	settings, err := s.settingsStorage.Get(ctx, userID)
+	if err != nil {
+		return nil, err
 	}
-	
-	setID := ""
-	if session.SetID != nil {
-		setID = *session.SetID
+
+	now := time.Now()
+	next := NewScheduler(settings.Scheduler).Schedule(*progress, rating, now)
+	becameLeech := ApplyLapse(progress.Status, &next, rating, *settings, now)
+	review := &models.Review{
+		UserID:               userID,
+		CardID:               card.ID,
+		Item:                 item,
+		SetID:                card.SetID,
+		Source:               source,
+		SessionID:            &sessionID,
+		Rating:               rating,
+		StatusBefore:         progress.Status,
+		StatusAfter:          next.Status,
+		PreviousIntervalDays: progress.IntervalDays,
+		IntervalDays:         next.IntervalDays,
+		Scheduler:            settings.Scheduler,
+		ResponseTimeMs:       max(timeSpentMs, 0),
+	}
+	if err := s.progressStorage.RecordReview(ctx, &next, review); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrAlreadyAnswered
+		}
+		return nil, err
 	}
-	_ = s.statsStorage.RecordStudySession(ctx, userID, setID, 1, timeSpentMinutes)
+
+	// Sessions across all sets have no set of their own, history is kept per card's set
+	studyDate := LocalDate(now, settings.Timezone)
+	_ = s.statsStorage.RecordStudySession(ctx, userID, card.SetID, studyDate, 1, StudyTimeSeconds(timeSpentMs))
 
 	return &AnswerResult{
-		CardID:     cardID,
-		NewStatus:  newStatus,
-		NextReview: nextReview,
-		Streak:     streak,
-		ErrorCount: errorCount,
-		LastRating: rating,
+		CardID:         cardID,
+		Item:           item,
+		PreviousStatus: progress.Status,
+		NewStatus:      next.Status,
+		NextReview:     *next.NextReview,
+		IntervalDays:   next.IntervalDays,
+		Streak:         next.Streak,
+		ErrorCount:     next.ErrorCount,
+		LastRating:     rating,
+		Lapses:         next.Lapses,
+		Leech:          becameLeech,
+		Suspended:      becameLeech && next.SuspendedAt != nil,
 	}, nil
 }
 
-func (s *LearningService) GetSetStatistics(ctx context.Context, setID string) (*models.SetStatistics, error) {
-	return s.statsStorage.GetSetStatistics(ctx, setID)
+// SuspendCard leaves every review item of a card out of the user's study and
+// quizzes until the user unsuspends it.
+func (s *LearningService) SuspendCard(ctx context.Context, cardID, userID string) error {
+	card, _, err := s.authz.AuthorizeCard(ctx, cardID, userID, ActionView)
+	if err != nil {
+		return err
+	}
+
+	return s.progressStorage.Suspend(ctx, userID, card.ID, CardItems(card))
 }
 
-func (s *LearningService) GetUserStatistics(ctx context.Context, userID string) (*models.UserStatistics, error) {
-	return s.statsStorage.GetUserStatistics(ctx, userID)
+// BuryCard leaves every review item of a card out of the user's study and
+// quizzes until the next day begins in the user's timezone.
+func (s *LearningService) BuryCard(ctx context.Context, cardID, userID string) (time.Time, error) {
+	card, _, err := s.authz.AuthorizeCard(ctx, cardID, userID, ActionView)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	settings, err := s.settingsStorage.Get(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	until := NextDayStart(time.Now(), settings.Timezone)
+	if err := s.progressStorage.Bury(ctx, userID, card.ID, CardItems(card), until); err != nil {
+		return time.Time{}, err
+	}
+	return until, nil
 }
 
-func CalculateSpacedRepetition(currentStatus models.CardStatus, errorCount int32, rating models.CardRating) (models.CardStatus, time.Time, int32, int32) {
-	var nextStatus models.CardStatus
-	var daysUntilReview int
-	var streak int32 = 1
-	var newErrorCount int32 = errorCount
+// UnsuspendCard brings a suspended or buried card back to the user's study.
+// Leech items start counting lapses over again.
+func (s *LearningService) UnsuspendCard(ctx context.Context, cardID, userID string) error {
+	card, _, err := s.authz.AuthorizeCard(ctx, cardID, userID, ActionView)
+	if err != nil {
+		return err
+	}
 
-	if rating == models.RatingRemember {
-		if newErrorCount > 0 {
-			newErrorCount--
-		}
+	return s.progressStorage.Unsuspend(ctx, userID, card.ID)
+}
 
-		switch currentStatus {
-		case models.StatusNew:
-			nextStatus = models.StatusLearning
-			daysUntilReview = 1
-			streak = 1
-		case models.StatusLearning:
-			nextStatus = models.StatusReviewing
-			daysUntilReview = 3
-			streak = 2
-		case models.StatusReviewing:
-			nextStatus = models.StatusReviewing
-			daysUntilReview = 7
-			streak = 3
-		case models.StatusMastered:
-			nextStatus = models.StatusMastered
-			daysUntilReview = 30
-			streak = 5
-		default:
-			nextStatus = models.StatusLearning
-			daysUntilReview = 1
+// GetLeeches lists the review items of a set that became leeches for the user,
+// the most lapses first.
+func (s *LearningService) GetLeeches(ctx context.Context, setID, userID string) ([]models.Card, error) {
+	if _, err := s.authz.Authorize(ctx, setID, userID, ActionView); err != nil {
+		return nil, err
+	}
+
+	cards, err := s.cardStorage.GetLeeches(ctx, setID, userID)
+	if err != nil {
+		return nil, err
+	}
+	renderQueue(cards)
+	return cards, nil
+}
+
+func (s *LearningService) GetSetStatistics(ctx context.Context, setID, userID string) (*models.SetStatistics, error) {
+	if _, err := s.authz.Authorize(ctx, setID, userID, ActionView); err != nil {
+		return nil, err
+	}
+
+	settings, err := s.settingsStorage.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.statsStorage.GetSetStatistics(ctx, setID, userID, LocalDate(time.Now(), settings.Timezone))
+}
+
+// GetUserStatistics returns the user's totals together with the streak, counted
+// in the user's timezone against their daily goal.
+func (s *LearningService) GetUserStatistics(ctx context.Context, userID string) (*models.UserStatistics, error) {
+	settings, err := s.settingsStorage.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().In(userLocation(settings.Timezone))
+	stats, err := s.statsStorage.GetUserStatistics(ctx, userID, now.Format(dateLayout))
+	if err != nil {
+		return nil, err
+	}
+
+	days, err := s.statsStorage.GetStudyDays(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	streak := ComputeStreak(days, now, *settings)
+	stats.CurrentStreak = streak.Current
+	stats.LongestStreak = streak.Longest
+	stats.GoalMetToday = streak.GoalMetToday
+	stats.FrozenDays = streak.FrozenDays
+	stats.FreezesLeftThisWeek = streak.FreezesLeftThisWeek
+	stats.DailyGoalType = settings.DailyGoalType
+	stats.DailyGoal = settings.DailyGoal
+
+	if len(days) > 0 {
+		if last, err := time.Parse(dateLayout, days[len(days)-1].Date); err == nil {
+			stats.LastStudyDate = &last
 		}
-	} else {
-		newErrorCount++
+	}
+
+	return stats, nil
+}
 
-		nextStatus = models.StatusLearning
-		daysUntilReview = 1
-		streak = 0
+// MaxAnswerTime caps the study time counted for a single answer, so a card left
+// open while the user is away does not count as hours of study.
+const MaxAnswerTime = 5 * time.Minute
+
+// StudyTimeSeconds converts the time spent on one answer to whole seconds for
+// study history, rounding to the nearest second and capping it at MaxAnswerTime.
+func StudyTimeSeconds(timeSpentMs int64) int64 {
+	if timeSpentMs <= 0 {
+		return 0
+	}
+	if timeSpentMs > MaxAnswerTime.Milliseconds() {
+		timeSpentMs = MaxAnswerTime.Milliseconds()
 	}
+	return (timeSpentMs + 500) / 1000
+}
+
+// startQueue renders the queue of a new session and points it at its first entry.
+func startQueue(session *models.StudySession) {
+	renderQueue(session.Cards)
+	session.AnsweredCardIDs, session.AnsweredItems = []string{}, []models.CardItem{}
+	session.CurrentCardID, session.CurrentItem = session.Cards[0].ID, session.Cards[0].Item
+}
 
-	nextReview := time.Now().AddDate(0, 0, daysUntilReview)
-	return nextStatus, nextReview, streak, newErrorCount
+// renderQueue fills in what each entry of a study queue asks and answers.
+func renderQueue(cards []models.Card) {
+	for i := range cards {
+		cards[i].Prompt, cards[i].Answer, _ = RenderItem(&cards[i], cards[i].Item)
+	}
+}
+
+// applyProgress overlays a user's learning state onto the card content.
+func applyProgress(card *models.Card, progress *models.CardProgress) {
+	card.Item = progress.Item
+	card.Status = progress.Status
+	card.ErrorCount = progress.ErrorCount
+	card.LastRating = progress.LastRating
+	card.NextReview = progress.NextReview
+	card.Lapses = progress.Lapses
+	card.Leech = progress.Leech
+	card.Suspended = progress.SuspendedAt != nil
+	if progress.BuriedUntil != nil && progress.BuriedUntil.After(time.Now()) {
+		card.BuriedUntil = progress.BuriedUntil
+	}
 }
 
 type AnswerResult struct {
-	CardID     string            `json:"card_id"`
-	NewStatus  models.CardStatus `json:"new_status"`
-	NextReview time.Time         `json:"next_review"`
-	Streak     int32             `json:"streak"`
-	ErrorCount int32             `json:"error_count"`
-	LastRating models.CardRating `json:"last_rating"`
+	CardID         string            `json:"card_id"`
+	Item           int16             `json:"item,omitempty"`
+	PreviousStatus models.CardStatus `json:"previous_status"`
+	NewStatus      models.CardStatus `json:"new_status"`
+	NextReview     time.Time         `json:"next_review"`
+	IntervalDays   int32             `json:"interval_days"`
+	Streak         int32             `json:"streak"`
+	ErrorCount     int32             `json:"error_count"`
+	LastRating     models.CardRating `json:"last_rating"`
+	Lapses         int32             `json:"lapses"`
+	// Leech and Suspended tell the client the item just became a leech and,
+	// with the user's settings, left study.
+	Leech     bool `json:"leech"`
+	Suspended bool `json:"suspended"`
 }
@@ -9,60 +9,84 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func TestCalculateSpacedRepetition_CorrectAnswer(t *testing.T) {
+func TestSM2Scheduler_CorrectAnswer(t *testing.T) {
 	tests := []struct {
 		name           string
-		currentStatus  models.CardStatus
-		errorCount     int32
+		progress       models.CardProgress
+		rating         models.CardRating
 		expectedStatus models.CardStatus
-		expectedDays   int
+		expectedDays   int32
 		expectedStreak int32
 		expectedErrors int32
 	}{
-		{"New to Learning", models.StatusNew, 0, models.StatusLearning, 1, 1, 0},
-		{"Learning to Reviewing", models.StatusLearning, 0, models.StatusReviewing, 3, 2, 0},
-		{"Reviewing stays Reviewing", models.StatusReviewing, 0, models.StatusReviewing, 7, 3, 0},
-		{"Mastered stays Mastered", models.StatusMastered, 0, models.StatusMastered, 30, 5, 0},
-		{"Reviewing with errors", models.StatusReviewing, 3, models.StatusReviewing, 7, 3, 2},
+		{"New to Learning", models.CardProgress{Status: models.StatusNew}, models.RatingGood, models.StatusLearning, 1, 1, 0},
+		{"New easy", models.CardProgress{Status: models.StatusNew}, models.RatingEasy, models.StatusLearning, 4, 1, 0},
+		{"Learning to Reviewing", models.CardProgress{Status: models.StatusLearning, Reps: 1, IntervalDays: 1, Ease: 2.5, Streak: 1}, models.RatingGood, models.StatusReviewing, 6, 2, 0},
+		{"Reviewing grows by ease", models.CardProgress{Status: models.StatusReviewing, Reps: 2, IntervalDays: 6, Ease: 2.5, Streak: 2}, models.RatingGood, models.StatusReviewing, 15, 3, 0},
+		{"Reviewing to Mastered", models.CardProgress{Status: models.StatusReviewing, Reps: 3, IntervalDays: 15, Ease: 2.5, Streak: 3}, models.RatingGood, models.StatusMastered, 38, 4, 0},
+		{"Hard grows slowly", models.CardProgress{Status: models.StatusReviewing, Reps: 2, IntervalDays: 10, Ease: 2.5, Streak: 2}, models.RatingHard, models.StatusReviewing, 12, 3, 0},
+		{"Reviewing with errors", models.CardProgress{Status: models.StatusReviewing, Reps: 2, IntervalDays: 6, Ease: 2.5, ErrorCount: 3}, models.RatingGood, models.StatusReviewing, 15, 1, 2},
 	}
 
+	scheduler := services.NewSM2Scheduler()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			status, nextReview, streak, errorCount := services.CalculateSpacedRepetition(tt.currentStatus, tt.errorCount, true)
+			now := time.Now()
+			next := scheduler.Schedule(tt.progress, tt.rating, now)
 
-			assert.Equal(t, tt.expectedStatus, status)
-			assert.Equal(t, tt.expectedStreak, streak)
-			assert.Equal(t, tt.expectedErrors, errorCount)
-
-			expectedDate := time.Now().AddDate(0, 0, tt.expectedDays)
-			assert.InDelta(t, expectedDate.Unix(), nextReview.Unix(), 60)
+			assert.Equal(t, tt.expectedStatus, next.Status)
+			assert.Equal(t, tt.expectedDays, next.IntervalDays)
+			assert.Equal(t, tt.expectedStreak, next.Streak)
+			assert.Equal(t, tt.expectedErrors, next.ErrorCount)
+			assert.Equal(t, tt.rating, next.LastRating)
+			assert.Equal(t, now.AddDate(0, 0, int(tt.expectedDays)).Unix(), next.NextReview.Unix())
 		})
 	}
 }
 
-func TestCalculateSpacedRepetition_IncorrectAnswer(t *testing.T) {
+func TestSM2Scheduler_IncorrectAnswer(t *testing.T) {
 	tests := []struct {
 		name           string
-		currentStatus  models.CardStatus
-		errorCount     int32
+		progress       models.CardProgress
 		expectedErrors int32
+		expectedEase   float64
 	}{
-		{"New with errors", models.StatusNew, 0, 1},
-		{"Learning with errors", models.StatusLearning, 1, 2},
-		{"Reviewing with errors", models.StatusReviewing, 2, 3},
-		{"Mastered with errors", models.StatusMastered, 0, 1},
+		{"New with errors", models.CardProgress{Status: models.StatusNew}, 1, 2.3},
+		{"Learning with errors", models.CardProgress{Status: models.StatusLearning, ErrorCount: 1, Ease: 2.5, Reps: 1, IntervalDays: 1}, 2, 2.3},
+		{"Reviewing with errors", models.CardProgress{Status: models.StatusReviewing, ErrorCount: 2, Ease: 2.0, Reps: 3, IntervalDays: 15}, 3, 1.8},
+		{"Mastered keeps min ease", models.CardProgress{Status: models.StatusMastered, Ease: 1.3, Reps: 5, IntervalDays: 60}, 1, 1.3},
 	}
 
+	scheduler := services.NewSM2Scheduler()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			status, nextReview, streak, errorCount := services.CalculateSpacedRepetition(tt.currentStatus, tt.errorCount, false)
+			next := scheduler.Schedule(tt.progress, models.RatingAgain, time.Now())
+
+			assert.Equal(t, models.StatusLearning, next.Status)
+			assert.Equal(t, int32(0), next.Streak)
+			assert.Equal(t, int32(0), next.Reps)
+			assert.Equal(t, int32(1), next.IntervalDays)
+			assert.Equal(t, tt.expectedErrors, next.ErrorCount)
+			assert.InDelta(t, tt.expectedEase, next.Ease, 0.001)
+		})
+	}
+}
 
-			assert.Equal(t, models.StatusLearning, status)
-			assert.Equal(t, int32(0), streak)
-			assert.Equal(t, tt.expectedErrors, errorCount)
+func TestHighlightHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		snippet  string
+		expected string
+	}{
+		{"Empty", "", ""},
+		{"No match", "Irregular verbs", "Irregular verbs"},
+		{"Marked words", "Irregular \x02verbs\x03 and \x02глаголы\x03", "Irregular <mark>verbs</mark> and <mark>глаголы</mark>"},
+		{"Escapes card text", "\x02a\x03 < b & <script>", "<mark>a</mark> &lt; b &amp; &lt;script&gt;"},
+	}
 
-			expectedDate := time.Now().AddDate(0, 0, 1)
-			assert.InDelta(t, expectedDate.Unix(), nextReview.Unix(), 60)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, services.HighlightHTML(tt.snippet))
 		})
 	}
 }
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/storage"
+)
+
+type SettingsService struct {
+	settingsStorage storage.UserSettingsStorage
+}
+
+func NewSettingsService(settingsStorage storage.UserSettingsStorage) *SettingsService {
+	return &SettingsService{settingsStorage: settingsStorage}
+}
+
+func (s *SettingsService) GetSettings(ctx context.Context, userID string) (*models.UserSettings, error) {
+	return s.settingsStorage.Get(ctx, userID)
+}
+
+// UpdateSettings changes only the fields that are set in the update.
+func (s *SettingsService) UpdateSettings(ctx context.Context, userID string, update SettingsUpdate) (*models.UserSettings, error) {
+	settings, err := s.settingsStorage.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Scheduler != nil {
+		switch *update.Scheduler {
+		case models.SchedulerSM2, models.SchedulerFSRS:
+			settings.Scheduler = *update.Scheduler
+		default:
+			return nil, ErrInvalidParam
+		}
+	}
+
+	if update.Timezone != nil {
+		if *update.Timezone == "" {
+			return nil, ErrInvalidParam
+		}
+		if _, err := time.LoadLocation(*update.Timezone); err != nil {
+			return nil, ErrInvalidParam
+		}
+		settings.Timezone = *update.Timezone
+	}
+
+	if update.DailyGoalType != nil {
+		switch *update.DailyGoalType {
+		case models.DailyGoalCards, models.DailyGoalMinutes:
+			settings.DailyGoalType = *update.DailyGoalType
+		default:
+			return nil, ErrInvalidParam
+		}
+	}
+
+	if update.DailyGoal != nil {
+		if *update.DailyGoal < 1 || *update.DailyGoal > MaxDailyGoal {
+			return nil, ErrInvalidParam
+		}
+		settings.DailyGoal = *update.DailyGoal
+	}
+
+	if update.StreakFreezesPerWeek != nil {
+		if *update.StreakFreezesPerWeek < 0 || *update.StreakFreezesPerWeek > MaxStreakFreezesPerWeek {
+			return nil, ErrInvalidParam
+		}
+		settings.StreakFreezesPerWeek = *update.StreakFreezesPerWeek
+	}
+
+	if update.LeechThreshold != nil {
+		if *update.LeechThreshold < 0 || *update.LeechThreshold > MaxLeechThreshold {
+			return nil, ErrInvalidParam
+		}
+		settings.LeechThreshold = *update.LeechThreshold
+	}
+
+	if update.SuspendLeeches != nil {
+		settings.SuspendLeeches = *update.SuspendLeeches
+	}
+
+	if err := s.settingsStorage.Upsert(ctx, userID, settings); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+const (
+	// MaxDailyGoal bounds the daily goal, in cards or minutes.
+	MaxDailyGoal = 1000
+	// MaxStreakFreezesPerWeek keeps at least one day a week that has to be studied.
+	MaxStreakFreezesPerWeek = 6
+)
+
+type SettingsUpdate struct {
+	Scheduler            *models.SchedulerType
+	Timezone             *string
+	DailyGoalType        *models.DailyGoalType
+	DailyGoal            *int32
+	StreakFreezesPerWeek *int32
+	LeechThreshold       *int32
+	SuspendLeeches       *bool
+}
@@ -0,0 +1,179 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/storage"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/userclient"
+)
+
+// ErrUserNotFound is returned when sharing with a username that doesn't exist.
+var ErrUserNotFound = errors.New("user not found")
+
+type SharingService struct {
+	shareStorage storage.SetShareStorage
+	authz        *Authorizer
+	userClient   *userclient.Client
+}
+
+func NewSharingService(shareStorage storage.SetShareStorage, authz *Authorizer, userClient *userclient.Client) *SharingService {
+	return &SharingService{shareStorage: shareStorage, authz: authz, userClient: userClient}
+}
+
+func validShareRole(role models.SetRole) bool {
+	return role == models.SetRoleViewer || role == models.SetRoleEditor
+}
+
+// ShareSet invites a user, found by username, to the set with the given role.
+// Sharing again with someone already invited changes their role.
+func (s *SharingService) ShareSet(ctx context.Context, setID, userID, username string, role models.SetRole) (*models.SetShare, error) {
+	if !validShareRole(role) {
+		return nil, ErrInvalidParam
+	}
+
+	set, err := s.authz.Authorize(ctx, setID, userID, ActionManage)
+	if err != nil {
+		return nil, err
+	}
+
+	profile, err := s.userClient.GetPublicProfileByUsername(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if profile == nil {
+		return nil, ErrUserNotFound
+	}
+	if profile.ID == set.OwnerID {
+		return nil, ErrInvalidParam
+	}
+
+	share := &models.SetShare{
+		SetID:     set.ID,
+		UserID:    profile.ID,
+		Role:      role,
+		InvitedBy: userID,
+		CreatedAt: time.Now(),
+		User: &models.AuthorInfo{
+			ID:    profile.ID,
+			Name:  profile.Name,
+			Photo: profile.PhotoURL,
+		},
+	}
+	if err := s.shareStorage.Upsert(ctx, share); err != nil {
+		return nil, err
+	}
+
+	return share, nil
+}
+
+// GetShares lists everyone the set is shared with or who is invited to it.
+func (s *SharingService) GetShares(ctx context.Context, setID, userID string) ([]models.SetShare, error) {
+	if _, err := s.authz.Authorize(ctx, setID, userID, ActionManage); err != nil {
+		return nil, err
+	}
+
+	shares, err := s.shareStorage.GetBySet(ctx, setID)
+	if err != nil {
+		return nil, err
+	}
+
+	users := newAuthorCache(s.userClient)
+	for i := range shares {
+		shares[i].User = users.get(ctx, shares[i].UserID)
+	}
+
+	return shares, nil
+}
+
+func (s *SharingService) UpdateShareRole(ctx context.Context, setID, userID, targetUserID string, role models.SetRole) error {
+	if !validShareRole(role) {
+		return ErrInvalidParam
+	}
+
+	if _, err := s.authz.Authorize(ctx, setID, userID, ActionManage); err != nil {
+		return err
+	}
+
+	updated, err := s.shareStorage.UpdateRole(ctx, setID, targetUserID, role)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// RemoveShare revokes a share or invitation. Besides the owner, users may
+// remove their own share to leave a set.
+func (s *SharingService) RemoveShare(ctx context.Context, setID, userID, targetUserID string) error {
+	if userID != targetUserID {
+		if _, err := s.authz.Authorize(ctx, setID, userID, ActionManage); err != nil {
+			return err
+		}
+	}
+
+	removed, err := s.shareStorage.Delete(ctx, setID, targetUserID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetInvitations lists the user's pending invitations, newest first.
+func (s *SharingService) GetInvitations(ctx context.Context, userID string, offset, limit int32) ([]models.SetShare, error) {
+	invitations, err := s.shareStorage.GetByUser(ctx, userID, models.ShareStatusPending, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	users := newAuthorCache(s.userClient)
+	for i := range invitations {
+		invitations[i].Set.Author = users.get(ctx, invitations[i].Set.OwnerID)
+		// Until accepted the invitation gives no access
+		invitations[i].Set.Role = ""
+	}
+
+	return invitations, nil
+}
+
+// RespondToInvitation accepts or declines a pending invitation to a set.
+func (s *SharingService) RespondToInvitation(ctx context.Context, setID, userID string, accept bool) error {
+	status := models.ShareStatusDeclined
+	if accept {
+		status = models.ShareStatusAccepted
+	}
+
+	responded, err := s.shareStorage.Respond(ctx, setID, userID, status)
+	if err != nil {
+		return err
+	}
+	if !responded {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// GetSharedSets lists the sets other users shared with the user, with the user's role on each.
+func (s *SharingService) GetSharedSets(ctx context.Context, userID string, offset, limit int32) ([]models.CardSet, error) {
+	shares, err := s.shareStorage.GetByUser(ctx, userID, models.ShareStatusAccepted, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	users := newAuthorCache(s.userClient)
+	sets := make([]models.CardSet, 0, len(shares))
+	for _, share := range shares {
+		set := *share.Set
+		set.Author = users.get(ctx, set.OwnerID)
+		sets = append(sets, set)
+	}
+
+	return sets, nil
+}
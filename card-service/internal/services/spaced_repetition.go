@@ -0,0 +1,251 @@
+package services
+
+import (
+	"math"
+	"time"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+)
+
+const (
+	// MasteredIntervalDays is the interval from which a card counts as mastered.
+	MasteredIntervalDays = 21
+	// MaxIntervalDays caps how far into the future a review can be scheduled.
+	MaxIntervalDays = 3650
+)
+
+// Scheduler decides when a card is shown again after the user grades it.
+// Implementations only compute the next state; persisting it is up to the caller.
+// A rating other than again..easy leaves the progress as it is.
+type Scheduler interface {
+	Schedule(progress models.CardProgress, rating models.CardRating, now time.Time) models.CardProgress
+}
+
+// NewScheduler returns the scheduler for the given algorithm, SM-2 by default.
+func NewScheduler(schedulerType models.SchedulerType) Scheduler {
+	switch schedulerType {
+	case models.SchedulerFSRS:
+		return NewFSRSScheduler()
+	default:
+		return NewSM2Scheduler()
+	}
+}
+
+// validRating tells whether the rating is one of Again, Hard, Good and Easy.
+func validRating(rating models.CardRating) bool {
+	return rating >= models.RatingAgain && rating <= models.RatingEasy
+}
+
+// finishReview fills in the fields shared by all schedulers once the next interval is known.
+func finishReview(progress models.CardProgress, rating models.CardRating, intervalDays int, now time.Time) models.CardProgress {
+	if intervalDays < 1 {
+		intervalDays = 1
+	}
+	if intervalDays > MaxIntervalDays {
+		intervalDays = MaxIntervalDays
+	}
+
+	if rating == models.RatingAgain {
+		progress.ErrorCount++
+		progress.Reps = 0
+		progress.Streak = 0
+	} else {
+		if progress.ErrorCount > 0 {
+			progress.ErrorCount--
+		}
+		progress.Reps++
+		progress.Streak++
+	}
+
+	switch {
+	case rating == models.RatingAgain:
+		progress.Status = models.StatusLearning
+	case intervalDays >= MasteredIntervalDays:
+		progress.Status = models.StatusMastered
+	case progress.Reps >= 2:
+		progress.Status = models.StatusReviewing
+	default:
+		progress.Status = models.StatusLearning
+	}
+
+	nextReview := now.AddDate(0, 0, intervalDays)
+	reviewedAt := now
+	progress.IntervalDays = int32(intervalDays)
+	progress.NextReview = &nextReview
+	progress.LastReviewedAt = &reviewedAt
+	progress.LastRating = rating
+	return progress
+}
+
+const (
+	sm2DefaultEase = 2.5
+	sm2MinEase     = 1.3
+	sm2HardFactor  = 1.2
+	sm2EasyBonus   = 1.3
+)
+
+// SM2Scheduler is the SuperMemo-2 algorithm with Anki-style hard/easy grades.
+type SM2Scheduler struct{}
+
+func NewSM2Scheduler() *SM2Scheduler {
+	return &SM2Scheduler{}
+}
+
+func (s *SM2Scheduler) Schedule(progress models.CardProgress, rating models.CardRating, now time.Time) models.CardProgress {
+	if !validRating(rating) {
+		return progress
+	}
+
+	ease := progress.Ease
+	if ease == 0 {
+		ease = sm2DefaultEase
+	}
+
+	var interval float64
+	prev := float64(progress.IntervalDays)
+
+	switch {
+	case rating == models.RatingAgain:
+		interval = 1
+	case progress.Reps == 0:
+		interval = 1
+		if rating == models.RatingEasy {
+			interval = 4
+		}
+	case progress.Reps == 1:
+		switch rating {
+		case models.RatingHard:
+			interval = 3
+		case models.RatingEasy:
+			interval = 6 * sm2EasyBonus
+		default:
+			interval = 6
+		}
+	default:
+		switch rating {
+		case models.RatingHard:
+			interval = prev * sm2HardFactor
+		case models.RatingEasy:
+			interval = prev * ease * sm2EasyBonus
+		default:
+			interval = prev * ease
+		}
+		// A successful review never shortens the interval
+		interval = math.Max(interval, prev+1)
+	}
+
+	if rating == models.RatingAgain {
+		ease -= 0.2
+	} else {
+		// q is the SM-2 response quality: 3 = hard, 4 = good, 5 = easy
+		q := float64(rating) + 1
+		ease += 0.1 - (5-q)*(0.08+(5-q)*0.02)
+	}
+	progress.Ease = math.Max(ease, sm2MinEase)
+
+	return finishReview(progress, rating, int(math.Round(interval)), now)
+}
+
+// fsrsWeights are the default FSRS-4.5 parameters.
+var fsrsWeights = [17]float64{
+	0.4872, 1.4003, 3.7145, 13.8206, 5.1618, 1.2298, 0.8975, 0.031,
+	1.6474, 0.1367, 1.0461, 2.1072, 0.0793, 0.3246, 1.587, 0.2272, 2.8755,
+}
+
+const (
+	fsrsDecay            = -0.5
+	fsrsFactor           = 19.0 / 81.0
+	fsrsRequestRetention = 0.9
+	// fsrsDefaultDifficulty is the difficulty given to items scheduled by SM-2
+	// before, the middle of the 1-10 range.
+	fsrsDefaultDifficulty = 5
+)
+
+// FSRSScheduler is the Free Spaced Repetition Scheduler (FSRS-4.5) with default weights.
+type FSRSScheduler struct {
+	w                []float64
+	requestRetention float64
+}
+
+func NewFSRSScheduler() *FSRSScheduler {
+	return &FSRSScheduler{w: fsrsWeights[:], requestRetention: fsrsRequestRetention}
+}
+
+func (s *FSRSScheduler) Schedule(progress models.CardProgress, rating models.CardRating, now time.Time) models.CardProgress {
+	if !validRating(rating) {
+		return progress
+	}
+	g := float64(rating)
+
+	// Items reviewed with SM-2 have no FSRS state yet: start from their interval
+	// as the stability rather than treating them as new
+	if progress.Stability <= 0 && progress.IntervalDays > 0 && progress.Status != models.StatusNew {
+		progress.Stability = float64(progress.IntervalDays)
+		progress.Difficulty = fsrsDefaultDifficulty
+	}
+
+	if progress.Stability <= 0 || progress.Difficulty <= 0 {
+		progress.Stability = s.w[rating-1]
+		progress.Difficulty = s.initDifficulty(g)
+	} else {
+		elapsed := float64(progress.IntervalDays)
+		if progress.LastReviewedAt != nil {
+			elapsed = math.Max(0, now.Sub(*progress.LastReviewedAt).Hours()/24)
+		}
+		r := s.retrievability(elapsed, progress.Stability)
+
+		if rating == models.RatingAgain {
+			progress.Stability = s.forgetStability(progress.Difficulty, progress.Stability, r)
+		} else {
+			progress.Stability = s.recallStability(progress.Difficulty, progress.Stability, r, rating)
+		}
+		progress.Difficulty = s.nextDifficulty(progress.Difficulty, g)
+	}
+
+	interval := 1
+	if rating != models.RatingAgain {
+		interval = s.nextInterval(progress.Stability)
+	}
+
+	return finishReview(progress, rating, interval, now)
+}
+
+func (s *FSRSScheduler) retrievability(elapsedDays, stability float64) float64 {
+	return math.Pow(1+fsrsFactor*elapsedDays/stability, fsrsDecay)
+}
+
+func (s *FSRSScheduler) nextInterval(stability float64) int {
+	interval := stability / fsrsFactor * (math.Pow(s.requestRetention, 1/fsrsDecay) - 1)
+	return int(math.Max(1, math.Round(interval)))
+}
+
+func (s *FSRSScheduler) initDifficulty(g float64) float64 {
+	return clamp(s.w[4]-(g-3)*s.w[5], 1, 10)
+}
+
+func (s *FSRSScheduler) nextDifficulty(d, g float64) float64 {
+	next := d - s.w[6]*(g-3)
+	// Mean reversion towards the difficulty of a card first rated "good"
+	next = s.w[7]*s.initDifficulty(3) + (1-s.w[7])*next
+	return clamp(next, 1, 10)
+}
+
+func (s *FSRSScheduler) recallStability(d, stability, r float64, rating models.CardRating) float64 {
+	hardPenalty, easyBonus := 1.0, 1.0
+	if rating == models.RatingHard {
+		hardPenalty = s.w[15]
+	}
+	if rating == models.RatingEasy {
+		easyBonus = s.w[16]
+	}
+	return stability * (1 + math.Exp(s.w[8])*(11-d)*math.Pow(stability, -s.w[9])*(math.Exp((1-r)*s.w[10])-1)*hardPenalty*easyBonus)
+}
+
+func (s *FSRSScheduler) forgetStability(d, stability, r float64) float64 {
+	next := s.w[11] * math.Pow(d, -s.w[12]) * (math.Pow(stability+1, s.w[13]) - 1) * math.Exp((1-r)*s.w[14])
+	return math.Min(next, stability)
+}
+
+func clamp(v, min, max float64) float64 {
+	return math.Max(min, math.Min(max, v))
+}
@@ -9,44 +9,106 @@ import (
 	"github.com/stretchr/testify/assert"
 )
 
-func TestCalculateSpacedRepetition_LearnMode(t *testing.T) {
+func TestFSRSScheduler_FirstReview(t *testing.T) {
 	tests := []struct {
 		name           string
-		currentStatus  models.CardStatus
-		errorCount     int32
 		rating         models.CardRating
 		expectedStatus models.CardStatus
-		expectedDays   int
-		expectedStreak int32
-		expectedErrors int32
+		expectedDays   int32
 	}{
-		// Помню - прогресс вперёд
-		{"New→Learning", models.StatusNew, 0, models.RatingRemember, models.StatusLearning, 1, 1, 0},
-		{"Learning→Reviewing", models.StatusLearning, 0, models.RatingRemember, models.StatusReviewing, 3, 2, 0},
-		{"Reviewing→Reviewing(7d)", models.StatusReviewing, 0, models.RatingRemember, models.StatusReviewing, 7, 3, 0},
-		{"Mastered→Mastered(30d)", models.StatusMastered, 0, models.RatingRemember, models.StatusMastered, 30, 5, 0},
-		
-		// Не помню - сброс на learning
-		{"New→Learning(forgot)", models.StatusNew, 0, models.RatingForgot, models.StatusLearning, 1, 0, 1},
-		{"Learning→Learning(forgot)", models.StatusLearning, 0, models.RatingForgot, models.StatusLearning, 1, 0, 1},
-		{"Reviewing→Learning(forgot)", models.StatusReviewing, 2, models.RatingForgot, models.StatusLearning, 1, 0, 3},
-		{"Mastered→Learning(forgot)", models.StatusMastered, 0, models.RatingForgot, models.StatusLearning, 1, 0, 1},
+		{"Again", models.RatingAgain, models.StatusLearning, 1},
+		{"Hard", models.RatingHard, models.StatusLearning, 1},
+		{"Good", models.RatingGood, models.StatusLearning, 4},
+		{"Easy", models.RatingEasy, models.StatusLearning, 14},
 	}
 
+	scheduler := services.NewFSRSScheduler()
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			status, nextReview, streak, errorCount := services.CalculateSpacedRepetition(
-				tt.currentStatus,
-				tt.errorCount,
-				tt.rating,
-			)
-
-			assert.Equal(t, tt.expectedStatus, status)
-			assert.Equal(t, tt.expectedStreak, streak)
-			assert.Equal(t, tt.expectedErrors, errorCount)
-
-			expectedDate := time.Now().AddDate(0, 0, tt.expectedDays)
-			assert.InDelta(t, expectedDate.Unix(), nextReview.Unix(), 60)
+			next := scheduler.Schedule(models.CardProgress{Status: models.StatusNew}, tt.rating, time.Now())
+
+			assert.Equal(t, tt.expectedStatus, next.Status)
+			assert.Equal(t, tt.expectedDays, next.IntervalDays)
+			assert.Greater(t, next.Stability, 0.0)
+			assert.GreaterOrEqual(t, next.Difficulty, 1.0)
+			assert.LessOrEqual(t, next.Difficulty, 10.0)
 		})
 	}
 }
+
+func TestFSRSScheduler_IntervalsGrowUntilMastered(t *testing.T) {
+	scheduler := services.NewFSRSScheduler()
+	progress := models.CardProgress{Status: models.StatusNew}
+	now := time.Now()
+
+	// Отвечаем "good" точно в срок - интервал должен расти без потолка в 7 дней
+	var prevInterval int32
+	for i := 0; i < 4; i++ {
+		progress = scheduler.Schedule(progress, models.RatingGood, now)
+		assert.Greater(t, progress.IntervalDays, prevInterval)
+		prevInterval = progress.IntervalDays
+		now = *progress.NextReview
+	}
+
+	assert.Equal(t, models.StatusMastered, progress.Status)
+	assert.Equal(t, int32(4), progress.Streak)
+}
+
+func TestFSRSScheduler_LapseReducesStability(t *testing.T) {
+	scheduler := services.NewFSRSScheduler()
+	now := time.Now()
+	lastReview := now.AddDate(0, 0, -30)
+	progress := models.CardProgress{
+		Status:         models.StatusMastered,
+		Reps:           4,
+		IntervalDays:   30,
+		Stability:      30,
+		Difficulty:     5,
+		LastReviewedAt: &lastReview,
+	}
+
+	next := scheduler.Schedule(progress, models.RatingAgain, now)
+
+	assert.Equal(t, models.StatusLearning, next.Status)
+	assert.Equal(t, int32(1), next.IntervalDays)
+	assert.Less(t, next.Stability, progress.Stability)
+	assert.Greater(t, next.Difficulty, progress.Difficulty)
+	assert.Equal(t, int32(1), next.ErrorCount)
+}
+
+func TestFSRSScheduler_ContinuesFromSM2(t *testing.T) {
+	scheduler := services.NewFSRSScheduler()
+	now := time.Now()
+	lastReview := now.AddDate(0, 0, -30)
+	progress := models.CardProgress{
+		Status:         models.StatusMastered,
+		Reps:           5,
+		IntervalDays:   30,
+		Ease:           2.5,
+		LastReviewedAt: &lastReview,
+	}
+
+	next := scheduler.Schedule(progress, models.RatingGood, now)
+
+	assert.Equal(t, models.StatusMastered, next.Status)
+	assert.Greater(t, next.IntervalDays, progress.IntervalDays)
+	assert.Greater(t, next.Stability, 30.0)
+}
+
+func TestScheduler_InvalidRating(t *testing.T) {
+	progress := models.CardProgress{Status: models.StatusLearning, Reps: 1, IntervalDays: 1}
+
+	for _, scheduler := range []services.Scheduler{services.NewSM2Scheduler(), services.NewFSRSScheduler()} {
+		for _, rating := range []models.CardRating{0, models.RatingEasy + 1} {
+			assert.NotPanics(t, func() {
+				assert.Equal(t, progress, scheduler.Schedule(progress, rating, time.Now()))
+			})
+		}
+	}
+}
+
+func TestNewScheduler(t *testing.T) {
+	assert.IsType(t, &services.SM2Scheduler{}, services.NewScheduler(models.SchedulerSM2))
+	assert.IsType(t, &services.FSRSScheduler{}, services.NewScheduler(models.SchedulerFSRS))
+	assert.IsType(t, &services.SM2Scheduler{}, services.NewScheduler(""))
+}
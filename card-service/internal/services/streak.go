@@ -0,0 +1,130 @@
+package services
+
+import (
+	"time"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+)
+
+const dateLayout = "2006-01-02"
+
+// Streak is the user's study streak as of a given day.
+type Streak struct {
+	Current             int32
+	Longest             int32
+	GoalMetToday        bool
+	FrozenDays          []string
+	FreezesLeftThisWeek int32
+}
+
+// userLocation returns the location for an IANA timezone name, UTC if it is unknown.
+func userLocation(timezone string) *time.Location {
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// LocalDate returns the date (YYYY-MM-DD) it is at t in the given timezone.
+func LocalDate(t time.Time, timezone string) string {
+	return t.In(userLocation(timezone)).Format(dateLayout)
+}
+
+// DailyGoalMet reports whether a day of study counts towards the streak.
+func DailyGoalMet(day models.StudyDay, goalType models.DailyGoalType, goal int32) bool {
+	if goal < 1 {
+		goal = 1
+	}
+	if goalType == models.DailyGoalMinutes {
+		return day.TimeSpentSeconds >= int64(goal)*60
+	}
+	return day.CardsStudied >= goal
+}
+
+func weekKey(day time.Time) int {
+	year, week := day.ISOWeek()
+	return year*100 + week
+}
+
+// ComputeStreak counts consecutive days, up to today, on which the daily goal was met.
+// Days are YYYY-MM-DD dates in the user's timezone and today is taken by its
+// calendar date. Today not being done yet does not break the streak. A run of missed
+// days is covered by freezes only if every ISO week it touches has enough of its
+// StreakFreezesPerWeek left; frozen days keep the streak alive but do not add to it.
+func ComputeStreak(days []models.StudyDay, today time.Time, settings models.UserSettings) Streak {
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	todayKey := today.Format(dateLayout)
+
+	var first time.Time
+	met := make(map[string]bool, len(days))
+	for _, day := range days {
+		if !DailyGoalMet(day, settings.DailyGoalType, settings.DailyGoal) {
+			continue
+		}
+		date, err := time.Parse(dateLayout, day.Date)
+		if err != nil || date.After(today) {
+			continue
+		}
+		met[day.Date] = true
+		if first.IsZero() || date.Before(first) {
+			first = date
+		}
+	}
+
+	streak := Streak{GoalMetToday: met[todayKey], FrozenDays: []string{}}
+	used := make(map[int]int32)
+
+	var run, longest int32
+	var gap []time.Time
+	// bridge decides whether the missed days in gap end the current run
+	bridge := func() {
+		defer func() { gap = nil }()
+		if run == 0 {
+			return
+		}
+
+		needed := make(map[int]int32)
+		for _, day := range gap {
+			needed[weekKey(day)]++
+		}
+		for week, n := range needed {
+			if used[week]+n > settings.StreakFreezesPerWeek {
+				run = 0
+				streak.FrozenDays = []string{}
+				return
+			}
+		}
+		for week, n := range needed {
+			used[week] += n
+		}
+		for _, day := range gap {
+			streak.FrozenDays = append(streak.FrozenDays, day.Format(dateLayout))
+		}
+	}
+
+	if !first.IsZero() {
+		for day := first; !day.After(today); day = day.AddDate(0, 0, 1) {
+			if met[day.Format(dateLayout)] {
+				if len(gap) > 0 {
+					bridge()
+				}
+				run++
+				longest = max(longest, run)
+				continue
+			}
+			if day.Equal(today) {
+				break
+			}
+			gap = append(gap, day)
+		}
+		if len(gap) > 0 {
+			bridge()
+		}
+	}
+
+	streak.Current = run
+	streak.Longest = longest
+	streak.FreezesLeftThisWeek = max(settings.StreakFreezesPerWeek-used[weekKey(today)], 0)
+	return streak
+}
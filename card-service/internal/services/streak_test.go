@@ -0,0 +1,89 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func studyDays(dates ...string) []models.StudyDay {
+	days := make([]models.StudyDay, 0, len(dates))
+	for _, date := range dates {
+		days = append(days, models.StudyDay{Date: date, CardsStudied: 10, TimeSpentSeconds: 600})
+	}
+	return days
+}
+
+func TestComputeStreak(t *testing.T) {
+	// Wednesday
+	today := time.Date(2024, 5, 15, 18, 0, 0, 0, time.UTC)
+	settings := *models.DefaultUserSettings()
+	withFreeze := settings
+	withFreeze.StreakFreezesPerWeek = 1
+
+	tests := []struct {
+		name            string
+		days            []models.StudyDay
+		settings        models.UserSettings
+		expectedCurrent int32
+		expectedLongest int32
+		expectedToday   bool
+		expectedFrozen  []string
+	}{
+		{"No history", nil, settings, 0, 0, false, []string{}},
+		{"Studied today only", studyDays("2024-05-15"), settings, 1, 1, true, []string{}},
+		{"Today not done yet keeps the streak", studyDays("2024-05-13", "2024-05-14"), settings, 2, 2, false, []string{}},
+		{"Missed yesterday breaks the streak", studyDays("2024-05-12", "2024-05-13"), settings, 0, 2, false, []string{}},
+		{"Gap in the week is not a streak", studyDays("2024-05-09", "2024-05-11", "2024-05-13", "2024-05-15"), settings, 1, 1, true, []string{}},
+		{"Freeze covers a missed day", studyDays("2024-05-12", "2024-05-13", "2024-05-15"), withFreeze, 3, 3, true, []string{"2024-05-14"}},
+		{"One freeze does not cover two days", studyDays("2024-05-11", "2024-05-12", "2024-05-15"), withFreeze, 1, 2, true, []string{}},
+		{"Freezes are per ISO week", studyDays("2024-05-11", "2024-05-13", "2024-05-15"), withFreeze, 3, 3, true, []string{"2024-05-12", "2024-05-14"}},
+		{"Second miss in a week breaks the streak", studyDays("2024-05-09", "2024-05-11", "2024-05-13", "2024-05-15"), withFreeze, 2, 2, true, []string{"2024-05-14"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streak := services.ComputeStreak(tt.days, today, tt.settings)
+			assert.Equal(t, tt.expectedCurrent, streak.Current)
+			assert.Equal(t, tt.expectedLongest, streak.Longest)
+			assert.Equal(t, tt.expectedToday, streak.GoalMetToday)
+			assert.Equal(t, tt.expectedFrozen, streak.FrozenDays)
+		})
+	}
+}
+
+func TestComputeStreak_DailyGoal(t *testing.T) {
+	today := time.Date(2024, 5, 15, 9, 0, 0, 0, time.UTC)
+	days := []models.StudyDay{
+		{Date: "2024-05-13", CardsStudied: 30, TimeSpentSeconds: 900},
+		{Date: "2024-05-14", CardsStudied: 5, TimeSpentSeconds: 120},
+		{Date: "2024-05-15", CardsStudied: 25, TimeSpentSeconds: 660},
+	}
+
+	cards := models.UserSettings{DailyGoalType: models.DailyGoalCards, DailyGoal: 20}
+	assert.Equal(t, int32(1), services.ComputeStreak(days, today, cards).Current)
+
+	minutes := models.UserSettings{DailyGoalType: models.DailyGoalMinutes, DailyGoal: 2}
+	assert.Equal(t, int32(3), services.ComputeStreak(days, today, minutes).Current)
+}
+
+func TestComputeStreak_FreezesLeftThisWeek(t *testing.T) {
+	today := time.Date(2024, 5, 15, 9, 0, 0, 0, time.UTC)
+	settings := models.UserSettings{DailyGoalType: models.DailyGoalCards, DailyGoal: 1, StreakFreezesPerWeek: 2}
+
+	streak := services.ComputeStreak(studyDays("2024-05-12", "2024-05-13", "2024-05-15"), today, settings)
+
+	assert.Equal(t, int32(1), streak.FreezesLeftThisWeek)
+}
+
+func TestLocalDate(t *testing.T) {
+	instant := time.Date(2024, 5, 15, 22, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, "2024-05-15", services.LocalDate(instant, "UTC"))
+	assert.Equal(t, "2024-05-16", services.LocalDate(instant, "Europe/Moscow"))
+	assert.Equal(t, "2024-05-15", services.LocalDate(instant, "America/New_York"))
+	assert.Equal(t, "2024-05-15", services.LocalDate(instant, "Not/AZone"))
+}
@@ -0,0 +1,50 @@
+package services
+
+import (
+	"time"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+)
+
+// SummarizeStudySession builds the summary of a finished session from its card queue
+// and the wall-clock time stored when it was finished.
+// Accuracy is the percentage of answers that were not "again"; a card answered several
+// times counts once in CardsReviewed and each time in Answers.
+func SummarizeStudySession(sessionID string, startedAt, finishedAt time.Time, elapsedSeconds int64, queue []models.StudySessionCard) *models.StudySessionSummary {
+	summary := &models.StudySessionSummary{
+		SessionID:      sessionID,
+		TotalCards:     int32(len(queue)),
+		ElapsedSeconds: max(elapsedSeconds, 0),
+		Transitions:    []models.StatusTransition{},
+		StartedAt:      startedAt,
+		FinishedAt:     finishedAt,
+	}
+
+	transitions := make(map[[2]models.CardStatus]int)
+	for _, card := range queue {
+		if card.Attempts == 0 {
+			continue
+		}
+		summary.CardsReviewed++
+		summary.Answers += card.Attempts
+		summary.CorrectAnswers += card.CorrectAttempts
+		summary.TimeSpentMs += card.TimeSpentMs
+
+		if card.StatusBefore == nil || card.StatusAfter == nil {
+			continue
+		}
+		key := [2]models.CardStatus{*card.StatusBefore, *card.StatusAfter}
+		if i, ok := transitions[key]; ok {
+			summary.Transitions[i].Count++
+			continue
+		}
+		transitions[key] = len(summary.Transitions)
+		summary.Transitions = append(summary.Transitions, models.StatusTransition{From: key[0], To: key[1], Count: 1})
+	}
+
+	if summary.Answers > 0 {
+		summary.Accuracy = float32(summary.CorrectAnswers) / float32(summary.Answers) * 100
+	}
+
+	return summary
+}
@@ -0,0 +1,72 @@
+package services_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func statusPtr(status models.CardStatus) *models.CardStatus {
+	return &status
+}
+
+func TestSummarizeStudySession(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	finish := start.Add(5*time.Minute + 30*time.Second)
+
+	queue := []models.StudySessionCard{
+		{CardID: "a", Position: 0, StatusBefore: statusPtr(models.StatusNew), StatusAfter: statusPtr(models.StatusLearning), Attempts: 1, CorrectAttempts: 1, TimeSpentMs: 4000},
+		{CardID: "b", Position: 1, StatusBefore: statusPtr(models.StatusNew), StatusAfter: statusPtr(models.StatusLearning), Attempts: 2, CorrectAttempts: 1, TimeSpentMs: 9000},
+		{CardID: "c", Position: 2, StatusBefore: statusPtr(models.StatusReviewing), StatusAfter: statusPtr(models.StatusMastered), Attempts: 1, CorrectAttempts: 1, TimeSpentMs: 3000},
+		{CardID: "d", Position: 3},
+	}
+
+	summary := services.SummarizeStudySession("session", start, finish, 330, queue)
+
+	assert.Equal(t, int32(4), summary.TotalCards)
+	assert.Equal(t, int32(3), summary.CardsReviewed)
+	assert.Equal(t, int32(4), summary.Answers)
+	assert.Equal(t, int32(3), summary.CorrectAnswers)
+	assert.InDelta(t, 75, summary.Accuracy, 0.01)
+	assert.Equal(t, int64(330), summary.ElapsedSeconds)
+	assert.Equal(t, int64(16000), summary.TimeSpentMs)
+	assert.Equal(t, []models.StatusTransition{
+		{From: models.StatusNew, To: models.StatusLearning, Count: 2},
+		{From: models.StatusReviewing, To: models.StatusMastered, Count: 1},
+	}, summary.Transitions)
+}
+
+func TestSummarizeStudySession_NothingAnswered(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	summary := services.SummarizeStudySession("session", start, start, 0, []models.StudySessionCard{{CardID: "a"}})
+
+	assert.Equal(t, int32(1), summary.TotalCards)
+	assert.Zero(t, summary.CardsReviewed)
+	assert.Zero(t, summary.Accuracy)
+	assert.Empty(t, summary.Transitions)
+}
+
+func TestStudyTimeSeconds(t *testing.T) {
+	tests := []struct {
+		name        string
+		timeSpentMs int64
+		expected    int64
+	}{
+		{"Negative", -100, 0},
+		{"Zero", 0, 0},
+		{"Rounds down", 3400, 3},
+		{"Rounds up", 3600, 4},
+		{"Under a minute stays in seconds", 45000, 45},
+		{"Capped", (10 * time.Minute).Milliseconds(), int64(services.MaxAnswerTime.Seconds())},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, services.StudyTimeSeconds(tt.timeSpentMs))
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	MaxTagsPerSet = 10
+	MaxTagLength  = 32
+)
+
+// ErrInvalidTags is returned, wrapped with the reason, when tags can't be accepted.
+var ErrInvalidTags = errors.New("invalid tags")
+
+// NormalizeTag brings a tag to the form it is stored in: lower case, without a
+// leading '#', with inner whitespace turned into '-'. Empty means there is no tag.
+func NormalizeTag(tag string) string {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+	return strings.ToLower(strings.Join(strings.Fields(tag), "-"))
+}
+
+// NormalizeTags normalizes the tags of a set, dropping empty ones and
+// duplicates while keeping their order.
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := make([]string, 0, len(tags))
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = NormalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > MaxTagLength {
+			return nil, fmt.Errorf("%w: tag %q is longer than %d characters", ErrInvalidTags, tag, MaxTagLength)
+		}
+		for _, r := range tag {
+			if !unicode.IsLetter(r) && !unicode.IsDigit(r) && !strings.ContainsRune("-_+.#&", r) {
+				return nil, fmt.Errorf("%w: tag %q contains %q", ErrInvalidTags, tag, r)
+			}
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	if len(normalized) > MaxTagsPerSet {
+		return nil, fmt.Errorf("%w: a set can have at most %d tags", ErrInvalidTags, MaxTagsPerSet)
+	}
+	return normalized, nil
+}
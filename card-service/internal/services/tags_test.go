@@ -0,0 +1,64 @@
+package services_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/services"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeTag(t *testing.T) {
+	tests := []struct {
+		tag      string
+		expected string
+	}{
+		{"English", "english"},
+		{"  #Grammar ", "grammar"},
+		{"Phrasal   verbs", "phrasal-verbs"},
+		{"Английский", "английский"},
+		{"C++", "c++"},
+		{" # ", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			assert.Equal(t, tt.expected, services.NormalizeTag(tt.tag))
+		})
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	tags, err := services.NormalizeTags([]string{"English", "english", "", "#IELTS", "Vocabulary"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"english", "ielts", "vocabulary"}, tags)
+
+	tags, err = services.NormalizeTags(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{}, tags)
+}
+
+func TestNormalizeTags_Invalid(t *testing.T) {
+	tooMany := make([]string, services.MaxTagsPerSet+1)
+	for i := range tooMany {
+		tooMany[i] = strings.Repeat("a", i+1)
+	}
+
+	tests := []struct {
+		name string
+		tags []string
+	}{
+		{"Too many", tooMany},
+		{"Too long", []string{strings.Repeat("я", services.MaxTagLength+1)}},
+		{"Bad characters", []string{"a/b"}},
+		{"Quotes", []string{`"tag"`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := services.NormalizeTags(tt.tags)
+			assert.ErrorIs(t, err, services.ErrInvalidTags)
+		})
+	}
+}
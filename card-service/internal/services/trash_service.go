@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
+	"github.com/karto4ki/karto4ki-backend/card-service/internal/storage"
+)
+
+// ErrSetDeleted is returned when restoring a card whose set is in the trash;
+// the set has to be restored first.
+var ErrSetDeleted = errors.New("set is in the trash")
+
+// TrashService restores deleted sets and cards and purges those deleted longer
+// than the retention period ago.
+type TrashService struct {
+	trashStorage storage.TrashStorage
+	authz        *Authorizer
+	retention    time.Duration
+}
+
+func NewTrashService(trashStorage storage.TrashStorage, authz *Authorizer, retention time.Duration) *TrashService {
+	return &TrashService{trashStorage: trashStorage, authz: authz, retention: retention}
+}
+
+// GetTrash lists the sets the user deleted and the cards deleted from sets they
+// own or edit, most recently deleted first.
+func (s *TrashService) GetTrash(ctx context.Context, userID string, offset, limit int32) ([]models.TrashItem, error) {
+	items, err := s.trashStorage.List(ctx, userID, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range items {
+		items[i].PurgeAt = items[i].DeletedAt.Add(s.retention)
+	}
+	return items, nil
+}
+
+// RestoreSet takes a set the user may manage out of the trash, together with
+// the cards it had when it was deleted.
+func (s *TrashService) RestoreSet(ctx context.Context, setID, userID string) (*models.CardSet, error) {
+	set, err := s.trashStorage.GetSet(ctx, setID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if err := s.authz.Check(ctx, set, userID, ActionManage); err != nil {
+		return nil, err
+	}
+
+	if err := s.trashStorage.RestoreSet(ctx, set.ID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return s.authz.Authorize(ctx, set.ID, userID, ActionManage)
+}
+
+// RestoreCard takes a card out of the trash back into its set, which the user
+// must be allowed to edit cards of.
+func (s *TrashService) RestoreCard(ctx context.Context, cardID, userID string) (*models.Card, error) {
+	card, err := s.trashStorage.GetCard(ctx, cardID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	if _, err := s.authz.Authorize(ctx, card.SetID, userID, ActionEditCards); err != nil {
+		if err == ErrNotFound {
+			if set, setErr := s.trashStorage.GetSet(ctx, card.SetID); setErr == nil &&
+				s.authz.Check(ctx, set, userID, ActionEditCards) == nil {
+				return nil, ErrSetDeleted
+			}
+		}
+		return nil, err
+	}
+
+	if err := s.trashStorage.RestoreCard(ctx, card.ID, userID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	return card, nil
+}
+
+// PurgeExpired removes sets and cards that have been in the trash for longer
+// than the retention period. It returns how many were removed.
+func (s *TrashService) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.trashStorage.Purge(ctx, time.Now().Add(-s.retention))
+}
@@ -3,6 +3,9 @@ package storage
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"github.com/karto4ki/karto4ki-backend/card-service/internal/models"
@@ -12,36 +15,180 @@ import (
 
 type CardSetStorage interface {
 	Create(ctx context.Context, set *models.CardSet) error
+	CreateWithCards(ctx context.Context, set *models.CardSet, cards []models.Card) error
 	GetByID(ctx context.Context, id string) (*models.CardSet, error)
-	GetByOwner(ctx context.Context, ownerID string, offset, limit int32) ([]models.CardSet, error)
+	GetByOwner(ctx context.Context, ownerID string, tags []string, offset, limit int32) ([]models.CardSet, error)
 	Update(ctx context.Context, set *models.CardSet) error
 	Delete(ctx context.Context, id string) error
-	GetPublic(ctx context.Context, query string, offset, limit int32) ([]models.CardSet, error)
+	Purge(ctx context.Context, id string) error
+	Search(ctx context.Context, params models.SearchParams) ([]models.SearchResult, error)
+	Clone(ctx context.Context, sourceID string, clone *models.CardSet) (int32, error)
+	GetCloneState(ctx context.Context, cloneID, originalID string) ([]models.ClonedCardState, error)
+	PullFromOriginal(ctx context.Context, cloneID, originalID string, add, update, remove []string) (*models.PullResult, error)
+	GetOwnerTags(ctx context.Context, ownerID string) ([]models.TagCount, error)
+	SuggestPublicTags(ctx context.Context, prefix string, limit int32) ([]models.TagCount, error)
 }
 
 type CardStorage interface {
-	Create(ctx context.Context, card *models.Card) error
+	Create(ctx context.Context, card *models.Card, editorID string) error
 	GetByID(ctx context.Context, id string) (*models.Card, error)
-	GetBySetID(ctx context.Context, setID string, offset, limit int32) ([]models.Card, error)
-	Update(ctx context.Context, card *models.Card) error
-	Delete(ctx context.Context, id string) error
+	GetBySetID(ctx context.Context, setID, userID string, offset, limit int32) ([]models.Card, error)
+	Update(ctx context.Context, card *models.Card, editorID string) error
+	Delete(ctx context.Context, id, editorID string) error
 	GetCountBySet(ctx context.Context, setID string) (int32, error)
-	GetCardsForStudy(ctx context.Context, setID string, sessionType models.SessionType, limit int32) ([]models.Card, error)
+	CreateAt(ctx context.Context, card *models.Card, index int32, editorID string) error
+	GetOrder(ctx context.Context, setID string) ([]string, error)
+	SetOrder(ctx context.Context, setID string, ids []string) error
+	GetCardsForStudy(ctx context.Context, setID, userID string, sessionType models.SessionType, inSetOrder bool, limit int32) ([]models.Card, error)
 	GetCardsForStudyAll(ctx context.Context, userID string, sessionType models.SessionType, limit int32) ([]models.Card, error)
-	GetCardsForQuiz(ctx context.Context, setID string, limit int32) ([]models.Card, error)
-	UpdateCardStatus(ctx context.Context, cardID string, status models.CardStatus, errorCount int32, lastRating models.CardRating, nextReview time.Time, streak int32) error
+	GetCardsForStudyFolder(ctx context.Context, folderID, userID string, sessionType models.SessionType, limit int32) ([]models.Card, error)
+	GetCardsForQuiz(ctx context.Context, setID, userID string, limit int32) ([]models.Card, error)
+	GetLeeches(ctx context.Context, setID, userID string) ([]models.Card, error)
+	GetAllBySetID(ctx context.Context, setID string) ([]models.Card, error)
+	GetByIDs(ctx context.Context, ids []string, userID string) ([]models.Card, error)
+	GetItems(ctx context.Context, ids []string, userID string) ([]models.Card, error)
+	GetExistingIDs(ctx context.Context, setID string, ids []string) ([]string, error)
+	CreateBatch(ctx context.Context, cards []models.Card, editorID string) error
+	UpdateBatch(ctx context.Context, setID string, cards []models.Card, editorID string) error
+	DeleteBatch(ctx context.Context, setID string, ids []string, editorID string) error
+	MoveBatch(ctx context.Context, setID, targetSetID string, ids []string, editorID string) error
+}
+
+type CardProgressStorage interface {
+	Get(ctx context.Context, userID, cardID string, item int16) (*models.CardProgress, error)
+	GetBySet(ctx context.Context, userID, setID string) ([]models.CardProgress, error)
+	RecordReview(ctx context.Context, progress *models.CardProgress, review *models.Review) error
+	Suspend(ctx context.Context, userID, cardID string, items []int16) error
+	Bury(ctx context.Context, userID, cardID string, items []int16, until time.Time) error
+	Unsuspend(ctx context.Context, userID, cardID string) error
+}
+
+type UserSettingsStorage interface {
+	Get(ctx context.Context, userID string) (*models.UserSettings, error)
+	Upsert(ctx context.Context, userID string, settings *models.UserSettings) error
 }
 
 type StudySessionStorage interface {
 	Create(ctx context.Context, session *models.StudySession) error
 	GetByID(ctx context.Context, id string) (*models.StudySession, error)
+	GetCards(ctx context.Context, sessionID string) ([]models.StudySessionCard, error)
+	HasCard(ctx context.Context, sessionID, cardID string, item int16) (bool, error)
+	NextItem(ctx context.Context, sessionID, cardID string) (int16, error)
+	Finish(ctx context.Context, id string) (time.Time, int64, error)
 	Delete(ctx context.Context, id string) error
 }
 
+type QuizSessionStorage interface {
+	Create(ctx context.Context, session *models.QuizSession) error
+	GetByID(ctx context.Context, id string) (*models.QuizSession, error)
+	AddAnswer(ctx context.Context, sessionID string, answer *models.QuizAnswerResult, expiresAt time.Time) (bool, error)
+	Finish(ctx context.Context, id string) error
+	DeleteExpired(ctx context.Context) (int64, error)
+	SaveResult(ctx context.Context, userID string, result *models.QuizResult) error
+	GetResults(ctx context.Context, setID, userID string, offset, limit int32) ([]models.QuizResult, error)
+}
+
 type StatisticsStorage interface {
-	GetSetStatistics(ctx context.Context, setID string) (*models.SetStatistics, error)
-	GetUserStatistics(ctx context.Context, userID string) (*models.UserStatistics, error)
-	RecordStudySession(ctx context.Context, userID, setID string, cardsStudied int32, timeSpentMinutes int32) error
+	GetSetStatistics(ctx context.Context, setID, userID, today string) (*models.SetStatistics, error)
+	GetUserStatistics(ctx context.Context, userID, today string) (*models.UserStatistics, error)
+	GetStudyDays(ctx context.Context, userID string) ([]models.StudyDay, error)
+	RecordStudySession(ctx context.Context, userID, setID, studyDate string, cardsStudied int32, timeSpentSeconds int64) error
+}
+
+type SetShareStorage interface {
+	Upsert(ctx context.Context, share *models.SetShare) error
+	Get(ctx context.Context, setID, userID string) (*models.SetShare, error)
+	GetBySet(ctx context.Context, setID string) ([]models.SetShare, error)
+	GetByUser(ctx context.Context, userID string, status models.ShareStatus, offset, limit int32) ([]models.SetShare, error)
+	UpdateRole(ctx context.Context, setID, userID string, role models.SetRole) (bool, error)
+	Respond(ctx context.Context, setID, userID string, status models.ShareStatus) (bool, error)
+	Delete(ctx context.Context, setID, userID string) (bool, error)
+}
+
+type FolderStorage interface {
+	Create(ctx context.Context, folder *models.Folder, allowed FolderDepthCheck) (bool, error)
+	GetByID(ctx context.Context, id string) (*models.Folder, error)
+	GetPath(ctx context.Context, id string) ([]models.Folder, error)
+	GetChildren(ctx context.Context, ownerID string, parentID *string) ([]models.Folder, error)
+	GetSets(ctx context.Context, ownerID string, folderID *string) ([]models.CardSet, error)
+	Rename(ctx context.Context, id, name string) error
+	Move(ctx context.Context, id string, parentID *string, allowed FolderDepthCheck) (bool, error)
+	Delete(ctx context.Context, id string) error
+	MoveSet(ctx context.Context, setID string, folderID *string) error
+}
+
+// FolderDepthCheck decides whether a folder spanning levels levels of folders,
+// counting itself, may be put into a folder parentLevels deep, counting the
+// parent; parentLevels is 0 at the top level.
+type FolderDepthCheck func(parentLevels, levels int) bool
+
+type SetViewStorage interface {
+	RecordView(ctx context.Context, setID, userID string, dedupWindow time.Duration) (bool, error)
+	Cleanup(ctx context.Context) (int64, error)
+	GetTrending(ctx context.Context, window, halfLife time.Duration, offset, limit int32) ([]models.TrendingSet, error)
+}
+
+type TrashStorage interface {
+	List(ctx context.Context, userID string, offset, limit int32) ([]models.TrashItem, error)
+	GetSet(ctx context.Context, id string) (*models.CardSet, error)
+	GetCard(ctx context.Context, id string) (*models.Card, error)
+	RestoreSet(ctx context.Context, id string) error
+	RestoreCard(ctx context.Context, id, editorID string) error
+	Purge(ctx context.Context, deletedBefore time.Time) (int64, error)
+}
+
+type RevisionStorage interface {
+	GetByID(ctx context.Context, id int64) (*models.CardRevision, error)
+	GetByCard(ctx context.Context, cardID string, offset, limit int32) ([]models.CardRevision, error)
+	GetBySet(ctx context.Context, setID string, offset, limit int32) ([]models.CardRevision, error)
+	RevertSet(ctx context.Context, setID string, at time.Time, editorID string) (*models.SetRevertResult, error)
+}
+
+type ReviewStorage interface {
+	GetByCard(ctx context.Context, userID, cardID string, offset, limit int32) ([]models.Review, error)
+	GetTotalsByItem(ctx context.Context, userID, cardID string) (map[int16]models.ReviewTotals, error)
+	GetByUser(ctx context.Context, userID string, after models.ReviewCursor, limit int32) ([]models.Review, error)
+}
+
+// withTx runs fn in a transaction, committing if it returns nil and rolling back otherwise.
+func withTx(ctx context.Context, db *postgres.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// richContent reads and writes the rich_content column of a card, which is
+// NULL for cards without rich content.
+type richContent struct {
+	content **models.RichContent
+}
+
+func (r richContent) Scan(src any) error {
+	*r.content = nil
+	switch data := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(data, r.content)
+	case string:
+		return json.Unmarshal([]byte(data), r.content)
+	}
+	return fmt.Errorf("cannot read rich content from %T", src)
+}
+
+func (r richContent) Value() (driver.Value, error) {
+	if *r.content == nil {
+		return nil, nil
+	}
+	return json.Marshal(*r.content)
 }
 
 type cardSetStorage struct {
@@ -53,17 +200,46 @@ func NewCardSetStorage(db *postgres.DB) CardSetStorage {
 }
 
 func (s *cardSetStorage) Create(ctx context.Context, set *models.CardSet) error {
-	query := `INSERT INTO card_sets (id, owner_id, name, description, is_public, created_at)
-			  VALUES ($1, $2, $3, $4, $5, $6)`
-	_, err := s.db.ExecContext(ctx, query, set.ID, set.OwnerID, set.Name, set.Description, set.IsPublic, set.CreatedAt)
+	query := `INSERT INTO card_sets (id, owner_id, name, description, is_public, tags, created_at, cloned_from_set_id)
+			  VALUES ($1, $2, $3, $4, $5, COALESCE($6, '{}'::text[]), $7, $8)`
+	_, err := s.db.ExecContext(ctx, query, set.ID, set.OwnerID, set.Name, set.Description, set.IsPublic, pq.Array(set.Tags), set.CreatedAt,
+		set.ClonedFromSetID)
 	return err
 }
 
+// CreateWithCards stores a new set together with its cards, all or nothing.
+func (s *cardSetStorage) CreateWithCards(ctx context.Context, set *models.CardSet, cards []models.Card) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		query := `INSERT INTO card_sets (id, owner_id, name, description, is_public, tags, created_at)
+				  VALUES ($1, $2, $3, $4, $5, COALESCE($6, '{}'::text[]), $7)`
+		if _, err := tx.ExecContext(ctx, query, set.ID, set.OwnerID, set.Name, set.Description, set.IsPublic, pq.Array(set.Tags), set.CreatedAt); err != nil {
+			return err
+		}
+
+		stmt, err := tx.PrepareContext(ctx, `INSERT INTO cards (id, set_id, front, back, image_url, audio_url, rich_content, created_at, position, updated_by)
+											 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for i, card := range cards {
+			if _, err := stmt.ExecContext(ctx, card.ID, set.ID, card.Front, card.Back, card.ImageURL, card.AudioURL, richContent{&card.Rich}, card.CreatedAt, i+1, set.OwnerID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func (s *cardSetStorage) GetByID(ctx context.Context, id string) (*models.CardSet, error) {
-	query := `SELECT id, owner_id, name, description, is_public, created_at FROM card_sets WHERE id = $1`
+	query := `SELECT id, owner_id, name, description, is_public, COALESCE(tags, '{}'),
+			  COALESCE(views_count, 0), COALESCE(clones_count, 0), created_at, cloned_from_set_id, synced_at, folder_id
+			  FROM card_sets WHERE id = $1 AND deleted_at IS NULL`
 	set := &models.CardSet{}
 	err := s.db.QueryRowContext(ctx, query, id).Scan(
-		&set.ID, &set.OwnerID, &set.Name, &set.Description, &set.IsPublic, &set.CreatedAt,
+		&set.ID, &set.OwnerID, &set.Name, &set.Description, &set.IsPublic, pq.Array(&set.Tags),
+		&set.ViewsCount, &set.ClonesCount, &set.CreatedAt, &set.ClonedFromSetID, &set.SyncedAt, &set.FolderID,
 	)
 	if err != nil {
 		return nil, err
@@ -71,10 +247,16 @@ func (s *cardSetStorage) GetByID(ctx context.Context, id string) (*models.CardSe
 	return set, nil
 }
 
-func (s *cardSetStorage) GetByOwner(ctx context.Context, ownerID string, offset, limit int32) ([]models.CardSet, error) {
-	query := `SELECT id, owner_id, name, description, is_public, created_at FROM card_sets 
-			  WHERE owner_id = $1 ORDER BY created_at DESC OFFSET $2 LIMIT $3`
-	rows, err := s.db.QueryContext(ctx, query, ownerID, offset, limit)
+// GetByOwner lists the owner's sets; with tags given, only sets having all of them.
+func (s *cardSetStorage) GetByOwner(ctx context.Context, ownerID string, tags []string, offset, limit int32) ([]models.CardSet, error) {
+	if tags == nil {
+		tags = []string{}
+	}
+	query := `SELECT id, owner_id, name, description, is_public, COALESCE(tags, '{}'),
+			  COALESCE(views_count, 0), COALESCE(clones_count, 0), created_at, cloned_from_set_id, synced_at, folder_id FROM card_sets 
+			  WHERE owner_id = $1 AND deleted_at IS NULL AND (cardinality($2::text[]) = 0 OR tags @> $2::text[])
+			  ORDER BY created_at DESC OFFSET $3 LIMIT $4`
+	rows, err := s.db.QueryContext(ctx, query, ownerID, pq.Array(tags), offset, limit)
 	if err != nil {
 		return nil, err
 	}
@@ -83,7 +265,8 @@ func (s *cardSetStorage) GetByOwner(ctx context.Context, ownerID string, offset,
 	var sets []models.CardSet
 	for rows.Next() {
 		var set models.CardSet
-		if err := rows.Scan(&set.ID, &set.OwnerID, &set.Name, &set.Description, &set.IsPublic, &set.CreatedAt); err != nil {
+		if err := rows.Scan(&set.ID, &set.OwnerID, &set.Name, &set.Description, &set.IsPublic, pq.Array(&set.Tags),
+			&set.ViewsCount, &set.ClonesCount, &set.CreatedAt, &set.ClonedFromSetID, &set.SyncedAt, &set.FolderID); err != nil {
 			return nil, err
 		}
 		sets = append(sets, set)
@@ -92,82 +275,320 @@ func (s *cardSetStorage) GetByOwner(ctx context.Context, ownerID string, offset,
 }
 
 func (s *cardSetStorage) Update(ctx context.Context, set *models.CardSet) error {
-	query := `UPDATE card_sets SET name = $1, description = $2, is_public = $3 WHERE id = $4`
-	_, err := s.db.ExecContext(ctx, query, set.Name, set.Description, set.IsPublic, set.ID)
+	query := `UPDATE card_sets SET name = $1, description = $2, is_public = $3, tags = COALESCE($4, '{}'::text[])
+			  WHERE id = $5 AND deleted_at IS NULL`
+	_, err := s.db.ExecContext(ctx, query, set.Name, set.Description, set.IsPublic, pq.Array(set.Tags), set.ID)
 	return err
 }
 
+// Delete moves the set to the trash. Its cards stay as they are and come back
+// with it on restore; Purge removes the set for good.
 func (s *cardSetStorage) Delete(ctx context.Context, id string) error {
+	query := `UPDATE card_sets SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
+	_, err := s.db.ExecContext(ctx, query, id)
+	return err
+}
+
+// Purge removes the set for good, without going through the trash. Its cards,
+// progress and study history go with it.
+func (s *cardSetStorage) Purge(ctx context.Context, id string) error {
 	query := `DELETE FROM card_sets WHERE id = $1`
 	_, err := s.db.ExecContext(ctx, query, id)
 	return err
 }
 
-func (s *cardSetStorage) GetPublic(ctx context.Context, query string, offset, limit int32) ([]models.CardSet, error) {
-	sqlQuery := `SELECT id, owner_id, name, description, is_public, created_at FROM card_sets 
-				 WHERE is_public = true AND name ILIKE $1 ORDER BY created_at DESC OFFSET $2 LIMIT $3`
-	rows, err := s.db.QueryContext(ctx, sqlQuery, "%"+query+"%", offset, limit)
+// Clone copies the source set with all its cards into clone and counts the
+// clone on the source, all or nothing. It returns the number of cards copied.
+func (s *cardSetStorage) Clone(ctx context.Context, sourceID string, clone *models.CardSet) (int32, error) {
+	var copied int64
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		query := `INSERT INTO card_sets (id, owner_id, name, description, is_public, tags, created_at, cloned_from_set_id, synced_at)
+				  VALUES ($1, $2, $3, $4, $5, COALESCE($6, '{}'::text[]), $7, $8, $7)`
+		if _, err := tx.ExecContext(ctx, query, clone.ID, clone.OwnerID, clone.Name, clone.Description, clone.IsPublic,
+			pq.Array(clone.Tags), clone.CreatedAt, sourceID); err != nil {
+			return err
+		}
+
+		result, err := tx.ExecContext(ctx, `INSERT INTO cards (id, set_id, front, back, image_url, audio_url, rich_content, created_at, origin_card_id, origin_hash, position, updated_by)
+											SELECT gen_random_uuid(), $1, front, back, image_url, audio_url, rich_content, $2, id,
+												   card_content_hash(front, back, image_url, audio_url, rich_content), position, $4
+											FROM cards WHERE set_id = $3 AND deleted_at IS NULL`, clone.ID, clone.CreatedAt, sourceID, clone.OwnerID)
+		if err != nil {
+			return err
+		}
+		if copied, err = result.RowsAffected(); err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE card_sets SET clones_count = COALESCE(clones_count, 0) + 1 WHERE id = $1`, sourceID)
+		return err
+	})
+	return int32(copied), err
+}
+
+// GetCloneState pairs the cards of the original with the cards copied from it.
+// Cards added to the clone by its owner have no origin and are left out.
+func (s *cardSetStorage) GetCloneState(ctx context.Context, cloneID, originalID string) ([]models.ClonedCardState, error) {
+	query := `SELECT o.id, o.front, o.back, o.image_url, o.audio_url, o.rich_content,
+					 COALESCE(card_content_hash(o.front, o.back, o.image_url, o.audio_url, o.rich_content) <> c.origin_hash, false),
+					 c.id, c.front, c.back, c.image_url, c.audio_url, c.rich_content,
+					 COALESCE(card_content_hash(c.front, c.back, c.image_url, c.audio_url, c.rich_content) <> c.origin_hash, false)
+			  FROM (SELECT * FROM cards WHERE set_id = $2 AND deleted_at IS NULL) o
+			  FULL JOIN (SELECT * FROM cards WHERE set_id = $1 AND origin_card_id IS NOT NULL AND deleted_at IS NULL) c ON c.origin_card_id = o.id
+			  ORDER BY COALESCE(o.created_at, c.created_at), COALESCE(o.id, c.id)`
+	rows, err := s.db.QueryContext(ctx, query, cloneID, originalID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var sets []models.CardSet
+	var states []models.ClonedCardState
 	for rows.Next() {
-		var set models.CardSet
-		if err := rows.Scan(&set.ID, &set.OwnerID, &set.Name, &set.Description, &set.IsPublic, &set.CreatedAt); err != nil {
+		var state models.ClonedCardState
+		var originalID, copyID, originalFront, originalBack, copyFront, copyBack sql.NullString
+		var original, copied models.CardContent
+		if err := rows.Scan(&originalID, &originalFront, &originalBack, &original.ImageURL, &original.AudioURL, richContent{&original.Rich}, &state.OriginalChanged,
+			&copyID, &copyFront, &copyBack, &copied.ImageURL, &copied.AudioURL, richContent{&copied.Rich}, &state.CopyChanged); err != nil {
 			return nil, err
 		}
-		sets = append(sets, set)
+		if originalID.Valid {
+			original.Front, original.Back = originalFront.String, originalBack.String
+			state.OriginalID, state.Original = originalID.String, &original
+		}
+		if copyID.Valid {
+			copied.Front, copied.Back = copyFront.String, copyBack.String
+			state.CopyID, state.Copy = copyID.String, &copied
+		}
+		states = append(states, state)
 	}
-	return sets, rows.Err()
+	return states, rows.Err()
 }
 
-type cardStorage struct {
-	db *postgres.DB
+// PullFromOriginal copies the given original cards to the end of the clone, overwrites
+// the copies of the cards in update with their current content and moves the
+// copies in remove to the trash. Copies are updated in place, so progress on them is kept.
+func (s *cardSetStorage) PullFromOriginal(ctx context.Context, cloneID, originalID string, add, update, remove []string) (*models.PullResult, error) {
+	result := &models.PullResult{}
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		affected := func(query string, args ...any) (int32, error) {
+			res, err := tx.ExecContext(ctx, query, args...)
+			if err != nil {
+				return 0, err
+			}
+			n, err := res.RowsAffected()
+			return int32(n), err
+		}
+
+		var err error
+		result.Added, err = affected(`INSERT INTO cards (id, set_id, front, back, image_url, audio_url, rich_content, created_at, origin_card_id, origin_hash, position, updated_by)
+									  SELECT gen_random_uuid(), $1, o.front, o.back, o.image_url, o.audio_url, o.rich_content, NOW(), o.id,
+											 card_content_hash(o.front, o.back, o.image_url, o.audio_url, o.rich_content),
+											 (SELECT COALESCE(MAX(position), 0) FROM cards WHERE set_id = $1)
+											 + ROW_NUMBER() OVER (ORDER BY o.position, o.created_at, o.id),
+											 (SELECT owner_id FROM card_sets WHERE id = $1)
+									  FROM cards o
+									  WHERE o.set_id = $2 AND o.id = ANY($3::uuid[]) AND o.deleted_at IS NULL
+										AND NOT EXISTS (SELECT 1 FROM cards c WHERE c.set_id = $1 AND c.origin_card_id = o.id AND c.deleted_at IS NULL)`,
+			cloneID, originalID, pq.Array(add))
+		if err != nil {
+			return err
+		}
+
+		result.Updated, err = affected(`UPDATE cards c
+										SET front = o.front, back = o.back, image_url = o.image_url, audio_url = o.audio_url, rich_content = o.rich_content,
+											origin_hash = card_content_hash(o.front, o.back, o.image_url, o.audio_url, o.rich_content),
+											updated_by = (SELECT owner_id FROM card_sets WHERE id = $1)
+										FROM cards o
+										WHERE c.set_id = $1 AND c.origin_card_id = o.id AND c.deleted_at IS NULL
+										  AND o.set_id = $2 AND o.id = ANY($3::uuid[]) AND o.deleted_at IS NULL`,
+			cloneID, originalID, pq.Array(update))
+		if err != nil {
+			return err
+		}
+
+		result.Removed, err = affected(`UPDATE cards SET deleted_at = NOW(), updated_by = (SELECT owner_id FROM card_sets WHERE id = $1)
+										WHERE set_id = $1 AND origin_card_id IS NOT NULL AND id = ANY($2::uuid[]) AND deleted_at IS NULL`,
+			cloneID, pq.Array(remove))
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `UPDATE card_sets SET synced_at = NOW() WHERE id = $1`, cloneID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
-func NewCardStorage(db *postgres.DB) CardStorage {
-	return &cardStorage{db: db}
+// GetOwnerTags returns every tag the owner uses with the number of their sets
+// carrying it, most used first.
+func (s *cardSetStorage) GetOwnerTags(ctx context.Context, ownerID string) ([]models.TagCount, error) {
+	query := `SELECT tag, COUNT(*) FROM card_sets, unnest(tags) AS tag
+			  WHERE owner_id = $1 AND deleted_at IS NULL
+			  GROUP BY tag ORDER BY COUNT(*) DESC, tag`
+	return s.queryTags(ctx, query, ownerID)
 }
 
-func (c *cardStorage) Create(ctx context.Context, card *models.Card) error {
-	query := `INSERT INTO cards (id, set_id, front, back, image_url, audio_url, status, error_count, created_at)
-			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
-	_, err := c.db.ExecContext(ctx, query, card.ID, card.SetID, card.Front, card.Back, card.ImageURL, card.AudioURL, card.Status, card.ErrorCount, card.CreatedAt)
-	return err
+// SuggestPublicTags returns tags of public sets starting with the prefix, most used first.
+func (s *cardSetStorage) SuggestPublicTags(ctx context.Context, prefix string, limit int32) ([]models.TagCount, error) {
+	query := `SELECT tag, COUNT(*) FROM card_sets, unnest(tags) AS tag
+			  WHERE is_public = true AND deleted_at IS NULL AND starts_with(tag, $1)
+			  GROUP BY tag ORDER BY COUNT(*) DESC, tag LIMIT $2`
+	return s.queryTags(ctx, query, prefix, limit)
 }
 
-func (c *cardStorage) GetByID(ctx context.Context, id string) (*models.Card, error) {
-	query := `SELECT id, set_id, front, back, image_url, audio_url, status, error_count, next_review, created_at FROM cards WHERE id = $1`
-	card := &models.Card{}
-	var nextReview sql.NullTime
-	err := c.db.QueryRowContext(ctx, query, id).Scan(
-		&card.ID, &card.SetID, &card.Front, &card.Back, &card.ImageURL, &card.AudioURL, &card.Status, &card.ErrorCount, &nextReview, &card.CreatedAt,
-	)
-	if nextReview.Valid {
-		card.NextReview = &nextReview.Time
-	}
+func (s *cardSetStorage) queryTags(ctx context.Context, query string, args ...any) ([]models.TagCount, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
-	return card, nil
+	defer rows.Close()
+
+	tags := []models.TagCount{}
+	for rows.Next() {
+		var tag models.TagCount
+		if err := rows.Scan(&tag.Tag, &tag.Count); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
 }
 
-func (c *cardStorage) GetBySetID(ctx context.Context, setID string, offset, limit int32) ([]models.Card, error) {
-	query := `SELECT id, set_id, front, back, image_url, audio_url, status, error_count, next_review, created_at FROM cards
-			  WHERE set_id = $1 ORDER BY created_at DESC OFFSET $2 LIMIT $3`
-	rows, err := c.db.QueryContext(ctx, query, setID, offset, limit)
+// Matched words in search highlights are wrapped in these markers; they can't
+// occur in card text, so the caller can escape the snippet and then mark it up.
+const (
+	HighlightStart = "\x02"
+	HighlightStop  = "\x03"
+)
+
+// searchQuery ranks public sets by how well their own text and their best
+// matching card match the query, boosted logarithmically by views and clones
+// so popular sets win among equally relevant ones. Snippets are built only for
+// the page being returned, as ts_headline is expensive. Text is indexed with
+// both the russian and english configurations, and search_headline marks it up
+// with the one it matched in.
+const searchQuery = `
+WITH q AS (
+	SELECT russian || english AS query, russian, english
+	FROM websearch_to_tsquery('russian', $1) AS russian, websearch_to_tsquery('english', $1) AS english
+),
+card_matches AS (
+	SELECT c.set_id, MAX(ts_rank(c.search_vector, q.query)) AS rank
+	FROM cards c, q
+	WHERE $1 <> '' AND c.deleted_at IS NULL AND c.search_vector @@ q.query
+	GROUP BY c.set_id
+),
+ranked AS (
+	SELECT cs.id, cs.owner_id, cs.name, cs.description, cs.is_public, COALESCE(cs.tags, '{}') AS tags,
+		   COALESCE(cs.views_count, 0) AS views_count, COALESCE(cs.clones_count, 0) AS clones_count,
+		   cs.created_at, cc.card_count, cm.set_id IS NOT NULL AS card_matched,
+		   (CASE WHEN $1 = '' THEN 1
+				 ELSE ts_rank(cs.search_vector, q.query) + 0.5 * COALESCE(cm.rank, 0) END)
+		   * (1 + 0.1 * ln(1 + COALESCE(cs.views_count, 0)) + 0.2 * ln(1 + COALESCE(cs.clones_count, 0))) AS rank
+	FROM card_sets cs
+	CROSS JOIN q
+	LEFT JOIN card_matches cm ON cm.set_id = cs.id
+	CROSS JOIN LATERAL (SELECT COUNT(*)::int AS card_count FROM cards WHERE set_id = cs.id AND deleted_at IS NULL) cc
+	WHERE cs.is_public = true AND cs.deleted_at IS NULL
+	  AND ($1 = '' OR cs.search_vector @@ q.query OR cm.set_id IS NOT NULL)
+	  AND (cardinality($2::text[]) = 0 OR cs.tags @> $2::text[])
+	  AND ($3::int IS NULL OR cc.card_count >= $3)
+	  AND ($4::int IS NULL OR cc.card_count <= $4)
+	  AND ($5::uuid IS NULL OR cs.owner_id = $5::uuid)
+	ORDER BY rank DESC, cs.created_at DESC
+	OFFSET $6 LIMIT $7
+)
+SELECT r.id, r.owner_id, r.name, r.description, r.is_public, r.tags, r.views_count, r.clones_count,
+	   r.created_at, r.card_count, r.rank,
+	   CASE WHEN $1 = '' THEN '' ELSE search_headline(r.name, q.russian, q.english, $8::text || ', HighlightAll=true') END,
+	   CASE WHEN $1 = '' THEN '' ELSE search_headline(COALESCE(r.description, ''), q.russian, q.english, $8) END,
+	   COALESCE(best.id::text, ''),
+	   COALESCE(search_headline(best.front, q.russian, q.english, $8::text || ', HighlightAll=true'), ''),
+	   COALESCE(search_headline(best.back, q.russian, q.english, $8), '')
+FROM ranked r
+CROSS JOIN q
+LEFT JOIN LATERAL (
+	SELECT c.id, c.front, c.back FROM cards c
+	WHERE r.card_matched AND c.set_id = r.id AND c.deleted_at IS NULL AND c.search_vector @@ q.query
+	ORDER BY ts_rank(c.search_vector, q.query) DESC
+	LIMIT 1
+) best ON true
+ORDER BY r.rank DESC, r.created_at DESC`
+
+const headlineOptions = "StartSel=" + HighlightStart + ", StopSel=" + HighlightStop +
+	`, MaxWords=25, MinWords=8, MaxFragments=2, FragmentDelimiter=" … "`
+
+func (s *cardSetStorage) Search(ctx context.Context, params models.SearchParams) ([]models.SearchResult, error) {
+	tags := params.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	var authorID *string
+	if params.AuthorID != "" {
+		authorID = &params.AuthorID
+	}
+
+	rows, err := s.db.QueryContext(ctx, searchQuery, params.Query, pq.Array(tags), params.MinCards, params.MaxCards,
+		authorID, params.Offset, params.Limit, headlineOptions)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	results := []models.SearchResult{}
+	for rows.Next() {
+		var r models.SearchResult
+		var card models.CardHighlight
+		if err := rows.Scan(&r.ID, &r.OwnerID, &r.Name, &r.Description, &r.IsPublic, pq.Array(&r.Tags), &r.ViewsCount, &r.ClonesCount,
+			&r.CreatedAt, &r.CardCount, &r.Rank, &r.Highlights.Name, &r.Highlights.Description,
+			&card.CardID, &card.Front, &card.Back); err != nil {
+			return nil, err
+		}
+		if card.CardID != "" {
+			r.Highlights.Card = &card
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+type cardStorage struct {
+	db *postgres.DB
+}
+
+func NewCardStorage(db *postgres.DB) CardStorage {
+	return &cardStorage{db: db}
+}
+
+// cardWithProgressColumns selects card content together with the learning
+// progress of one user (joined as p). Cards the user has never studied read as new.
+const cardWithProgressColumns = `c.id, c.set_id, c.front, c.back, c.image_url, c.audio_url, c.rich_content, i.item,
+	COALESCE(p.status, 'new'), COALESCE(p.error_count, 0), COALESCE(p.last_rating, 0), p.next_review,
+	COALESCE(p.lapses, 0), COALESCE(p.leech, FALSE), p.suspended_at IS NOT NULL, CASE WHEN p.buried_until > NOW() THEN p.buried_until END,
+	c.created_at`
+
+// availableItem leaves out the items the user suspended or buried, with the
+// user's progress joined as p.
+const availableItem = `AND p.suspended_at IS NULL AND (p.buried_until IS NULL OR p.buried_until <= NOW())`
+
+// cardItems gives a row for every review item of a card c as i.item, while
+// firstCardItem only gives its first item, the one lists show progress on.
+const (
+	cardItems     = `CROSS JOIN LATERAL unnest(card_items(c.rich_content)) AS i(item)`
+	firstCardItem = `CROSS JOIN LATERAL (SELECT (card_items(c.rich_content))[1] AS item) i`
+)
+
+func scanCardsWithProgress(rows *sql.Rows) ([]models.Card, error) {
 	var cards []models.Card
 	for rows.Next() {
 		var card models.Card
 		var nextReview sql.NullTime
-		if err := rows.Scan(&card.ID, &card.SetID, &card.Front, &card.Back, &card.ImageURL, &card.AudioURL, &card.Status, &card.ErrorCount, &nextReview, &card.CreatedAt); err != nil {
+		if err := rows.Scan(&card.ID, &card.SetID, &card.Front, &card.Back, &card.ImageURL, &card.AudioURL, richContent{&card.Rich}, &card.Item,
+			&card.Status, &card.ErrorCount, &card.LastRating, &nextReview,
+			&card.Lapses, &card.Leech, &card.Suspended, &card.BuriedUntil, &card.CreatedAt); err != nil {
 			return nil, err
 		}
 		if nextReview.Valid {
@@ -178,316 +599,912 @@ func (c *cardStorage) GetBySetID(ctx context.Context, setID string, offset, limi
 	return cards, rows.Err()
 }
 
-func (c *cardStorage) Update(ctx context.Context, card *models.Card) error {
-	query := `UPDATE cards SET front = $1, back = $2, image_url = $3, audio_url = $4, error_count = $5 WHERE id = $6`
-	_, err := c.db.ExecContext(ctx, query, card.Front, card.Back, card.ImageURL, card.AudioURL, card.ErrorCount, card.ID)
+// Create adds the card at the end of its set.
+func (c *cardStorage) Create(ctx context.Context, card *models.Card, editorID string) error {
+	query := `INSERT INTO cards (id, set_id, front, back, image_url, audio_url, rich_content, created_at, position, updated_by)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, (SELECT COALESCE(MAX(position), 0) + 1 FROM cards WHERE set_id = $2), $9)`
+	_, err := c.db.ExecContext(ctx, query, card.ID, card.SetID, card.Front, card.Back, card.ImageURL, card.AudioURL, richContent{&card.Rich}, card.CreatedAt, editorID)
+	return err
+}
+
+// GetByID returns the card content only; Status is always new.
+// Use CardProgressStorage to get the state for a particular user.
+func (c *cardStorage) GetByID(ctx context.Context, id string) (*models.Card, error) {
+	query := `SELECT id, set_id, front, back, image_url, audio_url, rich_content, created_at FROM cards WHERE id = $1 AND deleted_at IS NULL`
+	card := &models.Card{Status: models.StatusNew}
+	err := c.db.QueryRowContext(ctx, query, id).Scan(
+		&card.ID, &card.SetID, &card.Front, &card.Back, &card.ImageURL, &card.AudioURL, richContent{&card.Rich}, &card.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+func (c *cardStorage) GetBySetID(ctx context.Context, setID, userID string, offset, limit int32) ([]models.Card, error) {
+	query := `SELECT ` + cardWithProgressColumns + ` FROM cards c ` + firstCardItem + `
+			  LEFT JOIN card_progress p ON p.card_id = c.id AND p.user_id = $2 AND p.item = i.item
+			  WHERE c.set_id = $1 AND c.deleted_at IS NULL ORDER BY c.position, c.created_at, c.id OFFSET $3 LIMIT $4`
+	rows, err := c.db.QueryContext(ctx, query, setID, userID, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanCardsWithProgress(rows)
+}
+
+func (c *cardStorage) Update(ctx context.Context, card *models.Card, editorID string) error {
+	query := `UPDATE cards SET front = $1, back = $2, image_url = $3, audio_url = $4, rich_content = $5, updated_by = $7
+			  WHERE id = $6 AND deleted_at IS NULL`
+	_, err := c.db.ExecContext(ctx, query, card.Front, card.Back, card.ImageURL, card.AudioURL, richContent{&card.Rich}, card.ID, editorID)
 	return err
 }
 
-func (c *cardStorage) Delete(ctx context.Context, id string) error {
-	query := `DELETE FROM cards WHERE id = $1`
-	_, err := c.db.ExecContext(ctx, query, id)
+// Delete moves the card to the trash, keeping everyone's progress on it.
+func (c *cardStorage) Delete(ctx context.Context, id, editorID string) error {
+	query := `UPDATE cards SET deleted_at = NOW(), updated_by = $2 WHERE id = $1 AND deleted_at IS NULL`
+	_, err := c.db.ExecContext(ctx, query, id, editorID)
 	return err
 }
 
 func (c *cardStorage) GetCountBySet(ctx context.Context, setID string) (int32, error) {
-	query := `SELECT COUNT(*) FROM cards WHERE set_id = $1`
+	query := `SELECT COUNT(*) FROM cards WHERE set_id = $1 AND deleted_at IS NULL`
 	var count int32
 	err := c.db.QueryRowContext(ctx, query, setID).Scan(&count)
 	return count, err
 }
 
-func (c *cardStorage) GetCardsForStudy(ctx context.Context, setID string, sessionType models.SessionType, limit int32) ([]models.Card, error) {
-	var query string
-	switch sessionType {
-	case models.SessionTypeReview:
-		query = `SELECT id, set_id, front, back, image_url, audio_url, status, error_count, last_rating, next_review, created_at FROM cards
-				 WHERE set_id = $1 AND (next_review IS NULL OR next_review <= NOW())
-				 ORDER BY
-					CASE WHEN next_review IS NULL THEN 0 ELSE 1 END,
-					error_count DESC,
-					next_review ASC
-				 LIMIT $2`
-	case models.SessionTypeLearn:
-		query = `SELECT id, set_id, front, back, image_url, audio_url, status, error_count, last_rating, next_review, created_at FROM cards
-				 WHERE set_id = $1
-				 ORDER BY
-					last_rating ASC,
-					CASE WHEN next_review IS NULL OR next_review <= NOW() THEN 0 ELSE 1 END,
-					error_count DESC,
-					created_at ASC
-				 LIMIT $2`
-	case models.SessionTypeTest:
-		query = `SELECT id, set_id, front, back, image_url, audio_url, status, error_count, last_rating, next_review, created_at FROM cards
-				 WHERE set_id = $1 ORDER BY RANDOM() LIMIT $2`
-	case models.SessionTypeAudio:
-		query = `SELECT id, set_id, front, back, image_url, audio_url, status, error_count, last_rating, next_review, created_at FROM cards
-				 WHERE set_id = $1 AND audio_url IS NOT NULL ORDER BY RANDOM() LIMIT $2`
-	default:
-		query = `SELECT id, set_id, front, back, image_url, audio_url, status, error_count, last_rating, next_review, created_at FROM cards
-				 WHERE set_id = $1 ORDER BY last_rating ASC, error_count DESC, created_at ASC LIMIT $2`
-	}
+// CreateAt adds the card to its set at a zero-based index, moving the cards
+// from there on one place down. An index past the end appends the card.
+func (c *cardStorage) CreateAt(ctx context.Context, card *models.Card, index int32, editorID string) error {
+	return withTx(ctx, c.db, func(tx *sql.Tx) error {
+		var position int32
+		err := tx.QueryRowContext(ctx, `SELECT position FROM cards WHERE set_id = $1 AND deleted_at IS NULL
+										ORDER BY position, created_at, id OFFSET $2 LIMIT 1`, card.SetID, index).Scan(&position)
+		if err == sql.ErrNoRows {
+			err = tx.QueryRowContext(ctx, `SELECT COALESCE(MAX(position), 0) + 1 FROM cards WHERE set_id = $1`, card.SetID).Scan(&position)
+		} else if err == nil {
+			_, err = tx.ExecContext(ctx, `UPDATE cards SET position = position + 1 WHERE set_id = $1 AND position >= $2`, card.SetID, position)
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO cards (id, set_id, front, back, image_url, audio_url, rich_content, created_at, position, updated_by)
+									  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+			card.ID, card.SetID, card.Front, card.Back, card.ImageURL, card.AudioURL, richContent{&card.Rich}, card.CreatedAt, position, editorID)
+		return err
+	})
+}
 
-	rows, err := c.db.QueryContext(ctx, query, setID, limit)
+// GetOrder returns the ids of the cards in the set, in set order.
+func (c *cardStorage) GetOrder(ctx context.Context, setID string) ([]string, error) {
+	query := `SELECT id FROM cards WHERE set_id = $1 AND deleted_at IS NULL ORDER BY position, created_at, id`
+	rows, err := c.db.QueryContext(ctx, query, setID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var cards []models.Card
+	ids := []string{}
 	for rows.Next() {
-		var card models.Card
-		var nextReview sql.NullTime
-		if err := rows.Scan(&card.ID, &card.SetID, &card.Front, &card.Back, &card.ImageURL, &card.AudioURL, &card.Status, &card.ErrorCount, &card.LastRating, &nextReview, &card.CreatedAt); err != nil {
+		var id string
+		if err := rows.Scan(&id); err != nil {
 			return nil, err
 		}
-		if nextReview.Valid {
-			card.NextReview = &nextReview.Time
-		}
-		cards = append(cards, card)
+		ids = append(ids, id)
 	}
-	return cards, rows.Err()
+	return ids, rows.Err()
 }
 
-// GetCardsForStudyAll returns cards from ALL sets owned by the user for study
-func (c *cardStorage) GetCardsForStudyAll(ctx context.Context, userID string, sessionType models.SessionType, limit int32) ([]models.Card, error) {
-	setsQuery := `SELECT id FROM card_sets WHERE owner_id = $1`
-	setRows, err := c.db.QueryContext(ctx, setsQuery, userID)
-	if err != nil {
-		return nil, err
-	}
-	defer setRows.Close()
-
-	var setIDs []string
-	for setRows.Next() {
-		var setID string
-		if err := setRows.Scan(&setID); err != nil {
-			return nil, err
+// SetOrder numbers the cards of the set in the given order. It fails with
+// sql.ErrNoRows unless ids are exactly the cards in the set, e.g. when a card
+// was added or deleted since the order was read.
+func (c *cardStorage) SetOrder(ctx context.Context, setID string, ids []string) error {
+	return withTx(ctx, c.db, func(tx *sql.Tx) error {
+		var count int
+		if err := tx.QueryRowContext(ctx, `SELECT COUNT(*) FROM cards WHERE set_id = $1 AND deleted_at IS NULL`, setID).Scan(&count); err != nil {
+			return err
+		}
+		if count != len(ids) {
+			return sql.ErrNoRows
 		}
-		setIDs = append(setIDs, setID)
-	}
 
-	if len(setIDs) == 0 {
-		return []models.Card{}, nil
-	}
+		result, err := tx.ExecContext(ctx, `UPDATE cards c SET position = o.ord
+											FROM unnest($2::uuid[]) WITH ORDINALITY AS o(id, ord)
+											WHERE c.id = o.id AND c.set_id = $1 AND c.deleted_at IS NULL`, setID, pq.Array(ids))
+		if err != nil {
+			return err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n != int64(len(ids)) {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
 
-	var query string
+// studyOrder returns the filter and ordering used to pick cards for a session
+// type. Learn sessions in set order take the cards as the user arranged them.
+func studyOrder(sessionType models.SessionType, inSetOrder bool) (filter, order string) {
 	switch sessionType {
 	case models.SessionTypeReview:
-		query = `SELECT id, set_id, front, back, image_url, audio_url, status, error_count, last_rating, next_review, created_at FROM cards
-				 WHERE set_id = ANY($1) AND (next_review IS NULL OR next_review <= NOW())
-				 ORDER BY
-					CASE WHEN next_review IS NULL THEN 0 ELSE 1 END,
-					error_count DESC,
-					next_review ASC
-				 LIMIT $2`
+		return `AND (p.next_review IS NULL OR p.next_review <= NOW())`,
+			`CASE WHEN p.next_review IS NULL THEN 0 ELSE 1 END,
+					COALESCE(p.error_count, 0) DESC,
+					p.next_review ASC`
 	case models.SessionTypeLearn:
-		query = `SELECT id, set_id, front, back, image_url, audio_url, status, error_count, last_rating, next_review, created_at FROM cards
-				 WHERE set_id = ANY($1)
-				 ORDER BY
-					last_rating ASC,
-					CASE WHEN next_review IS NULL OR next_review <= NOW() THEN 0 ELSE 1 END,
-					error_count DESC,
-					created_at ASC
-				 LIMIT $2`
+		if inSetOrder {
+			return ``, `c.position, c.created_at, c.id, i.item`
+		}
+		return ``,
+			`COALESCE(p.last_rating, 0) ASC,
+					CASE WHEN p.next_review IS NULL OR p.next_review <= NOW() THEN 0 ELSE 1 END,
+					COALESCE(p.error_count, 0) DESC,
+					c.created_at ASC`
 	case models.SessionTypeTest:
-		query = `SELECT id, set_id, front, back, image_url, audio_url, status, error_count, last_rating, next_review, created_at FROM cards
-				 WHERE set_id = ANY($1) ORDER BY RANDOM() LIMIT $2`
+		return ``, `RANDOM()`
 	case models.SessionTypeAudio:
-		query = `SELECT id, set_id, front, back, image_url, audio_url, status, error_count, last_rating, next_review, created_at FROM cards
-				 WHERE set_id = ANY($1) AND audio_url IS NOT NULL ORDER BY RANDOM() LIMIT $2`
+		return `AND c.audio_url IS NOT NULL`, `RANDOM()`
 	default:
-		query = `SELECT id, set_id, front, back, image_url, audio_url, status, error_count, last_rating, next_review, created_at FROM cards
-				 WHERE set_id = ANY($1) ORDER BY last_rating ASC, error_count DESC, created_at ASC LIMIT $2`
+		return ``, `COALESCE(p.last_rating, 0) ASC, COALESCE(p.error_count, 0) DESC, c.created_at ASC`
 	}
+}
+
+func (c *cardStorage) GetCardsForStudy(ctx context.Context, setID, userID string, sessionType models.SessionType, inSetOrder bool, limit int32) ([]models.Card, error) {
+	filter, order := studyOrder(sessionType, inSetOrder)
+	query := `SELECT ` + cardWithProgressColumns + ` FROM cards c ` + cardItems + `
+			  LEFT JOIN card_progress p ON p.card_id = c.id AND p.user_id = $2 AND p.item = i.item
+			  WHERE c.set_id = $1 AND c.deleted_at IS NULL ` + availableItem + ` ` + filter + `
+			  ORDER BY ` + order + `
+			  LIMIT $3`
 
-	rows, err := c.db.QueryContext(ctx, query, pq.Array(setIDs), limit)
+	rows, err := c.db.QueryContext(ctx, query, setID, userID, limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var cards []models.Card
-	for rows.Next() {
-		var card models.Card
-		var nextReview sql.NullTime
-		if err := rows.Scan(&card.ID, &card.SetID, &card.Front, &card.Back, &card.ImageURL, &card.AudioURL, &card.Status, &card.ErrorCount, &card.LastRating, &nextReview, &card.CreatedAt); err != nil {
-			return nil, err
-		}
-		if nextReview.Valid {
-			card.NextReview = &nextReview.Time
-		}
-		cards = append(cards, card)
-	}
-	return cards, rows.Err()
+	return scanCardsWithProgress(rows)
 }
 
-func (c *cardStorage) GetCardsForQuiz(ctx context.Context, setID string, limit int32) ([]models.Card, error) {
-	query := `SELECT id, set_id, front, back, image_url, audio_url, status, error_count, last_rating, next_review, created_at FROM cards
-			  WHERE set_id = $1 ORDER BY RANDOM() LIMIT $2`
+// GetCardsForStudyAll returns cards from ALL sets owned by the user for study
+func (c *cardStorage) GetCardsForStudyAll(ctx context.Context, userID string, sessionType models.SessionType, limit int32) ([]models.Card, error) {
+	filter, order := studyOrder(sessionType, false)
+	query := `SELECT ` + cardWithProgressColumns + ` FROM cards c
+			  JOIN card_sets cs ON cs.id = c.set_id ` + cardItems + `
+			  LEFT JOIN card_progress p ON p.card_id = c.id AND p.user_id = $1 AND p.item = i.item
+			  WHERE (cs.owner_id = $1 OR EXISTS (
+				  SELECT 1 FROM set_shares sh WHERE sh.set_id = cs.id AND sh.user_id = $1 AND sh.status = 'accepted'
+			  )) AND cs.deleted_at IS NULL AND c.deleted_at IS NULL ` + availableItem + ` ` + filter + `
+			  ORDER BY ` + order + `
+			  LIMIT $2`
 
-	rows, err := c.db.QueryContext(ctx, query, setID, limit)
+	rows, err := c.db.QueryContext(ctx, query, userID, limit)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var cards []models.Card
-	for rows.Next() {
-		var card models.Card
-		var nextReview sql.NullTime
-		if err := rows.Scan(&card.ID, &card.SetID, &card.Front, &card.Back, &card.ImageURL, &card.AudioURL, &card.Status, &card.ErrorCount, &card.LastRating, &nextReview, &card.CreatedAt); err != nil {
-			return nil, err
-		}
-		if nextReview.Valid {
-			card.NextReview = &nextReview.Time
-		}
-		cards = append(cards, card)
-	}
-	return cards, rows.Err()
-}
-
-func (c *cardStorage) UpdateCardStatus(ctx context.Context, cardID string, status models.CardStatus, errorCount int32, lastRating models.CardRating, nextReview time.Time, streak int32) error {
-	query := `UPDATE cards SET status = $1, error_count = $2, last_rating = $3, next_review = $4 WHERE id = $5`
-	_, err := c.db.ExecContext(ctx, query, status, errorCount, lastRating, nextReview, cardID)
-	return err
+	return scanCardsWithProgress(rows)
 }
 
-type studySessionStorage struct {
-	db *postgres.DB
-}
+// maxFolderDepth bounds the walks up and down the folder tree, so that a
+// cycle, should one get in, cannot make them run forever. Folders are nested
+// at most services.MaxFolderDepth levels deep, which the walks cover in full.
+const maxFolderDepth = 100
 
-func NewStudySessionStorage(db *postgres.DB) StudySessionStorage {
-	return &studySessionStorage{db: db}
-}
+// folderTree selects the ids of a folder and all folders below it.
+var folderTree = `WITH RECURSIVE tree AS (
+				  SELECT id, 0 AS depth FROM folders WHERE id = $1
+				  UNION ALL
+				  SELECT f.id, t.depth + 1 FROM folders f JOIN tree t ON f.parent_id = t.id
+				  WHERE t.depth < ` + fmt.Sprint(maxFolderDepth) + `
+			  )`
 
-func (s *studySessionStorage) Create(ctx context.Context, session *models.StudySession) error {
-	query := `INSERT INTO study_sessions (id, set_id, user_id, session_type, created_at) VALUES ($1, $2, $3, $4, $5)`
-	_, err := s.db.ExecContext(ctx, query, session.ID, session.SetID, session.UserID, session.SessionType, session.CreatedAt)
-	return err
-}
+// GetCardsForStudyFolder returns cards from the sets in a folder and its subfolders for study
+func (c *cardStorage) GetCardsForStudyFolder(ctx context.Context, folderID, userID string, sessionType models.SessionType, limit int32) ([]models.Card, error) {
+	filter, order := studyOrder(sessionType, false)
+	query := folderTree + `
+			  SELECT ` + cardWithProgressColumns + ` FROM cards c
+			  JOIN card_sets cs ON cs.id = c.set_id ` + cardItems + `
+			  LEFT JOIN card_progress p ON p.card_id = c.id AND p.user_id = $2 AND p.item = i.item
+			  WHERE cs.folder_id IN (SELECT id FROM tree) AND cs.deleted_at IS NULL AND c.deleted_at IS NULL ` + availableItem + ` ` + filter + `
+			  ORDER BY ` + order + `
+			  LIMIT $3`
 
-func (s *studySessionStorage) GetByID(ctx context.Context, id string) (*models.StudySession, error) {
-	query := `SELECT id, set_id, user_id, session_type, created_at FROM study_sessions WHERE id = $1`
-	session := &models.StudySession{}
-	var setID sql.NullString
-	err := s.db.QueryRowContext(ctx, query, id).Scan(&session.ID, &setID, &session.UserID, &session.SessionType, &session.CreatedAt)
+	rows, err := c.db.QueryContext(ctx, query, folderID, userID, limit)
 	if err != nil {
 		return nil, err
 	}
-	if setID.Valid {
-		session.SetID = &setID.String
-	}
-	return session, nil
-}
+	defer rows.Close()
 
-func (s *studySessionStorage) Delete(ctx context.Context, id string) error {
-	query := `DELETE FROM study_sessions WHERE id = $1`
-	_, err := s.db.ExecContext(ctx, query, id)
-	return err
+	return scanCardsWithProgress(rows)
 }
 
-type statisticsStorage struct {
-	db *postgres.DB
-}
+// GetCardsForQuiz returns random review items of the set's cards, one entry
+// each, leaving out the ones the user suspended or buried.
+func (c *cardStorage) GetCardsForQuiz(ctx context.Context, setID, userID string, limit int32) ([]models.Card, error) {
+	query := `SELECT ` + cardWithProgressColumns + ` FROM cards c ` + cardItems + `
+			  LEFT JOIN card_progress p ON p.card_id = c.id AND p.user_id = $2 AND p.item = i.item
+			  WHERE c.set_id = $1 AND c.deleted_at IS NULL ` + availableItem + `
+			  ORDER BY RANDOM() LIMIT $3`
 
-func NewStatisticsStorage(db *postgres.DB) StatisticsStorage {
-	return &statisticsStorage{db: db}
+	rows, err := c.db.QueryContext(ctx, query, setID, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanCardsWithProgress(rows)
 }
 
-func (s *statisticsStorage) GetSetStatistics(ctx context.Context, setID string) (*models.SetStatistics, error) {
-	stats := &models.SetStatistics{SetID: setID}
+// GetLeeches returns the items of the set's cards that are leeches for the
+// user, most lapses first.
+func (c *cardStorage) GetLeeches(ctx context.Context, setID, userID string) ([]models.Card, error) {
+	query := `SELECT ` + cardWithProgressColumns + ` FROM cards c ` + cardItems + `
+			  JOIN card_progress p ON p.card_id = c.id AND p.user_id = $2 AND p.item = i.item
+			  WHERE c.set_id = $1 AND c.deleted_at IS NULL AND p.leech
+			  ORDER BY p.lapses DESC, c.position, c.created_at, c.id, i.item`
 
-	query := `SELECT
-			  COUNT(*) FILTER (WHERE status = 'new') as new_cards,
-			  COUNT(*) FILTER (WHERE status = 'learning') as learning_cards,
-			  COUNT(*) FILTER (WHERE status IN ('reviewing', 'mastered')) as learned_cards,
-			  COUNT(*) as total_cards
-			  FROM cards WHERE set_id = $1`
-	err := s.db.QueryRowContext(ctx, query, setID).Scan(&stats.NewCards, &stats.LearningCards, &stats.LearnedCards, &stats.TotalCards)
+	rows, err := c.db.QueryContext(ctx, query, setID, userID)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
 
-	if stats.TotalCards > 0 {
-		stats.MasteryPercentage = float32(stats.LearnedCards) / float32(stats.TotalCards) * 100
-	}
+	return scanCardsWithProgress(rows)
+}
 
-	historyQuery := `SELECT study_date, cards_studied, time_spent_minutes
-					 FROM study_history
-					 WHERE set_id = $1 AND study_date >= CURRENT_DATE - INTERVAL '7 days'
-					 ORDER BY study_date DESC`
-	rows, err := s.db.QueryContext(ctx, historyQuery, setID)
+// GetAllBySetID returns the content of every card in the set, in set order.
+func (c *cardStorage) GetAllBySetID(ctx context.Context, setID string) ([]models.Card, error) {
+	query := `SELECT id, set_id, front, back, image_url, audio_url, rich_content, created_at FROM cards
+			  WHERE set_id = $1 AND deleted_at IS NULL ORDER BY position, created_at, id`
+
+	rows, err := c.db.QueryContext(ctx, query, setID)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	var cards []models.Card
 	for rows.Next() {
-		var day models.StudyDay
-		if err := rows.Scan(&day.Date, &day.CardsStudied, &day.TimeSpentMinutes); err != nil {
+		card := models.Card{Status: models.StatusNew}
+		if err := rows.Scan(&card.ID, &card.SetID, &card.Front, &card.Back, &card.ImageURL, &card.AudioURL, richContent{&card.Rich}, &card.CreatedAt); err != nil {
 			return nil, err
 		}
-		stats.StudyHistory = append(stats.StudyHistory, day)
+		cards = append(cards, card)
 	}
+	return cards, rows.Err()
+}
 
-	return stats, nil
+// GetByIDs returns the given cards with the user's progress on their first
+// item, in no particular order. Cards in the trash, or in a set that is, are left out.
+func (c *cardStorage) GetByIDs(ctx context.Context, ids []string, userID string) ([]models.Card, error) {
+	return c.getByIDs(ctx, firstCardItem, ids, userID)
 }
 
-func (s *statisticsStorage) GetUserStatistics(ctx context.Context, userID string) (*models.UserStatistics, error) {
-	stats := &models.UserStatistics{}
+// GetItems is GetByIDs with an entry for every review item of the cards.
+func (c *cardStorage) GetItems(ctx context.Context, ids []string, userID string) ([]models.Card, error) {
+	return c.getByIDs(ctx, cardItems, ids, userID)
+}
 
-	query := `SELECT
-			  COUNT(DISTINCT cs.id) as total_sets,
-			  COUNT(DISTINCT c.id) as total_cards,
-			  COUNT(DISTINCT c.id) FILTER (WHERE c.status IN ('reviewing', 'mastered')) as learned_cards
-			  FROM card_sets cs
-			  LEFT JOIN cards c ON cs.id = c.set_id
-			  WHERE cs.owner_id = $1`
-	err := s.db.QueryRowContext(ctx, query, userID).Scan(&stats.TotalSets, &stats.TotalCards, &stats.LearnedCards)
+func (c *cardStorage) getByIDs(ctx context.Context, items string, ids []string, userID string) ([]models.Card, error) {
+	query := `SELECT ` + cardWithProgressColumns + ` FROM cards c
+			  JOIN card_sets cs ON cs.id = c.set_id ` + items + `
+			  LEFT JOIN card_progress p ON p.card_id = c.id AND p.user_id = $2 AND p.item = i.item
+			  WHERE c.id = ANY($1::uuid[]) AND c.deleted_at IS NULL AND cs.deleted_at IS NULL`
+	rows, err := c.db.QueryContext(ctx, query, pq.Array(ids), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanCardsWithProgress(rows)
+}
+
+// GetExistingIDs returns which of the given cards are in the set.
+func (c *cardStorage) GetExistingIDs(ctx context.Context, setID string, ids []string) ([]string, error) {
+	query := `SELECT id FROM cards WHERE set_id = $1 AND id = ANY($2::uuid[]) AND deleted_at IS NULL`
+	rows, err := c.db.QueryContext(ctx, query, setID, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var existing []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		existing = append(existing, id)
+	}
+	return existing, rows.Err()
+}
+
+// CreateBatch adds the cards to the end of their sets in the given order, all or nothing.
+func (c *cardStorage) CreateBatch(ctx context.Context, cards []models.Card, editorID string) error {
+	return withTx(ctx, c.db, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, `INSERT INTO cards (id, set_id, front, back, image_url, audio_url, rich_content, created_at, position, updated_by)
+											 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, (SELECT COALESCE(MAX(position), 0) + 1 FROM cards WHERE set_id = $2), $9)`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, card := range cards {
+			if _, err := stmt.ExecContext(ctx, card.ID, card.SetID, card.Front, card.Back, card.ImageURL, card.AudioURL, richContent{&card.Rich}, card.CreatedAt, editorID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UpdateBatch changes the content of cards in the set, all or nothing. It
+// fails with sql.ErrNoRows if any card is not in the set, and fills in the
+// set and creation time of the cards otherwise.
+func (c *cardStorage) UpdateBatch(ctx context.Context, setID string, cards []models.Card, editorID string) error {
+	return withTx(ctx, c.db, func(tx *sql.Tx) error {
+		stmt, err := tx.PrepareContext(ctx, `UPDATE cards SET front = $3, back = $4, image_url = $5, audio_url = $6, rich_content = $7, updated_by = $8
+											 WHERE id = $1 AND set_id = $2 AND deleted_at IS NULL RETURNING created_at`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for i := range cards {
+			card := &cards[i]
+			if err := stmt.QueryRowContext(ctx, card.ID, setID, card.Front, card.Back, card.ImageURL, card.AudioURL, richContent{&card.Rich}, editorID).Scan(&card.CreatedAt); err != nil {
+				return err
+			}
+			card.SetID = setID
+		}
+		return nil
+	})
+}
+
+// DeleteBatch moves cards of the set to the trash, all or nothing. It fails
+// with sql.ErrNoRows if any card is not in the set.
+func (c *cardStorage) DeleteBatch(ctx context.Context, setID string, ids []string, editorID string) error {
+	query := `UPDATE cards SET deleted_at = NOW(), updated_by = $3 WHERE set_id = $1 AND id = ANY($2::uuid[]) AND deleted_at IS NULL`
+	return c.execBatch(ctx, len(ids), query, setID, pq.Array(ids), editorID)
+}
+
+// MoveBatch moves cards of the set to the end of another set, in the given
+// order, together with everyone's progress on them, all or nothing. It fails
+// with sql.ErrNoRows if any card is not in the set.
+func (c *cardStorage) MoveBatch(ctx context.Context, setID, targetSetID string, ids []string, editorID string) error {
+	query := `UPDATE cards c SET set_id = $3, position = (SELECT COALESCE(MAX(position), 0) FROM cards WHERE set_id = $3) + o.ord, updated_by = $4
+			  FROM unnest($2::uuid[]) WITH ORDINALITY AS o(id, ord)
+			  WHERE c.id = o.id AND c.set_id = $1 AND c.deleted_at IS NULL`
+	return c.execBatch(ctx, len(ids), query, setID, pq.Array(ids), targetSetID, editorID)
+}
+
+// execBatch runs a statement that must affect exactly want rows, rolling it back otherwise.
+func (c *cardStorage) execBatch(ctx context.Context, want int, query string, args ...any) error {
+	return withTx(ctx, c.db, func(tx *sql.Tx) error {
+		result, err := tx.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if n != int64(want) {
+			return sql.ErrNoRows
+		}
+		return nil
+	})
+}
+
+type cardProgressStorage struct {
+	db *postgres.DB
+}
+
+func NewCardProgressStorage(db *postgres.DB) CardProgressStorage {
+	return &cardProgressStorage{db: db}
+}
+
+// Get returns the user's progress on a review item of the card. An item the user
+// has never studied yields a fresh progress in the new status rather than an error.
+func (s *cardProgressStorage) Get(ctx context.Context, userID, cardID string, item int16) (*models.CardProgress, error) {
+	query := `SELECT status, error_count, last_rating, next_review, streak,
+			  reps, interval_days, ease, stability, difficulty, last_reviewed_at, lapses, leech, suspended_at, buried_until, updated_at
+			  FROM card_progress WHERE user_id = $1 AND card_id = $2 AND item = $3`
+	progress := &models.CardProgress{UserID: userID, CardID: cardID, Item: item}
+	var nextReview, lastReviewedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, query, userID, cardID, item).Scan(
+		&progress.Status, &progress.ErrorCount, &progress.LastRating, &nextReview, &progress.Streak,
+		&progress.Reps, &progress.IntervalDays, &progress.Ease, &progress.Stability, &progress.Difficulty, &lastReviewedAt,
+		&progress.Lapses, &progress.Leech, &progress.SuspendedAt, &progress.BuriedUntil, &progress.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		progress.Status = models.StatusNew
+		return progress, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if nextReview.Valid {
+		progress.NextReview = &nextReview.Time
+	}
+	if lastReviewedAt.Valid {
+		progress.LastReviewedAt = &lastReviewedAt.Time
+	}
+	return progress, nil
+}
+
+// GetBySet returns the user's progress on the first item of each card of a set;
+// cards never studied have no entry.
+func (s *cardProgressStorage) GetBySet(ctx context.Context, userID, setID string) ([]models.CardProgress, error) {
+	query := `SELECT p.card_id, p.item, p.status, p.error_count, p.last_rating, p.next_review, p.streak,
+			  p.reps, p.interval_days, p.ease, p.stability, p.difficulty, p.last_reviewed_at,
+			  p.lapses, p.leech, p.suspended_at, p.buried_until, p.updated_at
+			  FROM card_progress p
+			  JOIN cards c ON c.id = p.card_id
+			  WHERE p.user_id = $1 AND c.set_id = $2 AND c.deleted_at IS NULL
+			  AND p.item = (card_items(c.rich_content))[1]`
+	rows, err := s.db.QueryContext(ctx, query, userID, setID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.CardProgress
+	for rows.Next() {
+		progress := models.CardProgress{UserID: userID}
+		var nextReview, lastReviewedAt sql.NullTime
+		if err := rows.Scan(&progress.CardID, &progress.Item, &progress.Status, &progress.ErrorCount, &progress.LastRating, &nextReview, &progress.Streak,
+			&progress.Reps, &progress.IntervalDays, &progress.Ease, &progress.Stability, &progress.Difficulty, &lastReviewedAt,
+			&progress.Lapses, &progress.Leech, &progress.SuspendedAt, &progress.BuriedUntil, &progress.UpdatedAt); err != nil {
+			return nil, err
+		}
+		if nextReview.Valid {
+			progress.NextReview = &nextReview.Time
+		}
+		if lastReviewedAt.Valid {
+			progress.LastReviewedAt = &lastReviewedAt.Time
+		}
+		result = append(result, progress)
+	}
+	return result, rows.Err()
+}
+
+// RecordReview stores the progress on a review item together with the review
+// that led to it, which is appended to the review log and gets its id and time.
+// A quiz answer is reviewed once: if it has been already, nothing changes and
+// sql.ErrNoRows is returned. A study answer is also stored in its session, in
+// the same transaction, so a failed answer can be retried without being
+// counted twice.
+func (s *cardProgressStorage) RecordReview(ctx context.Context, progress *models.CardProgress, review *models.Review) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		query := `INSERT INTO card_progress (user_id, card_id, status, error_count, last_rating, next_review, streak,
+				  reps, interval_days, ease, stability, difficulty, last_reviewed_at, item, lapses, leech, suspended_at, updated_at)
+				  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, NOW())
+				  ON CONFLICT (user_id, card_id, item)
+				  DO UPDATE SET status = $3, error_count = $4, last_rating = $5, next_review = $6, streak = $7,
+				  reps = $8, interval_days = $9, ease = $10, stability = $11, difficulty = $12, last_reviewed_at = $13,
+				  lapses = $15, leech = $16, suspended_at = $17, updated_at = NOW()`
+		_, err := tx.ExecContext(ctx, query, progress.UserID, progress.CardID, progress.Status, progress.ErrorCount, progress.LastRating, progress.NextReview, progress.Streak,
+			progress.Reps, progress.IntervalDays, progress.Ease, progress.Stability, progress.Difficulty, progress.LastReviewedAt, progress.Item,
+			progress.Lapses, progress.Leech, progress.SuspendedAt)
+		if err != nil {
+			return err
+		}
+
+		logQuery := `INSERT INTO review_log (user_id, card_id, item, set_id, source, session_id, rating, status_before, status_after,
+					 previous_interval_days, interval_days, scheduler, response_time_ms)
+					 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+					 ON CONFLICT (session_id, card_id, item) WHERE source = 'quiz' DO NOTHING
+					 RETURNING id, reviewed_at`
+		err = tx.QueryRowContext(ctx, logQuery, review.UserID, review.CardID, review.Item, review.SetID, review.Source, review.SessionID, review.Rating,
+			review.StatusBefore, review.StatusAfter, review.PreviousIntervalDays, review.IntervalDays, review.Scheduler, review.ResponseTimeMs,
+		).Scan(&review.ID, &review.ReviewedAt)
+		if err != nil || review.Source != models.ReviewFromStudy {
+			return err
+		}
+		return recordStudyAnswer(ctx, tx, review)
+	})
+}
+
+// recordStudyAnswer stores the review as an answer to its item in the study
+// session. An item may be answered several times; the status before its first
+// answer is kept.
+func recordStudyAnswer(ctx context.Context, tx *sql.Tx, review *models.Review) error {
+	correct := 0
+	if review.Rating != models.RatingAgain {
+		correct = 1
+	}
+
+	query := `UPDATE study_session_cards
+			  SET status_before = COALESCE(status_before, $3),
+				  status_after = $4,
+				  last_rating = $5,
+				  attempts = attempts + 1,
+				  correct_attempts = correct_attempts + $6,
+				  time_spent_ms = time_spent_ms + $7,
+				  answered_at = NOW()
+			  WHERE session_id = $1 AND card_id = $2 AND item = $8`
+	_, err := tx.ExecContext(ctx, query, review.SessionID, review.CardID, review.StatusBefore, review.StatusAfter, review.Rating, correct,
+		review.ResponseTimeMs, review.Item)
+	return err
+}
+
+// Suspend leaves the items of the card out of the user's study and quizzes
+// until they are unsuspended. Items suspended already keep their time.
+func (s *cardProgressStorage) Suspend(ctx context.Context, userID, cardID string, items []int16) error {
+	query := `INSERT INTO card_progress (user_id, card_id, item, suspended_at)
+			  SELECT $1, $2, item, NOW() FROM unnest($3::smallint[]) AS t(item)
+			  ON CONFLICT (user_id, card_id, item)
+			  DO UPDATE SET suspended_at = COALESCE(card_progress.suspended_at, NOW()), updated_at = NOW()`
+	_, err := s.db.ExecContext(ctx, query, userID, cardID, pq.Array(items))
+	return err
+}
+
+// Bury leaves the items of the card out of the user's study and quizzes until the given time.
+func (s *cardProgressStorage) Bury(ctx context.Context, userID, cardID string, items []int16, until time.Time) error {
+	query := `INSERT INTO card_progress (user_id, card_id, item, buried_until)
+			  SELECT $1, $2, item, $4 FROM unnest($3::smallint[]) AS t(item)
+			  ON CONFLICT (user_id, card_id, item)
+			  DO UPDATE SET buried_until = $4, updated_at = NOW()`
+	_, err := s.db.ExecContext(ctx, query, userID, cardID, pq.Array(items), until)
+	return err
+}
+
+// Unsuspend brings every item of the card back to the user's study, suspended
+// or buried. Suspended leeches get a fresh start: they are leeches again only
+// after as many new lapses. Items that were only buried keep their lapses.
+func (s *cardProgressStorage) Unsuspend(ctx context.Context, userID, cardID string) error {
+	query := `UPDATE card_progress SET suspended_at = NULL, buried_until = NULL,
+			  leech = leech AND suspended_at IS NULL,
+			  lapses = CASE WHEN suspended_at IS NULL THEN lapses ELSE 0 END, updated_at = NOW()
+			  WHERE user_id = $1 AND card_id = $2`
+	_, err := s.db.ExecContext(ctx, query, userID, cardID)
+	return err
+}
+
+type userSettingsStorage struct {
+	db *postgres.DB
+}
+
+func NewUserSettingsStorage(db *postgres.DB) UserSettingsStorage {
+	return &userSettingsStorage{db: db}
+}
+
+// Get returns the user's settings, or the defaults if the user never changed them.
+func (s *userSettingsStorage) Get(ctx context.Context, userID string) (*models.UserSettings, error) {
+	query := `SELECT scheduler, timezone, daily_goal_type, daily_goal, streak_freezes_per_week, leech_threshold, suspend_leeches
+			  FROM user_settings WHERE user_id = $1`
+	settings := &models.UserSettings{}
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&settings.Scheduler, &settings.Timezone, &settings.DailyGoalType, &settings.DailyGoal, &settings.StreakFreezesPerWeek,
+		&settings.LeechThreshold, &settings.SuspendLeeches)
+	if err == sql.ErrNoRows {
+		return models.DefaultUserSettings(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return settings, nil
+}
+
+func (s *userSettingsStorage) Upsert(ctx context.Context, userID string, settings *models.UserSettings) error {
+	query := `INSERT INTO user_settings (user_id, scheduler, timezone, daily_goal_type, daily_goal, streak_freezes_per_week, leech_threshold, suspend_leeches, updated_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())
+			  ON CONFLICT (user_id)
+			  DO UPDATE SET scheduler = $2, timezone = $3, daily_goal_type = $4, daily_goal = $5, streak_freezes_per_week = $6,
+			  leech_threshold = $7, suspend_leeches = $8, updated_at = NOW()`
+	_, err := s.db.ExecContext(ctx, query, userID, settings.Scheduler, settings.Timezone, settings.DailyGoalType, settings.DailyGoal, settings.StreakFreezesPerWeek,
+		settings.LeechThreshold, settings.SuspendLeeches)
+	return err
+}
+
+type studySessionStorage struct {
+	db *postgres.DB
+}
+
+func NewStudySessionStorage(db *postgres.DB) StudySessionStorage {
+	return &studySessionStorage{db: db}
+}
+
+// Create stores the session together with its queue of review items in the order of session.Cards.
+func (s *studySessionStorage) Create(ctx context.Context, session *models.StudySession) error {
+	cardIDs := make([]string, 0, len(session.Cards))
+	items := make([]int64, 0, len(session.Cards))
+	for _, card := range session.Cards {
+		cardIDs = append(cardIDs, card.ID)
+		items = append(items, int64(card.Item))
+	}
+
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		query := `INSERT INTO study_sessions (id, set_id, folder_id, user_id, session_type, created_at) VALUES ($1, $2, $3, $4, $5, $6)`
+		if _, err := tx.ExecContext(ctx, query, session.ID, session.SetID, session.FolderID, session.UserID, session.SessionType, session.CreatedAt); err != nil {
+			return err
+		}
+
+		cardsQuery := `INSERT INTO study_session_cards (session_id, card_id, item, position)
+					   SELECT $1, card_id, item, position - 1
+					   FROM unnest($2::uuid[], $3::smallint[]) WITH ORDINALITY AS t(card_id, item, position)`
+		_, err := tx.ExecContext(ctx, cardsQuery, session.ID, pq.Array(cardIDs), pq.Array(items))
+		return err
+	})
+}
+
+func (s *studySessionStorage) GetByID(ctx context.Context, id string) (*models.StudySession, error) {
+	query := `SELECT id, set_id, folder_id, user_id, session_type, created_at, finished_at, elapsed_seconds FROM study_sessions WHERE id = $1`
+	session := &models.StudySession{}
+	var setID sql.NullString
+	var finishedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&session.ID, &setID, &session.FolderID, &session.UserID, &session.SessionType, &session.CreatedAt, &finishedAt,
+		&session.ElapsedSeconds)
+	if err != nil {
+		return nil, err
+	}
+	if setID.Valid {
+		session.SetID = &setID.String
+	}
+	if finishedAt.Valid {
+		session.FinishedAt = &finishedAt.Time
+	}
+	return session, nil
+}
+
+// GetCards returns the session's queue of review items in order.
+func (s *studySessionStorage) GetCards(ctx context.Context, sessionID string) ([]models.StudySessionCard, error) {
+	query := `SELECT card_id, item, position, status_before, status_after, last_rating, attempts, correct_attempts, time_spent_ms, answered_at
+			  FROM study_session_cards WHERE session_id = $1 ORDER BY position`
+	rows, err := s.db.QueryContext(ctx, query, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cards []models.StudySessionCard
+	for rows.Next() {
+		var card models.StudySessionCard
+		var statusBefore, statusAfter sql.NullString
+		var answeredAt sql.NullTime
+		if err := rows.Scan(&card.CardID, &card.Item, &card.Position, &statusBefore, &statusAfter, &card.LastRating,
+			&card.Attempts, &card.CorrectAttempts, &card.TimeSpentMs, &answeredAt); err != nil {
+			return nil, err
+		}
+		if statusBefore.Valid {
+			status := models.CardStatus(statusBefore.String)
+			card.StatusBefore = &status
+		}
+		if statusAfter.Valid {
+			status := models.CardStatus(statusAfter.String)
+			card.StatusAfter = &status
+		}
+		if answeredAt.Valid {
+			card.AnsweredAt = &answeredAt.Time
+		}
+		cards = append(cards, card)
+	}
+	return cards, rows.Err()
+}
+
+func (s *studySessionStorage) HasCard(ctx context.Context, sessionID, cardID string, item int16) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM study_session_cards WHERE session_id = $1 AND card_id = $2 AND item = $3)`
+	var exists bool
+	err := s.db.QueryRowContext(ctx, query, sessionID, cardID, item).Scan(&exists)
+	return exists, err
+}
+
+// NextItem returns the card's first item in the session queue that has not
+// been answered yet, or its first item if all have been. It returns
+// sql.ErrNoRows if the card is not in the session.
+func (s *studySessionStorage) NextItem(ctx context.Context, sessionID, cardID string) (int16, error) {
+	query := `SELECT item FROM study_session_cards WHERE session_id = $1 AND card_id = $2
+			  ORDER BY answered_at IS NOT NULL, position LIMIT 1`
+	var item int16
+	err := s.db.QueryRowContext(ctx, query, sessionID, cardID).Scan(&item)
+	return item, err
+}
+
+// Finish marks the session finished and returns when that happened; finishing
+// an already finished session keeps the original time.
+func (s *studySessionStorage) Finish(ctx context.Context, id string) (time.Time, int64, error) {
+	query := `UPDATE study_sessions SET finished_at = COALESCE(finished_at, NOW()),
+			  elapsed_seconds = COALESCE(elapsed_seconds, GREATEST(EXTRACT(EPOCH FROM NOW() - created_at), 0)::bigint)
+			  WHERE id = $1 RETURNING finished_at, elapsed_seconds`
+	var finishedAt time.Time
+	var elapsedSeconds int64
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&finishedAt, &elapsedSeconds)
+	return finishedAt, elapsedSeconds, err
+}
+
+func (s *studySessionStorage) Delete(ctx context.Context, id string) error {
+	query := `DELETE FROM study_sessions WHERE id = $1`
+	_, err := s.db.ExecContext(ctx, query, id)
+	return err
+}
+
+type quizSessionStorage struct {
+	db *postgres.DB
+}
+
+func NewQuizSessionStorage(db *postgres.DB) QuizSessionStorage {
+	return &quizSessionStorage{db: db}
+}
+
+func (s *quizSessionStorage) Create(ctx context.Context, session *models.QuizSession) error {
+	questions, err := json.Marshal(session.Questions)
+	if err != nil {
+		return err
+	}
+
+	query := `INSERT INTO quiz_sessions (id, set_id, user_id, questions, created_at, expires_at)
+			  VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err = s.db.ExecContext(ctx, query, session.ID, session.SetID, session.UserID, questions, session.CreatedAt, session.ExpiresAt)
+	return err
+}
+
+// GetByID returns the session with its answers. Expired sessions are treated as missing.
+func (s *quizSessionStorage) GetByID(ctx context.Context, id string) (*models.QuizSession, error) {
+	query := `SELECT id, set_id, user_id, questions, created_at, expires_at, finished_at
+			  FROM quiz_sessions WHERE id = $1 AND expires_at > NOW()`
+	session := &models.QuizSession{}
+	var questions []byte
+	var finishedAt sql.NullTime
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&session.ID, &session.SetID, &session.UserID, &questions, &session.CreatedAt, &session.ExpiresAt, &finishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(questions, &session.Questions); err != nil {
+		return nil, err
+	}
+	session.QuestionCount = len(session.Questions)
+	if finishedAt.Valid {
+		session.FinishedAt = &finishedAt.Time
+	}
+
+	answersQuery := `SELECT question_index, selected_index, COALESCE(text_answer, ''), bool_answer, is_correct, time_spent_ms
+					 FROM quiz_answers WHERE session_id = $1 ORDER BY question_index`
+	rows, err := s.db.QueryContext(ctx, answersQuery, id)
 	if err != nil {
 		return nil, err
 	}
+	defer rows.Close()
+
+	session.Answers = []models.QuizAnswerResult{}
+	for rows.Next() {
+		var answer models.QuizAnswerResult
+		var boolAnswer sql.NullBool
+		if err := rows.Scan(&answer.QuestionIndex, &answer.SelectedIndex, &answer.TextAnswer, &boolAnswer, &answer.IsCorrect, &answer.TimeSpentMs); err != nil {
+			return nil, err
+		}
+		if boolAnswer.Valid {
+			answer.BoolAnswer = &boolAnswer.Bool
+		}
+		if answer.QuestionIndex >= 0 && answer.QuestionIndex < len(session.Questions) {
+			question := session.Questions[answer.QuestionIndex]
+			answer.CorrectIndex = question.CorrectIndex
+			answer.CorrectAnswer = question.Answer()
+		}
+		session.Answers = append(session.Answers, answer)
+	}
+	return session, rows.Err()
+}
+
+// AddAnswer stores the answer and extends the session lifetime. It reports false
+// if the question has already been answered.
+func (s *quizSessionStorage) AddAnswer(ctx context.Context, sessionID string, answer *models.QuizAnswerResult, expiresAt time.Time) (bool, error) {
+	query := `INSERT INTO quiz_answers (session_id, question_index, selected_index, text_answer, bool_answer, is_correct, time_spent_ms)
+			  VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7)
+			  ON CONFLICT (session_id, question_index) DO NOTHING`
+	res, err := s.db.ExecContext(ctx, query, sessionID, answer.QuestionIndex, answer.SelectedIndex, answer.TextAnswer, answer.BoolAnswer, answer.IsCorrect, answer.TimeSpentMs)
+	if err != nil {
+		return false, err
+	}
+	inserted, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if inserted == 0 {
+		return false, nil
+	}
+
+	touchQuery := `UPDATE quiz_sessions SET expires_at = $1 WHERE id = $2`
+	_, err = s.db.ExecContext(ctx, touchQuery, expiresAt, sessionID)
+	return true, err
+}
+
+func (s *quizSessionStorage) Finish(ctx context.Context, id string) error {
+	query := `UPDATE quiz_sessions SET finished_at = NOW() WHERE id = $1 AND finished_at IS NULL`
+	_, err := s.db.ExecContext(ctx, query, id)
+	return err
+}
+
+func (s *quizSessionStorage) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `DELETE FROM quiz_sessions WHERE expires_at <= NOW()`
+	res, err := s.db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// SaveResult stores the result of a finished quiz; finishing the same quiz again keeps the first result.
+func (s *quizSessionStorage) SaveResult(ctx context.Context, userID string, result *models.QuizResult) error {
+	query := `INSERT INTO quiz_results (session_id, set_id, user_id, total_questions, correct_answers, incorrect_answers, score_percentage, time_spent_ms, finished_at)
+			  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			  ON CONFLICT (session_id) DO NOTHING`
+	_, err := s.db.ExecContext(ctx, query, result.SessionID, result.SetID, userID, result.TotalQuestions, result.CorrectAnswers,
+		result.IncorrectAnswers, result.ScorePercentage, result.TimeSpentMs, result.FinishedAt)
+	return err
+}
 
-	streakQuery := `SELECT
-					COUNT(DISTINCT study_date) FILTER (WHERE study_date >= CURRENT_DATE - INTERVAL '7 days') as current_streak,
-					SUM(time_spent_minutes) FILTER (WHERE study_date >= CURRENT_DATE - INTERVAL '30 days') as total_time
-					FROM study_history
-					WHERE user_id = $1`
-	err = s.db.QueryRowContext(ctx, streakQuery, userID).Scan(&stats.CurrentStreak, &stats.TotalStudyTimeMinutes)
+func (s *quizSessionStorage) GetResults(ctx context.Context, setID, userID string, offset, limit int32) ([]models.QuizResult, error) {
+	query := `SELECT session_id, set_id, total_questions, correct_answers, incorrect_answers, score_percentage, time_spent_ms, finished_at
+			  FROM quiz_results WHERE set_id = $1 AND user_id = $2
+			  ORDER BY finished_at DESC OFFSET $3 LIMIT $4`
+	rows, err := s.db.QueryContext(ctx, query, setID, userID, offset, limit)
 	if err != nil {
-		stats.CurrentStreak = 0
-		stats.TotalStudyTimeMinutes = 0
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []models.QuizResult{}
+	for rows.Next() {
+		var result models.QuizResult
+		if err := rows.Scan(&result.SessionID, &result.SetID, &result.TotalQuestions, &result.CorrectAnswers,
+			&result.IncorrectAnswers, &result.ScorePercentage, &result.TimeSpentMs, &result.FinishedAt); err != nil {
+			return nil, err
+		}
+		results = append(results, result)
 	}
+	return results, rows.Err()
+}
+
+type statisticsStorage struct {
+	db *postgres.DB
+}
+
+func NewStatisticsStorage(db *postgres.DB) StatisticsStorage {
+	return &statisticsStorage{db: db}
+}
+
+// GetSetStatistics returns the user's progress on the set; today is the user's
+// local date (YYYY-MM-DD) the last seven days of history are counted back from.
+func (s *statisticsStorage) GetSetStatistics(ctx context.Context, setID, userID, today string) (*models.SetStatistics, error) {
+	stats := &models.SetStatistics{SetID: setID}
 
-	longestStreakQuery := `SELECT COUNT(*) as streak
-						   FROM (
-								SELECT study_date,
-									   study_date - (ROW_NUMBER() OVER (ORDER BY study_date))::int * INTERVAL '1 day' as grp
-								FROM study_history
-								WHERE user_id = $1
-						   ) sub
-						   GROUP BY grp
-						   ORDER BY streak DESC
-						   LIMIT 1`
-	err = s.db.QueryRowContext(ctx, longestStreakQuery, userID).Scan(&stats.LongestStreak)
+	query := `SELECT
+			  COUNT(*) FILTER (WHERE p.status = 'new') as new_cards,
+			  COUNT(*) FILTER (WHERE p.status = 'learning') as learning_cards,
+			  COUNT(*) FILTER (WHERE p.status IN ('reviewing', 'mastered')) as learned_cards,
+			  COUNT(*) as total_cards
+			  FROM cards c
+			  CROSS JOIN LATERAL card_status(c.id, $2, c.rich_content) AS p(status)
+			  WHERE c.set_id = $1 AND c.deleted_at IS NULL`
+	err := s.db.QueryRowContext(ctx, query, setID, userID).Scan(&stats.NewCards, &stats.LearningCards, &stats.LearnedCards, &stats.TotalCards)
 	if err != nil {
-		stats.LongestStreak = 0
+		return nil, err
+	}
+
+	if stats.TotalCards > 0 {
+		stats.MasteryPercentage = float32(stats.LearnedCards) / float32(stats.TotalCards) * 100
 	}
 
-	lastDateQuery := `SELECT MAX(study_date) FROM study_history WHERE user_id = $1`
-	var lastDate sql.NullTime
-	err = s.db.QueryRowContext(ctx, lastDateQuery, userID).Scan(&lastDate)
-	if err == nil && lastDate.Valid {
-		stats.LastStudyDate = &lastDate.Time
+	totalTimeQuery := `SELECT COALESCE(SUM(time_spent_seconds), 0) FROM study_history WHERE set_id = $1 AND user_id = $2`
+	if err := s.db.QueryRowContext(ctx, totalTimeQuery, setID, userID).Scan(&stats.TotalStudyTimeSeconds); err != nil {
+		return nil, err
 	}
 
-	historyQuery := `SELECT study_date, SUM(cards_studied) as cards_studied, SUM(time_spent_minutes) as time_spent_minutes
+	historyQuery := `SELECT study_date, cards_studied, time_spent_seconds
 					 FROM study_history
-					 WHERE user_id = $1 AND study_date >= CURRENT_DATE - INTERVAL '7 days'
-					 GROUP BY study_date
+					 WHERE set_id = $1 AND user_id = $2 AND study_date >= $3::date - 6
 					 ORDER BY study_date DESC`
-	rows, err := s.db.QueryContext(ctx, historyQuery, userID)
+	rows, err := s.db.QueryContext(ctx, historyQuery, setID, userID, today)
 	if err != nil {
 		return nil, err
 	}
@@ -495,20 +1512,902 @@ func (s *statisticsStorage) GetUserStatistics(ctx context.Context, userID string
 
 	for rows.Next() {
 		var day models.StudyDay
-		if err := rows.Scan(&day.Date, &day.CardsStudied, &day.TimeSpentMinutes); err != nil {
+		if err := rows.Scan(&day.Date, &day.CardsStudied, &day.TimeSpentSeconds); err != nil {
 			return nil, err
 		}
+		day.TimeSpentMinutes = int32(day.TimeSpentSeconds / 60)
 		stats.StudyHistory = append(stats.StudyHistory, day)
 	}
 
 	return stats, nil
 }
 
-func (s *statisticsStorage) RecordStudySession(ctx context.Context, userID, setID string, cardsStudied int32, timeSpentMinutes int32) error {
-	query := `INSERT INTO study_history (user_id, set_id, cards_studied, time_spent_minutes, study_date)
-			  VALUES ($1, $2, $3, $4, CURRENT_DATE)
-			  ON CONFLICT (user_id, set_id, study_date) 
-			  DO UPDATE SET cards_studied = study_history.cards_studied + $3, time_spent_minutes = study_history.time_spent_minutes + $4`
-	_, err := s.db.ExecContext(ctx, query, userID, setID, cardsStudied, timeSpentMinutes)
-	return err
+// GetUserStatistics returns the user's totals. Streak fields are left for the
+// caller; today is the user's local date (YYYY-MM-DD) the recent history is counted from.
+func (s *statisticsStorage) GetUserStatistics(ctx context.Context, userID, today string) (*models.UserStatistics, error) {
+	stats := &models.UserStatistics{}
+
+	query := `SELECT
+			  COUNT(DISTINCT cs.id) as total_sets,
+			  COUNT(DISTINCT c.id) as total_cards,
+			  COUNT(DISTINCT c.id) FILTER (WHERE card_status(c.id, cs.owner_id, c.rich_content) IN ('reviewing', 'mastered')) as learned_cards
+			  FROM card_sets cs
+			  LEFT JOIN cards c ON cs.id = c.set_id AND c.deleted_at IS NULL
+			  WHERE cs.owner_id = $1 AND cs.deleted_at IS NULL`
+	err := s.db.QueryRowContext(ctx, query, userID).Scan(&stats.TotalSets, &stats.TotalCards, &stats.LearnedCards)
+	if err != nil {
+		return nil, err
+	}
+
+	timeQuery := `SELECT COALESCE(SUM(time_spent_seconds), 0)
+				  FROM study_history
+				  WHERE user_id = $1 AND study_date >= $2::date - INTERVAL '30 days'`
+	if err := s.db.QueryRowContext(ctx, timeQuery, userID, today).Scan(&stats.TotalStudyTimeSeconds); err != nil {
+		return nil, err
+	}
+	stats.TotalStudyTimeMinutes = int32(stats.TotalStudyTimeSeconds / 60)
+
+	historyQuery := `SELECT study_date, SUM(cards_studied) as cards_studied, SUM(time_spent_seconds) as time_spent_seconds
+					 FROM study_history
+					 WHERE user_id = $1 AND study_date >= $2::date - INTERVAL '7 days'
+					 GROUP BY study_date
+					 ORDER BY study_date DESC`
+	rows, err := s.db.QueryContext(ctx, historyQuery, userID, today)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day models.StudyDay
+		if err := rows.Scan(&day.Date, &day.CardsStudied, &day.TimeSpentSeconds); err != nil {
+			return nil, err
+		}
+		day.TimeSpentMinutes = int32(day.TimeSpentSeconds / 60)
+		stats.StudyHistory = append(stats.StudyHistory, day)
+	}
+
+	return stats, rows.Err()
+}
+
+// GetStudyDays returns the user's whole study history summed over all sets, oldest
+// day first, with dates as YYYY-MM-DD.
+func (s *statisticsStorage) GetStudyDays(ctx context.Context, userID string) ([]models.StudyDay, error) {
+	query := `SELECT to_char(study_date, 'YYYY-MM-DD'), SUM(cards_studied), SUM(time_spent_seconds)
+			  FROM study_history
+			  WHERE user_id = $1
+			  GROUP BY study_date
+			  ORDER BY study_date`
+	rows, err := s.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var days []models.StudyDay
+	for rows.Next() {
+		var day models.StudyDay
+		if err := rows.Scan(&day.Date, &day.CardsStudied, &day.TimeSpentSeconds); err != nil {
+			return nil, err
+		}
+		day.TimeSpentMinutes = int32(day.TimeSpentSeconds / 60)
+		days = append(days, day)
+	}
+	return days, rows.Err()
+}
+
+// RecordStudySession adds to the user's history for studyDate, the user's local date (YYYY-MM-DD).
+func (s *statisticsStorage) RecordStudySession(ctx context.Context, userID, setID, studyDate string, cardsStudied int32, timeSpentSeconds int64) error {
+	query := `INSERT INTO study_history (user_id, set_id, cards_studied, time_spent_seconds, study_date)
+			  VALUES ($1, $2, $3, $4, $5::date)
+			  ON CONFLICT (user_id, set_id, study_date) 
+			  DO UPDATE SET cards_studied = study_history.cards_studied + $3, time_spent_seconds = study_history.time_spent_seconds + $4`
+	_, err := s.db.ExecContext(ctx, query, userID, setID, cardsStudied, timeSpentSeconds, studyDate)
+	return err
+}
+
+type setViewStorage struct {
+	db *postgres.DB
+}
+
+func NewSetViewStorage(db *postgres.DB) SetViewStorage {
+	return &setViewStorage{db: db}
+}
+
+// RecordView stores a view of the set unless the user already viewed it within
+// dedupWindow. It reports whether the view was stored.
+func (s *setViewStorage) RecordView(ctx context.Context, setID, userID string, dedupWindow time.Duration) (bool, error) {
+	query := `INSERT INTO set_views (set_id, user_id)
+			  SELECT $1, $2
+			  WHERE NOT EXISTS (
+				  SELECT 1 FROM set_views
+				  WHERE set_id = $1 AND user_id = $2 AND viewed_at > NOW() - make_interval(secs => $3)
+			  )`
+	result, err := s.db.ExecContext(ctx, query, setID, userID, dedupWindow.Seconds())
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// Cleanup removes old views and brings views_count of the sets that lost views
+// up to date, as the trigger only recounts when a set gets a new view.
+func (s *setViewStorage) Cleanup(ctx context.Context) (int64, error) {
+	var updated int64
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `SELECT cleanup_old_set_views()`); err != nil {
+			return err
+		}
+
+		query := `WITH counts AS (
+					  SELECT cs.id, (SELECT COUNT(DISTINCT v.user_id) FROM set_views v
+									 WHERE v.set_id = cs.id AND v.viewed_at > NOW() - INTERVAL '30 days') AS views
+					  FROM card_sets cs
+					  WHERE cs.views_count > 0
+				  )
+				  UPDATE card_sets cs SET views_count = counts.views
+				  FROM counts
+				  WHERE cs.id = counts.id AND cs.views_count <> counts.views`
+		result, err := tx.ExecContext(ctx, query)
+		if err != nil {
+			return err
+		}
+		updated, err = result.RowsAffected()
+		return err
+	})
+	return updated, err
+}
+
+// GetTrending ranks public sets viewed or cloned within the window. Every view
+// counts half as much each halfLife; a clone counts as much as cloneWeight views.
+func (s *setViewStorage) GetTrending(ctx context.Context, window, halfLife time.Duration, offset, limit int32) ([]models.TrendingSet, error) {
+	const cloneWeight = 3
+	query := `WITH events AS (
+				  SELECT set_id, viewed_at AS at, 1 AS weight, 1 AS views
+				  FROM set_views
+				  WHERE viewed_at > NOW() - make_interval(secs => $1)
+				  UNION ALL
+				  SELECT cloned_from_set_id, created_at, $5, 0
+				  FROM card_sets
+				  WHERE cloned_from_set_id IS NOT NULL AND created_at > NOW() - make_interval(secs => $1)
+			  ),
+			  heat AS (
+				  SELECT set_id, SUM(views) AS views,
+						 SUM(weight * power(0.5, EXTRACT(EPOCH FROM NOW() - at) / $2)) AS score
+				  FROM events
+				  GROUP BY set_id
+			  )
+			  SELECT cs.id, cs.owner_id, cs.name, cs.description, cs.is_public, COALESCE(cs.tags, '{}'),
+					 COALESCE(cs.views_count, 0), COALESCE(cs.clones_count, 0), cs.created_at,
+					 (SELECT COUNT(*) FROM cards WHERE set_id = cs.id AND deleted_at IS NULL)::int, h.views, h.score AS trending_score
+			  FROM heat h
+			  JOIN card_sets cs ON cs.id = h.set_id
+			  WHERE cs.is_public = true AND cs.deleted_at IS NULL
+			  ORDER BY trending_score DESC, cs.created_at DESC
+			  OFFSET $3 LIMIT $4`
+	rows, err := s.db.QueryContext(ctx, query, window.Seconds(), halfLife.Seconds(), offset, limit, cloneWeight)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sets := []models.TrendingSet{}
+	for rows.Next() {
+		var set models.TrendingSet
+		if err := rows.Scan(&set.ID, &set.OwnerID, &set.Name, &set.Description, &set.IsPublic, pq.Array(&set.Tags),
+			&set.ViewsCount, &set.ClonesCount, &set.CreatedAt, &set.CardCount, &set.RecentViews, &set.Score); err != nil {
+			return nil, err
+		}
+		sets = append(sets, set)
+	}
+	return sets, rows.Err()
+}
+
+type setShareStorage struct {
+	db *postgres.DB
+}
+
+func NewSetShareStorage(db *postgres.DB) SetShareStorage {
+	return &setShareStorage{db: db}
+}
+
+// Upsert invites a user or changes the role of an existing share. A declined
+// invitation becomes pending again; an accepted share stays accepted.
+func (s *setShareStorage) Upsert(ctx context.Context, share *models.SetShare) error {
+	query := `INSERT INTO set_shares (set_id, user_id, role, status, invited_by, created_at)
+			  VALUES ($1, $2, $3, 'pending', $4, $5)
+			  ON CONFLICT (set_id, user_id) DO UPDATE SET
+				  role = EXCLUDED.role,
+				  invited_by = EXCLUDED.invited_by,
+				  status = CASE WHEN set_shares.status = 'declined' THEN 'pending' ELSE set_shares.status END,
+				  created_at = CASE WHEN set_shares.status = 'declined' THEN EXCLUDED.created_at ELSE set_shares.created_at END,
+				  responded_at = CASE WHEN set_shares.status = 'declined' THEN NULL ELSE set_shares.responded_at END
+			  RETURNING status, created_at, responded_at`
+	return s.db.QueryRowContext(ctx, query, share.SetID, share.UserID, share.Role, share.InvitedBy, share.CreatedAt).
+		Scan(&share.Status, &share.CreatedAt, &share.RespondedAt)
+}
+
+const setShareColumns = `sh.set_id, sh.user_id, sh.role, sh.status, sh.invited_by, sh.created_at, sh.responded_at`
+
+func (s *setShareStorage) Get(ctx context.Context, setID, userID string) (*models.SetShare, error) {
+	query := `SELECT ` + setShareColumns + ` FROM set_shares sh WHERE sh.set_id = $1 AND sh.user_id = $2`
+	share := &models.SetShare{}
+	err := s.db.QueryRowContext(ctx, query, setID, userID).Scan(
+		&share.SetID, &share.UserID, &share.Role, &share.Status, &share.InvitedBy, &share.CreatedAt, &share.RespondedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+func (s *setShareStorage) GetBySet(ctx context.Context, setID string) ([]models.SetShare, error) {
+	query := `SELECT ` + setShareColumns + ` FROM set_shares sh WHERE sh.set_id = $1 ORDER BY sh.created_at`
+	rows, err := s.db.QueryContext(ctx, query, setID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []models.SetShare{}
+	for rows.Next() {
+		var share models.SetShare
+		if err := rows.Scan(&share.SetID, &share.UserID, &share.Role, &share.Status, &share.InvitedBy, &share.CreatedAt, &share.RespondedAt); err != nil {
+			return nil, err
+		}
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
+
+// GetByUser lists the shares of a user in the given status together with their sets, newest first.
+func (s *setShareStorage) GetByUser(ctx context.Context, userID string, status models.ShareStatus, offset, limit int32) ([]models.SetShare, error) {
+	query := `SELECT ` + setShareColumns + `,
+					 cs.id, cs.owner_id, cs.name, cs.description, cs.is_public, COALESCE(cs.tags, '{}'),
+					 COALESCE(cs.views_count, 0), COALESCE(cs.clones_count, 0), cs.created_at,
+					 (SELECT COUNT(*) FROM cards WHERE set_id = cs.id AND deleted_at IS NULL)::int
+			  FROM set_shares sh
+			  JOIN card_sets cs ON cs.id = sh.set_id
+			  WHERE sh.user_id = $1 AND sh.status = $2 AND cs.deleted_at IS NULL
+			  ORDER BY sh.created_at DESC
+			  OFFSET $3 LIMIT $4`
+	rows, err := s.db.QueryContext(ctx, query, userID, status, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []models.SetShare{}
+	for rows.Next() {
+		var share models.SetShare
+		set := &models.CardSet{}
+		if err := rows.Scan(&share.SetID, &share.UserID, &share.Role, &share.Status, &share.InvitedBy, &share.CreatedAt, &share.RespondedAt,
+			&set.ID, &set.OwnerID, &set.Name, &set.Description, &set.IsPublic, pq.Array(&set.Tags),
+			&set.ViewsCount, &set.ClonesCount, &set.CreatedAt, &set.CardCount); err != nil {
+			return nil, err
+		}
+		set.Role = share.Role
+		share.Set = set
+		shares = append(shares, share)
+	}
+	return shares, rows.Err()
+}
+
+func (s *setShareStorage) UpdateRole(ctx context.Context, setID, userID string, role models.SetRole) (bool, error) {
+	query := `UPDATE set_shares SET role = $3 WHERE set_id = $1 AND user_id = $2`
+	return s.exec(ctx, query, setID, userID, role)
+}
+
+// Respond accepts or declines a pending invitation.
+func (s *setShareStorage) Respond(ctx context.Context, setID, userID string, status models.ShareStatus) (bool, error) {
+	query := `UPDATE set_shares SET status = $3, responded_at = NOW()
+			  WHERE set_id = $1 AND user_id = $2 AND status = 'pending'`
+	return s.exec(ctx, query, setID, userID, status)
+}
+
+func (s *setShareStorage) Delete(ctx context.Context, setID, userID string) (bool, error) {
+	query := `DELETE FROM set_shares WHERE set_id = $1 AND user_id = $2`
+	return s.exec(ctx, query, setID, userID)
+}
+
+func (s *setShareStorage) exec(ctx context.Context, query string, args ...any) (bool, error) {
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+type folderStorage struct {
+	db *postgres.DB
+}
+
+func NewFolderStorage(db *postgres.DB) FolderStorage {
+	return &folderStorage{db: db}
+}
+
+// Create adds the folder. It reports false, adding nothing, if allowed
+// rejects the depth of its parent. The owner's folders are locked while
+// checking, so that moves at the same time cannot nest it deeper.
+func (s *folderStorage) Create(ctx context.Context, folder *models.Folder, allowed FolderDepthCheck) (bool, error) {
+	created := false
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `SELECT id FROM folders WHERE owner_id = $1 FOR UPDATE`, folder.OwnerID); err != nil {
+			return err
+		}
+
+		parentLevels := 0
+		if folder.ParentID != nil {
+			levels, _, err := folderPathLevels(ctx, tx, *folder.ParentID, folder.ID)
+			if err != nil {
+				return err
+			}
+			parentLevels = levels
+		}
+		if !allowed(parentLevels, 1) {
+			return nil
+		}
+
+		query := `INSERT INTO folders (id, owner_id, parent_id, name, created_at) VALUES ($1, $2, $3, $4, $5)`
+		if _, err := tx.ExecContext(ctx, query, folder.ID, folder.OwnerID, folder.ParentID, folder.Name, folder.CreatedAt); err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	return created, err
+}
+
+// folderPathLevels returns how many folders deep the folder is, counting
+// itself, and whether other is on its path up to the top.
+func folderPathLevels(ctx context.Context, tx *sql.Tx, id, other string) (int, bool, error) {
+	query := `WITH RECURSIVE path AS (
+				  SELECT id, parent_id, 1 AS levels FROM folders WHERE id = $1
+				  UNION ALL
+				  SELECT f.id, f.parent_id, p.levels + 1 FROM folders f JOIN path p ON f.id = p.parent_id
+				  WHERE p.levels <= $3
+			  )
+			  SELECT COALESCE(MAX(levels), 0), COALESCE(BOOL_OR(id = $2), false) FROM path`
+	var levels int
+	var found bool
+	err := tx.QueryRowContext(ctx, query, id, other, maxFolderDepth).Scan(&levels, &found)
+	return levels, found, err
+}
+
+// folderTreeLevels returns how many levels of folders the folder spans,
+// counting itself.
+func folderTreeLevels(ctx context.Context, tx *sql.Tx, id string) (int, error) {
+	query := `WITH RECURSIVE tree AS (
+				  SELECT id, 1 AS levels FROM folders WHERE id = $1
+				  UNION ALL
+				  SELECT f.id, t.levels + 1 FROM folders f JOIN tree t ON f.parent_id = t.id
+				  WHERE t.levels <= $2
+			  )
+			  SELECT COALESCE(MAX(levels), 0) FROM tree`
+	var levels int
+	err := tx.QueryRowContext(ctx, query, id, maxFolderDepth).Scan(&levels)
+	return levels, err
+}
+
+// GetByID returns the folder without counts.
+func (s *folderStorage) GetByID(ctx context.Context, id string) (*models.Folder, error) {
+	query := `SELECT id, owner_id, parent_id, name, created_at FROM folders WHERE id = $1`
+	folder := &models.Folder{}
+	err := s.db.QueryRowContext(ctx, query, id).Scan(&folder.ID, &folder.OwnerID, &folder.ParentID, &folder.Name, &folder.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return folder, nil
+}
+
+// GetPath returns the folder and the folders above it, starting from the top, without counts.
+func (s *folderStorage) GetPath(ctx context.Context, id string) ([]models.Folder, error) {
+	query := `WITH RECURSIVE path AS (
+				  SELECT id, owner_id, parent_id, name, created_at, 0 AS depth FROM folders WHERE id = $1
+				  UNION ALL
+				  SELECT f.id, f.owner_id, f.parent_id, f.name, f.created_at, p.depth + 1
+				  FROM folders f JOIN path p ON f.id = p.parent_id
+				  WHERE p.depth < $2
+			  )
+			  SELECT id, owner_id, parent_id, name, created_at FROM path ORDER BY depth DESC`
+	rows, err := s.db.QueryContext(ctx, query, id, maxFolderDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	path := []models.Folder{}
+	for rows.Next() {
+		var folder models.Folder
+		if err := rows.Scan(&folder.ID, &folder.OwnerID, &folder.ParentID, &folder.Name, &folder.CreatedAt); err != nil {
+			return nil, err
+		}
+		path = append(path, folder)
+	}
+	return path, rows.Err()
+}
+
+// GetChildren lists the folders directly in the parent, or at the top level if
+// it is nil, by name. Counts cover everything below each folder, with progress
+// of the owner.
+func (s *folderStorage) GetChildren(ctx context.Context, ownerID string, parentID *string) ([]models.Folder, error) {
+	query := `WITH RECURSIVE tree AS (
+				  SELECT id AS root_id, id, 0 AS depth FROM folders
+				  WHERE owner_id = $1 AND parent_id IS NOT DISTINCT FROM $2::uuid
+				  UNION ALL
+				  SELECT t.root_id, f.id, t.depth + 1 FROM folders f JOIN tree t ON f.parent_id = t.id
+				  WHERE t.depth < $3
+			  )
+			  SELECT f.id, f.owner_id, f.parent_id, f.name, f.created_at,
+					 COUNT(DISTINCT cs.id)::int,
+					 COUNT(c.id)::int,
+					 (COUNT(c.id) FILTER (WHERE card_status(c.id, $1, c.rich_content) IN ('reviewing', 'mastered')))::int
+			  FROM folders f
+			  JOIN tree t ON t.root_id = f.id
+			  LEFT JOIN card_sets cs ON cs.folder_id = t.id AND cs.deleted_at IS NULL
+			  LEFT JOIN cards c ON c.set_id = cs.id AND c.deleted_at IS NULL
+			  GROUP BY f.id
+			  ORDER BY f.name, f.created_at`
+	rows, err := s.db.QueryContext(ctx, query, ownerID, parentID, maxFolderDepth)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	folders := []models.Folder{}
+	for rows.Next() {
+		var folder models.Folder
+		if err := rows.Scan(&folder.ID, &folder.OwnerID, &folder.ParentID, &folder.Name, &folder.CreatedAt,
+			&folder.SetCount, &folder.CardCount, &folder.LearnedCount); err != nil {
+			return nil, err
+		}
+		folders = append(folders, folder)
+	}
+	return folders, rows.Err()
+}
+
+// GetSets lists the owner's sets directly in the folder, or at the top level if
+// it is nil, by name, with card counts and the owner's progress.
+func (s *folderStorage) GetSets(ctx context.Context, ownerID string, folderID *string) ([]models.CardSet, error) {
+	query := `SELECT cs.id, cs.owner_id, cs.name, cs.description, cs.is_public, COALESCE(cs.tags, '{}'),
+					 COALESCE(cs.views_count, 0), COALESCE(cs.clones_count, 0), cs.created_at,
+					 cs.cloned_from_set_id, cs.synced_at, cs.folder_id,
+					 COUNT(c.id)::int,
+					 (COUNT(c.id) FILTER (WHERE card_status(c.id, $1, c.rich_content) IN ('reviewing', 'mastered')))::int
+			  FROM card_sets cs
+			  LEFT JOIN cards c ON c.set_id = cs.id AND c.deleted_at IS NULL
+			  WHERE cs.owner_id = $1 AND cs.deleted_at IS NULL AND cs.folder_id IS NOT DISTINCT FROM $2::uuid
+			  GROUP BY cs.id
+			  ORDER BY cs.name, cs.created_at`
+	rows, err := s.db.QueryContext(ctx, query, ownerID, folderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sets := []models.CardSet{}
+	for rows.Next() {
+		var set models.CardSet
+		if err := rows.Scan(&set.ID, &set.OwnerID, &set.Name, &set.Description, &set.IsPublic, pq.Array(&set.Tags),
+			&set.ViewsCount, &set.ClonesCount, &set.CreatedAt, &set.ClonedFromSetID, &set.SyncedAt, &set.FolderID,
+			&set.CardCount, &set.LearnedCount); err != nil {
+			return nil, err
+		}
+		sets = append(sets, set)
+	}
+	return sets, rows.Err()
+}
+
+func (s *folderStorage) Rename(ctx context.Context, id, name string) error {
+	query := `UPDATE folders SET name = $2 WHERE id = $1`
+	_, err := s.db.ExecContext(ctx, query, id, name)
+	return err
+}
+
+// Move puts the folder into the parent, or at the top level if it is nil. It
+// reports false, moving nothing, if the parent is the folder itself or is below
+// it, or if allowed rejects the depth the folder and its subfolders would end
+// up at. The owner's folders are locked while checking, so that two moves at
+// once cannot make a cycle or nest folders too deep.
+func (s *folderStorage) Move(ctx context.Context, id string, parentID *string, allowed FolderDepthCheck) (bool, error) {
+	moved := false
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		lockQuery := `SELECT id FROM folders WHERE owner_id = (SELECT owner_id FROM folders WHERE id = $1) FOR UPDATE`
+		if _, err := tx.ExecContext(ctx, lockQuery, id); err != nil {
+			return err
+		}
+
+		parentLevels := 0
+		if parentID != nil {
+			levels, cycle, err := folderPathLevels(ctx, tx, *parentID, id)
+			if err != nil {
+				return err
+			}
+			if cycle {
+				return nil
+			}
+			parentLevels = levels
+		}
+
+		levels, err := folderTreeLevels(ctx, tx, id)
+		if err != nil {
+			return err
+		}
+		if !allowed(parentLevels, levels) {
+			return nil
+		}
+
+		if _, err := tx.ExecContext(ctx, `UPDATE folders SET parent_id = $2 WHERE id = $1`, id, parentID); err != nil {
+			return err
+		}
+		moved = true
+		return nil
+	})
+	return moved, err
+}
+
+// Delete removes the folder with its subfolders. Sets in them are moved to the
+// folder's parent rather than deleted.
+func (s *folderStorage) Delete(ctx context.Context, id string) error {
+	return withTx(ctx, s.db, func(tx *sql.Tx) error {
+		query := folderTree + `
+				  UPDATE card_sets SET folder_id = (SELECT parent_id FROM folders WHERE id = $1)
+				  WHERE folder_id IN (SELECT id FROM tree)`
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return err
+		}
+
+		_, err := tx.ExecContext(ctx, `DELETE FROM folders WHERE id = $1`, id)
+		return err
+	})
+}
+
+func (s *folderStorage) MoveSet(ctx context.Context, setID string, folderID *string) error {
+	query := `UPDATE card_sets SET folder_id = $2 WHERE id = $1`
+	_, err := s.db.ExecContext(ctx, query, setID, folderID)
+	return err
+}
+
+type trashStorage struct {
+	db *postgres.DB
+}
+
+func NewTrashStorage(db *postgres.DB) TrashStorage {
+	return &trashStorage{db: db}
+}
+
+// List returns the user's trash, most recently deleted first: the sets they
+// deleted, and cards deleted from sets they own or edit that aren't deleted
+// themselves. PurgeAt is left for the caller.
+func (s *trashStorage) List(ctx context.Context, userID string, offset, limit int32) ([]models.TrashItem, error) {
+	query := `SELECT 'set', cs.id, cs.id, cs.name, cs.name,
+					 (SELECT COUNT(*) FROM cards WHERE set_id = cs.id AND deleted_at IS NULL)::int, cs.deleted_at
+			  FROM card_sets cs
+			  WHERE cs.owner_id = $1 AND cs.deleted_at IS NOT NULL
+			  UNION ALL
+			  SELECT 'card', c.id, cs.id, c.front, cs.name, 0, c.deleted_at
+			  FROM cards c
+			  JOIN card_sets cs ON cs.id = c.set_id
+			  WHERE c.deleted_at IS NOT NULL AND cs.deleted_at IS NULL AND (cs.owner_id = $1 OR EXISTS (
+				  SELECT 1 FROM set_shares sh
+				  WHERE sh.set_id = cs.id AND sh.user_id = $1 AND sh.status = 'accepted' AND sh.role = 'editor'
+			  ))
+			  ORDER BY 7 DESC, 2
+			  OFFSET $2 LIMIT $3`
+	rows, err := s.db.QueryContext(ctx, query, userID, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []models.TrashItem{}
+	for rows.Next() {
+		var item models.TrashItem
+		if err := rows.Scan(&item.Type, &item.ID, &item.SetID, &item.Title, &item.SetName, &item.CardCount, &item.DeletedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
+// GetSet returns a set in the trash, with sql.ErrNoRows if it isn't there.
+func (s *trashStorage) GetSet(ctx context.Context, id string) (*models.CardSet, error) {
+	query := `SELECT id, owner_id, name, description, is_public, COALESCE(tags, '{}'), created_at
+			  FROM card_sets WHERE id = $1 AND deleted_at IS NOT NULL`
+	set := &models.CardSet{}
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&set.ID, &set.OwnerID, &set.Name, &set.Description, &set.IsPublic, pq.Array(&set.Tags), &set.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return set, nil
+}
+
+// GetCard returns a card in the trash, with sql.ErrNoRows if it isn't there.
+func (s *trashStorage) GetCard(ctx context.Context, id string) (*models.Card, error) {
+	query := `SELECT id, set_id, front, back, image_url, audio_url, rich_content, created_at
+			  FROM cards WHERE id = $1 AND deleted_at IS NOT NULL`
+	card := &models.Card{Status: models.StatusNew}
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&card.ID, &card.SetID, &card.Front, &card.Back, &card.ImageURL, &card.AudioURL, richContent{&card.Rich}, &card.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return card, nil
+}
+
+func (s *trashStorage) RestoreSet(ctx context.Context, id string) error {
+	return s.restore(ctx, `UPDATE card_sets SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL`, id)
+}
+
+func (s *trashStorage) RestoreCard(ctx context.Context, id, editorID string) error {
+	return s.restore(ctx, `UPDATE cards SET deleted_at = NULL, updated_by = $2 WHERE id = $1 AND deleted_at IS NOT NULL`, id, editorID)
+}
+
+// restore fails with sql.ErrNoRows if there was nothing in the trash to restore.
+func (s *trashStorage) restore(ctx context.Context, query string, args ...any) error {
+	result, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// Purge removes sets and cards deleted before the given time for good, together
+// with their cards, progress and study history. It returns how many sets and
+// cards were removed.
+func (s *trashStorage) Purge(ctx context.Context, deletedBefore time.Time) (int64, error) {
+	var purged int64
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		for _, query := range []string{
+			`DELETE FROM cards WHERE deleted_at < $1`,
+			`DELETE FROM card_sets WHERE deleted_at < $1`,
+		} {
+			result, err := tx.ExecContext(ctx, query, deletedBefore)
+			if err != nil {
+				return err
+			}
+			n, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			purged += n
+		}
+		return nil
+	})
+	return purged, err
+}
+
+type revisionStorage struct {
+	db *postgres.DB
+}
+
+func NewRevisionStorage(db *postgres.DB) RevisionStorage {
+	return &revisionStorage{db: db}
+}
+
+// revisionColumns selects a revision (as r) with the content of the revision
+// of the same card right before it.
+const revisionColumns = `r.id, r.card_id, r.set_id, r.editor_id, r.action, r.front, r.back, r.image_url, r.audio_url, r.rich_content, r.created_at,
+	LAG(r.id) OVER w IS NOT NULL AS has_previous, LAG(r.front) OVER w AS previous_front, LAG(r.back) OVER w AS previous_back,
+	LAG(r.image_url) OVER w AS previous_image_url, LAG(r.audio_url) OVER w AS previous_audio_url,
+	LAG(r.rich_content) OVER w AS previous_rich_content`
+
+func scanRevisions(rows *sql.Rows) ([]models.CardRevision, error) {
+	revisions := []models.CardRevision{}
+	for rows.Next() {
+		var rev models.CardRevision
+		var hasPrevious bool
+		var previousFront, previousBack sql.NullString
+		var previous models.CardContent
+		if err := rows.Scan(&rev.ID, &rev.CardID, &rev.SetID, &rev.EditorID, &rev.Action, &rev.Front, &rev.Back, &rev.ImageURL, &rev.AudioURL, richContent{&rev.Rich}, &rev.CreatedAt,
+			&hasPrevious, &previousFront, &previousBack, &previous.ImageURL, &previous.AudioURL, richContent{&previous.Rich}); err != nil {
+			return nil, err
+		}
+		if hasPrevious {
+			previous.Front, previous.Back = previousFront.String, previousBack.String
+			rev.Previous = &previous
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
+
+// GetByID returns a revision without the content before it.
+func (s *revisionStorage) GetByID(ctx context.Context, id int64) (*models.CardRevision, error) {
+	query := `SELECT id, card_id, set_id, editor_id, action, front, back, image_url, audio_url, rich_content, created_at
+			  FROM card_revisions WHERE id = $1`
+	rev := &models.CardRevision{}
+	err := s.db.QueryRowContext(ctx, query, id).Scan(
+		&rev.ID, &rev.CardID, &rev.SetID, &rev.EditorID, &rev.Action, &rev.Front, &rev.Back, &rev.ImageURL, &rev.AudioURL, richContent{&rev.Rich}, &rev.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return rev, nil
+}
+
+// GetByCard lists the revisions of a card, newest first.
+func (s *revisionStorage) GetByCard(ctx context.Context, cardID string, offset, limit int32) ([]models.CardRevision, error) {
+	query := `SELECT ` + revisionColumns + `
+			  FROM card_revisions r
+			  WHERE r.card_id = $1
+			  WINDOW w AS (ORDER BY r.id)
+			  ORDER BY r.id DESC
+			  OFFSET $2 LIMIT $3`
+	rows, err := s.db.QueryContext(ctx, query, cardID, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRevisions(rows)
+}
+
+// GetBySet lists the revisions made to cards while they were in the set, newest first.
+func (s *revisionStorage) GetBySet(ctx context.Context, setID string, offset, limit int32) ([]models.CardRevision, error) {
+	query := `SELECT * FROM (
+				  SELECT ` + revisionColumns + `
+				  FROM card_revisions r
+				  WHERE r.card_id IN (SELECT card_id FROM card_revisions WHERE set_id = $1)
+				  WINDOW w AS (PARTITION BY r.card_id ORDER BY r.id)
+			  ) r
+			  WHERE r.set_id = $1
+			  ORDER BY r.id DESC
+			  OFFSET $2 LIMIT $3`
+	rows, err := s.db.QueryContext(ctx, query, setID, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanRevisions(rows)
+}
+
+// revisionStateAt selects, for every card now in set $1 including those in the
+// trash, its last revision up to time $2. Cards created later have no revision.
+const revisionStateAt = `WITH state AS (
+				  SELECT DISTINCT ON (c.id) c.id, r.action, r.set_id, r.front, r.back, r.image_url, r.audio_url, r.rich_content
+				  FROM cards c
+				  LEFT JOIN card_revisions r ON r.card_id = c.id AND r.created_at <= $2
+				  WHERE c.set_id = $1
+				  ORDER BY c.id, r.id DESC NULLS LAST
+			  )`
+
+// RevertSet brings the cards of the set back to how they were at the given
+// time, all or nothing: cards created since then go to the trash, cards
+// deleted since then come back, and changed cards get their earlier content.
+// Cards that were in another set at that time are left as they are.
+func (s *revisionStorage) RevertSet(ctx context.Context, setID string, at time.Time, editorID string) (*models.SetRevertResult, error) {
+	result := &models.SetRevertResult{}
+	err := withTx(ctx, s.db, func(tx *sql.Tx) error {
+		affected := func(query string) (int32, error) {
+			res, err := tx.ExecContext(ctx, query, setID, at, editorID)
+			if err != nil {
+				return 0, err
+			}
+			n, err := res.RowsAffected()
+			return int32(n), err
+		}
+
+		var err error
+		result.Deleted, err = affected(revisionStateAt + `
+				  UPDATE cards c SET deleted_at = NOW(), updated_by = $3
+				  FROM state s
+				  WHERE c.id = s.id AND c.deleted_at IS NULL
+					AND (s.action IS NULL OR (s.action = 'deleted' AND s.set_id = $1))`)
+		if err != nil {
+			return err
+		}
+
+		result.Reverted, err = affected(revisionStateAt + `
+				  UPDATE cards c SET front = s.front, back = s.back, image_url = s.image_url, audio_url = s.audio_url,
+									 rich_content = s.rich_content, deleted_at = NULL, updated_by = $3
+				  FROM state s
+				  WHERE c.id = s.id AND s.action <> 'deleted' AND s.set_id = $1
+					AND (c.deleted_at IS NOT NULL OR c.front <> s.front OR c.back <> s.back
+						 OR c.image_url IS DISTINCT FROM s.image_url OR c.audio_url IS DISTINCT FROM s.audio_url
+						 OR c.rich_content IS DISTINCT FROM s.rich_content)`)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type reviewStorage struct {
+	db *postgres.DB
+}
+
+func NewReviewStorage(db *postgres.DB) ReviewStorage {
+	return &reviewStorage{db: db}
+}
+
+const reviewColumns = `id, card_id, item, set_id, source, session_id, rating, status_before, status_after,
+	previous_interval_days, interval_days, scheduler, response_time_ms, reviewed_at`
+
+func scanReviews(rows *sql.Rows, userID string) ([]models.Review, error) {
+	reviews := []models.Review{}
+	for rows.Next() {
+		review := models.Review{UserID: userID}
+		if err := rows.Scan(&review.ID, &review.CardID, &review.Item, &review.SetID, &review.Source, &review.SessionID, &review.Rating,
+			&review.StatusBefore, &review.StatusAfter, &review.PreviousIntervalDays, &review.IntervalDays, &review.Scheduler,
+			&review.ResponseTimeMs, &review.ReviewedAt); err != nil {
+			return nil, err
+		}
+		reviews = append(reviews, review)
+	}
+	return reviews, rows.Err()
+}
+
+// GetByCard returns the user's reviews of the card, newest first.
+func (s *reviewStorage) GetByCard(ctx context.Context, userID, cardID string, offset, limit int32) ([]models.Review, error) {
+	query := `SELECT ` + reviewColumns + ` FROM review_log
+			  WHERE user_id = $1 AND card_id = $2
+			  ORDER BY reviewed_at DESC, id DESC OFFSET $3 LIMIT $4`
+	rows, err := s.db.QueryContext(ctx, query, userID, cardID, offset, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanReviews(rows, userID)
+}
+
+// GetTotalsByItem sums up the user's reviews of the card for each item reviewed.
+func (s *reviewStorage) GetTotalsByItem(ctx context.Context, userID, cardID string) (map[int16]models.ReviewTotals, error) {
+	query := `SELECT item,
+			  COUNT(*)::int,
+			  (COUNT(*) FILTER (WHERE rating <> $3))::int,
+			  (COUNT(*) FILTER (WHERE rating = $3 AND status_before IN ('reviewing', 'mastered')))::int,
+			  (COUNT(*) FILTER (WHERE response_time_ms > 0))::int,
+			  COALESCE(SUM(response_time_ms), 0)::bigint,
+			  MIN(reviewed_at), MAX(reviewed_at)
+			  FROM review_log
+			  WHERE user_id = $1 AND card_id = $2
+			  GROUP BY item`
+	rows, err := s.db.QueryContext(ctx, query, userID, cardID, models.RatingAgain)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	totals := make(map[int16]models.ReviewTotals)
+	for rows.Next() {
+		var item int16
+		var t models.ReviewTotals
+		if err := rows.Scan(&item, &t.Reviews, &t.Correct, &t.Lapses, &t.Timed, &t.ResponseTimeMs, &t.FirstReviewedAt, &t.LastReviewedAt); err != nil {
+			return nil, err
+		}
+		totals[item] = t
+	}
+	return totals, rows.Err()
+}
+
+// GetByUser pages through the user's review log, oldest first, returning the
+// reviews that come after the cursor.
+func (s *reviewStorage) GetByUser(ctx context.Context, userID string, after models.ReviewCursor, limit int32) ([]models.Review, error) {
+	query := `SELECT ` + reviewColumns + ` FROM review_log
+			  WHERE user_id = $1 AND (reviewed_at, id) > ($2, $3)
+			  ORDER BY reviewed_at, id LIMIT $4`
+	rows, err := s.db.QueryContext(ctx, query, userID, after.ReviewedAt, after.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanReviews(rows, userID)
 }
@@ -0,0 +1,21 @@
+-- Study sessions keep their card queue and answers so they can be resumed on
+-- another device and summarized when finished
+
+ALTER TABLE study_sessions
+ADD COLUMN IF NOT EXISTS finished_at TIMESTAMP WITH TIME ZONE;
+
+CREATE TABLE IF NOT EXISTS study_session_cards (
+    session_id UUID NOT NULL REFERENCES study_sessions(id) ON DELETE CASCADE,
+    card_id UUID NOT NULL REFERENCES cards(id) ON DELETE CASCADE,
+    position INT NOT NULL,
+    status_before VARCHAR(20),
+    status_after VARCHAR(20),
+    last_rating INT NOT NULL DEFAULT 0,
+    attempts INT NOT NULL DEFAULT 0,
+    correct_attempts INT NOT NULL DEFAULT 0,
+    time_spent_ms BIGINT NOT NULL DEFAULT 0,
+    answered_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (session_id, card_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_study_session_cards_card_id ON study_session_cards(card_id);
@@ -0,0 +1,10 @@
+-- Study time is tracked in seconds instead of whole minutes. Existing rows were
+-- rounded up to a minute per answer, so they are only approximate.
+
+ALTER TABLE study_history
+ADD COLUMN IF NOT EXISTS time_spent_seconds BIGINT NOT NULL DEFAULT 0;
+
+UPDATE study_history SET time_spent_seconds = COALESCE(time_spent_minutes, 0) * 60;
+
+ALTER TABLE study_history
+DROP COLUMN IF EXISTS time_spent_minutes;
@@ -0,0 +1,8 @@
+-- Streaks are counted in the user's timezone against a daily goal, with an
+-- optional number of missed days per week that do not break the streak
+
+ALTER TABLE user_settings
+ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC',
+ADD COLUMN IF NOT EXISTS daily_goal_type VARCHAR(10) NOT NULL DEFAULT 'cards',
+ADD COLUMN IF NOT EXISTS daily_goal INT NOT NULL DEFAULT 1,
+ADD COLUMN IF NOT EXISTS streak_freezes_per_week INT NOT NULL DEFAULT 0;
@@ -0,0 +1,63 @@
+-- Full-text search over public sets: sets are matched by name (weight A),
+-- description and tags (weight B), and by the contents of their cards. Text is
+-- indexed with both the russian and english configurations so either language
+-- matches regardless of which one the set is written in
+
+CREATE OR REPLACE FUNCTION search_document(config regconfig, content TEXT, weight "char")
+RETURNS tsvector AS $$
+    SELECT setweight(to_tsvector(config, COALESCE(content, '')), weight)
+$$ LANGUAGE sql IMMUTABLE;
+
+ALTER TABLE card_sets
+ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+ALTER TABLE cards
+ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+CREATE OR REPLACE FUNCTION update_card_set_search_vector()
+RETURNS TRIGGER AS $$
+DECLARE
+    tags TEXT := array_to_string(NEW.tags, ' ');
+BEGIN
+    NEW.search_vector :=
+        search_document('russian', NEW.name, 'A') || search_document('english', NEW.name, 'A') ||
+        search_document('russian', NEW.description, 'B') || search_document('english', NEW.description, 'B') ||
+        search_document('russian', tags, 'B') || search_document('english', tags, 'B');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION update_card_search_vector()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector :=
+        search_document('russian', NEW.front, 'A') || search_document('english', NEW.front, 'A') ||
+        search_document('russian', NEW.back, 'B') || search_document('english', NEW.back, 'B');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_card_sets_search_vector ON card_sets;
+CREATE TRIGGER trigger_card_sets_search_vector
+BEFORE INSERT OR UPDATE OF name, description, tags ON card_sets
+FOR EACH ROW
+EXECUTE FUNCTION update_card_set_search_vector();
+
+DROP TRIGGER IF EXISTS trigger_cards_search_vector ON cards;
+CREATE TRIGGER trigger_cards_search_vector
+BEFORE INSERT OR UPDATE OF front, back ON cards
+FOR EACH ROW
+EXECUTE FUNCTION update_card_search_vector();
+
+-- Fire the triggers once to fill in existing rows, without touching updated_at
+ALTER TABLE card_sets DISABLE TRIGGER update_card_sets_updated_at;
+UPDATE card_sets SET name = name;
+ALTER TABLE card_sets ENABLE TRIGGER update_card_sets_updated_at;
+UPDATE cards SET front = front;
+
+CREATE INDEX IF NOT EXISTS idx_card_sets_search_vector ON card_sets USING GIN (search_vector);
+CREATE INDEX IF NOT EXISTS idx_cards_search_vector ON cards USING GIN (search_vector);
+
+-- The ILIKE search on names is gone
+DROP INDEX IF EXISTS idx_card_sets_public_search;
+DROP INDEX IF EXISTS idx_cards_public_search;
@@ -0,0 +1,5 @@
+-- Sets are filtered by tags both in the owner's list and in search
+
+UPDATE card_sets SET tags = '{}' WHERE tags IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_card_sets_tags ON card_sets USING GIN (tags);
@@ -0,0 +1,12 @@
+-- A user's repeated views of a set are recorded once per dedup window
+
+CREATE INDEX IF NOT EXISTS idx_set_views_set_user_viewed_at ON set_views(set_id, user_id, viewed_at DESC);
+
+-- Clones remember the set they were copied from, so recent clones count
+-- towards the original's trending score
+
+ALTER TABLE card_sets
+ADD COLUMN IF NOT EXISTS cloned_from_set_id UUID REFERENCES card_sets(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_card_sets_cloned_from ON card_sets(cloned_from_set_id, created_at)
+WHERE cloned_from_set_id IS NOT NULL;
@@ -0,0 +1,22 @@
+-- Clones remember when they were last synced, and each copied card the card
+-- it came from together with a hash of the content it had then. Comparing the
+-- hash with both cards tells whether the original or the copy changed since.
+
+CREATE OR REPLACE FUNCTION card_content_hash(front TEXT, back TEXT, image_url TEXT, audio_url TEXT)
+RETURNS TEXT AS $$
+    SELECT md5(concat_ws(E'\x1f', front, back, COALESCE(image_url, ''), COALESCE(audio_url, '')))
+$$ LANGUAGE sql IMMUTABLE;
+
+ALTER TABLE card_sets
+ADD COLUMN IF NOT EXISTS synced_at TIMESTAMP WITH TIME ZONE;
+
+-- No foreign key: a copy keeps pointing at its origin after the origin is
+-- deleted, so the deletion can be shown in the diff
+ALTER TABLE cards
+ADD COLUMN IF NOT EXISTS origin_card_id UUID,
+ADD COLUMN IF NOT EXISTS origin_hash TEXT;
+
+CREATE INDEX IF NOT EXISTS idx_cards_origin_card_id ON cards(origin_card_id)
+WHERE origin_card_id IS NOT NULL;
+
+UPDATE card_sets SET clones_count = 0 WHERE clones_count IS NULL;
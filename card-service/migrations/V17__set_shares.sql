@@ -0,0 +1,17 @@
+-- Sets can be shared with other users as viewers or editors. A share starts as
+-- an invitation and gives access once the invited user accepts it.
+
+CREATE TABLE IF NOT EXISTS set_shares (
+    set_id UUID NOT NULL REFERENCES card_sets(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    role VARCHAR(10) NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'pending',
+    invited_by UUID NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    responded_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (set_id, user_id),
+    CONSTRAINT set_shares_role_check CHECK (role IN ('viewer', 'editor')),
+    CONSTRAINT set_shares_status_check CHECK (status IN ('pending', 'accepted', 'declined'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_set_shares_user_status ON set_shares(user_id, status, created_at DESC);
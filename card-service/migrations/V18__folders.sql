@@ -0,0 +1,23 @@
+-- Folders let users organize their sets in a tree. Deleting a folder deletes
+-- its subfolders; the service moves the sets out of them first
+
+CREATE TABLE IF NOT EXISTS folders (
+    id UUID PRIMARY KEY,
+    owner_id UUID NOT NULL,
+    parent_id UUID REFERENCES folders(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    CONSTRAINT folders_not_own_parent CHECK (parent_id <> id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_folders_owner_parent ON folders(owner_id, parent_id);
+CREATE INDEX IF NOT EXISTS idx_folders_parent ON folders(parent_id);
+
+ALTER TABLE card_sets
+ADD COLUMN IF NOT EXISTS folder_id UUID REFERENCES folders(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_card_sets_folder_id ON card_sets(folder_id);
+
+-- A study session is over a single set, a folder, or every set of the user
+ALTER TABLE study_sessions
+ADD COLUMN IF NOT EXISTS folder_id UUID REFERENCES folders(id) ON DELETE SET NULL;
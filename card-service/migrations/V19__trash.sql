@@ -0,0 +1,13 @@
+-- Deleted sets and cards go to the trash and are purged after a retention
+-- period. A deleted set keeps its cards as they are, so restoring it brings
+-- them back; study history and progress are only lost when the purge runs
+
+ALTER TABLE card_sets
+ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE cards
+ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_card_sets_trash ON card_sets(owner_id, deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_cards_trash ON cards(set_id, deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_cards_deleted_at ON cards(deleted_at) WHERE deleted_at IS NOT NULL;
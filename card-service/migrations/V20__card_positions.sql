@@ -0,0 +1,14 @@
+-- Cards keep an explicit position within their set so users can arrange them.
+-- Existing cards are numbered in the order they were created
+
+ALTER TABLE cards
+ADD COLUMN IF NOT EXISTS position INTEGER;
+
+UPDATE cards c SET position = o.position
+FROM (SELECT id, ROW_NUMBER() OVER (PARTITION BY set_id ORDER BY created_at, id) AS position FROM cards) o
+WHERE c.id = o.id;
+
+ALTER TABLE cards
+ALTER COLUMN position SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_cards_set_position ON cards(set_id, position);
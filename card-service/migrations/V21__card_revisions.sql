@@ -0,0 +1,69 @@
+-- Every change to a card's content, set or trash state is kept as a revision
+-- holding the card as it was after the change. Writes set cards.updated_by to
+-- the user making the change; the trigger copies it into the revision
+
+ALTER TABLE cards
+ADD COLUMN IF NOT EXISTS updated_by UUID;
+
+CREATE TABLE IF NOT EXISTS card_revisions (
+    id BIGSERIAL PRIMARY KEY,
+    card_id UUID NOT NULL REFERENCES cards(id) ON DELETE CASCADE,
+    set_id UUID NOT NULL,
+    editor_id UUID,
+    action VARCHAR(16) NOT NULL,
+    front TEXT NOT NULL,
+    back TEXT NOT NULL,
+    image_url TEXT,
+    audio_url TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_card_revisions_card ON card_revisions(card_id, id);
+CREATE INDEX IF NOT EXISTS idx_card_revisions_set ON card_revisions(set_id, id);
+
+CREATE OR REPLACE FUNCTION record_card_revision()
+RETURNS TRIGGER AS $$
+DECLARE
+    change VARCHAR(16) := 'updated';
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        change := 'created';
+    ELSIF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+        change := 'deleted';
+    ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+        change := 'restored';
+    ELSIF OLD.set_id <> NEW.set_id THEN
+        change := 'moved';
+    END IF;
+
+    INSERT INTO card_revisions (card_id, set_id, editor_id, action, front, back, image_url, audio_url)
+    VALUES (NEW.id, NEW.set_id, NEW.updated_by, change, NEW.front, NEW.back, NEW.image_url, NEW.audio_url);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_cards_revision_insert ON cards;
+CREATE TRIGGER trigger_cards_revision_insert
+AFTER INSERT ON cards
+FOR EACH ROW
+EXECUTE FUNCTION record_card_revision();
+
+DROP TRIGGER IF EXISTS trigger_cards_revision_update ON cards;
+CREATE TRIGGER trigger_cards_revision_update
+AFTER UPDATE ON cards
+FOR EACH ROW
+WHEN (OLD.front IS DISTINCT FROM NEW.front OR OLD.back IS DISTINCT FROM NEW.back
+      OR OLD.image_url IS DISTINCT FROM NEW.image_url OR OLD.audio_url IS DISTINCT FROM NEW.audio_url
+      OR OLD.set_id IS DISTINCT FROM NEW.set_id OR OLD.deleted_at IS DISTINCT FROM NEW.deleted_at)
+EXECUTE FUNCTION record_card_revision();
+
+-- History starts with the cards as they are now, dated when they were created
+-- so that reverting to any earlier point keeps them
+INSERT INTO card_revisions (card_id, set_id, action, front, back, image_url, audio_url, created_at)
+SELECT id, set_id, 'created', front, back, image_url, audio_url, COALESCE(created_at, NOW())
+FROM cards;
+
+INSERT INTO card_revisions (card_id, set_id, action, front, back, image_url, audio_url, created_at)
+SELECT id, set_id, 'deleted', front, back, image_url, audio_url, deleted_at
+FROM cards
+WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,50 @@
+-- Cards with rich content (content version 2) keep it as JSON: Markdown or
+-- plain text sides, images per side, notes, hint and example. front and back
+-- hold its plain-text rendering for older clients. NULL for plain cards
+
+ALTER TABLE cards
+ADD COLUMN IF NOT EXISTS rich_content JSONB;
+
+ALTER TABLE card_revisions
+ADD COLUMN IF NOT EXISTS rich_content JSONB;
+
+-- Same hash as before for plain cards, so clones made earlier don't all show
+-- up as changed
+CREATE OR REPLACE FUNCTION card_content_hash(front TEXT, back TEXT, image_url TEXT, audio_url TEXT, rich_content JSONB)
+RETURNS TEXT AS $$
+    SELECT CASE
+        WHEN rich_content IS NULL THEN card_content_hash(front, back, image_url, audio_url)
+        ELSE md5(concat_ws(E'\x1f', front, back, COALESCE(image_url, ''), COALESCE(audio_url, ''), rich_content::text))
+    END
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE OR REPLACE FUNCTION record_card_revision()
+RETURNS TRIGGER AS $$
+DECLARE
+    change VARCHAR(16) := 'updated';
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        change := 'created';
+    ELSIF OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL THEN
+        change := 'deleted';
+    ELSIF OLD.deleted_at IS NOT NULL AND NEW.deleted_at IS NULL THEN
+        change := 'restored';
+    ELSIF OLD.set_id <> NEW.set_id THEN
+        change := 'moved';
+    END IF;
+
+    INSERT INTO card_revisions (card_id, set_id, editor_id, action, front, back, image_url, audio_url, rich_content)
+    VALUES (NEW.id, NEW.set_id, NEW.updated_by, change, NEW.front, NEW.back, NEW.image_url, NEW.audio_url, NEW.rich_content);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_cards_revision_update ON cards;
+CREATE TRIGGER trigger_cards_revision_update
+AFTER UPDATE ON cards
+FOR EACH ROW
+WHEN (OLD.front IS DISTINCT FROM NEW.front OR OLD.back IS DISTINCT FROM NEW.back
+      OR OLD.image_url IS DISTINCT FROM NEW.image_url OR OLD.audio_url IS DISTINCT FROM NEW.audio_url
+      OR OLD.rich_content IS DISTINCT FROM NEW.rich_content
+      OR OLD.set_id IS DISTINCT FROM NEW.set_id OR OLD.deleted_at IS DISTINCT FROM NEW.deleted_at)
+EXECUTE FUNCTION record_card_revision();
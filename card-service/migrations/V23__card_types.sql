@@ -0,0 +1,39 @@
+-- Cards can be basic, reversible or cloze. The type lives in rich_content
+-- together with the card's review items: both directions of a reversible card
+-- (0 and 1), or the deletion numbers of a cloze. Basic cards have the one item
+-- 0. Progress and study queues are kept per review item
+
+CREATE OR REPLACE FUNCTION card_items(rich_content JSONB)
+RETURNS SMALLINT[] AS $$
+    SELECT COALESCE(
+        (SELECT array_agg(item::smallint ORDER BY ord)
+         FROM jsonb_array_elements_text(rich_content->'items') WITH ORDINALITY AS t(item, ord)),
+        ARRAY[0]::smallint[])
+$$ LANGUAGE sql IMMUTABLE;
+
+ALTER TABLE card_progress
+ADD COLUMN IF NOT EXISTS item SMALLINT NOT NULL DEFAULT 0;
+
+ALTER TABLE card_progress DROP CONSTRAINT IF EXISTS card_progress_pkey;
+ALTER TABLE card_progress ADD PRIMARY KEY (user_id, card_id, item);
+
+ALTER TABLE study_session_cards
+ADD COLUMN IF NOT EXISTS item SMALLINT NOT NULL DEFAULT 0;
+
+ALTER TABLE study_session_cards DROP CONSTRAINT IF EXISTS study_session_cards_pkey;
+ALTER TABLE study_session_cards ADD PRIMARY KEY (session_id, card_id, item);
+
+-- The status of a whole card for a user: learned (reviewing, or mastered if
+-- every item is) once all of its items are, learning once any item was
+-- studied, new before that
+CREATE OR REPLACE FUNCTION card_status(card_id UUID, user_id UUID, rich_content JSONB)
+RETURNS VARCHAR AS $$
+    SELECT CASE
+        WHEN COUNT(*) FILTER (WHERE p.status IN ('reviewing', 'mastered')) = cardinality(card_items($3)) THEN
+            CASE WHEN bool_and(p.status = 'mastered') THEN 'mastered' ELSE 'reviewing' END
+        WHEN COUNT(*) FILTER (WHERE p.status <> 'new') > 0 THEN 'learning'
+        ELSE 'new'
+    END
+    FROM card_progress p
+    WHERE p.card_id = $1 AND p.user_id = $2 AND p.item = ANY(card_items($3))
+$$ LANGUAGE sql STABLE;
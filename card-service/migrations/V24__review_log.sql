@@ -0,0 +1,37 @@
+-- Every answer to a review item, from study sessions and quizzes, for
+-- analytics and fitting scheduler parameters. The log is append-only and
+-- outlives the cards it refers to, so it has no foreign keys
+
+CREATE TABLE IF NOT EXISTS review_log (
+    id BIGSERIAL PRIMARY KEY,
+    user_id UUID NOT NULL,
+    card_id UUID NOT NULL,
+    item SMALLINT NOT NULL DEFAULT 0,
+    set_id UUID NOT NULL,
+    source VARCHAR(16) NOT NULL,
+    session_id UUID,
+    rating INT NOT NULL,
+    status_before VARCHAR(20) NOT NULL,
+    status_after VARCHAR(20) NOT NULL,
+    previous_interval_days INT NOT NULL,
+    interval_days INT NOT NULL,
+    scheduler VARCHAR(16) NOT NULL,
+    response_time_ms BIGINT NOT NULL DEFAULT 0,
+    reviewed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_review_log_user_reviewed_at ON review_log(user_id, reviewed_at);
+CREATE INDEX IF NOT EXISTS idx_review_log_card_user ON review_log(card_id, user_id, reviewed_at);
+
+CREATE OR REPLACE FUNCTION reject_review_log_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'review_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_review_log_append_only ON review_log;
+CREATE TRIGGER trigger_review_log_append_only
+BEFORE UPDATE OR DELETE ON review_log
+FOR EACH ROW
+EXECUTE FUNCTION reject_review_log_change();
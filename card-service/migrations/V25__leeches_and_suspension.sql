@@ -0,0 +1,26 @@
+-- Review items a user keeps forgetting become leeches after a number of lapses
+-- set in the user's settings. Users can suspend items, until unsuspended, or
+-- bury them until the next day; either way they are left out of study and quizzes
+
+ALTER TABLE card_progress
+ADD COLUMN IF NOT EXISTS lapses INT NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS leech BOOLEAN NOT NULL DEFAULT FALSE,
+ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS buried_until TIMESTAMP WITH TIME ZONE;
+
+-- Lapses so far are the reviews rated again of an item that had been learned
+UPDATE card_progress p
+SET lapses = l.lapses
+FROM (
+    SELECT user_id, card_id, item, COUNT(*) AS lapses
+    FROM review_log
+    WHERE rating = 1 AND status_before IN ('reviewing', 'mastered')
+    GROUP BY user_id, card_id, item
+) l
+WHERE p.user_id = l.user_id AND p.card_id = l.card_id AND p.item = l.item;
+
+CREATE INDEX IF NOT EXISTS idx_card_progress_leech ON card_progress(user_id, card_id) WHERE leech;
+
+ALTER TABLE user_settings
+ADD COLUMN IF NOT EXISTS leech_threshold INT NOT NULL DEFAULT 8,
+ADD COLUMN IF NOT EXISTS suspend_leeches BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,4 @@
+-- A quiz answer is reviewed once. The answer is stored before the review, and
+-- a retry after a failed review must not grade the card twice
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_review_log_quiz_answer ON review_log(session_id, card_id, item) WHERE source = 'quiz';
@@ -0,0 +1,8 @@
+-- V6 approximated reps from streak, which was never stored and so was always 0.
+-- SM-2 then started reviewing and mastered items over as if they were new.
+-- Any such item with no reps still has the V6 state: every successful review
+-- counts a rep since, and a failed one makes the item learning again
+
+UPDATE card_progress
+SET reps = 2
+WHERE reps = 0 AND status IN ('reviewing', 'mastered');
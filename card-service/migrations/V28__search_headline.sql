@@ -0,0 +1,12 @@
+-- Search snippets are highlighted with the text search configuration the text
+-- matched in. Documents are indexed with both russian and english, so a word
+-- found only through the english one would not be marked with russian alone
+
+CREATE OR REPLACE FUNCTION search_headline(content TEXT, russian tsquery, english tsquery, options TEXT)
+RETURNS TEXT AS $$
+    SELECT CASE
+        WHEN NOT to_tsvector('russian', content) @@ russian AND to_tsvector('english', content) @@ english
+            THEN ts_headline('english', content, english, options)
+        ELSE ts_headline('russian', content, russian, options)
+    END
+$$ LANGUAGE sql IMMUTABLE;
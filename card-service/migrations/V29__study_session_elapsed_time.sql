@@ -0,0 +1,9 @@
+-- The wall-clock time of a study session is stored when it is finished, so the
+-- summary and statistics don't have to work it out again
+
+ALTER TABLE study_sessions
+ADD COLUMN IF NOT EXISTS elapsed_seconds BIGINT;
+
+UPDATE study_sessions
+SET elapsed_seconds = GREATEST(EXTRACT(EPOCH FROM finished_at - created_at), 0)::bigint
+WHERE finished_at IS NOT NULL;
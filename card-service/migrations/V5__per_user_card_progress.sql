@@ -0,0 +1,45 @@
+-- Learning progress is tracked per (user, card) instead of on the shared card row,
+-- so studying a public set no longer overwrites the owner's scheduling.
+
+CREATE TABLE IF NOT EXISTS card_progress (
+    user_id UUID NOT NULL,
+    card_id UUID NOT NULL REFERENCES cards(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'new',
+    error_count INT NOT NULL DEFAULT 0,
+    last_rating INT NOT NULL DEFAULT 0,
+    next_review TIMESTAMP WITH TIME ZONE,
+    streak INT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, card_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_card_progress_card_id ON card_progress(card_id);
+CREATE INDEX IF NOT EXISTS idx_card_progress_user_next_review ON card_progress(user_id, next_review);
+
+-- Progress stored on the card row so far could only have come from the set owner
+INSERT INTO card_progress (user_id, card_id, status, error_count, last_rating, next_review, streak)
+SELECT cs.owner_id,
+       c.id,
+       COALESCE(c.status, 'new'),
+       COALESCE(c.error_count, 0),
+       COALESCE(c.last_rating, 0),
+       c.next_review,
+       COALESCE(c.streak, 0)
+FROM cards c
+JOIN card_sets cs ON cs.id = c.set_id
+WHERE COALESCE(c.status, 'new') <> 'new'
+   OR c.next_review IS NOT NULL
+   OR COALESCE(c.error_count, 0) > 0
+ON CONFLICT (user_id, card_id) DO NOTHING;
+
+DROP INDEX IF EXISTS idx_cards_status;
+DROP INDEX IF EXISTS idx_cards_next_review;
+DROP INDEX IF EXISTS idx_cards_error_count;
+DROP INDEX IF EXISTS idx_cards_last_rating;
+
+ALTER TABLE cards
+DROP COLUMN IF EXISTS status,
+DROP COLUMN IF EXISTS next_review,
+DROP COLUMN IF EXISTS streak,
+DROP COLUMN IF EXISTS error_count,
+DROP COLUMN IF EXISTS last_rating;
@@ -0,0 +1,37 @@
+-- Scheduler state for SM-2 (ease) and FSRS (stability, difficulty) and four-grade ratings
+
+ALTER TABLE card_progress
+ADD COLUMN IF NOT EXISTS reps INT NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS interval_days INT NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS ease REAL NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS stability REAL NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS difficulty REAL NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS last_reviewed_at TIMESTAMP WITH TIME ZONE;
+
+-- Ratings used to be binary (0 = forgot, 1 = remember); they are now 1 = again .. 4 = easy
+-- and 0 means "not rated yet". Every existing progress row has been answered at least once.
+UPDATE card_progress
+SET last_rating = CASE WHEN last_rating = 1 THEN 3 ELSE 1 END;
+
+-- Approximate the scheduler state from the old fixed 1/3/7/30-day ladder
+UPDATE card_progress
+SET reps = streak,
+    interval_days = CASE status
+        WHEN 'learning' THEN 1
+        WHEN 'reviewing' THEN 7
+        WHEN 'mastered' THEN 30
+        ELSE 0
+    END,
+    ease = 2.5,
+    last_reviewed_at = updated_at;
+
+UPDATE card_progress
+SET stability = interval_days,
+    difficulty = 5
+WHERE interval_days > 0;
+
+CREATE TABLE IF NOT EXISTS user_settings (
+    user_id UUID PRIMARY KEY,
+    scheduler VARCHAR(20) NOT NULL DEFAULT 'sm2',
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
@@ -0,0 +1,25 @@
+-- Quiz sessions used to live in process memory; persist them so they survive
+-- restarts and work across replicas
+
+CREATE TABLE IF NOT EXISTS quiz_sessions (
+    id UUID PRIMARY KEY,
+    set_id UUID NOT NULL REFERENCES card_sets(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    questions JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    finished_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_quiz_sessions_user_id ON quiz_sessions(user_id);
+CREATE INDEX IF NOT EXISTS idx_quiz_sessions_expires_at ON quiz_sessions(expires_at);
+
+CREATE TABLE IF NOT EXISTS quiz_answers (
+    session_id UUID NOT NULL REFERENCES quiz_sessions(id) ON DELETE CASCADE,
+    question_index INT NOT NULL,
+    selected_index INT NOT NULL,
+    is_correct BOOLEAN NOT NULL,
+    time_spent_ms BIGINT NOT NULL DEFAULT 0,
+    answered_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (session_id, question_index)
+);
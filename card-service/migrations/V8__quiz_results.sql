@@ -0,0 +1,16 @@
+-- Finished quizzes are kept so users can see their quiz history per set.
+-- Results outlive the quiz session itself, which is removed once it expires.
+
+CREATE TABLE IF NOT EXISTS quiz_results (
+    session_id UUID PRIMARY KEY,
+    set_id UUID NOT NULL REFERENCES card_sets(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL,
+    total_questions INT NOT NULL,
+    correct_answers INT NOT NULL,
+    incorrect_answers INT NOT NULL,
+    score_percentage REAL NOT NULL,
+    time_spent_ms BIGINT NOT NULL DEFAULT 0,
+    finished_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_quiz_results_user_set ON quiz_results(user_id, set_id, finished_at DESC);
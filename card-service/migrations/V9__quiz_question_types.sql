@@ -0,0 +1,5 @@
+-- Typed and true/false quiz questions are answered with text or a boolean instead of an option index
+
+ALTER TABLE quiz_answers
+ADD COLUMN IF NOT EXISTS text_answer TEXT,
+ADD COLUMN IF NOT EXISTS bool_answer BOOLEAN;
@@ -1046,6 +1046,610 @@ func (*DeleteCardResponse) Descriptor() ([]byte, []int) {
 	return file_proto_card_card_proto_rawDescGZIP(), []int{17}
 }
 
+// Карточка для создания или, с card_id, для обновления
+type CardInput struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	CardId        string                 `protobuf:"bytes,1,opt,name=card_id,json=cardId,proto3" json:"card_id,omitempty"`
+	Front         string                 `protobuf:"bytes,2,opt,name=front,proto3" json:"front,omitempty"`
+	Back          string                 `protobuf:"bytes,3,opt,name=back,proto3" json:"back,omitempty"`
+	ImageUrl      string                 `protobuf:"bytes,4,opt,name=image_url,json=imageUrl,proto3" json:"image_url,omitempty"`
+	AudioUrl      string                 `protobuf:"bytes,5,opt,name=audio_url,json=audioUrl,proto3" json:"audio_url,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CardInput) Reset() {
+	*x = CardInput{}
+	mi := &file_proto_card_card_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CardInput) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CardInput) ProtoMessage() {}
+
+func (x *CardInput) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_card_card_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CardInput.ProtoReflect.Descriptor instead.
+func (*CardInput) Descriptor() ([]byte, []int) {
+	return file_proto_card_card_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *CardInput) GetCardId() string {
+	if x != nil {
+		return x.CardId
+	}
+	return ""
+}
+
+func (x *CardInput) GetFront() string {
+	if x != nil {
+		return x.Front
+	}
+	return ""
+}
+
+func (x *CardInput) GetBack() string {
+	if x != nil {
+		return x.Back
+	}
+	return ""
+}
+
+func (x *CardInput) GetImageUrl() string {
+	if x != nil {
+		return x.ImageUrl
+	}
+	return ""
+}
+
+func (x *CardInput) GetAudioUrl() string {
+	if x != nil {
+		return x.AudioUrl
+	}
+	return ""
+}
+
+// Результат по одному элементу пакета, в порядке запроса
+type BatchItemResult struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Index  int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	CardId string                 `protobuf:"bytes,2,opt,name=card_id,json=cardId,proto3" json:"card_id,omitempty"`
+	// Пусто, если элемент прошёл проверку
+	Error         string `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	Card          *Card  `protobuf:"bytes,4,opt,name=card,proto3" json:"card,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchItemResult) Reset() {
+	*x = BatchItemResult{}
+	mi := &file_proto_card_card_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchItemResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchItemResult) ProtoMessage() {}
+
+func (x *BatchItemResult) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_card_card_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchItemResult.ProtoReflect.Descriptor instead.
+func (*BatchItemResult) Descriptor() ([]byte, []int) {
+	return file_proto_card_card_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *BatchItemResult) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *BatchItemResult) GetCardId() string {
+	if x != nil {
+		return x.CardId
+	}
+	return ""
+}
+
+func (x *BatchItemResult) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *BatchItemResult) GetCard() *Card {
+	if x != nil {
+		return x.Card
+	}
+	return nil
+}
+
+type BatchCreateCardsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SetId         string                 `protobuf:"bytes,1,opt,name=set_id,json=setId,proto3" json:"set_id,omitempty"`
+	OwnerId       string                 `protobuf:"bytes,2,opt,name=owner_id,json=ownerId,proto3" json:"owner_id,omitempty"`
+	Cards         []*CardInput           `protobuf:"bytes,3,rep,name=cards,proto3" json:"cards,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchCreateCardsRequest) Reset() {
+	*x = BatchCreateCardsRequest{}
+	mi := &file_proto_card_card_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchCreateCardsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCreateCardsRequest) ProtoMessage() {}
+
+func (x *BatchCreateCardsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_card_card_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCreateCardsRequest.ProtoReflect.Descriptor instead.
+func (*BatchCreateCardsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_card_card_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *BatchCreateCardsRequest) GetSetId() string {
+	if x != nil {
+		return x.SetId
+	}
+	return ""
+}
+
+func (x *BatchCreateCardsRequest) GetOwnerId() string {
+	if x != nil {
+		return x.OwnerId
+	}
+	return ""
+}
+
+func (x *BatchCreateCardsRequest) GetCards() []*CardInput {
+	if x != nil {
+		return x.Cards
+	}
+	return nil
+}
+
+type BatchCreateCardsResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// false, если хотя бы один элемент не прошёл проверку и ничего не изменено
+	Applied       bool               `protobuf:"varint,1,opt,name=applied,proto3" json:"applied,omitempty"`
+	Results       []*BatchItemResult `protobuf:"bytes,2,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchCreateCardsResponse) Reset() {
+	*x = BatchCreateCardsResponse{}
+	mi := &file_proto_card_card_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchCreateCardsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchCreateCardsResponse) ProtoMessage() {}
+
+func (x *BatchCreateCardsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_card_card_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchCreateCardsResponse.ProtoReflect.Descriptor instead.
+func (*BatchCreateCardsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_card_card_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *BatchCreateCardsResponse) GetApplied() bool {
+	if x != nil {
+		return x.Applied
+	}
+	return false
+}
+
+func (x *BatchCreateCardsResponse) GetResults() []*BatchItemResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type BatchUpdateCardsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SetId         string                 `protobuf:"bytes,1,opt,name=set_id,json=setId,proto3" json:"set_id,omitempty"`
+	OwnerId       string                 `protobuf:"bytes,2,opt,name=owner_id,json=ownerId,proto3" json:"owner_id,omitempty"`
+	Cards         []*CardInput           `protobuf:"bytes,3,rep,name=cards,proto3" json:"cards,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchUpdateCardsRequest) Reset() {
+	*x = BatchUpdateCardsRequest{}
+	mi := &file_proto_card_card_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchUpdateCardsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchUpdateCardsRequest) ProtoMessage() {}
+
+func (x *BatchUpdateCardsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_card_card_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchUpdateCardsRequest.ProtoReflect.Descriptor instead.
+func (*BatchUpdateCardsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_card_card_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *BatchUpdateCardsRequest) GetSetId() string {
+	if x != nil {
+		return x.SetId
+	}
+	return ""
+}
+
+func (x *BatchUpdateCardsRequest) GetOwnerId() string {
+	if x != nil {
+		return x.OwnerId
+	}
+	return ""
+}
+
+func (x *BatchUpdateCardsRequest) GetCards() []*CardInput {
+	if x != nil {
+		return x.Cards
+	}
+	return nil
+}
+
+type BatchUpdateCardsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Applied       bool                   `protobuf:"varint,1,opt,name=applied,proto3" json:"applied,omitempty"`
+	Results       []*BatchItemResult     `protobuf:"bytes,2,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchUpdateCardsResponse) Reset() {
+	*x = BatchUpdateCardsResponse{}
+	mi := &file_proto_card_card_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchUpdateCardsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchUpdateCardsResponse) ProtoMessage() {}
+
+func (x *BatchUpdateCardsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_card_card_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchUpdateCardsResponse.ProtoReflect.Descriptor instead.
+func (*BatchUpdateCardsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_card_card_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *BatchUpdateCardsResponse) GetApplied() bool {
+	if x != nil {
+		return x.Applied
+	}
+	return false
+}
+
+func (x *BatchUpdateCardsResponse) GetResults() []*BatchItemResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type BatchDeleteCardsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SetId         string                 `protobuf:"bytes,1,opt,name=set_id,json=setId,proto3" json:"set_id,omitempty"`
+	OwnerId       string                 `protobuf:"bytes,2,opt,name=owner_id,json=ownerId,proto3" json:"owner_id,omitempty"`
+	CardIds       []string               `protobuf:"bytes,3,rep,name=card_ids,json=cardIds,proto3" json:"card_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchDeleteCardsRequest) Reset() {
+	*x = BatchDeleteCardsRequest{}
+	mi := &file_proto_card_card_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchDeleteCardsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchDeleteCardsRequest) ProtoMessage() {}
+
+func (x *BatchDeleteCardsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_card_card_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchDeleteCardsRequest.ProtoReflect.Descriptor instead.
+func (*BatchDeleteCardsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_card_card_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *BatchDeleteCardsRequest) GetSetId() string {
+	if x != nil {
+		return x.SetId
+	}
+	return ""
+}
+
+func (x *BatchDeleteCardsRequest) GetOwnerId() string {
+	if x != nil {
+		return x.OwnerId
+	}
+	return ""
+}
+
+func (x *BatchDeleteCardsRequest) GetCardIds() []string {
+	if x != nil {
+		return x.CardIds
+	}
+	return nil
+}
+
+type BatchDeleteCardsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Applied       bool                   `protobuf:"varint,1,opt,name=applied,proto3" json:"applied,omitempty"`
+	Results       []*BatchItemResult     `protobuf:"bytes,2,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchDeleteCardsResponse) Reset() {
+	*x = BatchDeleteCardsResponse{}
+	mi := &file_proto_card_card_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchDeleteCardsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchDeleteCardsResponse) ProtoMessage() {}
+
+func (x *BatchDeleteCardsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_card_card_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchDeleteCardsResponse.ProtoReflect.Descriptor instead.
+func (*BatchDeleteCardsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_card_card_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *BatchDeleteCardsResponse) GetApplied() bool {
+	if x != nil {
+		return x.Applied
+	}
+	return false
+}
+
+func (x *BatchDeleteCardsResponse) GetResults() []*BatchItemResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type BatchMoveCardsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SetId         string                 `protobuf:"bytes,1,opt,name=set_id,json=setId,proto3" json:"set_id,omitempty"`
+	OwnerId       string                 `protobuf:"bytes,2,opt,name=owner_id,json=ownerId,proto3" json:"owner_id,omitempty"`
+	CardIds       []string               `protobuf:"bytes,3,rep,name=card_ids,json=cardIds,proto3" json:"card_ids,omitempty"`
+	TargetSetId   string                 `protobuf:"bytes,4,opt,name=target_set_id,json=targetSetId,proto3" json:"target_set_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchMoveCardsRequest) Reset() {
+	*x = BatchMoveCardsRequest{}
+	mi := &file_proto_card_card_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchMoveCardsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchMoveCardsRequest) ProtoMessage() {}
+
+func (x *BatchMoveCardsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_card_card_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchMoveCardsRequest.ProtoReflect.Descriptor instead.
+func (*BatchMoveCardsRequest) Descriptor() ([]byte, []int) {
+	return file_proto_card_card_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *BatchMoveCardsRequest) GetSetId() string {
+	if x != nil {
+		return x.SetId
+	}
+	return ""
+}
+
+func (x *BatchMoveCardsRequest) GetOwnerId() string {
+	if x != nil {
+		return x.OwnerId
+	}
+	return ""
+}
+
+func (x *BatchMoveCardsRequest) GetCardIds() []string {
+	if x != nil {
+		return x.CardIds
+	}
+	return nil
+}
+
+func (x *BatchMoveCardsRequest) GetTargetSetId() string {
+	if x != nil {
+		return x.TargetSetId
+	}
+	return ""
+}
+
+type BatchMoveCardsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Applied       bool                   `protobuf:"varint,1,opt,name=applied,proto3" json:"applied,omitempty"`
+	Results       []*BatchItemResult     `protobuf:"bytes,2,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchMoveCardsResponse) Reset() {
+	*x = BatchMoveCardsResponse{}
+	mi := &file_proto_card_card_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchMoveCardsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchMoveCardsResponse) ProtoMessage() {}
+
+func (x *BatchMoveCardsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_card_card_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchMoveCardsResponse.ProtoReflect.Descriptor instead.
+func (*BatchMoveCardsResponse) Descriptor() ([]byte, []int) {
+	return file_proto_card_card_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *BatchMoveCardsResponse) GetApplied() bool {
+	if x != nil {
+		return x.Applied
+	}
+	return false
+}
+
+func (x *BatchMoveCardsResponse) GetResults() []*BatchItemResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
 var File_proto_card_card_proto protoreflect.FileDescriptor
 
 const file_proto_card_card_proto_rawDesc = "" +
@@ -1123,7 +1727,48 @@ const file_proto_card_card_proto_rawDesc = "" +
 	".card.CardR\x04card\",\n" +
 	"\x11DeleteCardRequest\x12\x17\n" +
 	"\acard_id\x18\x01 \x01(\tR\x06cardId\"\x14\n" +
-	"\x12DeleteCardResponse2\xa7\x04\n" +
+	"\x12DeleteCardResponse\"\x88\x01\n" +
+	"\tCardInput\x12\x17\n" +
+	"\acard_id\x18\x01 \x01(\tR\x06cardId\x12\x14\n" +
+	"\x05front\x18\x02 \x01(\tR\x05front\x12\x12\n" +
+	"\x04back\x18\x03 \x01(\tR\x04back\x12\x1b\n" +
+	"\timage_url\x18\x04 \x01(\tR\bimageUrl\x12\x1b\n" +
+	"\taudio_url\x18\x05 \x01(\tR\baudioUrl\"v\n" +
+	"\x0fBatchItemResult\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12\x17\n" +
+	"\acard_id\x18\x02 \x01(\tR\x06cardId\x12\x14\n" +
+	"\x05error\x18\x03 \x01(\tR\x05error\x12\x1e\n" +
+	"\x04card\x18\x04 \x01(\v2\n" +
+	".card.CardR\x04card\"r\n" +
+	"\x17BatchCreateCardsRequest\x12\x15\n" +
+	"\x06set_id\x18\x01 \x01(\tR\x05setId\x12\x19\n" +
+	"\bowner_id\x18\x02 \x01(\tR\aownerId\x12%\n" +
+	"\x05cards\x18\x03 \x03(\v2\x0f.card.CardInputR\x05cards\"e\n" +
+	"\x18BatchCreateCardsResponse\x12\x18\n" +
+	"\aapplied\x18\x01 \x01(\bR\aapplied\x12/\n" +
+	"\aresults\x18\x02 \x03(\v2\x15.card.BatchItemResultR\aresults\"r\n" +
+	"\x17BatchUpdateCardsRequest\x12\x15\n" +
+	"\x06set_id\x18\x01 \x01(\tR\x05setId\x12\x19\n" +
+	"\bowner_id\x18\x02 \x01(\tR\aownerId\x12%\n" +
+	"\x05cards\x18\x03 \x03(\v2\x0f.card.CardInputR\x05cards\"e\n" +
+	"\x18BatchUpdateCardsResponse\x12\x18\n" +
+	"\aapplied\x18\x01 \x01(\bR\aapplied\x12/\n" +
+	"\aresults\x18\x02 \x03(\v2\x15.card.BatchItemResultR\aresults\"f\n" +
+	"\x17BatchDeleteCardsRequest\x12\x15\n" +
+	"\x06set_id\x18\x01 \x01(\tR\x05setId\x12\x19\n" +
+	"\bowner_id\x18\x02 \x01(\tR\aownerId\x12\x19\n" +
+	"\bcard_ids\x18\x03 \x03(\tR\acardIds\"e\n" +
+	"\x18BatchDeleteCardsResponse\x12\x18\n" +
+	"\aapplied\x18\x01 \x01(\bR\aapplied\x12/\n" +
+	"\aresults\x18\x02 \x03(\v2\x15.card.BatchItemResultR\aresults\"\x88\x01\n" +
+	"\x15BatchMoveCardsRequest\x12\x15\n" +
+	"\x06set_id\x18\x01 \x01(\tR\x05setId\x12\x19\n" +
+	"\bowner_id\x18\x02 \x01(\tR\aownerId\x12\x19\n" +
+	"\bcard_ids\x18\x03 \x03(\tR\acardIds\x12\"\n" +
+	"\rtarget_set_id\x18\x04 \x01(\tR\vtargetSetId\"c\n" +
+	"\x16BatchMoveCardsResponse\x12\x18\n" +
+	"\aapplied\x18\x01 \x01(\bR\aapplied\x12/\n" +
+	"\aresults\x18\x02 \x03(\v2\x15.card.BatchItemResultR\aresults2\xed\x06\n" +
 	"\vCardService\x12H\n" +
 	"\rCreateCardSet\x12\x1a.card.CreateCardSetRequest\x1a\x1b.card.CreateCardSetResponse\x12?\n" +
 	"\n" +
@@ -1136,7 +1781,11 @@ const file_proto_card_card_proto_rawDesc = "" +
 	"\n" +
 	"UpdateCard\x12\x17.card.UpdateCardRequest\x1a\x18.card.UpdateCardResponse\x12?\n" +
 	"\n" +
-	"DeleteCard\x12\x17.card.DeleteCardRequest\x1a\x18.card.DeleteCardResponseB=Z;github.com/karto4ki/karto4ki-backend/shared/proto/card;cardb\x06proto3"
+	"DeleteCard\x12\x17.card.DeleteCardRequest\x1a\x18.card.DeleteCardResponse\x12Q\n" +
+	"\x10BatchCreateCards\x12\x1d.card.BatchCreateCardsRequest\x1a\x1e.card.BatchCreateCardsResponse\x12Q\n" +
+	"\x10BatchUpdateCards\x12\x1d.card.BatchUpdateCardsRequest\x1a\x1e.card.BatchUpdateCardsResponse\x12Q\n" +
+	"\x10BatchDeleteCards\x12\x1d.card.BatchDeleteCardsRequest\x1a\x1e.card.BatchDeleteCardsResponse\x12K\n" +
+	"\x0eBatchMoveCards\x12\x1b.card.BatchMoveCardsRequest\x1a\x1c.card.BatchMoveCardsResponseB=Z;github.com/karto4ki/karto4ki-backend/shared/proto/card;cardb\x06proto3"
 
 var (
 	file_proto_card_card_proto_rawDescOnce sync.Once
@@ -1150,60 +1799,85 @@ func file_proto_card_card_proto_rawDescGZIP() []byte {
 	return file_proto_card_card_proto_rawDescData
 }
 
-var file_proto_card_card_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
+var file_proto_card_card_proto_msgTypes = make([]protoimpl.MessageInfo, 28)
 var file_proto_card_card_proto_goTypes = []any{
-	(*CardSet)(nil),               // 0: card.CardSet
-	(*CreateCardSetRequest)(nil),  // 1: card.CreateCardSetRequest
-	(*CreateCardSetResponse)(nil), // 2: card.CreateCardSetResponse
-	(*GetCardSetRequest)(nil),     // 3: card.GetCardSetRequest
-	(*GetCardSetResponse)(nil),    // 4: card.GetCardSetResponse
-	(*UpdateCardSetRequest)(nil),  // 5: card.UpdateCardSetRequest
-	(*UpdateCardSetResponse)(nil), // 6: card.UpdateCardSetResponse
-	(*DeleteCardSetRequest)(nil),  // 7: card.DeleteCardSetRequest
-	(*DeleteCardSetResponse)(nil), // 8: card.DeleteCardSetResponse
-	(*Card)(nil),                  // 9: card.Card
-	(*CreateCardRequest)(nil),     // 10: card.CreateCardRequest
-	(*CreateCardResponse)(nil),    // 11: card.CreateCardResponse
-	(*GetCardRequest)(nil),        // 12: card.GetCardRequest
-	(*GetCardResponse)(nil),       // 13: card.GetCardResponse
-	(*UpdateCardRequest)(nil),     // 14: card.UpdateCardRequest
-	(*UpdateCardResponse)(nil),    // 15: card.UpdateCardResponse
-	(*DeleteCardRequest)(nil),     // 16: card.DeleteCardRequest
-	(*DeleteCardResponse)(nil),    // 17: card.DeleteCardResponse
-	(*timestamppb.Timestamp)(nil), // 18: google.protobuf.Timestamp
+	(*CardSet)(nil),                  // 0: card.CardSet
+	(*CreateCardSetRequest)(nil),     // 1: card.CreateCardSetRequest
+	(*CreateCardSetResponse)(nil),    // 2: card.CreateCardSetResponse
+	(*GetCardSetRequest)(nil),        // 3: card.GetCardSetRequest
+	(*GetCardSetResponse)(nil),       // 4: card.GetCardSetResponse
+	(*UpdateCardSetRequest)(nil),     // 5: card.UpdateCardSetRequest
+	(*UpdateCardSetResponse)(nil),    // 6: card.UpdateCardSetResponse
+	(*DeleteCardSetRequest)(nil),     // 7: card.DeleteCardSetRequest
+	(*DeleteCardSetResponse)(nil),    // 8: card.DeleteCardSetResponse
+	(*Card)(nil),                     // 9: card.Card
+	(*CreateCardRequest)(nil),        // 10: card.CreateCardRequest
+	(*CreateCardResponse)(nil),       // 11: card.CreateCardResponse
+	(*GetCardRequest)(nil),           // 12: card.GetCardRequest
+	(*GetCardResponse)(nil),          // 13: card.GetCardResponse
+	(*UpdateCardRequest)(nil),        // 14: card.UpdateCardRequest
+	(*UpdateCardResponse)(nil),       // 15: card.UpdateCardResponse
+	(*DeleteCardRequest)(nil),        // 16: card.DeleteCardRequest
+	(*DeleteCardResponse)(nil),       // 17: card.DeleteCardResponse
+	(*CardInput)(nil),                // 18: card.CardInput
+	(*BatchItemResult)(nil),          // 19: card.BatchItemResult
+	(*BatchCreateCardsRequest)(nil),  // 20: card.BatchCreateCardsRequest
+	(*BatchCreateCardsResponse)(nil), // 21: card.BatchCreateCardsResponse
+	(*BatchUpdateCardsRequest)(nil),  // 22: card.BatchUpdateCardsRequest
+	(*BatchUpdateCardsResponse)(nil), // 23: card.BatchUpdateCardsResponse
+	(*BatchDeleteCardsRequest)(nil),  // 24: card.BatchDeleteCardsRequest
+	(*BatchDeleteCardsResponse)(nil), // 25: card.BatchDeleteCardsResponse
+	(*BatchMoveCardsRequest)(nil),    // 26: card.BatchMoveCardsRequest
+	(*BatchMoveCardsResponse)(nil),   // 27: card.BatchMoveCardsResponse
+	(*timestamppb.Timestamp)(nil),    // 28: google.protobuf.Timestamp
 }
 var file_proto_card_card_proto_depIdxs = []int32{
-	18, // 0: card.CardSet.created_at:type_name -> google.protobuf.Timestamp
-	18, // 1: card.CardSet.updated_at:type_name -> google.protobuf.Timestamp
+	28, // 0: card.CardSet.created_at:type_name -> google.protobuf.Timestamp
+	28, // 1: card.CardSet.updated_at:type_name -> google.protobuf.Timestamp
 	0,  // 2: card.CreateCardSetResponse.set:type_name -> card.CardSet
 	0,  // 3: card.GetCardSetResponse.set:type_name -> card.CardSet
 	0,  // 4: card.UpdateCardSetResponse.set:type_name -> card.CardSet
-	18, // 5: card.Card.created_at:type_name -> google.protobuf.Timestamp
-	18, // 6: card.Card.updated_at:type_name -> google.protobuf.Timestamp
+	28, // 5: card.Card.created_at:type_name -> google.protobuf.Timestamp
+	28, // 6: card.Card.updated_at:type_name -> google.protobuf.Timestamp
 	9,  // 7: card.CreateCardResponse.card:type_name -> card.Card
 	9,  // 8: card.GetCardResponse.card:type_name -> card.Card
 	9,  // 9: card.UpdateCardResponse.card:type_name -> card.Card
-	1,  // 10: card.CardService.CreateCardSet:input_type -> card.CreateCardSetRequest
-	3,  // 11: card.CardService.GetCardSet:input_type -> card.GetCardSetRequest
-	5,  // 12: card.CardService.UpdateCardSet:input_type -> card.UpdateCardSetRequest
-	7,  // 13: card.CardService.DeleteCardSet:input_type -> card.DeleteCardSetRequest
-	10, // 14: card.CardService.CreateCard:input_type -> card.CreateCardRequest
-	12, // 15: card.CardService.GetCard:input_type -> card.GetCardRequest
-	14, // 16: card.CardService.UpdateCard:input_type -> card.UpdateCardRequest
-	16, // 17: card.CardService.DeleteCard:input_type -> card.DeleteCardRequest
-	2,  // 18: card.CardService.CreateCardSet:output_type -> card.CreateCardSetResponse
-	4,  // 19: card.CardService.GetCardSet:output_type -> card.GetCardSetResponse
-	6,  // 20: card.CardService.UpdateCardSet:output_type -> card.UpdateCardSetResponse
-	8,  // 21: card.CardService.DeleteCardSet:output_type -> card.DeleteCardSetResponse
-	11, // 22: card.CardService.CreateCard:output_type -> card.CreateCardResponse
-	13, // 23: card.CardService.GetCard:output_type -> card.GetCardResponse
-	15, // 24: card.CardService.UpdateCard:output_type -> card.UpdateCardResponse
-	17, // 25: card.CardService.DeleteCard:output_type -> card.DeleteCardResponse
-	18, // [18:26] is the sub-list for method output_type
-	10, // [10:18] is the sub-list for method input_type
-	10, // [10:10] is the sub-list for extension type_name
-	10, // [10:10] is the sub-list for extension extendee
-	0,  // [0:10] is the sub-list for field type_name
+	9,  // 10: card.BatchItemResult.card:type_name -> card.Card
+	18, // 11: card.BatchCreateCardsRequest.cards:type_name -> card.CardInput
+	19, // 12: card.BatchCreateCardsResponse.results:type_name -> card.BatchItemResult
+	18, // 13: card.BatchUpdateCardsRequest.cards:type_name -> card.CardInput
+	19, // 14: card.BatchUpdateCardsResponse.results:type_name -> card.BatchItemResult
+	19, // 15: card.BatchDeleteCardsResponse.results:type_name -> card.BatchItemResult
+	19, // 16: card.BatchMoveCardsResponse.results:type_name -> card.BatchItemResult
+	1,  // 17: card.CardService.CreateCardSet:input_type -> card.CreateCardSetRequest
+	3,  // 18: card.CardService.GetCardSet:input_type -> card.GetCardSetRequest
+	5,  // 19: card.CardService.UpdateCardSet:input_type -> card.UpdateCardSetRequest
+	7,  // 20: card.CardService.DeleteCardSet:input_type -> card.DeleteCardSetRequest
+	10, // 21: card.CardService.CreateCard:input_type -> card.CreateCardRequest
+	12, // 22: card.CardService.GetCard:input_type -> card.GetCardRequest
+	14, // 23: card.CardService.UpdateCard:input_type -> card.UpdateCardRequest
+	16, // 24: card.CardService.DeleteCard:input_type -> card.DeleteCardRequest
+	20, // 25: card.CardService.BatchCreateCards:input_type -> card.BatchCreateCardsRequest
+	22, // 26: card.CardService.BatchUpdateCards:input_type -> card.BatchUpdateCardsRequest
+	24, // 27: card.CardService.BatchDeleteCards:input_type -> card.BatchDeleteCardsRequest
+	26, // 28: card.CardService.BatchMoveCards:input_type -> card.BatchMoveCardsRequest
+	2,  // 29: card.CardService.CreateCardSet:output_type -> card.CreateCardSetResponse
+	4,  // 30: card.CardService.GetCardSet:output_type -> card.GetCardSetResponse
+	6,  // 31: card.CardService.UpdateCardSet:output_type -> card.UpdateCardSetResponse
+	8,  // 32: card.CardService.DeleteCardSet:output_type -> card.DeleteCardSetResponse
+	11, // 33: card.CardService.CreateCard:output_type -> card.CreateCardResponse
+	13, // 34: card.CardService.GetCard:output_type -> card.GetCardResponse
+	15, // 35: card.CardService.UpdateCard:output_type -> card.UpdateCardResponse
+	17, // 36: card.CardService.DeleteCard:output_type -> card.DeleteCardResponse
+	21, // 37: card.CardService.BatchCreateCards:output_type -> card.BatchCreateCardsResponse
+	23, // 38: card.CardService.BatchUpdateCards:output_type -> card.BatchUpdateCardsResponse
+	25, // 39: card.CardService.BatchDeleteCards:output_type -> card.BatchDeleteCardsResponse
+	27, // 40: card.CardService.BatchMoveCards:output_type -> card.BatchMoveCardsResponse
+	29, // [29:41] is the sub-list for method output_type
+	17, // [17:29] is the sub-list for method input_type
+	17, // [17:17] is the sub-list for extension type_name
+	17, // [17:17] is the sub-list for extension extendee
+	0,  // [0:17] is the sub-list for field type_name
 }
 
 func init() { file_proto_card_card_proto_init() }
@@ -1217,7 +1891,7 @@ func file_proto_card_card_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_card_card_proto_rawDesc), len(file_proto_card_card_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   18,
+			NumMessages:   28,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
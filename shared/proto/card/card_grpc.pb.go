@@ -19,14 +19,18 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	CardService_CreateCardSet_FullMethodName = "/card.CardService/CreateCardSet"
-	CardService_GetCardSet_FullMethodName    = "/card.CardService/GetCardSet"
-	CardService_UpdateCardSet_FullMethodName = "/card.CardService/UpdateCardSet"
-	CardService_DeleteCardSet_FullMethodName = "/card.CardService/DeleteCardSet"
-	CardService_CreateCard_FullMethodName    = "/card.CardService/CreateCard"
-	CardService_GetCard_FullMethodName       = "/card.CardService/GetCard"
-	CardService_UpdateCard_FullMethodName    = "/card.CardService/UpdateCard"
-	CardService_DeleteCard_FullMethodName    = "/card.CardService/DeleteCard"
+	CardService_CreateCardSet_FullMethodName    = "/card.CardService/CreateCardSet"
+	CardService_GetCardSet_FullMethodName       = "/card.CardService/GetCardSet"
+	CardService_UpdateCardSet_FullMethodName    = "/card.CardService/UpdateCardSet"
+	CardService_DeleteCardSet_FullMethodName    = "/card.CardService/DeleteCardSet"
+	CardService_CreateCard_FullMethodName       = "/card.CardService/CreateCard"
+	CardService_GetCard_FullMethodName          = "/card.CardService/GetCard"
+	CardService_UpdateCard_FullMethodName       = "/card.CardService/UpdateCard"
+	CardService_DeleteCard_FullMethodName       = "/card.CardService/DeleteCard"
+	CardService_BatchCreateCards_FullMethodName = "/card.CardService/BatchCreateCards"
+	CardService_BatchUpdateCards_FullMethodName = "/card.CardService/BatchUpdateCards"
+	CardService_BatchDeleteCards_FullMethodName = "/card.CardService/BatchDeleteCards"
+	CardService_BatchMoveCards_FullMethodName   = "/card.CardService/BatchMoveCards"
 )
 
 // CardServiceClient is the client API for CardService service.
@@ -45,6 +49,11 @@ type CardServiceClient interface {
 	GetCard(ctx context.Context, in *GetCardRequest, opts ...grpc.CallOption) (*GetCardResponse, error)
 	UpdateCard(ctx context.Context, in *UpdateCardRequest, opts ...grpc.CallOption) (*UpdateCardResponse, error)
 	DeleteCard(ctx context.Context, in *DeleteCardRequest, opts ...grpc.CallOption) (*DeleteCardResponse, error)
+	// Пакетные операции: выполняются в одной транзакции целиком или не выполняются вовсе
+	BatchCreateCards(ctx context.Context, in *BatchCreateCardsRequest, opts ...grpc.CallOption) (*BatchCreateCardsResponse, error)
+	BatchUpdateCards(ctx context.Context, in *BatchUpdateCardsRequest, opts ...grpc.CallOption) (*BatchUpdateCardsResponse, error)
+	BatchDeleteCards(ctx context.Context, in *BatchDeleteCardsRequest, opts ...grpc.CallOption) (*BatchDeleteCardsResponse, error)
+	BatchMoveCards(ctx context.Context, in *BatchMoveCardsRequest, opts ...grpc.CallOption) (*BatchMoveCardsResponse, error)
 }
 
 type cardServiceClient struct {
@@ -135,6 +144,46 @@ func (c *cardServiceClient) DeleteCard(ctx context.Context, in *DeleteCardReques
 	return out, nil
 }
 
+func (c *cardServiceClient) BatchCreateCards(ctx context.Context, in *BatchCreateCardsRequest, opts ...grpc.CallOption) (*BatchCreateCardsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchCreateCardsResponse)
+	err := c.cc.Invoke(ctx, CardService_BatchCreateCards_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cardServiceClient) BatchUpdateCards(ctx context.Context, in *BatchUpdateCardsRequest, opts ...grpc.CallOption) (*BatchUpdateCardsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchUpdateCardsResponse)
+	err := c.cc.Invoke(ctx, CardService_BatchUpdateCards_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cardServiceClient) BatchDeleteCards(ctx context.Context, in *BatchDeleteCardsRequest, opts ...grpc.CallOption) (*BatchDeleteCardsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchDeleteCardsResponse)
+	err := c.cc.Invoke(ctx, CardService_BatchDeleteCards_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *cardServiceClient) BatchMoveCards(ctx context.Context, in *BatchMoveCardsRequest, opts ...grpc.CallOption) (*BatchMoveCardsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchMoveCardsResponse)
+	err := c.cc.Invoke(ctx, CardService_BatchMoveCards_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // CardServiceServer is the server API for CardService service.
 // All implementations must embed UnimplementedCardServiceServer
 // for forward compatibility.
@@ -151,6 +200,11 @@ type CardServiceServer interface {
 	GetCard(context.Context, *GetCardRequest) (*GetCardResponse, error)
 	UpdateCard(context.Context, *UpdateCardRequest) (*UpdateCardResponse, error)
 	DeleteCard(context.Context, *DeleteCardRequest) (*DeleteCardResponse, error)
+	// Пакетные операции: выполняются в одной транзакции целиком или не выполняются вовсе
+	BatchCreateCards(context.Context, *BatchCreateCardsRequest) (*BatchCreateCardsResponse, error)
+	BatchUpdateCards(context.Context, *BatchUpdateCardsRequest) (*BatchUpdateCardsResponse, error)
+	BatchDeleteCards(context.Context, *BatchDeleteCardsRequest) (*BatchDeleteCardsResponse, error)
+	BatchMoveCards(context.Context, *BatchMoveCardsRequest) (*BatchMoveCardsResponse, error)
 	mustEmbedUnimplementedCardServiceServer()
 }
 
@@ -185,6 +239,18 @@ func (UnimplementedCardServiceServer) UpdateCard(context.Context, *UpdateCardReq
 func (UnimplementedCardServiceServer) DeleteCard(context.Context, *DeleteCardRequest) (*DeleteCardResponse, error) {
 	return nil, status.Error(codes.Unimplemented, "method DeleteCard not implemented")
 }
+func (UnimplementedCardServiceServer) BatchCreateCards(context.Context, *BatchCreateCardsRequest) (*BatchCreateCardsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method BatchCreateCards not implemented")
+}
+func (UnimplementedCardServiceServer) BatchUpdateCards(context.Context, *BatchUpdateCardsRequest) (*BatchUpdateCardsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method BatchUpdateCards not implemented")
+}
+func (UnimplementedCardServiceServer) BatchDeleteCards(context.Context, *BatchDeleteCardsRequest) (*BatchDeleteCardsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method BatchDeleteCards not implemented")
+}
+func (UnimplementedCardServiceServer) BatchMoveCards(context.Context, *BatchMoveCardsRequest) (*BatchMoveCardsResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method BatchMoveCards not implemented")
+}
 func (UnimplementedCardServiceServer) mustEmbedUnimplementedCardServiceServer() {}
 func (UnimplementedCardServiceServer) testEmbeddedByValue()                     {}
 
@@ -350,6 +416,78 @@ func _CardService_DeleteCard_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _CardService_BatchCreateCards_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchCreateCardsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CardServiceServer).BatchCreateCards(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CardService_BatchCreateCards_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CardServiceServer).BatchCreateCards(ctx, req.(*BatchCreateCardsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CardService_BatchUpdateCards_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchUpdateCardsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CardServiceServer).BatchUpdateCards(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CardService_BatchUpdateCards_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CardServiceServer).BatchUpdateCards(ctx, req.(*BatchUpdateCardsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CardService_BatchDeleteCards_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchDeleteCardsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CardServiceServer).BatchDeleteCards(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CardService_BatchDeleteCards_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CardServiceServer).BatchDeleteCards(ctx, req.(*BatchDeleteCardsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _CardService_BatchMoveCards_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchMoveCardsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(CardServiceServer).BatchMoveCards(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: CardService_BatchMoveCards_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(CardServiceServer).BatchMoveCards(ctx, req.(*BatchMoveCardsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // CardService_ServiceDesc is the grpc.ServiceDesc for CardService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -389,6 +527,22 @@ var CardService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteCard",
 			Handler:    _CardService_DeleteCard_Handler,
 		},
+		{
+			MethodName: "BatchCreateCards",
+			Handler:    _CardService_BatchCreateCards_Handler,
+		},
+		{
+			MethodName: "BatchUpdateCards",
+			Handler:    _CardService_BatchUpdateCards_Handler,
+		},
+		{
+			MethodName: "BatchDeleteCards",
+			Handler:    _CardService_BatchDeleteCards_Handler,
+		},
+		{
+			MethodName: "BatchMoveCards",
+			Handler:    _CardService_BatchMoveCards_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/card/card.proto",